@@ -0,0 +1,21 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package cachecmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+)
+
+// NewCacheCommand returns a cobra command for `cache` subcommands
+func NewCacheCommand(cmdCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of downloaded artifacts",
+	}
+	cmd.AddCommand(
+		NewPurgeCommand(cmdCli),
+	)
+	return cmd
+}
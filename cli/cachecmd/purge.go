@@ -0,0 +1,32 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package cachecmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+)
+
+// NewPurgeCommand returns a command which removes all cache entries
+func NewPurgeCommand(cliContext cli.Cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "purge",
+		Short: "Remove all downloaded artifacts from the cache",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			artifactCache := cliContext.GetCache()
+			reclaimed, err := artifactCache.Purge()
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Purged cache. dir=%s, reclaimed=%s\n", artifactCache.Dir, units.HumanSize(float64(reclaimed)))
+			return err
+		},
+	}
+}
@@ -0,0 +1,21 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package configcmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+)
+
+// NewConfigCommand returns a cobra command for `config` subcommands
+func NewConfigCommand(cmdCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	cmd.AddCommand(
+		NewEnvCommand(cmdCli),
+	)
+	return cmd
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package configcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+)
+
+// NewEnvCommand returns a command which prints the environment variable of each configuration key
+func NewEnvCommand(cliContext cli.Cli) *cobra.Command {
+	outputJSON := false
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Print the environment variable and effective value of each configuration key",
+		Long: `Print the environment variable which can be used to set each configuration key,
+along with the key's effective value (from the defaults, configuration file, profile, environment or flags).
+
+Structured settings, e.g. monitor.metrics.exporters, can be set to a json value.
+`,
+		Example: `tedge-container config env
+tedge-container config env --json`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			settings := cliContext.GetEnvSettings()
+
+			stdout := cmd.OutOrStdout()
+			if outputJSON {
+				b, err := json.MarshalIndent(settings, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(stdout, "%s\n", b)
+				return err
+			}
+
+			for _, setting := range settings {
+				fmt.Fprintln(stdout, strings.Join([]string{
+					setting.Key,
+					setting.Env,
+					formatValue(setting.Value),
+				}, "\t"))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "Print the settings as json")
+	return cmd
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case []any, map[string]any:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/archive"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// NewArchiveCommand represents the archive command
+func NewArchiveCommand(cliContext cli.Cli) *cobra.Command {
+	outputJSON := false
+	cmd := &cobra.Command{
+		Use:   "archive [CONTAINER_NAME]",
+		Short: "Show the last known state of removed containers",
+		Long: `Show when the removed containers were last seen and their final state, as recorded by the monitor.
+
+The records are read from the local archive file, and are kept for the configured retention period (monitor.archive.retention).
+`,
+		Example: `tedge-container container archive
+tedge-container container archive nginx --json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			containerArchive, err := cliContext.GetArchive()
+			if err != nil {
+				return err
+			}
+			if containerArchive == nil {
+				return fmt.Errorf("container archive is disabled. Enable it using monitor.archive.enabled")
+			}
+
+			records := containerArchive.List(time.Now())
+			if len(args) > 0 {
+				filtered := make([]archive.Record, 0, 1)
+				for _, record := range records {
+					if record.Name == args[0] {
+						filtered = append(filtered, record)
+					}
+				}
+				if len(filtered) == 0 {
+					return fmt.Errorf("archive record %w. name=%s", container.ErrNotFound, args[0])
+				}
+				records = filtered
+			}
+
+			stdout := cmd.OutOrStdout()
+			if outputJSON {
+				b, err := json.MarshalIndent(records, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(stdout, "%s\n", b)
+				return err
+			}
+			for _, record := range records {
+				columns := []string{
+					record.Name,
+					record.Image,
+					record.State,
+					"lastSeen=" + record.LastSeen.Local().Format(time.RFC3339),
+					"removedAt=" + record.RemovedAt.Local().Format(time.RFC3339),
+				}
+				fmt.Fprintln(stdout, strings.Join(columns, "\t"))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "Print the records as json")
+	return cmd
+}
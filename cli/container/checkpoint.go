@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// NewCheckpointCommand represents the checkpoint command
+func NewCheckpointCommand(cliContext cli.Cli) *cobra.Command {
+	opts := container.CheckpointOptions{}
+	cmd := &cobra.Command{
+		Use:   "checkpoint <CONTAINER_NAME>",
+		Short: "Create a checkpoint of a running container (experimental)",
+		Long: `Create a checkpoint (CRIU) of a running container, so that it can be restored later using the restore command.
+
+The container engine must have the experimental features enabled and CRIU must be installed.
+The container is stopped after the checkpoint has been created, unless --leave-running is used.
+`,
+		Example: `tedge-container container checkpoint app1
+tedge-container container checkpoint app1 --id before-update --leave-running`,
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			ctx := context.Background()
+			containerName := args[0]
+			if opts.Dir == "" {
+				opts.Dir = cliContext.GetCheckpointDir()
+			}
+
+			containerCli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+
+			checkpointID, err := containerCli.CreateCheckpoint(ctx, containerName, opts)
+			cliContext.Audit(audit.Entry{
+				Action:  audit.ActionCheckpoint,
+				Type:    container.ContainerType,
+				Name:    containerName,
+				Version: checkpointID,
+			}, err)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), checkpointID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&opts.ID, "id", "", "Checkpoint name. Defaults to <CONTAINER_NAME>-<timestamp>")
+	cmd.Flags().StringVar(&opts.Dir, "dir", "", "Custom checkpoint directory")
+	cmd.Flags().BoolVar(&opts.LeaveRunning, "leave-running", false, "Keep the container running after creating the checkpoint")
+	return cmd
+}
+
+// NewCheckpointsCommand represents the checkpoints command
+func NewCheckpointsCommand(cliContext cli.Cli) *cobra.Command {
+	dir := ""
+	cmd := &cobra.Command{
+		Use:     "checkpoints <CONTAINER_NAME>",
+		Short:   "List the checkpoints of a container (experimental)",
+		Example: `tedge-container container checkpoints app1`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			if dir == "" {
+				dir = cliContext.GetCheckpointDir()
+			}
+			containerCli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			names, err := containerCli.ListCheckpoints(context.Background(), args[0], dir)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Fprintln(cmd.OutOrStdout(), name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", "", "Custom checkpoint directory")
+	return cmd
+}
+
+// NewRestoreCommand represents the restore command
+func NewRestoreCommand(cliContext cli.Cli) *cobra.Command {
+	opts := container.CheckpointOptions{}
+	cmd := &cobra.Command{
+		Use:   "restore <CONTAINER_NAME>",
+		Short: "Restore a stopped container from a checkpoint (experimental)",
+		Long: `Start a stopped container from a checkpoint which was created using the checkpoint command.
+
+The container engine must have the experimental features enabled and CRIU must be installed.
+`,
+		Example:     `tedge-container container restore app1 --id before-update`,
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			ctx := context.Background()
+			containerName := args[0]
+			if opts.Dir == "" {
+				opts.Dir = cliContext.GetCheckpointDir()
+			}
+
+			containerCli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			err = containerCli.RestoreCheckpoint(ctx, containerName, opts)
+			cliContext.Audit(audit.Entry{
+				Action:  audit.ActionRestore,
+				Type:    container.ContainerType,
+				Name:    containerName,
+				Version: opts.ID,
+			}, err)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&opts.ID, "id", "", "Checkpoint name")
+	cmd.Flags().StringVar(&opts.Dir, "dir", "", "Custom checkpoint directory")
+	_ = cmd.MarkFlagRequired("id")
+	return cmd
+}
@@ -18,6 +18,15 @@ func NewContainerCommand(cmdCli cli.Cli) *cobra.Command {
 		NewUpdateListCommand(cmdCli),
 		NewListCommand(cmdCli),
 		NewFinalizeCommand(cmdCli),
+		NewInspectCommand(cmdCli),
+		NewHistoryCommand(cmdCli),
+		NewArchiveCommand(cmdCli),
+		NewCheckpointCommand(cmdCli),
+		NewCheckpointsCommand(cmdCli),
+		NewRestoreCommand(cmdCli),
+		NewExportCommand(cmdCli),
+		NewExplainFilterCommand(cmdCli),
+		NewStatsCommand(cmdCli),
 	)
 	return cmd
 }
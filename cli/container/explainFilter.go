@@ -0,0 +1,76 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// NewExplainFilterCommand represents the explain-filter command
+func NewExplainFilterCommand(cliContext cli.Cli) *cobra.Command {
+	outputJSON := false
+	cmd := &cobra.Command{
+		Use:   "explain-filter <CONTAINER_NAME_OR_ID>",
+		Short: "Explain why a container is included or excluded by the filters",
+		Long: `Evaluate the configured include, exclude and type filters against a container, and print
+which rule matched or excluded it.
+
+The filters are evaluated in the same order as they are applied by the monitor.
+`,
+		Example: `tedge-container container explain-filter nginx
+tedge-container container explain-filter 3f4e8c1a --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			cli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			explanation, err := cli.ExplainFilter(context.Background(), args[0], cliContext.GetFilterOptions())
+			if err != nil {
+				return err
+			}
+
+			stdout := cmd.OutOrStdout()
+			if outputJSON {
+				b, err := json.MarshalIndent(explanation, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(stdout, "%s\n", b)
+				return err
+			}
+
+			for _, step := range explanation.Steps {
+				columns := []string{
+					step.Filter,
+					step.Result,
+				}
+				if len(step.Values) > 0 {
+					columns = append(columns, "values="+strings.Join(step.Values, ","))
+				}
+				if step.Reason != "" {
+					columns = append(columns, step.Reason)
+				}
+				fmt.Fprintln(stdout, strings.Join(columns, "\t"))
+			}
+			result := "excluded"
+			if explanation.Included {
+				result = "included"
+			}
+			fmt.Fprintf(stdout, "\nContainer %s (type=%s) is %s\n", explanation.Name, explanation.ServiceType, result)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "Print the explanation as json")
+	return cmd
+}
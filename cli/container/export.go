@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+type ExportCommand struct {
+	*cobra.Command
+
+	Filesystem bool
+	Output     string
+	Upload     bool
+	UploadPath string
+}
+
+// NewExportCommand represents the export command
+func NewExportCommand(cliContext cli.Cli) *cobra.Command {
+	command := &ExportCommand{}
+	cmd := &cobra.Command{
+		Use:   "export <CONTAINER_NAME>",
+		Short: "Save a container's image or filesystem to a tarball",
+		Long: `Save the image (or the filesystem) of a container to a tarball, for example to capture the exact binaries
+which were involved in a field incident.
+
+The image is saved by its id if the container's image reference now points to a different image.
+The tarball can optionally be uploaded to the thin-edge.io file transfer service.
+`,
+		Example: `tedge-container container export app1
+tedge-container container export app1 --filesystem --output /tmp/app1.tar
+tedge-container container export app1 --upload`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			ctx := context.Background()
+			containerName := args[0]
+
+			exportType := container.ExportImage
+			if command.Filesystem {
+				exportType = container.ExportFilesystem
+			}
+			filename := fmt.Sprintf("%s-%s-%s.tar", containerName, exportType, time.Now().UTC().Format("20060102T150405Z"))
+			output := command.Output
+			if output == "" {
+				output = filepath.Join(os.TempDir(), filename)
+			}
+
+			containerCli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			info, err := containerCli.Export(ctx, containerName, exportType, file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(output)
+				return err
+			}
+			slog.Info("Exported container.", "name", containerName, "type", exportType, "path", output)
+
+			result := map[string]any{
+				"name":    info.Name,
+				"type":    info.Type,
+				"image":   info.Image,
+				"imageId": info.ImageID,
+				"path":    output,
+			}
+
+			if command.Upload {
+				uploadPath := command.UploadPath
+				if uploadPath == "" {
+					uploadPath = "container-export/" + filepath.Base(output)
+				}
+				fileURL, err := uploadExport(ctx, cliContext, output, uploadPath)
+				if err != nil {
+					return err
+				}
+				slog.Info("Uploaded container export.", "url", fileURL)
+				result["url"] = fileURL
+			}
+
+			b, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", b)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&command.Filesystem, "filesystem", false, "Export the container's filesystem (including runtime changes) instead of its image")
+	cmd.Flags().StringVarP(&command.Output, "output", "o", "", "Output file. Defaults to a file in the temp directory")
+	cmd.Flags().BoolVar(&command.Upload, "upload", false, "Upload the tarball to the thin-edge.io file transfer service")
+	cmd.Flags().StringVar(&command.UploadPath, "upload-path", "", "Path in the file transfer repository. Defaults to container-export/<file>")
+
+	viper.SetDefault("client.http.host", "127.0.0.1")
+	viper.SetDefault("client.http.port", 8000)
+	command.Command = cmd
+	return cmd
+}
+
+func uploadExport(ctx context.Context, cliContext cli.Cli, path string, uploadPath string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	client := tedge.NewFileTransferClient(cliContext.GetFileTransferHost(), cliContext.GetFileTransferPort(), cliContext.GetTedgeClientConfig())
+	return client.Upload(ctx, uploadPath, file)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/firewall"
+)
+
+// Allow access to the ports published by a container (if the firewall integration is enabled).
+// The ports are read from the running container, as the engine assigns random host ports if no host port is given
+func allowPublishedPorts(ctx context.Context, cliContext cli.Cli, cli *container.ContainerClient, containerName string) error {
+	fw, err := cliContext.GetFirewall()
+	if err != nil || fw == nil {
+		return err
+	}
+	return firewall.AllowContainer(ctx, fw, cli, containerName)
+}
+
+// Remove the firewall rules of a container (if the firewall integration is enabled)
+func removeFirewallRules(ctx context.Context, cliContext cli.Cli, containerName string) {
+	fw, err := cliContext.GetFirewall()
+	if err == nil && fw != nil {
+		err = fw.Remove(ctx, containerName)
+	}
+	if err != nil {
+		// non critical error
+		slog.Warn("Could not remove firewall rules.", "container", containerName, "err", err)
+	}
+}
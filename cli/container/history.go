@@ -0,0 +1,73 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+)
+
+// NewHistoryCommand represents the history command
+func NewHistoryCommand(cliContext cli.Cli) *cobra.Command {
+	since := time.Duration(0)
+	outputJSON := false
+	cmd := &cobra.Command{
+		Use:   "history <CONTAINER_NAME>",
+		Short: "Show the recent state transitions of a container",
+		Long: `Show the recent state transitions (e.g. started, died, stopped) of a container as recorded by the monitor.
+
+The transitions are read from the local history file, so the monitor does not need to be connected.
+`,
+		Example: `tedge-container container history nginx
+tedge-container container history nginx --since 12h --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			containerHistory, err := cliContext.GetHistory()
+			if err != nil {
+				return err
+			}
+			if containerHistory == nil {
+				return fmt.Errorf("container history is disabled. Enable it using monitor.history.enabled")
+			}
+
+			start := time.Time{}
+			if since > 0 {
+				start = time.Now().Add(-since)
+			}
+			items := containerHistory.Get(args[0], start)
+
+			stdout := cmd.OutOrStdout()
+			if outputJSON {
+				b, err := json.MarshalIndent(items, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(stdout, "%s\n", b)
+				return err
+			}
+			for _, item := range items {
+				columns := []string{
+					item.Time.Local().Format(time.RFC3339),
+					item.Action,
+					item.Image,
+				}
+				if item.ExitCode != "" {
+					columns = append(columns, "exitCode="+item.ExitCode)
+				}
+				fmt.Fprintln(stdout, strings.Join(columns, "\t"))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show transitions within the given duration, e.g. 12h")
+	cmd.Flags().BoolVar(&outputJSON, "json", false, "Print the transitions as json")
+	return cmd
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// NewInspectCommand represents the inspect command
+func NewInspectCommand(cliContext cli.Cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <SERVICE_NAME>",
+		Short: "Inspect a container by its thin-edge.io service name",
+		Long: `Inspect a container by its thin-edge.io service name and print the container information
+as it is published by the monitor.
+
+The service name of a container which belongs to a container-group is in the format <project>@<service>.
+`,
+		Example: `tedge-container container inspect nginx
+tedge-container container inspect app1@web`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Debug("Executing", "cmd", cmd.CalledAs(), "args", args)
+			cli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			item, err := cli.FindByServiceName(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			b, err := json.MarshalIndent(item, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", b)
+			return err
+		},
+	}
+}
@@ -6,10 +6,13 @@ package container
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"strings"
+	"time"
 
 	containerSDK "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -17,8 +20,10 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
 )
 
 type InstallCommand struct {
@@ -27,6 +32,9 @@ type InstallCommand struct {
 	CommandContext cli.Cli
 	ModuleVersion  string
 	File           string
+
+	// Consecutive pull failures of each image. nil = not tracked
+	PullFailures *container.PullFailures
 }
 
 type ImageResponse struct {
@@ -39,118 +47,277 @@ func NewInstallCommand(ctx cli.Cli) *cobra.Command {
 		CommandContext: ctx,
 	}
 	cmd := &cobra.Command{
-		Use:   "install <MODULE_NAME>",
-		Short: "Install/run a container",
-		Args:  cobra.ExactArgs(1),
-		RunE:  command.RunE,
+		Use:         "install <MODULE_NAME>",
+		Short:       "Install/run a container",
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
+		RunE:        command.RunE,
 	}
 
 	cmd.Flags().StringVar(&command.ModuleVersion, "module-version", "", "Software version to install")
 	cmd.Flags().StringVar(&command.File, "file", "", "File")
-	viper.SetDefault("container.alwaysPull", false)
+	viper.SetDefault("container.always_pull", false)
+	viper.SetDefault("container.default_image.policy", string(container.DefaultImagePolicyFail))
+	viper.SetDefault("container.default_image.template", "")
+	viper.SetDefault("container.stop_timeout", "0s")
+	viper.SetDefault("container.security.user", "")
+	viper.SetDefault("container.security.userns_mode", "")
+	viper.SetDefault("container.security.forbid_root", false)
 	command.Command = cmd
 	return cmd
 }
 
 func (c *InstallCommand) RunE(cmd *cobra.Command, args []string) error {
 	slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
-	commonNetwork := c.CommandContext.GetSharedContainerNetwork()
-	containerName := args[0]
-	imageRef := c.ModuleVersion
-
 	cli, err := container.NewContainerClient()
 	if err != nil {
 		return err
 	}
+	cli.Registries = c.CommandContext.GetRegistries()
+	cli.Mirrors = c.CommandContext.GetMirrors()
+	cli.StopTimeout = c.CommandContext.GetStopTimeout()
+	cli.Containerized = c.CommandContext.Containerized()
+
+	c.PullFailures = c.CommandContext.GetPullFailures()
 
 	ctx := context.Background()
+	if err := c.CommandContext.WaitForMaintenanceWindow(ctx, args[0]); err != nil {
+		return err
+	}
+	return c.Install(ctx, cli, args[0], c.ModuleVersion, c.File, false)
+}
+
+// Validate the container name and image reference, and return the normalized image reference.
+// The image reference is optional if the image is loaded from a file, otherwise
+// the default image policy is used. The image reference can also be the url of the file
+func ValidateInstallArgs(containerName string, imageRef string, file string, defaults container.DefaultImageOptions) (string, error) {
+	if err := container.ValidateContainerName(containerName); err != nil {
+		return "", err
+	}
+	if err := container.ValidateHostname(containerName); err != nil {
+		slog.Warn("Container name is not a valid hostname, so other containers may not be able to resolve it by name. Use a network alias instead.", "name", containerName)
+	}
+	if imageRef == "" {
+		if file != "" {
+			return imageRef, nil
+		}
+		return defaults.ImageRef(containerName)
+	}
+	if file == "" {
+		// The image (or container options) file is downloaded during the install
+		if _, ok, err := container.ParseArtifact(imageRef); ok {
+			return imageRef, err
+		}
+	}
+	return container.ParseImageRef(imageRef)
+}
+
+// Load an image from a file and return the loaded image reference.
+// If the reference can't be detected then the given image reference is returned
+func (c *InstallCommand) loadImage(ctx context.Context, cli *container.ContainerClient, path string, imageRef string) (string, error) {
+	slog.Info("Loading image from file.", "file", path)
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-	if c.File != "" {
-		slog.Info("Loading image from file.", "file", c.File)
-		file, err := os.Open(c.File)
+	imageResp, err := cli.Client.ImageLoad(ctx, file, true)
+	if err != nil {
+		return "", err
+	}
+	defer imageResp.Body.Close()
+	if imageResp.JSON {
+		b, err := io.ReadAll(imageResp.Body)
+		if err != nil {
+			return imageRef, nil
+		}
+		imageDetails := &ImageResponse{}
+		if err := json.Unmarshal(b, &imageDetails); err != nil {
+			return "", err
+		}
+
+		if strings.HasPrefix(imageDetails.Stream, "Loaded image: ") {
+			imageRef = strings.TrimPrefix(imageDetails.Stream, "Loaded image: ")
+			slog.Info("Using imageRef from loaded image.", "name", imageRef)
+		}
+		slog.Info("Loaded image.", "stream", imageDetails.Stream)
+	}
+	return imageRef, nil
+}
+
+// Pull the image if it is not present (or if it should always be pulled)
+func (c *InstallCommand) PullImage(ctx context.Context, cli *container.ContainerClient, containerName string, imageRef string) error {
+	images, err := cli.Client.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", imageRef)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 || c.CommandContext.GetBool("container.always_pull") {
+		slog.Info("Pulling image.", "ref", imageRef)
+		err := cli.ImagePull(ctx, imageRef, image.PullOptions{}, os.Stderr)
+		c.recordPullResult(containerName, imageRef, err)
 		if err != nil {
 			return err
 		}
+	} else {
+		slog.Info("Image already exists.", "ref", imageRef, "id", images[0].ID, "tags", images[0].RepoTags)
+	}
+	return nil
+}
+
+// Install a container and record the result in the audit log
+func (c *InstallCommand) Install(ctx context.Context, cli *container.ContainerClient, containerName string, imageRef string, file string, skipPull bool) error {
+	err := c.install(ctx, cli, containerName, imageRef, file, skipPull)
+	c.CommandContext.Audit(audit.Entry{
+		Action:  audit.ActionInstall,
+		Type:    container.ContainerType,
+		Name:    containerName,
+		Version: imageRef,
+		Images:  cli.GetContainerImageDigests(ctx, container.NameFilter(containerName)),
+	}, err)
+	return err
+}
 
-		imageResp, err := cli.Client.ImageLoad(ctx, file, true)
+// Install a container. The image pull can be skipped if it was already pulled beforehand.
+// The file can either be an image file or a json file containing the container options.
+// If no file is given, then the image reference can also be the url of the file (http(s):// or file://)
+func (c *InstallCommand) install(ctx context.Context, cli *container.ContainerClient, containerName string, imageRef string, file string, skipPull bool) error {
+	if file == "" {
+		path, cleanup, ok, err := c.CommandContext.FetchArtifact(ctx, imageRef)
 		if err != nil {
 			return err
 		}
-		defer imageResp.Body.Close()
-		if imageResp.JSON {
-			b, err := io.ReadAll(imageResp.Body)
-			if err != nil {
-				return nil
-			}
-			imageDetails := &ImageResponse{}
-			if err := json.Unmarshal(b, &imageDetails); err != nil {
-				return err
-			}
+		if ok {
+			defer cleanup()
+			file = path
+			imageRef = ""
+		}
+	}
 
-			if strings.HasPrefix(imageDetails.Stream, "Loaded image: ") {
-				imageRef = strings.TrimPrefix(imageDetails.Stream, "Loaded image: ")
-				slog.Info("Using imageRef from loaded image.", "name", imageRef)
+	options := &container.ContainerOptions{}
+	if file != "" {
+		fileOptions, ok, err := container.ReadContainerOptions(file)
+		if err != nil {
+			return err
+		}
+		if ok {
+			slog.Info("Using container options from file.", "file", file)
+			options = fileOptions
+			if imageRef == "" {
+				imageRef = options.Image
 			}
-			slog.Info("Loaded image.", "stream", imageDetails.Stream)
+			file = ""
 		}
 	}
 
+	imageRef, err := ValidateInstallArgs(containerName, imageRef, file, c.CommandContext.GetDefaultImageOptions())
+	if err != nil {
+		return err
+	}
+
+	commonNetwork := c.CommandContext.GetSharedContainerNetwork()
+
+	if file != "" {
+		ref, err := c.loadImage(ctx, cli, file, imageRef)
+		if err != nil {
+			return err
+		}
+		imageRef = ref
+	}
+
 	// Create shared network
-	if err := cli.CreateSharedNetwork(ctx, commonNetwork); err != nil {
+	if err := cli.CreateSharedNetwork(ctx, commonNetwork, c.CommandContext.GetSharedNetworkOptions()); err != nil {
 		return err
 	}
 
-	//
-	// Check and pull image if it is not present
-	images, err := cli.Client.ImageList(ctx, image.ListOptions{
-		Filters: filters.NewArgs(filters.Arg("reference", imageRef)),
-	})
+	// Create the dedicated networks of the container, which are removed together with the container
+	dedicatedNetworks, err := options.DedicatedNetworks(commonNetwork)
 	if err != nil {
 		return err
 	}
-
-	if len(images) == 0 || c.CommandContext.GetBool("container.alwaysPull") {
-		slog.Info("Pulling image.", "ref", imageRef)
-		out, err := cli.Client.ImagePull(ctx, imageRef, image.PullOptions{})
-		if err != nil {
+	for _, name := range dedicatedNetworks {
+		if err := cli.CreateNetwork(ctx, name); err != nil {
 			return err
 		}
-		defer out.Close()
-		if _, ioErr := io.Copy(os.Stderr, out); ioErr != nil {
-			slog.Warn("Could not write to stderr.", "err", ioErr)
+	}
+
+	//
+	// Check and pull image if it is not present
+	if !skipPull {
+		if err := c.PullImage(ctx, cli, containerName, imageRef); err != nil {
+			return err
 		}
-	} else {
-		slog.Info("Image already exists.", "ref", imageRef, "id", images[0].ID, "tags", images[0].RepoTags)
 	}
 
 	//
-	// Stop/remove any existing images with the same name
-	if err := cli.StopRemoveContainer(ctx, containerName); err != nil {
-		slog.Warn("Could not stop and remove the existing container.", "err", err)
+	// New container configuration
+	containerConfig := &containerSDK.Config{
+		Image: imageRef,
+		Labels: map[string]string{
+			container.LabelManaged:  "true",
+			container.LabelImageRef: imageRef,
+		},
+	}
+
+	// Record the digest so that the exact image being used can be reported
+	if digest, err := cli.GetImageDigest(ctx, imageRef); err != nil {
+		slog.Warn("Could not get image digest.", "ref", imageRef, "err", err)
+	} else if digest != "" {
+		containerConfig.Labels[container.LabelImageDigest] = digest
+	}
+
+	hostConfig := &containerSDK.HostConfig{
+		PublishAllPorts: true,
+	}
+	if err := options.Apply(containerConfig, hostConfig); err != nil {
+		return err
+	}
+	if err := cli.ApplyUserOptions(ctx, c.CommandContext.GetUserOptions(), containerConfig, hostConfig); err != nil {
+		return err
+	}
+	if containerConfig.StopTimeout == nil && cli.StopTimeout > 0 {
+		seconds := int(math.Ceil(cli.StopTimeout.Seconds()))
+		containerConfig.StopTimeout = &seconds
+	}
+
+	endpoint, err := options.NetworkEndpoint(commonNetwork)
+	if err != nil {
+		return err
+	}
+
+	// Check for port conflicts before replacing the existing container
+	conflicts, err := cli.CheckPortConflicts(ctx, containerName, container.GetHostPorts(hostConfig.PortBindings))
+	if err != nil {
+		return err
+	}
+	if err := container.PortConflictError(conflicts); err != nil {
 		return err
 	}
 
 	//
-	// Create new container
-	containerConfig := &containerSDK.Config{
-		Image:  imageRef,
-		Labels: map[string]string{},
+	// Stop/remove any existing images with the same name. The existing container
+	// is given the new container's stop timeout if it is longer than its own
+	stopTimeout := cli.StopTimeout
+	if containerConfig.StopTimeout != nil {
+		stopTimeout = max(stopTimeout, time.Duration(*containerConfig.StopTimeout)*time.Second)
+	}
+	if err := cli.StopRemoveContainerWithTimeout(ctx, containerName, stopTimeout); err != nil {
+		slog.Warn("Could not stop and remove the existing container.", "err", err)
+		return err
 	}
 
+	//
+	// Create new container
 	resp, err := cli.Client.ContainerCreate(
 		ctx,
 		containerConfig,
-		&containerSDK.HostConfig{
-			PublishAllPorts: true,
-			RestartPolicy: containerSDK.RestartPolicy{
-				Name: containerSDK.RestartPolicyAlways,
-			},
-		},
+		hostConfig,
 		&network.NetworkingConfig{
 			EndpointsConfig: map[string]*network.EndpointSettings{
-				commonNetwork: {
-					NetworkID: commonNetwork,
-				},
+				commonNetwork: endpoint,
 			},
 		},
 		nil,
@@ -160,10 +327,63 @@ func (c *InstallCommand) RunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Older engines only support connecting to a single network when creating the container
+	for _, name := range dedicatedNetworks {
+		if err := cli.Client.NetworkConnect(ctx, name, resp.ID, &network.EndpointSettings{Aliases: endpoint.Aliases}); err != nil {
+			return err
+		}
+	}
+
 	if err := cli.Client.ContainerStart(ctx, resp.ID, containerSDK.StartOptions{}); err != nil {
 		return err
 	}
 
+	// The firewall rules can only be added once the container is started, as the engine assigns
+	// the random host ports on start. Don't leave the container running if they can't be added
+	if err := allowPublishedPorts(ctx, c.CommandContext, cli, containerName); err != nil {
+		slog.Warn("Could not add firewall rules, removing the container.", "name", containerName, "err", err)
+		if removeErr := cli.StopRemoveContainer(ctx, containerName); removeErr != nil {
+			slog.Warn("Could not remove the container.", "name", containerName, "err", removeErr)
+		}
+		removeFirewallRules(ctx, c.CommandContext, containerName)
+		return err
+	}
+
 	slog.Info("created container.", "id", resp.ID, "name", containerName)
 	return nil
 }
+
+// Count the consecutive pull failures of the image, and raise an alarm with the categorized reason
+// once the threshold is reached. The alarm is cleared by the next successful pull
+func (c *InstallCommand) recordPullResult(containerName string, imageRef string, pullErr error) {
+	threshold := c.CommandContext.GetPullFailureThreshold()
+	if c.PullFailures == nil || threshold == 0 {
+		return
+	}
+	previous, count, err := c.PullFailures.Record(imageRef, pullErr)
+	if err != nil {
+		slog.Warn("Could not save the image pull failures.", "err", err)
+	}
+	if count < threshold && previous < threshold {
+		return
+	}
+
+	topic := tedge.GetAlarmTopic(c.CommandContext.GetDeviceTarget(), "container_image_pull_failed_"+containerName)
+	payload := []byte{}
+	if pullErr != nil {
+		reason := container.CategorizePullError(pullErr)
+		payload, err = tedge.PayloadAlarm(tedge.NewClock(c.CommandContext.GetTimeMode()), map[string]any{
+			"reason":   reason,
+			"image":    imageRef,
+			"failures": count,
+		}, fmt.Sprintf("Failed to pull image. name=%s, image=%s, reason=%s, failures=%d, err=%s", containerName, imageRef, reason, count, pullErr), tedge.AlarmSeverityMajor)
+		if err != nil {
+			slog.Warn("Could not marshal alarm payload.", "err", err)
+			return
+		}
+	}
+
+	if err := c.CommandContext.Publish("install#"+containerName, topic, true, payload); err != nil {
+		slog.Warn("Could not publish image pull alarm.", "topic", topic, "err", err)
+	}
+}
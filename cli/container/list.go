@@ -5,21 +5,62 @@ package container
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/docker/docker/api/types/events"
 	"github.com/spf13/cobra"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
 )
 
+type ListCommand struct {
+	*cobra.Command
+
+	ShowSource bool
+	ShowDigest bool
+	Watch      bool
+	JSON       bool
+}
+
+// Line which is printed by the list command in json mode (one line per change when watching)
+type ListOutput struct {
+	Time       time.Time       `json:"time"`
+	Action     string          `json:"action,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Containers []ListContainer `json:"containers"`
+}
+
+type ListContainer struct {
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	Status string `json:"status"`
+	Source string `json:"source,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
 // listCmd represents the list command
 func NewListCommand(cliContext cli.Cli) *cobra.Command {
-	return &cobra.Command{
+	command := &ListCommand{}
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List containers",
-		Args:  cobra.ExactArgs(0),
+		Long: `List the containers which match the configured filters.
+
+Use --watch to continuously update the list when the container engine reports changes,
+which shows what the monitor sees in real-time (e.g. for debugging the filters).
+`,
+		Example: `tedge-container container list
+tedge-container container list --watch
+tedge-container container list --watch --json`,
+		Args: cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
 			ctx := context.Background()
@@ -27,18 +68,142 @@ func NewListCommand(cliContext cli.Cli) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			containers, err := cli.List(ctx, cliContext.GetFilterOptions())
-			if err != nil {
-				return err
-			}
 			stdout := cmd.OutOrStdout()
-			for _, item := range containers {
-				if item.ServiceType == container.ContainerType {
-					version := item.Container.Image[strings.LastIndex(item.Container.Image, "/")+1:]
-					fmt.Fprintf(stdout, "%s\t%s\n", item.Name, version)
-				}
+			if !command.Watch {
+				return command.render(ctx, stdout, cli, cliContext.GetFilterOptions(), events.Message{})
 			}
-			return nil
+
+			ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			return command.watch(ctx, stdout, cli, cliContext.GetFilterOptions())
 		},
 	}
+	cmd.Flags().BoolVar(&command.ShowSource, "show-source", false, "Include whether the container is managed by thin-edge.io or external")
+	cmd.Flags().BoolVar(&command.ShowDigest, "show-digest", false, "Include the digest of the container's image")
+	cmd.Flags().BoolVar(&command.Watch, "watch", false, "Watch the container engine events and update the list on changes")
+	cmd.Flags().BoolVar(&command.JSON, "json", false, "Print the list as json (one line per change when watching)")
+	command.Command = cmd
+	return cmd
+}
+
+// Re-render the list each time the container engine reports a container change
+func (c *ListCommand) watch(ctx context.Context, w io.Writer, cli *container.ContainerClient, filterOptions container.FilterOptions) error {
+	evtCh, errCh := cli.MonitorEvents(ctx)
+	if err := c.render(ctx, w, cli, filterOptions, events.Message{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return err
+		case evt := <-evtCh:
+			if _, ok := containerActions[evt.Action]; !ok || evt.Type != events.ContainerEventType {
+				continue
+			}
+			if err := c.render(ctx, w, cli, filterOptions, evt); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Container actions which change the list
+var containerActions = map[events.Action]struct{}{
+	events.ActionCreate:  {},
+	events.ActionStart:   {},
+	events.ActionStop:    {},
+	events.ActionDie:     {},
+	events.ActionDestroy: {},
+	events.ActionRemove:  {},
+	events.ActionPause:   {},
+	events.ActionUnPause: {},
+	events.ActionRename:  {},
+}
+
+func (c *ListCommand) render(ctx context.Context, w io.Writer, cli *container.ContainerClient, filterOptions container.FilterOptions, evt events.Message) error {
+	containers, err := cli.List(ctx, filterOptions)
+	if err != nil {
+		return err
+	}
+
+	items := make([]ListContainer, 0, len(containers))
+	for _, item := range containers {
+		if item.ServiceType != container.ContainerType {
+			continue
+		}
+		image := item.Container.Image
+
+		// Report pinned images by the requested digest so that the version matches what was installed
+		if ref := item.Container.Labels[container.LabelImageRef]; strings.Contains(ref, "@") {
+			image = ref
+		}
+		listItem := ListContainer{
+			Name:   item.Name,
+			Image:  image[strings.LastIndex(image, "/")+1:],
+			Status: item.Container.State,
+		}
+		if c.ShowSource {
+			listItem.Source = item.Container.Source()
+		}
+		if c.ShowDigest {
+			listItem.Digest = item.Container.ImageDigest
+		}
+		items = append(items, listItem)
+	}
+
+	if c.JSON {
+		b, err := json.Marshal(ListOutput{
+			Time:       time.Now(),
+			Action:     string(evt.Action),
+			Name:       evt.Actor.Attributes["name"],
+			Containers: items,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	if c.Watch {
+		header := time.Now().Format(time.RFC3339)
+		if evt.Action != "" {
+			header += fmt.Sprintf(" (container %s. name=%s)", evt.Action, evt.Actor.Attributes["name"])
+		}
+		if isTerminal(w) {
+			// Clear the screen so that the list is re-rendered in place
+			fmt.Fprint(w, "\033[H\033[2J")
+		} else {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, header)
+	}
+	for _, item := range items {
+		columns := []string{
+			item.Name,
+			item.Image,
+		}
+		if c.Watch {
+			columns = append(columns, item.Status)
+		}
+		if c.ShowSource {
+			columns = append(columns, item.Source)
+		}
+		if c.ShowDigest {
+			columns = append(columns, item.Digest)
+		}
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+	return nil
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
 }
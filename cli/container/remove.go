@@ -8,6 +8,8 @@ import (
 	"log/slog"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
 )
@@ -16,15 +18,17 @@ type RemoveCommand struct {
 	*cobra.Command
 
 	ModuleVersion string
+	Force         bool
 }
 
 // removeCmd represents the remove command
-func NewRemoveCommand(ctx cli.Cli) *cobra.Command {
+func NewRemoveCommand(cliContext cli.Cli) *cobra.Command {
 	command := &RemoveCommand{}
 	cmd := &cobra.Command{
-		Use:   "remove",
-		Short: "Remove a container",
-		Args:  cobra.ExactArgs(1),
+		Use:         "remove",
+		Short:       "Remove a container",
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
 			ctx := context.Background()
@@ -34,10 +38,51 @@ func NewRemoveCommand(ctx cli.Cli) *cobra.Command {
 			if err != nil {
 				return err
 			}
-
-			return cli.StopRemoveContainer(ctx, containerName)
+			cli.StopTimeout = cliContext.GetStopTimeout()
+			return RemoveContainer(ctx, cliContext, cli, containerName, command.Force)
 		},
 	}
 	cmd.Flags().StringVar(&command.ModuleVersion, "module-version", "", "Software version to remove")
+	cmd.Flags().BoolVar(&command.Force, "force", false, "Remove the container even if it is protected")
+	cmd.Flags().Bool("remove-image", false, "Remove the container's image if it is no longer used")
+	cmd.Flags().Bool("remove-network", true, "Remove the container's dedicated networks if they are no longer used (use --remove-network=false to keep them)")
+
+	viper.SetDefault("container.remove_image", false)
+	viper.SetDefault("container.remove_network", true)
+	_ = viper.BindPFlag("container.remove_image", cmd.Flags().Lookup("remove-image"))
+	_ = viper.BindPFlag("container.remove_network", cmd.Flags().Lookup("remove-network"))
 	return cmd
 }
+
+// Remove a container and record the result in the audit log
+func RemoveContainer(ctx context.Context, cliContext cli.Cli, cli *container.ContainerClient, containerName string, force bool) error {
+	images := cli.GetContainerImageDigests(ctx, container.NameFilter(containerName))
+	err := removeContainer(ctx, cliContext, cli, containerName, force)
+	cliContext.Audit(audit.Entry{
+		Action: audit.ActionRemove,
+		Type:   container.ContainerType,
+		Name:   containerName,
+		Images: images,
+	}, err)
+	return err
+}
+
+// Remove a container (and its associated resources) unless it is protected
+func removeContainer(ctx context.Context, cliContext cli.Cli, cli *container.ContainerClient, containerName string, force bool) error {
+	if !force {
+		if err := cli.CheckProtected(ctx, containerName, cliContext.GetProtectionOptions()); err != nil {
+			return err
+		}
+	}
+
+	// Only remove the image if it is requested, or if images should be pruned anyway
+	if err := cli.RemoveContainer(ctx, containerName, container.RemoveOptions{
+		RemoveImage:    cliContext.GetBool("container.remove_image") || cliContext.GetBool("container.prune_images"),
+		RemoveNetworks: cliContext.GetBool("container.remove_network"),
+		SharedNetwork:  cliContext.GetSharedContainerNetwork(),
+	}); err != nil {
+		return err
+	}
+	removeFirewallRules(ctx, cliContext, containerName)
+	return nil
+}
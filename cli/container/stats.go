@@ -0,0 +1,209 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+type StatsCommand struct {
+	*cobra.Command
+
+	Watch    bool
+	Interval time.Duration
+	JSON     bool
+}
+
+// Line which is printed by the stats command in json mode (one line per container and sample).
+// The container field is the same as the resource_usage measurement which is published by the monitor
+type StatsOutput struct {
+	Time      time.Time                `json:"time"`
+	Name      string                   `json:"name"`
+	Container container.ContainerStats `json:"container"`
+	BlockIO   StatsBlockIO             `json:"blkio"`
+}
+
+type StatsBlockIO struct {
+	Read  float64 `json:"read"`
+	Write float64 `json:"write"`
+}
+
+// NewStatsCommand represents the stats command
+func NewStatsCommand(cliContext cli.Cli) *cobra.Command {
+	command := &StatsCommand{}
+	cmd := &cobra.Command{
+		Use:   "stats [CONTAINER_NAME...]",
+		Short: "Show the resource usage of the containers",
+		Long: `Show the resource usage of the containers which match the configured filters (or the given containers).
+
+The cpu, memory and netio values are normalized in the same way as the resource_usage measurements which are
+published by the monitor, so they can be compared with the values in the cloud. The block io (read/write bytes)
+is only shown locally.
+`,
+		Example: `tedge-container container stats
+tedge-container container stats nginx --watch
+tedge-container container stats --watch --interval 30s --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			ctx := context.Background()
+			cli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			stdout := cmd.OutOrStdout()
+			if !command.Watch {
+				return command.render(ctx, stdout, cli, cliContext.GetFilterOptions(), args)
+			}
+
+			ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			return command.watch(ctx, stdout, cli, cliContext.GetFilterOptions(), args)
+		},
+	}
+	cmd.Flags().BoolVar(&command.Watch, "watch", false, "Continuously show the resource usage")
+	cmd.Flags().DurationVar(&command.Interval, "interval", 10*time.Second, "Interval between the samples when watching")
+	cmd.Flags().BoolVar(&command.JSON, "json", false, "Print the stats as json (one line per container and sample)")
+	command.Command = cmd
+	return cmd
+}
+
+// Re-render the stats after each interval
+func (c *StatsCommand) watch(ctx context.Context, w io.Writer, cli *container.ContainerClient, filterOptions container.FilterOptions, names []string) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0. interval=%s", c.Interval)
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.render(ctx, w, cli, filterOptions, names); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get the containers to show. Without names, all containers matching the filters are shown except
+// the swarm task containers, which the monitor does not collect the metrics of
+func (c *StatsCommand) selectContainers(items []container.TedgeContainer, names []string) ([]container.TedgeContainer, error) {
+	selected := make([]container.TedgeContainer, 0, len(items))
+	for _, item := range items {
+		if len(names) == 0 && item.Container.StackName == "" {
+			selected = append(selected, item)
+		} else if slices.Contains(names, item.Name) || slices.Contains(names, item.Container.Name) {
+			selected = append(selected, item)
+		}
+	}
+	if c.Watch {
+		// Containers can be recreated while watching, so only show the ones which currently exist
+		return selected, nil
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(selected, func(item container.TedgeContainer) bool {
+			return item.Name == name || item.Container.Name == name
+		}) {
+			return nil, fmt.Errorf("container %w. name=%s", container.ErrNotFound, name)
+		}
+	}
+	return selected, nil
+}
+
+func (c *StatsCommand) render(ctx context.Context, w io.Writer, cli *container.ContainerClient, filterOptions container.FilterOptions, names []string) error {
+	items, err := cli.List(ctx, filterOptions)
+	if err != nil {
+		return err
+	}
+	items, err = c.selectContainers(items, names)
+	if err != nil {
+		return err
+	}
+
+	// Collect the stats concurrently, as the engine might wait for a second sample of each container
+	now := time.Now()
+	outputs := make([]StatsOutput, len(items))
+	errs := make([]error, len(items))
+	wg := sync.WaitGroup{}
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry, err := cli.GetStatsEntry(ctx, item.Container.Id)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			outputs[i] = StatsOutput{
+				Time:      now,
+				Name:      item.Name,
+				Container: container.NewContainerTelemetryMessage(entry).Container,
+				BlockIO: StatsBlockIO{
+					Read:  entry.BlockRead,
+					Write: entry.BlockWrite,
+				},
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.JSON {
+		for i, output := range outputs {
+			if errs[i] != nil {
+				slog.Warn("Could not get container stats.", "container", items[i].Name, "err", errs[i])
+				continue
+			}
+			b, err := json.Marshal(output)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.Watch {
+		if isTerminal(w) {
+			// Clear the screen so that the stats are re-rendered in place
+			fmt.Fprint(w, "\033[H\033[2J")
+		} else {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, now.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w, strings.Join([]string{"NAME", "CPU %", "MEM %", "NETIO", "BLOCK READ", "BLOCK WRITE"}, "\t"))
+	for i, output := range outputs {
+		if errs[i] != nil {
+			slog.Warn("Could not get container stats.", "container", items[i].Name, "err", errs[i])
+			continue
+		}
+		fmt.Fprintln(w, strings.Join([]string{
+			output.Name,
+			output.Container.Cpu.String(),
+			output.Container.Memory.String(),
+			output.Container.NetIO.String(),
+			fmt.Sprintf("%.0f", output.BlockIO.Read),
+			fmt.Sprintf("%.0f", output.BlockIO.Write),
+		}, "\t"))
+	}
+	return nil
+}
@@ -4,22 +4,179 @@ Copyright © 2024 thin-edge.io <info@thin-edge.io>
 package container
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
 )
 
+type UpdateAction struct {
+	Action  string
+	Name    string
+	Version string
+	File    string
+}
+
+// Parse the update-list input, where each line is in the format:
+// <install|remove>\t<name>\t<version>\t<file>
+func ParseUpdateList(r io.Reader) ([]UpdateAction, error) {
+	actions := make([]UpdateAction, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+		action := UpdateAction{
+			Action:  strings.TrimSpace(fields[0]),
+			Name:    strings.TrimSpace(fields[1]),
+			Version: strings.TrimSpace(fields[2]),
+			File:    strings.TrimSpace(fields[3]),
+		}
+		if action.Action != "install" && action.Action != "remove" {
+			return nil, fmt.Errorf("invalid update-list action. action=%s", action.Action)
+		}
+		if action.Name == "" {
+			return nil, fmt.Errorf("invalid update-list line, missing module name. line=%s", line)
+		}
+		actions = append(actions, action)
+	}
+	return actions, scanner.Err()
+}
+
 // updateListCmd represents the updateList command
-func NewUpdateListCommand(ctx cli.Cli) *cobra.Command {
-	return &cobra.Command{
-		Use:   "update-list",
-		Short: "Install/remove a list of containers",
-		Long:  `Not implemented`,
-		Run: func(cmd *cobra.Command, args []string) {
-			slog.Info("update-list is not supported")
-			os.Exit(1)
+func NewUpdateListCommand(cliContext cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "update-list",
+		Short:       "Install/remove a list of containers",
+		Annotations: cli.MutatingAnnotations(),
+		Long: `Install/remove a list of containers which are read from stdin.
+
+The images of the containers being installed are pulled in parallel (controlled by --concurrency),
+however the containers are created one after another in the given order.
+`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+			actions, err := ParseUpdateList(cmd.InOrStdin())
+			if err != nil {
+				return cli.NewExitCodeError(cli.ExitCodeUsage, err)
+			}
+
+			// Validate all of the actions before making any changes
+			for i, action := range actions {
+				if action.Action != "install" {
+					continue
+				}
+				imageRef, err := ValidateInstallArgs(action.Name, action.Version, action.File, cliContext.GetDefaultImageOptions())
+				if err != nil {
+					return err
+				}
+				actions[i].Version = imageRef
+			}
+
+			cli, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			cli.Registries = cliContext.GetRegistries()
+			cli.Mirrors = cliContext.GetMirrors()
+			cli.StopTimeout = cliContext.GetStopTimeout()
+			cli.Containerized = cliContext.Containerized()
+			ctx := context.Background()
+			if err := cliContext.WaitForMaintenanceWindow(ctx, "update-list"); err != nil {
+				return err
+			}
+			installer := &InstallCommand{
+				CommandContext: cliContext,
+				PullFailures:   cliContext.GetPullFailures(),
+			}
+
+			if err := pullImages(ctx, installer, cli, actions, viper.GetInt("container.update_list.concurrency")); err != nil {
+				return err
+			}
+
+			// Create/remove containers one after another
+			for _, action := range actions {
+				slog.Info("Processing update-list action.", "action", action.Action, "name", action.Name, "version", action.Version)
+				switch action.Action {
+				case "install":
+					err = installer.Install(ctx, cli, action.Name, action.Version, action.File, action.File == "" && !container.IsArtifact(action.Version))
+				case "remove":
+					err = RemoveContainer(ctx, cliContext, cli, action.Name, false)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
 		},
 	}
+	cmd.Flags().Int("concurrency", 2, "Maximum number of images to pull in parallel")
+	viper.SetDefault("container.update_list.concurrency", 2)
+	_ = viper.BindPFlag("container.update_list.concurrency", cmd.Flags().Lookup("concurrency"))
+	return cmd
+}
+
+// Pull the images of all of the install actions with limited concurrency.
+// The engine deduplicates any shared layers. The install of each action whose
+// image could not be pulled is recorded as failed (which raises the failure alarm)
+func pullImages(ctx context.Context, installer *InstallCommand, cli *container.ContainerClient, actions []UpdateAction, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	type pullResult struct {
+		action UpdateAction
+		err    error
+	}
+	jobs := make(chan UpdateAction, len(actions))
+	results := make(chan pullResult, len(actions))
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for action := range jobs {
+				results <- pullResult{action: action, err: installer.PullImage(ctx, cli, action.Name, action.Version)}
+			}
+		}()
+	}
+	for _, action := range actions {
+		// Images provided as a file (or file url) are loaded during the install
+		if action.Action == "install" && action.File == "" && !container.IsArtifact(action.Version) {
+			jobs <- action
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	errs := make([]error, 0)
+	for result := range results {
+		if result.err == nil {
+			continue
+		}
+		installer.CommandContext.Audit(audit.Entry{
+			Action:  audit.ActionInstall,
+			Type:    container.ContainerType,
+			Name:    result.action.Name,
+			Version: result.action.Version,
+		}, result.err)
+		errs = append(errs, result.err)
+	}
+	return errors.Join(errs...)
 }
@@ -4,6 +4,7 @@ Copyright © 2024 thin-edge.io <info@thin-edge.io>
 package container_group
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/spf13/cobra"
@@ -12,11 +13,12 @@ import (
 
 func NewFinalizeCommand(ctx cli.Cli) *cobra.Command {
 	return &cobra.Command{
-		Use:   "finalize",
-		Short: "Finalize container install/remove operation",
+		Use:         "finalize",
+		Short:       "Finalize container install/remove operation",
+		Annotations: cli.MutatingAnnotations(),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
-			return nil
+			return ctx.Finalize(context.Background(), "container-group")
 		},
 	}
 }
@@ -5,12 +5,14 @@ package container_group
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/codeclysm/extract/v4"
 	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
 	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
@@ -34,10 +36,11 @@ func NewInstallCommand(ctx cli.Cli) *cobra.Command {
 		CommandContext: ctx,
 	}
 	cmd := &cobra.Command{
-		Use:   "install <MODULE_NAME>",
-		Short: "Install/run a container-group",
-		Args:  cobra.ExactArgs(1),
-		RunE:  command.RunE,
+		Use:         "install <MODULE_NAME>",
+		Short:       "Install/run a container-group",
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
+		RunE:        command.RunE,
 	}
 
 	cmd.Flags().StringVar(&command.ModuleVersion, "module-version", "", "Software version to install")
@@ -49,7 +52,6 @@ func NewInstallCommand(ctx cli.Cli) *cobra.Command {
 func (c *InstallCommand) RunE(cmd *cobra.Command, args []string) error {
 	slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
 	projectName := args[0]
-	stderr := cmd.ErrOrStderr()
 
 	cli, err := container.NewContainerClient()
 	if err != nil {
@@ -57,12 +59,31 @@ func (c *InstallCommand) RunE(cmd *cobra.Command, args []string) error {
 	}
 
 	ctx := context.Background()
+	if err := c.CommandContext.WaitForMaintenanceWindow(ctx, projectName); err != nil {
+		return err
+	}
+	cli.Registries = c.CommandContext.GetRegistries()
+	if err := cli.ConfigureRegistries(ctx); err != nil {
+		return err
+	}
+
+	err = c.install(ctx, cmd.ErrOrStderr(), cli, projectName)
+	c.CommandContext.Audit(audit.Entry{
+		Action:  audit.ActionInstall,
+		Type:    container.ContainerGroupType,
+		Name:    projectName,
+		Version: c.ModuleVersion,
+		Images:  cli.GetContainerImageDigests(ctx, container.ProjectFilter(projectName)),
+	}, err)
+	return err
+}
+
+func (c *InstallCommand) install(ctx context.Context, stderr io.Writer, cli *container.ContainerClient, projectName string) error {
 
 	// Run docker compose down before up
 	// TODO: Move to settings file
 	downFirst := false
-	baseDir := "/var/tedge-container-plugin/compose"
-	workingDir := filepath.Join(baseDir, projectName)
+	workingDir := filepath.Join(container.ComposeBaseDir, projectName)
 
 	// Stop project
 	if downFirst && utils.PathExists(workingDir) {
@@ -71,30 +92,57 @@ func (c *InstallCommand) RunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Check artifact type
-	file, err := os.Open(c.File)
-	if err != nil {
-		return err
-	}
-
 	slog.Info("Creating project directory.", "path", workingDir)
 	if err := os.MkdirAll(workingDir, 0755); err != nil {
 		return err
 	}
 
 	composeUpExtraArgs := []string{"--build"}
-	if err := extract.Archive(ctx, file, workingDir, nil); err != nil {
-		// Fallback to treating it as a text file
-		dst := filepath.Join(workingDir, "docker-compose.yaml")
-		slog.Info("Copying file.", "src", c.File, "dst", dst)
-		if err := utils.CopyFile(c.File, dst); err != nil {
+	src, isBundle := container.ParseBundleSource(c.ModuleVersion)
+	file := c.File
+	if file == "" && !isBundle {
+		// The module version can also be the url of the project file (http(s):// or file://)
+		path, cleanup, ok, err := c.CommandContext.FetchArtifact(ctx, c.ModuleVersion)
+		if err != nil {
 			return err
 		}
-		composeUpExtraArgs = []string{}
+		if ok {
+			defer cleanup()
+			file = path
+		}
+	}
+
+	if isBundle && file == "" {
+		// Fetch the compose bundle from a git repository or OCI artifact
+		bundleDir, err := container.FetchBundle(ctx, stderr, src, c.CommandContext.GetCache())
+		if err != nil {
+			return err
+		}
+		slog.Info("Copying bundle.", "src", bundleDir, "dst", workingDir)
+		if err := utils.CopyDir(bundleDir, workingDir); err != nil {
+			return err
+		}
+	} else {
+		// Check artifact type
+		artifact, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer artifact.Close()
+
+		if err := extract.Archive(ctx, artifact, workingDir, nil); err != nil {
+			// Fallback to treating it as a text file
+			dst := filepath.Join(workingDir, "docker-compose.yaml")
+			slog.Info("Copying file.", "src", file, "dst", dst)
+			if err := utils.CopyFile(file, dst); err != nil {
+				return err
+			}
+			composeUpExtraArgs = []string{}
+		}
 	}
 
 	// Create shared network
-	if err := cli.CreateSharedNetwork(ctx, c.CommandContext.GetSharedContainerNetwork()); err != nil {
+	if err := cli.CreateSharedNetwork(ctx, c.CommandContext.GetSharedContainerNetwork(), c.CommandContext.GetSharedNetworkOptions()); err != nil {
 		return err
 	}
 
@@ -18,8 +18,17 @@ import (
 )
 
 type ComposeProject struct {
-	Name string
-	Dir  string
+	Name    string
+	Dir     string
+	Managed bool
+}
+
+// Get the source of the project, either "managed" or "external"
+func (cp *ComposeProject) Source() string {
+	if cp.Managed {
+		return "managed"
+	}
+	return "external"
 }
 
 func (cp *ComposeProject) GetVersion() string {
@@ -35,7 +44,8 @@ func (cp *ComposeProject) GetVersion() string {
 
 // listCmd represents the list command
 func NewListCommand(cliContext cli.Cli) *cobra.Command {
-	return &cobra.Command{
+	showSource := false
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List containers",
 		Args:  cobra.ExactArgs(0),
@@ -57,8 +67,9 @@ func NewListCommand(cliContext cli.Cli) *cobra.Command {
 			for _, item := range containers {
 				if project, ok := item.Container.Labels["com.docker.compose.project"]; ok {
 					projects[project] = ComposeProject{
-						Name: project,
-						Dir:  item.Container.Labels["com.docker.compose.project.working_dir"],
+						Name:    project,
+						Dir:     item.Container.Labels["com.docker.compose.project.working_dir"],
+						Managed: item.Container.Managed,
 					}
 				}
 			}
@@ -70,9 +81,15 @@ func NewListCommand(cliContext cli.Cli) *cobra.Command {
 
 			for _, key := range keys {
 				project := projects[key]
-				fmt.Fprintf(stdout, "%s\t%s\n", project.Name, project.GetVersion())
+				if showSource {
+					fmt.Fprintf(stdout, "%s\t%s\t%s\n", project.Name, project.GetVersion(), project.Source())
+				} else {
+					fmt.Fprintf(stdout, "%s\t%s\n", project.Name, project.GetVersion())
+				}
 			}
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&showSource, "show-source", false, "Include whether the project is managed by thin-edge.io or external")
+	return cmd
 }
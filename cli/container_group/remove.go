@@ -5,9 +5,11 @@ package container_group
 
 import (
 	"context"
+	"io"
 	"log/slog"
 
 	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
 )
@@ -16,15 +18,17 @@ type RemoveCommand struct {
 	*cobra.Command
 
 	ModuleVersion string
+	Force         bool
 }
 
 // removeCmd represents the remove command
-func NewRemoveCommand(ctx cli.Cli) *cobra.Command {
+func NewRemoveCommand(cliContext cli.Cli) *cobra.Command {
 	command := &RemoveCommand{}
 	cmd := &cobra.Command{
-		Use:   "remove",
-		Short: "Remove a container",
-		Args:  cobra.ExactArgs(1),
+		Use:         "remove",
+		Short:       "Remove a container",
+		Annotations: cli.MutatingAnnotations(),
+		Args:        cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
 			ctx := context.Background()
@@ -35,9 +39,28 @@ func NewRemoveCommand(ctx cli.Cli) *cobra.Command {
 				return err
 			}
 
-			return cli.ComposeDown(ctx, cmd.ErrOrStderr(), projectName)
+			images := cli.GetContainerImageDigests(ctx, container.ProjectFilter(projectName))
+			err = removeProject(ctx, cliContext, cmd.ErrOrStderr(), cli, projectName, command.Force)
+			cliContext.Audit(audit.Entry{
+				Action: audit.ActionRemove,
+				Type:   container.ContainerGroupType,
+				Name:   projectName,
+				Images: images,
+			}, err)
+			return err
 		},
 	}
 	cmd.Flags().StringVar(&command.ModuleVersion, "module-version", "", "Software version to remove")
+	cmd.Flags().BoolVar(&command.Force, "force", false, "Remove the container even if it is protected")
 	return cmd
 }
+
+// Remove a compose project unless it is protected
+func removeProject(ctx context.Context, cliContext cli.Cli, w io.Writer, cli *container.ContainerClient, projectName string, force bool) error {
+	if !force {
+		if err := cli.CheckProjectProtected(ctx, projectName, cliContext.GetProtectionOptions()); err != nil {
+			return err
+		}
+	}
+	return cli.ComposeDown(ctx, w, projectName)
+}
@@ -4,8 +4,8 @@ Copyright © 2024 thin-edge.io <info@thin-edge.io>
 package container_group
 
 import (
+	"errors"
 	"log/slog"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
@@ -17,9 +17,9 @@ func NewUpdateListCommand(ctx cli.Cli) *cobra.Command {
 		Use:   "update-list",
 		Short: "Install/remove a list of containers",
 		Long:  `Not implemented`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			slog.Info("update-list is not supported")
-			os.Exit(1)
+			return cli.NewExitCodeError(cli.ExitCodeNotSupported, errors.New("update-list is not supported"))
 		},
 	}
 }
@@ -22,6 +22,7 @@ func NewRunCommand(ctx cli.Cli) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:                "docker",
 		Short:              "Run a command using the detected container engine",
+		Annotations:        cli.MutatingAnnotations(),
 		Long:               `The command allows you to run the underlying container engine cli commands directly but ensuring the same DOCKER_HOST is being used`,
 		RunE:               command.RunE,
 		DisableFlagParsing: true,
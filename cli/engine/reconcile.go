@@ -0,0 +1,181 @@
+/*
+Copyright © 2024 thin-edge.io <info@thin-edge.io>
+*/
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/app"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/startup"
+)
+
+type ReconcileCommand struct {
+	*cobra.Command
+
+	CommandContext cli.Cli
+	DryRun         bool
+	OutputJSON     bool
+}
+
+// NewReconcileCommand creates a command which removes the stale services on demand
+func NewReconcileCommand(ctx cli.Cli) *cobra.Command {
+	command := &ReconcileCommand{
+		CommandContext: ctx,
+	}
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Remove stale container services and delete them from the cloud",
+		Long: `Detect the registered container services which no longer have a container, and remove them (and delete
+them from the cloud if enabled). The command can be used alongside the running service, e.g. to fix drift after maintenance.
+
+The cleanup settings (e.g. monitor.cleanup.exclude and monitor.delete_from_cloud) are applied in the same way as the service.
+`,
+		Example: `
+Show which services would be removed
+$ tedge-container engine reconcile --dry-run
+
+Remove the stale services
+$ tedge-container engine reconcile
+		`,
+		Args:         cobra.ExactArgs(0),
+		RunE:         command.RunE,
+		SilenceUsage: true,
+	}
+	cmd.Flags().BoolVar(&command.DryRun, "dry-run", false, "Only report the stale services, don't remove them")
+	cmd.Flags().BoolVar(&command.OutputJSON, "json", false, "Print the report as json")
+	command.Command = cmd
+	return cmd
+}
+
+func (c *ReconcileCommand) RunE(cmd *cobra.Command, args []string) error {
+	slog.Info("Executing", "cmd", cmd.CalledAs(), "args", args)
+	cliContext := c.CommandContext
+	if !c.DryRun && cliContext.ReadOnly() {
+		return fmt.Errorf("%w, so stale services can only be reported using --dry-run. cmd=%s", cli.ErrReadOnly, cmd.CommandPath())
+	}
+
+	reports := make([]app.ReconcileReport, 0)
+	errs := make([]error, 0)
+	for i, root := range cliContext.GetTopicRoots() {
+		report, err := c.reconcile(cmd.Context(), i, root)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w. root=%s", err, root))
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	stdout := cmd.OutOrStdout()
+	if c.OutputJSON {
+		b, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", b)
+		return errors.Join(errs...)
+	}
+	for _, report := range reports {
+		for _, item := range report.Stale {
+			columns := []string{
+				item.Action,
+				item.Topic,
+				"name=" + item.Name,
+				"type=" + item.Type,
+			}
+			if item.Pattern != "" {
+				columns = append(columns, "pattern="+item.Pattern)
+			}
+			fmt.Fprintln(stdout, strings.Join(columns, "\t"))
+		}
+		fmt.Fprintf(stdout, "Reconciled services. root=%s, registered=%d, containers=%d, stale=%d, dryRun=%v, deleteFromCloud=%v\n", report.Root, report.Registered, report.Containers, len(report.Stale), report.DryRun, report.DeleteFromCloud)
+	}
+	return errors.Join(errs...)
+}
+
+// Reconcile the services of a topic root, using a separate MQTT session so the running service is not affected
+func (c *ReconcileCommand) reconcile(ctx context.Context, i int, root string) (app.ReconcileReport, error) {
+	cliContext := c.CommandContext
+	config := app.Config{
+		ServiceName:     cliContext.GetServiceName(),
+		DeleteFromCloud: cliContext.DeleteFromCloud(),
+
+		DeleteGracePeriod: cliContext.GetDeleteGracePeriod(),
+		DeleteConcurrency: cliContext.GetDeleteConcurrency(),
+		DeleteRetries:     cliContext.GetDeleteRetries(),
+		DeleteRetention:   cliContext.GetDeleteRetention(),
+		StateDir:          cliContext.GetStateDir(),
+
+		CleanupExclude:       cliContext.GetCleanupExclude(),
+		StaleMaxRemovalRatio: cliContext.GetStaleMaxRemovalRatio(),
+		StaleConfirmDelay:    cliContext.GetStaleConfirmDelay(),
+
+		EnableHomeAssistant: cliContext.HomeAssistantEnabled(),
+		HomeAssistantPrefix: cliContext.GetHomeAssistantPrefix(),
+
+		MQTTHost:       cliContext.GetMQTTHost(),
+		MQTTPort:       cliContext.GetMQTTPort(),
+		MQTTClientID:   fmt.Sprintf("%s#reconcile#%d", cliContext.GetServiceName(), os.Getpid()),
+		CumulocityHost: cliContext.GetCumulocityHost(),
+		CumulocityPort: cliContext.GetCumulocityPort(),
+
+		KeyFile:  cliContext.GetKeyFile(),
+		CertFile: cliContext.GetCertificateFile(),
+		CAFile:   cliContext.GetCAFile(),
+
+		TimeMode: cliContext.GetTimeMode(),
+		Audit:    cliContext.GetAuditLogger(),
+	}
+	if i > 0 {
+		config.StateDir = filepath.Join(config.StateDir, "roots", root)
+	}
+
+	device := cliContext.GetDeviceTarget()
+	device.RootPrefix = root
+	application, err := app.NewApp(device, config)
+	if err != nil {
+		return app.ReconcileReport{}, err
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), cliContext.GetShutdownTimeout())
+		defer cancel()
+		_ = application.Stop(stopCtx, true)
+	}()
+
+	// Wait until the entity store has been filled, otherwise no services would be found
+	serviceName := cliContext.GetStartupDeviceService()
+	timeout := cliContext.GetStartupWaitTimeout("device")
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	wait := startup.Strategy{
+		Name:    "device",
+		Timeout: timeout,
+		Check: func(ctx context.Context) error {
+			if !application.HasMainDevice(serviceName) {
+				return fmt.Errorf("main device is not registered. root=%s, service=%s", root, serviceName)
+			}
+			return nil
+		},
+	}
+	if err := wait.Wait(ctx); err != nil {
+		if !errors.Is(err, startup.ErrTimeout) {
+			return app.ReconcileReport{}, err
+		}
+		slog.Warn("Main device is not registered yet. Only the registered services are reconciled.", "root", root, "err", err)
+	}
+
+	report, err := application.Reconcile(c.DryRun)
+	report.Root = root
+	return report, err
+}
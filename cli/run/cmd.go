@@ -6,9 +6,15 @@ package run
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,12 +23,19 @@ import (
 	"github.com/thin-edge/tedge-container-plugin/pkg/app"
 	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/eventsocket"
+	"github.com/thin-edge/tedge-container-plugin/pkg/homeassistant"
+	"github.com/thin-edge/tedge-container-plugin/pkg/lease"
+	"github.com/thin-edge/tedge-container-plugin/pkg/startup"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
 )
 
 var (
 	DefaultServiceName = "tedge-container-plugin"
 	DefaultTopicRoot   = "te"
 	DefaultTopicPrefix = "device/main//"
+	DefaultStateDir    = "/var/tedge-container-plugin"
+	DefaultStatusFile  = "/run/tedge-container-plugin/status.json"
 )
 
 type RunCommand struct {
@@ -43,68 +56,358 @@ func NewRunCommand(cliContext cli.Cli) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cliContext.PrintConfig()
 
-			device := cliContext.GetDeviceTarget()
-			application, err := app.NewApp(device, app.Config{
-				ServiceName:        cliContext.GetServiceName(),
-				EnableMetrics:      cliContext.MetricsEnabled(),
-				DeleteFromCloud:    cliContext.DeleteFromCloud(),
-				EnableEngineEvents: cliContext.EngineEventsEnabled(),
-
-				MQTTHost:       cliContext.GetMQTTHost(),
-				MQTTPort:       cliContext.GetMQTTPort(),
-				CumulocityHost: cliContext.GetCumulocityHost(),
-				CumulocityPort: cliContext.GetCumulocityPort(),
-
-				KeyFile:  cliContext.GetKeyFile(),
-				CertFile: cliContext.GetCertificateFile(),
-				CAFile:   cliContext.GetCAFile(),
-			})
+			if err := cliContext.CheckTopicID(); err != nil {
+				return err
+			}
+
+			if cliContext.Containerized() {
+				if err := setupContainerized(cmd.Context(), cliContext); err != nil {
+					return err
+				}
+			} else if container.RunningInContainer() {
+				slog.Info("Running inside a container. Use --containerized to validate the engine socket and to exclude the monitor's own container.")
+			}
+
+			if err := waitForDependencies(cmd.Context(), cliContext); err != nil {
+				return err
+			}
+
+			// Only one instance may publish the container state, other instances stay idle until the lease expires
+			var instanceLease *lease.Lease
+			if !command.RunOnce {
+				instanceLease = cliContext.GetLease()
+			}
+			if instanceLease != nil {
+				if err := instanceLease.Acquire(cmd.Context()); err != nil {
+					return err
+				}
+				defer instanceLease.Release()
+			}
+
+			commandVerifier, err := cliContext.GetCommandVerifier()
+			if err != nil {
+				return err
+			}
+
+			containerHistory, err := cliContext.GetHistory()
+			if err != nil {
+				return err
+			}
+
+			containerArchive, err := cliContext.GetArchive()
+			if err != nil {
+				return err
+			}
+
+			mqttBridge, err := cliContext.GetBridge()
+			if err != nil {
+				return err
+			}
+			if mqttBridge != nil {
+				if err := mqttBridge.Connect(); err != nil {
+					return err
+				}
+				defer mqttBridge.Disconnect()
+			}
+
+			containerFirewall, err := cliContext.GetFirewall()
 			if err != nil {
 				return err
 			}
 
-			// FIXME: Wait until the entity store has been filled
-			time.Sleep(200 * time.Millisecond)
+			metricsExporters := cliContext.GetMetricsExporters()
+			defer func() {
+				for _, exp := range metricsExporters {
+					_ = exp.Close()
+				}
+			}()
+
+			// Publish the container state under each topic root, using an independent application (and entity store) per root
+			applications := make([]*app.App, 0)
+			for i, root := range cliContext.GetTopicRoots() {
+				config := app.Config{
+					ServiceName:         cliContext.GetServiceName(),
+					BuildVersion:        cliContext.BuildVersion,
+					BuildBranch:         cliContext.BuildBranch,
+					ReadOnly:            cliContext.ReadOnly(),
+					CommandVerifier:     commandVerifier,
+					EnableMetrics:       cliContext.MetricsEnabled(),
+					DeleteFromCloud:     cliContext.DeleteFromCloud(),
+					EnableEngineEvents:  cliContext.EngineEventsEnabled(),
+					EnableChangeEvents:  cliContext.ChangeEventsEnabled(),
+					HealthNetworkInfo:   cliContext.HealthNetworkInfoEnabled(),
+					EnableCloudTags:     cliContext.CloudTagsEnabled(),
+					CloudTagMarker:      cliContext.GetCloudTagMarker(),
+					StatusDebounce:      cliContext.GetStatusDebounce(),
+					StartingStatus:      cliContext.GetStartingStatus(),
+					StartingGracePeriod: cliContext.GetStartingGracePeriod(),
+					EnableProvenance:    cliContext.ProvenanceEnabled(),
+					EnableSummary:       cliContext.SummaryEnabled(),
+					EnableLayerReport:   cliContext.LayerReportEnabled(),
+					EnableInventory:     cliContext.InventoryEnabled(),
+					EnableProbes:        cliContext.ProbesEnabled(),
+					ProbeRules:          cliContext.GetProbeRules(),
+					ProbeTimeout:        cliContext.GetProbeTimeout(),
+					EnableRestart:       cliContext.RestartEnabled(),
+					RestartBudgetMax:    cliContext.GetRestartBudgetMax(),
+					Enrichers:           cliContext.GetEnrichers(),
+					EnrichInterval:      cliContext.GetEnrichInterval(),
+					EnrichTimeout:       cliContext.GetEnrichTimeout(),
+					RestartBudgetWindow: cliContext.GetRestartBudgetWindow(),
+
+					BootstrapChunkSize:     cliContext.GetBootstrapChunkSize(),
+					BootstrapInterval:      cliContext.GetBootstrapInterval(),
+					BootstrapDeferMetrics:  cliContext.BootstrapDeferMetrics(),
+					BootstrapInitialStatus: cliContext.GetBootstrapInitialStatus(),
+					EnableScheduler:        cliContext.SchedulerEnabled(),
+					ScheduleRules:          cliContext.GetScheduleRules(),
+
+					UpdateWorkers: cliContext.GetUpdateWorkers(),
+
+					MetricsInterval:         cliContext.GetMetricsInterval(),
+					AdaptiveMetricsInterval: cliContext.GetAdaptiveMetricsInterval(),
+					AdaptiveMetricsHold:     cliContext.GetAdaptiveMetricsHold(),
+					MetricsDeadband:         cliContext.GetMetricsDeadband(),
+
+					DeleteGracePeriod: cliContext.GetDeleteGracePeriod(),
+					DeleteConcurrency: cliContext.GetDeleteConcurrency(),
+					DeleteRetries:     cliContext.GetDeleteRetries(),
+					DeleteRetention:   cliContext.GetDeleteRetention(),
+					StateDir:          cliContext.GetStateDir(),
+
+					CleanupExclude:       cliContext.GetCleanupExclude(),
+					CleanupDryRun:        cliContext.CleanupDryRun(),
+					StaleMaxRemovalRatio: cliContext.GetStaleMaxRemovalRatio(),
+					StaleConfirmDelay:    cliContext.GetStaleConfirmDelay(),
+
+					EnableEngineService: cliContext.EngineServiceEnabled(),
+					EngineServiceName:   cliContext.GetEngineServiceName(),
+
+					EnableProfiles: cliContext.ProfilesEnabled(),
+					ProfilesDir:    cliContext.GetProfilesDir(),
+
+					EnableCheckpoints: cliContext.CheckpointsEnabled(),
+					CheckpointDir:     cliContext.GetCheckpointDir(),
+
+					EnableProjectHealth:         cliContext.ProjectHealthEnabled(),
+					EnableProjectMetrics:        cliContext.ProjectMetricsEnabled(),
+					EnableProjectVersionChanges: cliContext.ProjectVersionChangesEnabled(),
+					ProjectVersionQuietPeriod:   cliContext.GetProjectVersionQuietPeriod(),
+					ProjectHealth:               cliContext.GetProjectHealthOptions(),
+					GroupMode:                   app.GroupMode(cliContext.GetComposeGroupMode()),
+					TwinMode:                    app.TwinMode(cliContext.GetTwinMode()),
+
+					EnableHomeAssistant: cliContext.HomeAssistantEnabled(),
+					HomeAssistantPrefix: cliContext.GetHomeAssistantPrefix(),
+
+					EnableModbus:  cliContext.ModbusEnabled(),
+					ModbusAddress: cliContext.GetModbusAddress(),
+
+					EnableEventSocket: cliContext.EventSocketEnabled(),
+					EventSocketPath:   cliContext.GetEventSocketPath(),
+					EventSocketType:   cliContext.GetEventSocketType(),
+					EventSocketBuffer: cliContext.GetEventSocketBuffer(),
+
+					EnableMDNS:      cliContext.MDNSEnabled(),
+					MDNSServicesDir: cliContext.GetMDNSServicesDir(),
+
+					MQTTHost:       cliContext.GetMQTTHost(),
+					MQTTPort:       cliContext.GetMQTTPort(),
+					CumulocityHost: cliContext.GetCumulocityHost(),
+					CumulocityPort: cliContext.GetCumulocityPort(),
+
+					KeyFile:  cliContext.GetKeyFile(),
+					CertFile: cliContext.GetCertificateFile(),
+					CAFile:   cliContext.GetCAFile(),
+
+					TimeMode: cliContext.GetTimeMode(),
+
+					Audit:          cliContext.GetAuditLogger(),
+					History:        containerHistory,
+					Archive:        containerArchive,
+					PublishArchive: cliContext.PublishArchive(),
+				}
+				if i > 0 {
+					// Keep the state of each topic root separate, and only manage the host side effects once,
+					// otherwise each container transition would be handled once per topic root
+					config.StateDir = filepath.Join(config.StateDir, "roots", root)
+					config.Secondary = true
+					config.EnableMDNS = false
+					config.EnableModbus = false
+					config.EnableEventSocket = false
+					config.EnableScheduler = false
+					config.EnableRestart = false
+					config.RestartBudgetMax = 0
+					config.Archive = nil
+					config.PublishArchive = false
+				} else {
+					// Only mirror the state (and export the metrics) of the primary topic root
+					config.Bridge = mqttBridge
+					config.Exporters = metricsExporters
+					config.Firewall = containerFirewall
+				}
+
+				device := cliContext.GetDeviceTarget()
+				device.RootPrefix = root
+				application, err := app.NewApp(device, config)
+				if err != nil {
+					stopApplications(applications, false, cliContext.GetShutdownTimeout())
+					return err
+				}
+				applications = append(applications, application)
+			}
+
+			// Wait until the entity store has been filled
+			if err := waitForDevice(cmd.Context(), cliContext, applications); err != nil {
+				stopApplications(applications, false, cliContext.GetShutdownTimeout())
+				return err
+			}
+
+			for _, application := range applications {
+				if err := application.PublishPluginInfo(); err != nil {
+					slog.Warn("Could not publish plugin information.", "err", err)
+				}
+			}
 
 			if command.RunOnce {
 				// Cleanly stop the application in run-once mode
 				// so that the service still appears to be "up" as the Last Will and Testament
 				// message should not be sent (as the exit is expected)
 				// This logic is similar to SystemD's RemainAfterExit=yes setting
-				defer application.Stop(true)
-				return application.Update(cliContext.GetFilterOptions())
+				errs := make([]error, 0)
+				for _, application := range applications {
+					if err := application.UpdateEngineStatus(); err != nil {
+						slog.Warn("Error updating container engine status.", "err", err)
+					}
+					errs = append(errs, application.Update(cliContext.GetFilterOptions()))
+				}
+				stopApplications(applications, true, cliContext.GetShutdownTimeout())
+				return errors.Join(errs...)
 			}
 
 			stop := make(chan os.Signal, 1)
 			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
-			// Start background monitor
 			ctx, cancel := context.WithCancel(context.Background())
-			go func() {
-				for {
-					slog.Info("Monitor container engine events")
-					err := application.Monitor(ctx, cliContext.GetFilterOptions())
-					if errors.Is(err, context.Canceled) {
-						return
+
+			for _, application := range applications {
+				if err := application.Subscribe(); err != nil {
+					slog.Warn("Could not subscribe to health check commands.", "err", err)
+				}
+				if err := application.SubscribeProfiles(); err != nil {
+					slog.Warn("Could not subscribe to profile commands.", "err", err)
+				}
+				if err := application.SubscribeHistory(); err != nil {
+					slog.Warn("Could not subscribe to history commands.", "err", err)
+				}
+				if err := application.SubscribeCheckpoints(); err != nil {
+					slog.Warn("Could not subscribe to checkpoint commands.", "err", err)
+				}
+				if err := application.SubscribeRestart(); err != nil {
+					slog.Warn("Could not subscribe to restart commands.", "err", err)
+				}
+
+				go func(application *app.App) {
+					if err := application.ServeModbus(ctx); err != nil && !errors.Is(err, context.Canceled) {
+						slog.Error("Modbus TCP server stopped.", "err", err)
+					}
+				}(application)
+
+				go func(application *app.App) {
+					if err := application.ServeEventSocket(ctx); err != nil && !errors.Is(err, context.Canceled) {
+						slog.Error("Event socket stopped.", "err", err)
 					}
-					if err != nil {
-						slog.Warn("Monitor stopped. Restarting after 2 seconds.", "err", err)
-						time.Sleep(2 * time.Second)
+				}(application)
+
+				// Start background monitor
+				go func(application *app.App) {
+					for {
+						slog.Info("Monitor container engine events", "root", application.Device.RootPrefix)
+						err := application.Monitor(ctx, cliContext.GetFilterOptions())
+						if errors.Is(err, context.Canceled) {
+							return
+						}
+						if err != nil {
+							slog.Warn("Monitor stopped. Restarting after 2 seconds.", "err", err)
+							time.Sleep(2 * time.Second)
+						}
+					}
+				}(application)
+
+				if cliContext.MetricsEnabled() {
+					go func(application *app.App) {
+						_ = backgroundMetric(ctx, cliContext, application, cliContext.GetMetricsInterval())
+					}(application)
+
+					if cliContext.AdaptiveMetricsEnabled() {
+						go func(application *app.App) {
+							_ = backgroundAdaptiveMetric(ctx, cliContext, application)
+						}(application)
 					}
 				}
-			}()
 
-			if cliContext.MetricsEnabled() {
+				if cliContext.EngineServiceEnabled() {
+					go func(application *app.App) {
+						_ = backgroundEngineCheck(ctx, application, cliContext.GetEngineCheckInterval())
+					}(application)
+				}
+
+				if cliContext.HeartbeatEnabled() {
+					go func(application *app.App) {
+						_ = backgroundHeartbeat(ctx, application, cliContext.GetHeartbeatInterval())
+					}(application)
+				}
+
+				if cliContext.LayerReportEnabled() {
+					go func(application *app.App) {
+						_ = backgroundLayerReport(ctx, application, cliContext.GetLayerReportInterval())
+					}(application)
+				}
+
+				if cliContext.InventoryEnabled() {
+					go func(application *app.App) {
+						_ = backgroundInventory(ctx, application, cliContext.GetInventoryInterval())
+					}(application)
+				}
+			}
+
+			if cliContext.SchedulerEnabled() {
+				// The schedule is only applied once, by the application of the primary topic root
+				go func() {
+					_ = backgroundScheduler(ctx, applications[0])
+				}()
+			}
+
+			if cliContext.StatusFileEnabled() {
 				go func() {
-					_ = backgroundMetric(ctx, cliContext, application, cliContext.GetMetricsInterval())
+					_ = backgroundStatusFile(ctx, applications, cliContext.GetStatusFilePath(), cliContext.GetStatusFileInterval())
 				}()
 			}
 
-			<-stop
+			leaseErr := make(chan error, 1)
+			if instanceLease != nil {
+				go func() {
+					leaseErr <- instanceLease.Hold(ctx)
+				}()
+			}
+
+			var runErr error
+			select {
+			case <-stop:
+			case runErr = <-leaseErr:
+			}
 			cancel()
-			application.Stop(false)
+
+			// Don't wait for the remaining tasks if another stop signal is received
+			go func() {
+				<-stop
+				slog.Warn("Received another stop signal. Exiting immediately.")
+				os.Exit(1)
+			}()
+
+			// Disconnect cleanly when another instance took over, so that its health status is not overwritten
+			stopApplications(applications, runErr != nil, cliContext.GetShutdownTimeout())
 			slog.Info("Shutting down...")
-			return nil
+			return runErr
 		},
 	}
 
@@ -112,25 +415,32 @@ func NewRunCommand(cliContext cli.Cli) *cobra.Command {
 	cmd.Flags().StringSlice("name", []string{}, "Only include given container names")
 	cmd.Flags().StringSlice("label", []string{}, "Only include containers with the given labels")
 	cmd.Flags().StringSlice("id", []string{}, "Only include containers with the given ids")
-	cmd.Flags().StringSlice("type", []string{container.ContainerType, container.ContainerGroupType}, "Filter by container type")
+	cmd.Flags().StringSlice("type", []string{container.ContainerType, container.ContainerGroupType, container.ContainerStackType}, "Filter by container type")
 	cmd.Flags().String("topic-root", DefaultTopicRoot, "MQTT root prefix")
 	cmd.Flags().String("topic-id", DefaultTopicPrefix, "The device MQTT topic identifier")
 	cmd.Flags().BoolVar(&command.RunOnce, "once", false, "Only run the monitor once")
 	cmd.Flags().String("device-id", "", "thin-edge.io device id")
 	cmd.Flags().Duration("interval", 300*time.Second, "Metrics update interval")
+	cmd.Flags().String("status", DefaultStatusFile, "Path of the status file which is written periodically")
+	cmd.Flags().Bool("containerized", false, "The monitor is running as a container with the container engine socket mounted")
 
 	//
 	// viper bindings
 
 	// Service
 	viper.SetDefault("service_name", DefaultServiceName)
+	viper.SetDefault("state_dir", DefaultStateDir)
 	_ = viper.BindPFlag("service_name", cmd.Flags().Lookup("service-name"))
 
 	// MQTT topics
 	viper.SetDefault("topic_root", DefaultTopicRoot)
 	_ = viper.BindPFlag("topic_root", cmd.Flags().Lookup("topic-root"))
+	// Additional topic roots which the container state is also published under
+	viper.SetDefault("topic_roots", []string{})
 	viper.SetDefault("topic_id", DefaultTopicPrefix)
 	_ = viper.BindPFlag("topic_id", cmd.Flags().Lookup("topic-id"))
+	// Template of the service topic identifiers, required when the device uses a custom topic scheme
+	viper.SetDefault("topic_service_id", tedge.DefaultServiceTemplate)
 	_ = viper.BindPFlag("device_id", cmd.Flags().Lookup("device-id"))
 
 	// Include filters
@@ -142,22 +452,171 @@ func NewRunCommand(cliContext cli.Cli) *cobra.Command {
 	// Exclude filters
 	viper.SetDefault("filter.exclude.names", "")
 	viper.SetDefault("filter.exclude.labels", []string{"tedge.ignore"})
+	viper.SetDefault("filter.exclude.ids", []string{})
+	viper.SetDefault("filter.exclude.defaults", true)
+
+	// Running as a container
+	viper.SetDefault("monitor.containerized.enabled", false)
+	viper.SetDefault("monitor.containerized.exclude_self", true)
+	viper.SetDefault("monitor.containerized.gateway", "")
+	_ = viper.BindPFlag("monitor.containerized.enabled", cmd.Flags().Lookup("containerized"))
+
+	// Status file
+	viper.SetDefault("monitor.status.enabled", true)
+	viper.SetDefault("monitor.status.interval", "30s")
+	viper.SetDefault("monitor.status.debounce", "30s")
+	viper.SetDefault("monitor.status.starting", "starting")
+	viper.SetDefault("monitor.status.starting_grace_period", "2m")
+	_ = viper.BindPFlag("monitor.status.path", cmd.Flags().Lookup("status"))
+
+	// Checkpoint and restore commands (experimental)
+	viper.SetDefault("monitor.checkpoint.enabled", false)
+
+	// Update request processing
+	viper.SetDefault("monitor.update.workers", 2)
+	viper.SetDefault("monitor.shutdown.timeout", "20s")
+
+	// Instance lease
+	viper.SetDefault("monitor.lease.enabled", true)
+	viper.SetDefault("monitor.lease.duration", "60s")
+	viper.SetDefault("monitor.lease.max_wait", "0s")
+
+	// Startup dependencies
+	viper.SetDefault("startup.broker.enabled", true)
+	viper.SetDefault("startup.broker.timeout", "120s")
+	viper.SetDefault("startup.engine.enabled", true)
+	viper.SetDefault("startup.engine.timeout", "120s")
+	viper.SetDefault("startup.device.enabled", true)
+	viper.SetDefault("startup.device.timeout", "60s")
+	viper.SetDefault("startup.device.service", "tedge-agent")
 
 	// Metrics
 	_ = viper.BindPFlag("metrics.interval", cmd.Flags().Lookup("interval"))
 	viper.SetDefault("metrics.interval", "300s")
 	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.adaptive.enabled", false)
+	viper.SetDefault("metrics.adaptive.interval", "30s")
+	viper.SetDefault("metrics.adaptive.hold", "5m")
+	viper.SetDefault("monitor.metrics.exporters", []any{})
 
 	// Feature flags
 	viper.SetDefault("events.enabled", true)
+	viper.SetDefault("events.changes", true)
+	viper.SetDefault("health.network", false)
+	viper.SetDefault("monitor.cloud_tags.enabled", false)
+	viper.SetDefault("monitor.cloud_tags.marker", "c8y_ContainerService")
+	viper.SetDefault("monitor.provenance.enabled", false)
+	viper.SetDefault("monitor.summary.enabled", true)
+	viper.SetDefault("monitor.twin.mode", string(app.TwinModeFragment))
+	viper.SetDefault("monitor.enrich.enabled", false)
+	viper.SetDefault("monitor.enrich.dir", "/etc/tedge-container-plugin/enrich.d")
+	viper.SetDefault("monitor.enrich.interval", "5m")
+	viper.SetDefault("monitor.enrich.timeout", "5s")
+	viper.SetDefault("monitor.restart_budget.enabled", false)
+	viper.SetDefault("monitor.restart_budget.max_starts", 5)
+	viper.SetDefault("monitor.restart_budget.window", "10m")
+	viper.SetDefault("monitor.bootstrap.enabled", false)
+	viper.SetDefault("monitor.bootstrap.chunk_size", 50)
+	viper.SetDefault("monitor.bootstrap.interval", "5s")
+	viper.SetDefault("monitor.bootstrap.defer_metrics", true)
+	viper.SetDefault("monitor.bootstrap.initial_status", "")
+	viper.SetDefault("monitor.heartbeat.enabled", false)
+	viper.SetDefault("monitor.heartbeat.interval", "5m")
+	viper.SetDefault("monitor.layers.enabled", false)
+	viper.SetDefault("monitor.layers.interval", "1h")
+	viper.SetDefault("monitor.inventory.enabled", false)
+	viper.SetDefault("monitor.inventory.interval", "1h")
+	viper.SetDefault("monitor.probes.enabled", false)
+	viper.SetDefault("monitor.probes.timeout", "5s")
+	viper.SetDefault("monitor.probes.rules", []any{})
+	viper.SetDefault("monitor.restart.enabled", true)
+	viper.SetDefault("monitor.scheduler.enabled", false)
+	viper.SetDefault("monitor.scheduler.rules", []any{})
 	viper.SetDefault("delete_from_cloud.enabled", true)
+	viper.SetDefault("delete_from_cloud.grace_period", "500ms")
+	viper.SetDefault("delete_from_cloud.concurrency", 5)
+	viper.SetDefault("delete_from_cloud.retries", 3)
+	// 0 = delete immediately, otherwise services are marked as removed until the retention expires
+	viper.SetDefault("delete_from_cloud.retention", "0s")
+
+	// Stale service cleanup
+	viper.SetDefault("monitor.cleanup.exclude", []string{})
+	viper.SetDefault("monitor.cleanup.dry_run", false)
+
+	// Stale service removal guard
+	viper.SetDefault("monitor.stale.max_removal_ratio", 0.5)
+	viper.SetDefault("monitor.stale.confirm_delay", "5s")
+
+	// Timestamps: local, omit or synced
+	viper.SetDefault("time.mode", string(tedge.TimeModeLocal))
+
+	// Container engine service
+	viper.SetDefault("monitor.engine.enabled", true)
+	viper.SetDefault("monitor.engine.service_name", "container-engine")
+	viper.SetDefault("monitor.engine.interval", "60s")
+
+	// Signed remote commands
+	viper.SetDefault("monitor.commands.verify.enabled", false)
+	viper.SetDefault("monitor.commands.verify.hmac_key_file", "")
+	viper.SetDefault("monitor.commands.verify.public_key_file", "")
+
+	// Pre-approved compose profiles
+	viper.SetDefault("monitor.profiles.enabled", false)
+	viper.SetDefault("monitor.profiles.dir", "/etc/tedge-container-plugin/profiles")
+
+	// Home Assistant MQTT discovery
+	viper.SetDefault("homeassistant.enabled", false)
+	viper.SetDefault("homeassistant.discovery_prefix", homeassistant.DefaultDiscoveryPrefix)
+
+	// Modbus TCP status export
+	viper.SetDefault("monitor.modbus.enabled", false)
+	viper.SetDefault("monitor.modbus.address", ":502")
+
+	// Local event socket
+	viper.SetDefault("monitor.event_socket.enabled", false)
+	viper.SetDefault("monitor.event_socket.type", eventsocket.TypeSocket)
+	viper.SetDefault("monitor.event_socket.path", "/run/tedge-container-plugin/events.sock")
+	viper.SetDefault("monitor.event_socket.buffer", 100)
+
+	// mDNS service announcements (opt-in per container via labels)
+	viper.SetDefault("monitor.mdns.enabled", false)
+	viper.SetDefault("monitor.mdns.services_dir", "/etc/avahi/services")
+
+	// Compose project registration: service or project
+	viper.SetDefault("monitor.compose.group_mode", string(app.GroupModeService))
+
+	// Compose project health aggregation
+	viper.SetDefault("monitor.compose.health.enabled", false)
+	viper.SetDefault("monitor.compose.health.policy", string(container.HealthPolicyAllUp))
+	viper.SetDefault("monitor.compose.health.quorum", 0.5)
+	viper.SetDefault("monitor.compose.health.critical_label", container.DefaultCriticalLabel)
+
+	// Compose project metrics aggregation
+	viper.SetDefault("monitor.compose.metrics.enabled", false)
+
+	// Compose project image changes (e.g. docker compose pull && up)
+	viper.SetDefault("monitor.compose.version_changes.enabled", false)
+	viper.SetDefault("monitor.compose.version_changes.quiet_period", "10s")
 
 	// thin-edge.io services
 	viper.SetDefault("client.mqtt.host", "127.0.0.1")
 	// client.mqtt.port: 0 = auto-detection, where 8883 is used when the cert files exist, or 1883 otherwise
 	viper.SetDefault("client.mqtt.port", 0)
-	viper.SetDefault("client.c8y.host", "127.0.0.1")
-	viper.SetDefault("client.c8y.port", 8001)
+
+	// Secondary (non thin-edge.io) broker
+	viper.SetDefault("bridge.enabled", false)
+	viper.SetDefault("bridge.host", "127.0.0.1")
+	viper.SetDefault("bridge.port", 1883)
+	viper.SetDefault("bridge.client_id", "")
+	viper.SetDefault("bridge.username", "")
+	viper.SetDefault("bridge.password", "")
+	viper.SetDefault("bridge.tls.enabled", false)
+	viper.SetDefault("bridge.tls.ca_file", "")
+	viper.SetDefault("bridge.tls.cert_file", "")
+	viper.SetDefault("bridge.tls.key_file", "")
+	viper.SetDefault("bridge.topics.registration", "tedge/{{device}}/{{name}}")
+	viper.SetDefault("bridge.topics.health", "tedge/{{device}}/{{name}}/health")
+	viper.SetDefault("bridge.topics.measurement", "tedge/{{device}}/{{name}}/measurements/{{type}}")
 
 	// TLS
 	viper.SetDefault("client.key", "")
@@ -168,6 +627,96 @@ func NewRunCommand(cliContext cli.Cli) *cobra.Command {
 	return cmd
 }
 
+// Wait for the MQTT broker and the container engine to be ready
+func waitForDependencies(ctx context.Context, cliContext cli.Cli) error {
+	strategies := make([]startup.Strategy, 0)
+	if cliContext.StartupWaitEnabled("broker") {
+		address := net.JoinHostPort(cliContext.GetMQTTHost(), strconv.Itoa(int(cliContext.GetMQTTPort())))
+		strategies = append(strategies, startup.Strategy{
+			Name:    "broker",
+			Timeout: cliContext.GetStartupWaitTimeout("broker"),
+			Check:   startup.TCP(address),
+		})
+	}
+	if cliContext.StartupWaitEnabled("engine") {
+		strategies = append(strategies, engineStrategy(cliContext))
+	}
+	return startup.WaitAll(ctx, strategies...)
+}
+
+func engineStrategy(cliContext cli.Cli) startup.Strategy {
+	return startup.Strategy{
+		Name:    "engine",
+		Timeout: cliContext.GetStartupWaitTimeout("engine"),
+		Check: func(ctx context.Context) error {
+			// Create a new client on each attempt as the socket might not exist yet
+			containerClient, err := container.NewContainerClient()
+			if err != nil {
+				return err
+			}
+			defer containerClient.Client.Close()
+			if status := containerClient.GetEngineStatus(ctx); status.Status != "up" {
+				return fmt.Errorf("%s. host=%s", status.Error, status.Host)
+			}
+			return nil
+		},
+	}
+}
+
+// Validate the mounted container engine socket and adjust the settings to the monitor's own container.
+// This has to be done before waiting for the broker, as the broker's address might change
+func setupContainerized(ctx context.Context, cliContext cli.Cli) error {
+	containerClient, err := container.NewContainerClient()
+	if err != nil {
+		return err
+	}
+	defer containerClient.Client.Close()
+
+	if err := container.CheckSocketMount(containerClient.Client.DaemonHost()); err != nil {
+		return err
+	}
+	if cliContext.StartupWaitEnabled("engine") {
+		if err := startup.WaitAll(ctx, engineStrategy(cliContext)); err != nil {
+			return err
+		}
+	}
+
+	self, err := containerClient.Self(ctx)
+	if err != nil {
+		slog.Warn("Could not verify that the container engine is running on the same host as the monitor.", "err", err)
+	}
+	cliContext.ApplyContainerized(self)
+	return nil
+}
+
+// Wait until the main device has been registered (e.g. by the tedge-agent). Only the primary topic root
+// is checked, as the other roots are not necessarily served by a tedge-agent. The monitor is still started if
+// the device is not registered within the timeout, as the services are registered once the entity store is filled
+func waitForDevice(ctx context.Context, cliContext cli.Cli, applications []*app.App) error {
+	if !cliContext.StartupWaitEnabled("device") || len(applications) == 0 {
+		return nil
+	}
+	serviceName := cliContext.GetStartupDeviceService()
+	application := applications[0]
+	wait := startup.Strategy{
+		Name:    "device",
+		Timeout: cliContext.GetStartupWaitTimeout("device"),
+		Check: func(ctx context.Context) error {
+			if !application.HasMainDevice(serviceName) {
+				return fmt.Errorf("main device is not registered. root=%s, service=%s", application.Device.RootPrefix, serviceName)
+			}
+			return nil
+		},
+	}
+	if err := wait.Wait(ctx); err != nil {
+		if !errors.Is(err, startup.ErrTimeout) {
+			return err
+		}
+		slog.Warn("Main device is not registered yet. Continuing without it.", "err", err)
+	}
+	return nil
+}
+
 func backgroundMetric(ctx context.Context, cliContext cli.Cli, application *app.App, interval time.Duration) error {
 	timerCh := time.NewTicker(interval)
 	for {
@@ -187,3 +736,166 @@ func backgroundMetric(ctx context.Context, cliContext cli.Cli, application *app.
 		}
 	}
 }
+
+// Stop the applications. Background tasks which are still running after the timeout
+// (e.g. blocked by a hanging container engine call) are abandoned so that the process can exit
+func stopApplications(applications []*app.App, clean bool, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, application := range applications {
+		if err := application.Stop(ctx, clean); err != nil {
+			slog.Warn("Background tasks did not stop in time. Abandoning them.", "root", application.Device.RootPrefix, "timeout", timeout, "err", err)
+		}
+	}
+}
+
+// Collect the metrics of the containers which recently restarted or became unhealthy
+func backgroundAdaptiveMetric(ctx context.Context, cliContext cli.Cli, application *app.App) error {
+	timerCh := time.NewTicker(5 * time.Second)
+	defer timerCh.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case now := <-timerCh.C:
+			ids := application.DueAdaptiveMetrics(now)
+			if len(ids) == 0 {
+				continue
+			}
+			filterOptions := cliContext.GetFilterOptions()
+			if len(filterOptions.IDs) > 0 {
+				// Only keep the containers which are included by the user's id filter
+				ids = slices.DeleteFunc(ids, func(id string) bool {
+					return !slices.ContainsFunc(filterOptions.IDs, func(prefix string) bool {
+						return strings.HasPrefix(id, prefix)
+					})
+				})
+				if len(ids) == 0 {
+					continue
+				}
+			}
+			filterOptions.IDs = ids
+			go func() {
+				slog.Info("Refreshing metrics of recently changed containers.", "containers", ids)
+				if err := application.UpdateMetrics(filterOptions); err != nil {
+					slog.Warn("Error updating metrics.", "err", err)
+				}
+			}()
+		}
+	}
+}
+
+func backgroundStatusFile(ctx context.Context, applications []*app.App, path string, interval time.Duration) error {
+	writeStatus := func() {
+		statusCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := app.WriteStatusFile(statusCtx, path, applications); err != nil {
+			slog.Warn("Could not write status file.", "path", path, "err", err)
+		}
+	}
+	writeStatus()
+	timerCh := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping status file task")
+			return ctx.Err()
+
+		case <-timerCh.C:
+			writeStatus()
+		}
+	}
+}
+
+// Apply the container schedules at the start of each minute
+func backgroundScheduler(ctx context.Context, application *app.App) error {
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Stopping container scheduler task")
+			return ctx.Err()
+
+		case <-timer.C:
+			if err := application.RunSchedule(next); err != nil {
+				slog.Warn("Error applying container schedules.", "err", err)
+			}
+		}
+	}
+}
+
+func backgroundHeartbeat(ctx context.Context, application *app.App, interval time.Duration) error {
+	timerCh := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping heartbeat task")
+			return ctx.Err()
+
+		case now := <-timerCh.C:
+			if err := application.PublishHeartbeat(now); err != nil {
+				slog.Warn("Error publishing heartbeat.", "err", err)
+			}
+		}
+	}
+}
+
+func backgroundLayerReport(ctx context.Context, application *app.App, interval time.Duration) error {
+	if err := application.UpdateLayerReport(); err != nil {
+		slog.Warn("Error updating image layer report.", "err", err)
+	}
+	timerCh := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping image layer report task")
+			return ctx.Err()
+
+		case <-timerCh.C:
+			if err := application.UpdateLayerReport(); err != nil {
+				slog.Warn("Error updating image layer report.", "err", err)
+			}
+		}
+	}
+}
+
+func backgroundInventory(ctx context.Context, application *app.App, interval time.Duration) error {
+	if err := application.UpdateInventory(); err != nil {
+		slog.Warn("Error updating engine inventory.", "err", err)
+	}
+	timerCh := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping engine inventory task")
+			return ctx.Err()
+
+		case <-timerCh.C:
+			if err := application.UpdateInventory(); err != nil {
+				slog.Warn("Error updating engine inventory.", "err", err)
+			}
+		}
+	}
+}
+
+func backgroundEngineCheck(ctx context.Context, application *app.App, interval time.Duration) error {
+	if err := application.UpdateEngineStatus(); err != nil {
+		slog.Warn("Error updating container engine status.", "err", err)
+	}
+	timerCh := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping container engine check task")
+			return ctx.Err()
+
+		case <-timerCh.C:
+			if err := application.UpdateEngineStatus(); err != nil {
+				slog.Warn("Error updating container engine status.", "err", err)
+			}
+		}
+	}
+}
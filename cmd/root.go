@@ -12,6 +12,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/cli/cachecmd"
+	"github.com/thin-edge/tedge-container-plugin/cli/configcmd"
 	"github.com/thin-edge/tedge-container-plugin/cli/container"
 	"github.com/thin-edge/tedge-container-plugin/cli/container_group"
 	"github.com/thin-edge/tedge-container-plugin/cli/engine"
@@ -30,7 +32,16 @@ var rootCmd = &cobra.Command{
 	Short:   "thin-edge.io container engine plugin to manage and monitor containers on a device",
 	Version: fmt.Sprintf("%s (branch=%s)", buildVersion, buildBranch),
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return SetLogLevel()
+		if err := SetLogLevel(); err != nil {
+			return err
+		}
+		if err := cli.CheckProfile(); err != nil {
+			return err
+		}
+		if err := cli.ApplyEngineHost(); err != nil {
+			return err
+		}
+		return cli.CheckReadOnly(cmd)
 	},
 }
 
@@ -42,6 +53,7 @@ func Execute() {
 	switch name {
 	case "container", "container-group":
 		slog.Debug("Calling as a software management plugin.", "name", name, "args", args)
+		viper.SetDefault("initiator", cli.InitiatorSoftwareManagement)
 		rootCmd.SetArgs(append([]string{name}, args[1:]...))
 	default:
 		slog.Debug("Using subcommands.", "args", args)
@@ -55,7 +67,7 @@ func Execute() {
 		default:
 			slog.Error("Command error", "err", err)
 		}
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
 
@@ -76,7 +88,10 @@ func SetLogLevel() error {
 }
 
 func init() {
-	cliConfig := cli.Cli{}
+	cliConfig := cli.Cli{
+		BuildVersion: buildVersion,
+		BuildBranch:  buildBranch,
+	}
 	cobra.OnInitialize(cliConfig.OnInit)
 	rootCmd.AddCommand(
 		container.NewContainerCommand(cliConfig),
@@ -84,11 +99,21 @@ func init() {
 		run.NewRunCommand(cliConfig),
 		engine.NewCliCommand(cliConfig),
 		initcmd.NewInitCommand(cliConfig),
+		cachecmd.NewCacheCommand(cliConfig),
+		configcmd.NewConfigCommand(cliConfig),
 	)
 
+	cli.SetUsageErrors(rootCmd)
+
 	rootCmd.PersistentFlags().String("log-level", "info", "Log level")
 	rootCmd.PersistentFlags().StringVarP(&cliConfig.ConfigFile, "config", "c", "", "Configuration file")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile (profiles.<name> in the configuration file) which is applied on top of the configuration")
+	rootCmd.PersistentFlags().String("host", "", "Container engine address, e.g. unix:///run/user/1000/podman/podman.sock. Takes precedence over DOCKER_HOST")
+	rootCmd.PersistentFlags().String("engine", "", "Well-known container engine to use (docker, docker-user, podman or podman-user)")
 
 	// viper.Bind
 	_ = viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
+	_ = viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	_ = viper.BindPFlag("engine.host", rootCmd.PersistentFlags().Lookup("host"))
+	_ = viper.BindPFlag("engine.name", rootCmd.PersistentFlags().Lookup("engine"))
 }
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+// Engine events which temporarily increase the metrics collection frequency of a container
+var adaptiveMetricsTriggers = map[events.Action]struct{}{
+	events.ActionStart:                 {},
+	events.ActionRestart:               {},
+	events.ActionDie:                   {},
+	events.ActionOOM:                   {},
+	events.ActionHealthStatusUnhealthy: {},
+}
+
+// Collection state of a container whose metrics are collected more often than the regular interval
+type adaptiveMetricsEntry struct {
+	triggeredAt time.Time
+	interval    time.Duration
+	nextDue     time.Time
+}
+
+// Schedule of the containers which recently restarted or became unhealthy. The metrics of these
+// containers are collected using the fast interval for the given hold duration, afterwards the
+// interval is doubled on each collection until it reaches the regular interval again
+type adaptiveMetrics struct {
+	Fast    time.Duration
+	Regular time.Duration
+	Hold    time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*adaptiveMetricsEntry
+}
+
+func newAdaptiveMetrics(fast time.Duration, regular time.Duration, hold time.Duration) *adaptiveMetrics {
+	return &adaptiveMetrics{
+		Fast:    fast,
+		Regular: regular,
+		Hold:    hold,
+		entries: make(map[string]*adaptiveMetricsEntry),
+	}
+}
+
+// Start (or restart) the fast collection of a container's metrics
+func (s *adaptiveMetrics) Trigger(id string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if entry, ok := s.entries[id]; ok {
+		entry.triggeredAt = now
+		entry.interval = s.Fast
+		if entry.nextDue.After(now.Add(s.Fast)) {
+			entry.nextDue = now.Add(s.Fast)
+		}
+		return
+	}
+	s.entries[id] = &adaptiveMetricsEntry{
+		triggeredAt: now,
+		interval:    s.Fast,
+		nextDue:     now.Add(s.Fast),
+	}
+}
+
+// Get the ids of the containers whose metrics are due, and schedule their next collection.
+// Containers are removed from the schedule once their interval has decayed to the regular interval
+func (s *adaptiveMetrics) Due(now time.Time) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ids := make([]string, 0)
+	for id, entry := range s.entries {
+		if now.Before(entry.nextDue) {
+			continue
+		}
+		ids = append(ids, id)
+
+		if now.Sub(entry.triggeredAt) >= s.Hold {
+			entry.interval *= 2
+		}
+		if entry.interval >= s.Regular {
+			delete(s.entries, id)
+			continue
+		}
+		entry.nextDue = now.Add(entry.interval)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop the fast collection of a container's metrics, e.g. when it was removed
+func (s *adaptiveMetrics) Remove(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, id)
+}
+
+func (s *adaptiveMetrics) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.entries)
+}
+
+// Increase the metrics collection frequency of a container in reaction to an engine event
+func (a *App) triggerAdaptiveMetrics(evt events.Message) {
+	if a.adaptive == nil {
+		return
+	}
+	switch evt.Action {
+	case events.ActionDestroy, events.ActionRemove:
+		a.adaptive.Remove(evt.Actor.ID)
+		return
+	}
+	if _, ok := adaptiveMetricsTriggers[evt.Action]; !ok {
+		return
+	}
+	slog.Info("Increasing metrics frequency of container.", "container", evt.Actor.ID, "action", evt.Action, "interval", a.adaptive.Fast)
+	a.adaptive.Trigger(evt.Actor.ID, time.Now())
+}
+
+// Get the ids of the containers whose metrics should be collected before the regular interval
+func (a *App) DueAdaptiveMetrics(now time.Time) []string {
+	if a.adaptive == nil {
+		return nil
+	}
+	return a.adaptive.Due(now)
+}
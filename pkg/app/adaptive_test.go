@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_adaptiveMetrics(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	schedule := newAdaptiveMetrics(30*time.Second, 300*time.Second, 60*time.Second)
+	schedule.Trigger("abc", now)
+
+	assert.Empty(t, schedule.Due(now.Add(10*time.Second)))
+
+	// Fast interval during the hold duration
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(30*time.Second)))
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(60*time.Second)))
+
+	// Decays back to the regular interval: 60s, 120s, 240s
+	assert.Empty(t, schedule.Due(now.Add(100*time.Second)))
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(120*time.Second)))
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(240*time.Second)))
+	assert.Equal(t, 1, schedule.Len())
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(480*time.Second)))
+	assert.Equal(t, 0, schedule.Len())
+}
+
+func Test_adaptiveMetricsRetrigger(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	schedule := newAdaptiveMetrics(30*time.Second, 300*time.Second, 0)
+	schedule.Trigger("abc", now)
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(30*time.Second)))
+
+	// A new trigger resets the interval
+	schedule.Trigger("abc", now.Add(40*time.Second))
+	assert.Empty(t, schedule.Due(now.Add(60*time.Second)))
+	assert.Equal(t, []string{"abc"}, schedule.Due(now.Add(70*time.Second)))
+
+	schedule.Remove("abc")
+	assert.Equal(t, 0, schedule.Len())
+}
@@ -7,26 +7,53 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"strings"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/api/types/events"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/thin-edge/tedge-container-plugin/pkg/archive"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/bridge"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/enrich"
+	"github.com/thin-edge/tedge-container-plugin/pkg/eventsocket"
+	"github.com/thin-edge/tedge-container-plugin/pkg/exporter"
+	"github.com/thin-edge/tedge-container-plugin/pkg/firewall"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+	"github.com/thin-edge/tedge-container-plugin/pkg/modbus"
+	"github.com/thin-edge/tedge-container-plugin/pkg/signature"
 	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
 )
 
+var ErrShutdownTimeout = errors.New("timed out waiting for the background tasks to stop")
+
 type Action int
 
 const (
 	ActionUpdateAll Action = iota
 	ActionUpdateMetrics
+	ActionRemoveServices
+)
+
+// Queue which an action request is processed by. Targeted requests (e.g. triggered by the events
+// of a single container) don't have to wait for full updates which can take a long time
+type Lane int
+
+const (
+	LaneFull Lane = iota
+	LaneTargeted
 )
 
 type ActionRequest struct {
 	Action  Action
 	Options any
+	Lane    Lane
+
+	// Channel which receives the result of the request. nil = the result is not awaited
+	result chan error
 }
 
 func NewUpdateAllAction(filter container.FilterOptions) ActionRequest {
@@ -36,6 +63,15 @@ func NewUpdateAllAction(filter container.FilterOptions) ActionRequest {
 	}
 }
 
+// Update a subset of the containers, e.g. the container which an engine event refers to
+func NewTargetedUpdateAction(filter container.FilterOptions) ActionRequest {
+	return ActionRequest{
+		Action:  ActionUpdateAll,
+		Options: filter,
+		Lane:    LaneTargeted,
+	}
+}
+
 func NewUpdateMetricsAction(filter container.FilterOptions) ActionRequest {
 	return ActionRequest{
 		Action:  ActionUpdateMetrics,
@@ -43,37 +79,260 @@ func NewUpdateMetricsAction(filter container.FilterOptions) ActionRequest {
 	}
 }
 
+// Controls how compose projects are registered
+type GroupMode string
+
+const (
+	// Register each compose service as a separate thin-edge.io service
+	GroupModeService GroupMode = "service"
+
+	// Register the whole compose project as a single thin-edge.io service
+	GroupModeProject GroupMode = "project"
+)
+
+func NewRemoveServicesAction(targets []tedge.Target) ActionRequest {
+	return ActionRequest{
+		Action:  ActionRemoveServices,
+		Options: targets,
+		Lane:    LaneTargeted,
+	}
+}
+
 type App struct {
 	client          *tedge.Client
 	ContainerClient *container.ContainerClient
 
 	Device *tedge.Target
 
-	config         Config
-	shutdown       chan struct{}
-	updateRequests chan ActionRequest
-	updateResults  chan error
-	wg             sync.WaitGroup
+	config           Config
+	modbus           *modbus.Server
+	eventSocket      *eventsocket.Server
+	snapshots        map[string]containerSnapshot
+	provenance       map[string]*container.Provenance
+	lastSeen         map[string]container.TedgeContainer
+	lastSeenAt       time.Time
+	status           statusTracker
+	tombstones       *Tombstones
+	shutdown         chan struct{}
+	adaptive         *adaptiveMetrics
+	deadband         *deadbandFilter
+	projectMetrics   *projectMetrics
+	summary          containerSummaryState
+	layerReport      layerReportState
+	inventory        inventoryState
+	crons            cronCache
+	probes           probeResults
+	debounce         *statusDebouncer
+	cloudTags        cloudTagState
+	starting         startingTracker
+	restartBudget    *restartBudget
+	enrichments      enrichmentCache
+	twinFields       twinFields
+	projectVersions  *projectVersionTracker
+	bootstrapState   bootstrapState
+	startedAt        time.Time
+	provenanceMutex  sync.Mutex
+	fullRequests     chan ActionRequest
+	targetedRequests chan ActionRequest
+	wg               sync.WaitGroup
+
+	// A confirmation of the stale services is scheduled
+	staleConfirmPending atomic.Bool
 }
 
 type Config struct {
 	ServiceName string
 
+	// Build information of the monitor, which is published on its service twin
+	BuildVersion string
+	BuildBranch  string
+
 	// TLS
 	KeyFile  string
 	CertFile string
 	CAFile   string
 
+	// Only observe and report, don't handle any commands which modify containers
+	ReadOnly bool
+
+	// Publish the container twin as a single fragment, or each property as a separate topic
+	TwinMode TwinMode
+
+	// Compute additional twin fields of each container (e.g. drop-in executables), which are refreshed after the interval
+	Enrichers      []enrich.Enricher
+	EnrichInterval time.Duration
+	EnrichTimeout  time.Duration
+
+	// Disable the restart policy of containers which are started more than RestartBudgetMax
+	// times within the RestartBudgetWindow. 0 = disabled
+	RestartBudgetMax    int
+	RestartBudgetWindow time.Duration
+
+	// Register the existing containers in chunks on the first full update when there are more unregistered
+	// services than the chunk size. 0 = disabled. The initial status is published instead of the
+	// container's status during the bootstrap (empty = container's status)
+	BootstrapChunkSize     int
+	BootstrapInterval      time.Duration
+	BootstrapDeferMetrics  bool
+	BootstrapInitialStatus string
+
+	// Verify the signature of commands which modify containers. nil = disabled
+	CommandVerifier *signature.Verifier
+
 	// Feature flags
 	EnableMetrics      bool
 	EnableEngineEvents bool
+	EnableChangeEvents bool
 	DeleteFromCloud    bool
 
+	// Number of workers which process targeted update requests concurrently
+	UpdateWorkers int
+
+	// Collect the metrics of containers which recently restarted or became unhealthy using a
+	// faster interval, which decays back to the regular metrics interval. 0 = disabled
+	MetricsInterval         time.Duration
+	AdaptiveMetricsInterval time.Duration
+	AdaptiveMetricsHold     time.Duration
+
+	// Publish the aggregated metrics of each compose project on the project's service
+	EnableProjectMetrics bool
+
+	// Publish an event and refresh the software list when the images of a compose project change,
+	// once the project did not change for the quiet period
+	EnableProjectVersionChanges bool
+	ProjectVersionQuietPeriod   time.Duration
+
+	// Only publish the measurement series (e.g. memory) when they change significantly. nil = disabled
+	MetricsDeadband map[string]Deadband
+
+	// Include the container's IP address and published ports in the health messages
+	HealthNetworkInfo bool
+
+	// Add marker fragments (e.g. the compose project) to the Cumulocity managed objects of the services
+	EnableCloudTags bool
+	CloudTagMarker  string
+
+	// Publish the provenance of the containers' images to the twin
+	EnableProvenance bool
+
+	// Publish the container counts to the device's twin
+	EnableSummary bool
+
+	// Publish the shared and unique image layer sizes to the device's twin
+	EnableLayerReport bool
+
+	// Publish the inventory of the networks and volumes to the device's twin
+	EnableInventory bool
+
+	// Only publish a service status change once it persisted for the given duration. 0 = disabled
+	StatusDebounce time.Duration
+
+	// Status of the containers which are created or restarting (e.g. "starting"), until they have been
+	// starting for longer than the grace period. Empty = the containers are reported as down
+	StartingStatus      string
+	StartingGracePeriod time.Duration
+
+	// Run readiness probes (tcp, http or exec) against the containers without a built-in health check.
+	// Containers whose probe fails are reported as down
+	EnableProbes bool
+	ProbeRules   []ProbeRule
+	ProbeTimeout time.Duration
+
+	// Support the container_restart command, which also restarts the dependent containers
+	EnableRestart bool
+
+	// Start and stop containers according to their schedule (labels or rules)
+	EnableScheduler bool
+	ScheduleRules   []ScheduleRule
+
+	// Cloud deletion of stale services
+	DeleteGracePeriod time.Duration
+	DeleteConcurrency int
+	DeleteRetries     int
+
+	// Services (name or topic patterns) which are never removed by the stale cleanup
+	CleanupExclude []string
+
+	// Only log the stale services which would be removed
+	CleanupDryRun bool
+
+	// Guard against removing too many services at once
+	StaleMaxRemovalRatio float64
+	StaleConfirmDelay    time.Duration
+
+	// Keep removed services (marked with a tombstone) for the given duration before deleting them
+	DeleteRetention time.Duration
+	StateDir        string
+
+	// Container engine service
+	EnableEngineService bool
+	EngineServiceName   string
+
+	// Pre-approved compose profiles
+	EnableProfiles bool
+	ProfilesDir    string
+
+	// Container checkpoint and restore commands (experimental, requires CRIU)
+	EnableCheckpoints bool
+	CheckpointDir     string
+
+	// Compose project health aggregation
+	EnableProjectHealth bool
+	ProjectHealth       container.ProjectHealthOptions
+	GroupMode           GroupMode
+
+	// Publish Home Assistant MQTT discovery messages
+	EnableHomeAssistant bool
+	HomeAssistantPrefix string
+
+	// Expose the container states via Modbus TCP
+	EnableModbus  bool
+	ModbusAddress string
+
+	// Forward the container lifecycle events (json lines) to local consumers via a unix socket or named pipe
+	EnableEventSocket bool
+	EventSocketPath   string
+	EventSocketType   string
+	EventSocketBuffer int
+
+	// Announce containers on the local network via mDNS (Avahi)
+	EnableMDNS      bool
+	MDNSServicesDir string
+
 	MQTTHost string
 	MQTTPort uint16
 
+	// Override the MQTT client id, so a one-off command does not take over the session of the running service
+	MQTTClientID string
+
 	CumulocityHost string
 	CumulocityPort uint16
+
+	// Controls which timestamps are included in the published messages
+	TimeMode tedge.TimeMode
+
+	// Audit log of mutating actions. nil = disabled
+	Audit *audit.Logger
+
+	// History of the container state transitions. nil = disabled
+	History *history.History
+
+	// The application publishes under an additional topic root. The history is shared by all topic roots,
+	// so only the primary application records the transitions (otherwise each one is recorded once per root)
+	Secondary bool
+
+	// Records of removed containers. nil = disabled
+	Archive        *archive.Archive
+	PublishArchive bool
+
+	// Additional backends (e.g. statsd or influx) which the container metrics are sent to
+	Exporters []exporter.Exporter
+
+	// Mirror the published messages to a secondary broker. nil = disabled
+	Bridge *bridge.Bridge
+
+	// Refresh the firewall rules of the installed containers when they are started. nil = disabled
+	Firewall *firewall.Firewall
 }
 
 func NewApp(device tedge.Target, config Config) (*App, error) {
@@ -86,8 +345,13 @@ func NewApp(device tedge.Target, config Config) (*App, error) {
 		CertFile: config.CertFile,
 		KeyFile:  config.KeyFile,
 		CAFile:   config.CAFile,
+		TimeMode: config.TimeMode,
+		ClientID: config.MQTTClientID,
 	}
 	tedgeClient := tedge.NewClient(device, *serviceTarget, config.ServiceName, tedgeOpts)
+	if config.Bridge != nil {
+		tedgeClient.Mirror = config.Bridge.Mirror
+	}
 
 	containerClient, err := container.NewContainerClient()
 	if err != nil {
@@ -98,103 +362,171 @@ func NewApp(device tedge.Target, config Config) (*App, error) {
 		return nil, err
 	}
 
+	application := &App{
+		client:           tedgeClient,
+		ContainerClient:  containerClient,
+		Device:           &device,
+		config:           config,
+		tombstones:       NewTombstones(filepath.Join(config.StateDir, "tombstones.json")),
+		fullRequests:     make(chan ActionRequest),
+		targetedRequests: make(chan ActionRequest),
+		shutdown:         make(chan struct{}),
+		startedAt:        time.Now(),
+		wg:               sync.WaitGroup{},
+	}
+
 	if tedgeClient.Target.CloudIdentity == "" {
-		for {
-			slog.Info("Looking up thin-edge.io Cumulocity ExternalID")
-			if currentUser, _, err := tedgeClient.CumulocityClient.User.GetCurrentUser(context.Background()); err == nil {
-				externalID := strings.TrimPrefix(currentUser.Username, "device_")
-				tedgeClient.Target.CloudIdentity = externalID
-				device.CloudIdentity = externalID
-				slog.Info("Found Cumulocity ExternalID", "value", tedgeClient.Target.CloudIdentity)
-				break
-			} else {
-				slog.Warn("Failed to lookup Cumulocity ExternalID.", "err", err)
-				// retry until it is successful
-				time.Sleep(10 * time.Second)
-			}
+		if err := application.resolveCloudIdentity(); err != nil {
+			return nil, err
 		}
 	}
 
-	application := &App{
-		client:          tedgeClient,
-		ContainerClient: containerClient,
-		Device:          &device,
-		config:          config,
-		updateRequests:  make(chan ActionRequest),
-		updateResults:   make(chan error),
-		shutdown:        make(chan struct{}),
-		wg:              sync.WaitGroup{},
+	if config.EnableMetrics && config.AdaptiveMetricsInterval > 0 && config.AdaptiveMetricsInterval < config.MetricsInterval {
+		application.adaptive = newAdaptiveMetrics(config.AdaptiveMetricsInterval, config.MetricsInterval, config.AdaptiveMetricsHold)
+	}
+
+	if config.EnableMetrics && config.EnableProjectMetrics && (config.EnableProjectHealth || config.GroupMode == GroupModeProject) {
+		// Members which were not collected in the last two intervals are no longer part of the aggregate
+		application.projectMetrics = newProjectMetrics(2 * config.MetricsInterval)
+	}
+
+	if config.EnableProjectVersionChanges {
+		application.projectVersions = &projectVersionTracker{QuietPeriod: config.ProjectVersionQuietPeriod}
+	}
+
+	if config.RestartBudgetMax > 0 && config.RestartBudgetWindow > 0 {
+		application.restartBudget = newRestartBudget(config.RestartBudgetMax, config.RestartBudgetWindow)
+	}
+
+	if config.StatusDebounce > 0 {
+		application.debounce = newStatusDebouncer(config.StatusDebounce)
+	}
+
+	if len(config.MetricsDeadband) > 0 {
+		application.deadband = newDeadbandFilter(config.MetricsDeadband)
+	}
+
+	if config.EnableModbus {
+		application.modbus = modbus.NewServer(config.ModbusAddress)
+	}
+
+	if config.EnableEventSocket {
+		application.eventSocket = eventsocket.NewServer(config.EventSocketPath, config.EventSocketType, config.EventSocketBuffer)
+	}
+
+	// Start background tasks to process requests. Full updates are processed one at a time,
+	// targeted updates are processed concurrently by a bounded number of workers
+	workers := max(config.UpdateWorkers, 1)
+	application.wg.Add(1 + workers)
+	go application.worker(application.fullRequests)
+	for i := 0; i < workers; i++ {
+		go application.worker(application.targetedRequests)
 	}
 
-	// Start background task to process requests
 	application.wg.Add(1)
-	go application.worker()
+	go application.watchEntities()
 
 	return application, nil
 }
 
+// Check if the main device of the topic root has been registered. The main device's registration
+// is not always retained, so the registration of one of its services (e.g. the tedge-agent) is also accepted
+func (a *App) HasMainDevice(serviceName string) bool {
+	main := tedge.NewTarget(a.Device.RootPrefix, "device/main//")
+	return a.client.HasEntity(*main) || (serviceName != "" && a.client.HasEntity(*main.Service(serviceName)))
+}
+
+// Subscribe to the health check requests of the container services. A request for the monitor's
+// own service refreshes all containers. The result is published once the refresh completed
 func (a *App) Subscribe() error {
 	topic := tedge.GetTopic(*a.Device.Service("+"), "cmd", "health", "check")
 	slog.Info("Listening to commands on topic.", "topic", topic)
 
-	a.client.Client.AddRoute(topic, func(c mqtt.Client, m mqtt.Message) {
-		parts := strings.Split(m.Topic(), "/")
-		if len(parts) > 5 {
-			slog.Info("Received request to update service data.", "service", parts[4], "topic", topic)
-			go func(name string) {
-				opts := container.FilterOptions{}
-				// If the name matches the current service name, then
-				// update all containers
-				if name != a.config.ServiceName {
-					opts.Names = []string{
-						fmt.Sprintf("^%s$", name),
-					}
-				}
-				a.updateRequests <- NewUpdateAllAction(opts)
-			}(parts[4])
+	return a.client.Subscribe(topic, 1, func(c mqtt.Client, m mqtt.Message) {
+		if m.Retained() {
+			// Only react to live requests
+			return
+		}
+		if name, ok := a.Device.ServiceName(m.Topic()); ok {
+			slog.Info("Received request to update service data.", "service", name, "topic", m.Topic())
+			go a.handleHealthCheck(m.Topic(), name, m.Payload())
 		}
 	})
+}
 
-	return nil
+func (a *App) handleHealthCheck(topic string, name string, payload []byte) {
+	opts := container.FilterOptions{}
+	// If the name matches the current service name, then
+	// update all containers
+	if name != a.config.ServiceName {
+		opts.Names = []string{
+			fmt.Sprintf("^%s$", name),
+		}
+	}
+	action := NewUpdateAllAction(opts)
+	if name != a.config.ServiceName {
+		action = NewTargetedUpdateAction(opts)
+	}
+	err := a.request(action)
+
+	// The requester can set an id to correlate the result with its request
+	request := struct {
+		ID string `json:"id"`
+	}{}
+	if len(payload) > 0 {
+		_ = json.Unmarshal(payload, &request)
+	}
+
+	result := map[string]any{
+		"status":  "successful",
+		"service": name,
+	}
+	if request.ID != "" {
+		result["id"] = request.ID
+	}
+	if err != nil {
+		result["status"] = "failed"
+		result["reason"] = err.Error()
+	}
+	resultTopic := topic + "/result"
+	slog.Info("Publishing health check result.", "topic", resultTopic, "status", result["status"])
+	if err := a.client.Publish(resultTopic, 1, false, mustMarshalJSON(a.client.Clock.SetTime(result))); err != nil {
+		slog.Warn("Could not publish health check result.", "topic", resultTopic, "err", err)
+	}
 }
 
-func (a *App) Stop(clean bool) {
+// Stop the background workers. If the workers don't stop before the context is done (e.g. as a call
+// to the container engine hangs), then they are abandoned and ErrShutdownTimeout is returned
+func (a *App) Stop(ctx context.Context, clean bool) error {
 	if a.client != nil {
 		if clean {
 			slog.Info("Disconnecting MQTT client cleanly")
 			a.client.Client.Disconnect(250)
 		}
 	}
-	a.shutdown <- struct{}{}
+	close(a.shutdown)
 
 	// Wait for shutdown confirmation
-	a.wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
 }
 
-func (a *App) worker() {
+func (a *App) worker(requests <-chan ActionRequest) {
 	defer a.wg.Done()
 	for {
 		select {
-		case opts := <-a.updateRequests:
-
-			switch opts.Action {
-			case ActionUpdateAll:
-				slog.Info("Processing update request")
-				err := a.doUpdate(opts.Options.(container.FilterOptions))
-				// Don't block when publishing results
-				go func() {
-					a.updateResults <- err
-				}()
-			case ActionUpdateMetrics:
-				items, err := a.ContainerClient.List(context.Background(), opts.Options.(container.FilterOptions))
-				if err != nil {
-					slog.Warn("Could not get container list.", "err", err)
-				} else {
-					if updateErr := a.updateMetrics(items); updateErr != nil {
-						slog.Warn("Error updating metrics.", "err", updateErr)
-					}
-				}
-			}
+		case opts := <-requests:
+			a.status.addQueued(-1)
+			a.process(opts)
 
 		case <-a.shutdown:
 			slog.Info("Stopping background task")
@@ -203,16 +535,57 @@ func (a *App) worker() {
 	}
 }
 
+func (a *App) process(opts ActionRequest) {
+	switch opts.Action {
+	case ActionUpdateAll:
+		slog.Info("Processing update request", "targeted", opts.Lane == LaneTargeted)
+		err := a.doUpdate(opts.Options.(container.FilterOptions))
+		a.status.recordError(err)
+		opts.reply(err)
+	case ActionRemoveServices:
+		a.removeServices(opts.Options.([]tedge.Target))
+		opts.reply(nil)
+	case ActionUpdateMetrics:
+		if a.config.BootstrapDeferMetrics && a.bootstrapState.Active() {
+			slog.Info("Deferring metrics until the bootstrap completed.")
+			opts.reply(nil)
+			return
+		}
+		items, err := a.ContainerClient.List(context.Background(), opts.Options.(container.FilterOptions))
+		if err != nil {
+			slog.Warn("Could not get container list.", "err", err)
+		} else {
+			err = a.updateMetrics(items)
+			if err != nil {
+				slog.Warn("Error updating metrics.", "err", err)
+			}
+			a.runProbes(items, opts.Options.(container.FilterOptions).IsEmpty())
+		}
+		a.status.recordError(err)
+		opts.reply(err)
+	}
+}
+
+// Send the result of a request to the caller without blocking the worker
+func (r ActionRequest) reply(err error) {
+	if r.result != nil {
+		r.result <- err
+	}
+}
+
+// Queue a request and wait for its result
+func (a *App) request(request ActionRequest) error {
+	request.result = make(chan error, 1)
+	a.enqueue(request)
+	return <-request.result
+}
+
 func (a *App) Update(filterOptions container.FilterOptions) error {
-	a.updateRequests <- NewUpdateAllAction(filterOptions)
-	err := <-a.updateResults
-	return err
+	return a.request(NewUpdateAllAction(filterOptions))
 }
 
 func (a *App) UpdateMetrics(filterOptions container.FilterOptions) error {
-	a.updateRequests <- NewUpdateMetricsAction(filterOptions)
-	err := <-a.updateResults
-	return err
+	return a.request(NewUpdateMetricsAction(filterOptions))
 }
 
 var ContainerEventText = map[events.Action]string{
@@ -227,6 +600,15 @@ var ContainerEventText = map[events.Action]string{
 	events.ActionExecDie: "process died",
 }
 
+// Options of the commands which modify containers, e.g. the commands must be signed when the verification is enabled
+func (a *App) commandOptions() tedge.CommandOptions {
+	opts := tedge.CommandOptions{}
+	if a.config.CommandVerifier != nil {
+		opts.Verify = a.config.CommandVerifier.Verify
+	}
+	return opts
+}
+
 func mustMarshalJSON(v any) []byte {
 	b, _ := json.Marshal(v)
 	return b
@@ -247,6 +629,9 @@ func getEventAttributes(attr map[string]string, props ...string) []string {
 func (a *App) Monitor(ctx context.Context, filterOptions container.FilterOptions) error {
 	evtCh, errCh := a.ContainerClient.MonitorEvents(ctx)
 
+	// Publish the events which were missed before subscribing
+	a.catchUpTransitions(ctx, filterOptions)
+
 	// Update after subscribing to the events but before reacting to them
 	if err := a.Update(filterOptions); err != nil {
 		slog.Warn("Error updating container state.", "err", err)
@@ -279,24 +664,46 @@ func (a *App) Monitor(ctx context.Context, filterOptions container.FilterOptions
 					payload["attributes"] = evt.Actor.Attributes
 				}
 
+				if evt.Action != events.ActionExecDie {
+					a.recordTransition(evt)
+					a.forwardEvent(evt)
+				}
+				a.triggerAdaptiveMetrics(evt)
+				a.resetProbeResult(evt)
+				a.checkRestartBudget(evt)
+				a.refreshFirewall(evt)
+
 				switch evt.Action {
-				case events.ActionCreate, events.ActionStart, events.ActionStop, events.ActionPause, events.ActionUnPause, events.ActionExecDie:
+				case events.ActionCreate, events.ActionStart, events.ActionStop, events.ActionPause, events.ActionUnPause, events.ActionExecDie, events.ActionDie:
 					go func() {
 						// Delay before trigger update to allow the service status to be updated
 						time.Sleep(500 * time.Millisecond)
-						if err := a.Update(container.FilterOptions{
+						if err := a.request(NewTargetedUpdateAction(container.FilterOptions{
 							IDs: []string{evt.Actor.ID},
-						}); err != nil {
+						})); err != nil {
 							slog.Warn("Error updating container state.", "err", err)
 						}
 					}()
-				case events.ActionDestroy, events.ActionRemove, events.ActionDie:
+				case events.ActionDestroy, events.ActionRemove:
 					slog.Info("Container removed/destroyed", "container", evt.Actor.ID, "attributes", evt.Actor.Attributes)
-					// TODO: Trigger a removal instead of checking the whole state
-					// Lookup container name by container id (from the entity store) as lookup by name won't work for container-groups
+					if a.deadband != nil {
+						a.deadband.Remove(evt.Actor.ID)
+					}
+					a.starting.Remove(evt.Actor.ID)
+					a.enrichments.Remove(evt.Actor.ID)
+
+					// Containers are often recreated under the same name (e.g. upgrades), so the service
+					// of the removed container is only removed by the full update, which applies the stale
+					// service guard and cleanup exclusions. Wait for the confirm delay so that the new
+					// container is already seen by the update
+					delay := 500 * time.Millisecond
+					if entry, ok := a.client.LookupContainer(evt.Actor.ID); ok && !entry.Shared {
+						slog.Info("Found service for removed container.", "container", evt.Actor.ID, "topic", entry.Target.Topic())
+						delay = max(delay, a.config.StaleConfirmDelay)
+					}
 					go func() {
 						// Delay before trigger update to allow the service status to be updated
-						time.Sleep(500 * time.Millisecond)
+						time.Sleep(delay)
 						if err := a.Update(container.FilterOptions{}); err != nil {
 							slog.Warn("Error updating container state.", "err", err)
 						}
@@ -324,21 +731,58 @@ func (a *App) Monitor(ctx context.Context, filterOptions container.FilterOptions
 	}
 }
 
-func (a *App) updateMetrics(items []container.TedgeContainer) error {
+func (a *App) updateMetrics(allItems []container.TedgeContainer) error {
+	// Swarm task containers are ephemeral so they are not registered as services
+	items := make([]container.TedgeContainer, 0, len(allItems))
+	for _, item := range allItems {
+		if item.Container.StackName == "" {
+			items = append(items, item)
+		}
+	}
+
 	totalWorkers := 5
 	numJobs := len(items)
 	jobs := make(chan container.TedgeContainer, numJobs)
 	results := make(chan error, numJobs)
 
+	samples := make([]exporter.Sample, 0, numJobs)
+	samplesMutex := sync.Mutex{}
+
 	doWork := func(jobs <-chan container.TedgeContainer, results chan<- error) {
 		for j := range jobs {
 			var jobErr error
 			stats, jobErr := a.ContainerClient.GetStats(context.Background(), j.Container.Id)
 
+			if jobErr == nil && (len(a.config.Exporters) > 0 || a.projectMetrics != nil) {
+				samplesMutex.Lock()
+				samples = append(samples, newMetricsSample(j, stats))
+				samplesMutex.Unlock()
+			}
+
 			if jobErr == nil {
 				target := a.Device.Service(j.Name)
+				var series any = stats.Container
+				if a.deadband != nil {
+					values := a.deadband.Filter(j.Container.Id, stats.Container.Series(), time.Now())
+					if len(values) == 0 {
+						slog.Debug("Skipping container stats without significant changes.", "container", j.Name)
+						results <- nil
+						continue
+					}
+					series = values
+				}
+				var data any = map[string]any{
+					"container": series,
+				}
+				if a.config.GroupMode == GroupModeProject && j.Container.ProjectName != "" {
+					// Publish the stats of each member under the project service
+					target = a.Device.Service(j.Container.ProjectName)
+					data = map[string]any{
+						j.Container.ServiceName: series,
+					}
+				}
 				topic := tedge.GetTopic(*target, "m", "resource_usage")
-				payload, err := json.Marshal(stats)
+				payload, err := json.Marshal(data)
 				if err == nil {
 					slog.Info("Publish container stats.", "topic", topic, "payload", payload)
 					jobErr = a.client.Publish(topic, 1, false, payload)
@@ -365,9 +809,138 @@ func (a *App) updateMetrics(items []container.TedgeContainer) error {
 			slog.Warn("Failed to update metrics.", "err", err)
 		}
 	}
+
+	a.exportMetrics(samples)
+	a.publishProjectMetrics(samples)
 	return errors.Join(jobErrors...)
 }
 
+// Publish the container engine availability as a dedicated service
+func (a *App) UpdateEngineStatus() error {
+	if !a.config.EnableEngineService {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status := a.ContainerClient.GetEngineStatus(ctx)
+
+	target := a.Device.Service(a.config.EngineServiceName)
+	entities, err := a.client.GetEntities()
+	if err != nil {
+		return err
+	}
+	if _, ok := entities[target.Topic()]; !ok {
+		payload, err := tedge.PayloadRegistration(map[string]any{"type": container.ContainerEngineType}, a.config.EngineServiceName, "service", "")
+		if err != nil {
+			return err
+		}
+		if err := a.client.Publish(target.Topic(), 1, true, payload); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(a.client.Clock.SetTime(map[string]any{
+		"status": status.Status,
+	}))
+	if err != nil {
+		return err
+	}
+	topic := tedge.GetHealthTopic(*target)
+	slog.Info("Publishing container engine health status", "topic", topic, "payload", b)
+	if err := a.client.Publish(topic, 1, true, b); err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return a.client.Publish(tedge.GetTopic(*target, "twin", "engine"), 1, true, b)
+}
+
+// Register a service if it is not already registered
+// The service is removed from the existing services so that it is not treated as stale
+func (a *App) registerService(name string, serviceType string, existingServices map[string]struct{}) {
+	target := a.Device.Service(name)
+
+	// Revive a service which was previously marked as removed
+	if removed, err := a.tombstones.Remove(target.Topic()); removed {
+		slog.Info("Removing tombstone from service.", "topic", target.Topic())
+		if err != nil {
+			slog.Warn("Could not save tombstones.", "err", err)
+		}
+		if err := a.client.Publish(tedge.GetTopic(*target, "twin", "tombstone"), 1, true, ""); err != nil {
+			slog.Warn("Could not clear tombstone.", "err", err)
+		}
+	}
+
+	// Skip registration message if it already exists
+	if _, ok := existingServices[target.Topic()]; ok {
+		slog.Debug("Container is already registered", "topic", target.Topic())
+		delete(existingServices, target.Topic())
+		return
+	}
+	delete(existingServices, target.Topic())
+
+	payload := map[string]any{
+		"@type": "service",
+		"name":  name,
+		"type":  serviceType,
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		slog.Warn("Could not marshal registration message", "err", err)
+		return
+	}
+	if err := a.client.Publish(target.Topic(), 1, true, b); err != nil {
+		slog.Error("Failed to register container", "target", target.Topic(), "err", err)
+	}
+}
+
+// Get the aggregated health of each compose project referenced by the given containers.
+// If the list is only partial (e.g. filtered to a single container), then all of
+// the project's containers are fetched so that the aggregated status is correct
+func (a *App) getProjectHealth(items []container.TedgeContainer, partial bool) []container.ProjectHealth {
+	projects := container.GroupByProject(items)
+	out := make([]container.ProjectHealth, 0, len(projects))
+	for name, members := range projects {
+		if partial {
+			allMembers, err := a.ContainerClient.List(context.Background(), container.FilterOptions{
+				Labels: []string{"com.docker.compose.project=" + name},
+			})
+			if err != nil {
+				slog.Warn("Could not get compose project containers.", "project", name, "err", err)
+				continue
+			}
+			members = allMembers
+		}
+		out = append(out, container.AggregateProjectHealth(name, members, a.config.ProjectHealth))
+	}
+	return out
+}
+
+// Get the swarm stacks referenced by the given containers.
+// Like the compose projects, all of the stack's containers are fetched if the list is only partial
+func (a *App) getStacks(items []container.TedgeContainer, partial bool) []container.Stack {
+	stacks := container.GroupByStack(items)
+	out := make([]container.Stack, 0, len(stacks))
+	for name, members := range stacks {
+		if partial {
+			allMembers, err := a.ContainerClient.List(context.Background(), container.FilterOptions{
+				Labels: []string{container.LabelStackNamespace + "=" + name},
+			})
+			if err != nil {
+				slog.Warn("Could not get stack containers.", "stack", name, "err", err)
+				continue
+			}
+			members = allMembers
+		}
+		replicas := a.ContainerClient.GetStackReplicas(context.Background(), name)
+		out = append(out, container.NewStack(name, members, replicas))
+	}
+	return out
+}
+
 func (a *App) doUpdate(filterOptions container.FilterOptions) error {
 	tedgeClient := a.client
 	entities, err := tedgeClient.GetEntities()
@@ -382,130 +955,281 @@ func (a *App) doUpdate(filterOptions container.FilterOptions) error {
 	// Record all registered services
 	existingServices := make(map[string]struct{})
 	for k, v := range entities {
-		if v.(map[string]any)["type"] == container.ContainerType || v.(map[string]any)["type"] == container.ContainerGroupType {
+		switch v.(map[string]any)["type"] {
+		case container.ContainerType, container.ContainerGroupType, container.ContainerStackType:
 			existingServices[k] = struct{}{}
 		}
 	}
+	totalRegistered := len(existingServices)
 	slog.Info("Found entities.", "total", len(entities))
 	for key := range entities {
 		slog.Debug("Entity store.", "key", key)
 	}
 
 	slog.Info("Reading containers")
-	items, err := a.ContainerClient.List(context.Background(), filterOptions)
+	result, err := a.ContainerClient.ListBounded(context.Background(), filterOptions)
 	if err != nil {
 		return err
 	}
+	items := result.Items
+	a.applyStartingStatus(items, time.Now())
+	a.applyProbeResults(items)
+
+	// A truncated list does not include all containers, so it can't be used to detect removed containers
+	complete := filterOptions.IsEmpty() && !result.Truncated
+	removeStaleServices = removeStaleServices && complete
+	if removeStaleServices {
+		// Only count the containers and detect removed containers when all containers were read
+		a.status.recordUpdate(len(items))
+		a.archiveRemoved(items)
+	}
+
+	if a.modbus != nil && complete {
+		a.modbus.SetRegisters(getModbusRegisters(items))
+	}
+	if a.config.EnableChangeEvents && complete {
+		a.publishContainerChanges(items)
+	}
+	if complete {
+		a.checkProjectVersions(items)
+	}
+	if a.config.EnableSummary {
+		a.publishSummary(items, complete)
+	}
+
+	projectMode := a.config.GroupMode == GroupModeProject
+	projects := make([]container.ProjectHealth, 0)
+	if a.config.EnableProjectHealth || projectMode {
+		projects = a.getProjectHealth(items, !filterOptions.IsEmpty())
+	}
+
+	stacks := a.getStacks(items, !filterOptions.IsEmpty())
+
+	// Containers which are registered as individual services.
+	// Swarm task containers are only registered via their stack
+	services := make([]container.TedgeContainer, 0, len(items))
+	nested := make([]container.TedgeContainer, 0)
+	for _, item := range items {
+		if item.Container.StackName != "" {
+			continue
+		}
+		if projectMode && item.Container.ProjectName != "" {
+			continue
+		}
+		// Nested thin-edge.io instances register their own entity
+		if _, ok := getNestedTopicID(item); ok {
+			nested = append(nested, item)
+			continue
+		}
+		services = append(services, item)
+	}
+
+	if complete {
+		a.bootstrap(services, projects, stacks, existingServices)
+	}
 
 	// Register devices
 	slog.Info("Registering containers")
-	for _, item := range items {
-		target := a.Device.Service(item.Name)
+	for _, item := range services {
+		a.registerService(item.Name, item.ServiceType, existingServices)
+	}
+	for _, project := range projects {
+		a.registerService(project.Name, container.ContainerGroupType, existingServices)
+	}
+	for _, stack := range stacks {
+		a.registerService(stack.Name, container.ContainerStackType, existingServices)
+	}
 
-		// Skip registration message if it already exists
-		if _, ok := existingServices[target.Topic()]; ok {
-			slog.Debug("Container is already registered", "topic", target.Topic())
-			delete(existingServices, target.Topic())
+	a.tagCloudServices(services, projects, stacks)
+
+	// Publish health messages. The messages are published as a batch so the round trips to the broker overlap
+	healthMessages := make([]tedge.Message, 0, len(services)+len(stacks)+len(projects))
+	for _, item := range services {
+		target := a.Device.Service(item.Name)
+		if !a.debounceStatus(target.Topic(), item.Status, item.Container.Labels, container.FilterOptions{IDs: []string{item.Container.Id}}) {
 			continue
 		}
-		delete(existingServices, target.Topic())
 
 		payload := map[string]any{
-			"@type": "service",
-			"name":  item.Name,
-			"type":  item.ServiceType,
+			"status": item.Status,
+		}
+		if a.config.HealthNetworkInfo {
+			payload = addHealthNetworkInfo(payload, item.Container)
 		}
-		b, err := json.Marshal(payload)
+		payload = a.addHealthProbeResult(payload, item)
+		b, err := json.Marshal(a.client.Clock.SetTime(payload))
 		if err != nil {
 			slog.Warn("Could not marshal registration message", "err", err)
 			continue
 		}
-		if err := tedgeClient.Publish(target.Topic(), 1, true, b); err != nil {
-			slog.Error("Failed to register container", "target", target.Topic(), "err", err)
-		}
+		topic := tedge.GetHealthTopic(*target)
+		slog.Info("Publishing container health status", "topic", topic, "payload", b)
+		healthMessages = append(healthMessages, tedge.Message{Topic: topic, Retained: true, Payload: b})
 	}
 
-	// Publish health messages
-	for _, item := range items {
-		target := a.Device.Service(item.Name)
+	// Publish swarm stack health messages
+	for _, stack := range stacks {
+		target := a.Device.Service(stack.Name)
+		if !a.debounceStatus(target.Topic(), stack.Status, nil, container.FilterOptions{Labels: []string{container.LabelStackNamespace + "=" + stack.Name}}) {
+			continue
+		}
+		b, err := json.Marshal(a.client.Clock.SetTime(map[string]any{
+			"status": stack.Status,
+		}))
+		if err != nil {
+			slog.Warn("Could not marshal stack health message", "err", err)
+			continue
+		}
+		topic := tedge.GetHealthTopic(*target)
+		slog.Info("Publishing stack health status", "topic", topic, "payload", b)
+		healthMessages = append(healthMessages, tedge.Message{Topic: topic, Retained: true, Payload: b})
+	}
 
-		payload := map[string]any{
-			"status": item.Status,
-			"time":   item.Time,
+	// Publish aggregated compose project health messages
+	for _, project := range projects {
+		target := a.Device.Service(project.Name)
+		if !a.debounceStatus(target.Topic(), project.Status, nil, container.FilterOptions{Labels: []string{"com.docker.compose.project=" + project.Name}}) {
+			continue
 		}
-		b, err := json.Marshal(payload)
+		payload := make(map[string]any)
+		if err := json.Unmarshal(mustMarshalJSON(project), &payload); err != nil {
+			slog.Warn("Could not marshal project health message", "err", err)
+			continue
+		}
+		b, err := json.Marshal(a.client.Clock.SetTime(payload))
 		if err != nil {
-			slog.Warn("Could not marshal registration message", "err", err)
+			slog.Warn("Could not marshal project health message", "err", err)
 			continue
 		}
 		topic := tedge.GetHealthTopic(*target)
-		slog.Info("Publishing container health status", "topic", topic, "payload", b)
-		if err := tedgeClient.Publish(topic, 1, true, b); err != nil {
-			slog.Error("Failed to update health status", "target", topic, "err", err)
+		slog.Info("Publishing project health status", "topic", topic, "payload", b)
+		healthMessages = append(healthMessages, tedge.Message{Topic: topic, Retained: true, Payload: b})
+	}
+
+	for _, result := range tedgeClient.PublishBatch(healthMessages) {
+		if result.Err != nil {
+			slog.Error("Failed to update health status", "target", result.Topic, "err", result.Err)
 		}
 	}
 
 	// update digital twin information
 	slog.Info("Updating digital twin information")
-	for _, item := range items {
+	twinMessages := make([]tedge.Message, 0, len(services)+len(projects)+len(stacks))
+	for _, item := range services {
 		target := a.Device.Service(item.Name)
-
-		topic := tedge.GetTopic(*target, "twin", "container")
+		var messages []tedge.Message
 
 		// Create status
-		payload, err := json.Marshal(item.Container)
+		payload, err := a.containerTwin(item)
+		if err == nil {
+			messages, err = a.twinMessages(*target, payload)
+		}
+		if err != nil {
+			slog.Error("Failed to convert payload to json", "err", err)
+			continue
+		}
+
+		slog.Info("Publishing container status", "topic", tedge.GetTopic(*target, "twin", "container"), "payload", payload)
+		twinMessages = append(twinMessages, messages...)
+	}
+
+	// In project mode, the project's twin lists all of the member containers
+	if projectMode {
+		for _, project := range projects {
+			target := a.Device.Service(project.Name)
+
+			members := make([]container.Container, 0, len(project.Members))
+			for _, member := range project.Members {
+				members = append(members, member.Container)
+			}
+			payload, err := json.Marshal(map[string]any{
+				"projectName": project.Name,
+				"containers":  members,
+			})
+			var messages []tedge.Message
+			if err == nil {
+				messages, err = a.twinMessages(*target, payload)
+			}
+			if err != nil {
+				slog.Error("Failed to convert payload to json", "err", err)
+				continue
+			}
+
+			slog.Info("Publishing project status", "topic", tedge.GetTopic(*target, "twin", "container"), "payload", payload)
+			twinMessages = append(twinMessages, messages...)
+		}
+	}
 
+	// The stack's twin lists the services and their replica counts
+	for _, stack := range stacks {
+		target := a.Device.Service(stack.Name)
+		payload, err := json.Marshal(stack)
+		var messages []tedge.Message
+		if err == nil {
+			messages, err = a.twinMessages(*target, payload)
+		}
 		if err != nil {
 			slog.Error("Failed to convert payload to json", "err", err)
 			continue
 		}
 
-		slog.Info("Publishing container status", "topic", topic, "payload", payload)
-		if err := tedgeClient.Publish(topic, 1, true, payload); err != nil {
-			slog.Error("Could not publish container status", "err", err)
+		slog.Info("Publishing stack status", "topic", tedge.GetTopic(*target, "twin", "container"), "payload", payload)
+		twinMessages = append(twinMessages, messages...)
+	}
+
+	for _, result := range tedgeClient.PublishBatch(twinMessages) {
+		if result.Err != nil {
+			slog.Error("Could not publish container status", "topic", result.Topic, "err", result.Err)
 		}
 	}
 
+	a.publishNested(nested, complete)
+
+	if a.config.EnableProvenance {
+		a.publishProvenance(services)
+	}
+
+	if a.config.EnableHomeAssistant {
+		a.publishDiscovery(services, projects, stacks)
+	}
+
 	// Delete removed values, via MQTT and c8y API
 	markedForDeletion := make([]tedge.Target, 0)
+	// Services which are already marked as removed have previously been confirmed
+	newStale := 0
+	for staleTopic := range existingServices {
+		if _, ok := a.tombstones.Get(staleTopic); !ok {
+			newStale++
+		}
+	}
+	if removeStaleServices && exceedsRemovalLimit(newStale, totalRegistered, a.config.StaleMaxRemovalRatio) {
+		slog.Warn("Removal of stale services exceeds the allowed limit. Confirming with a second sample.", "stale", newStale, "registered", totalRegistered, "delay", a.config.StaleConfirmDelay)
+		a.scheduleStaleConfirm(existingServices, entities, filterOptions)
+		existingServices = map[string]struct{}{}
+	}
 	if removeStaleServices {
 		slog.Info("Checking for any stale services")
 		for staleTopic := range existingServices {
-			slog.Info("Removing stale service", "topic", staleTopic)
+			slog.Info("Found stale service", "topic", staleTopic)
 			target, err := tedge.NewTargetFromTopic(staleTopic)
 			if err != nil {
 				slog.Warn("Invalid topic structure", "err", err)
 				continue
 			}
-
-			if err := tedgeClient.DeregisterEntity(*target, "twin/container"); err != nil {
-				slog.Warn("Failed to deregister entity.", "err", err)
-			}
-
-			// mark targets for deletion from the cloud, but don't delete them yet to give time
-			// for thin-edge.io to process the status updates
 			markedForDeletion = append(markedForDeletion, *target)
 		}
+		a.removeServices(a.previewStaleServices(markedForDeletion, entities))
 
-		// Delete cloud
-		if len(markedForDeletion) > 0 {
-			// Delay before deleting messages
-			time.Sleep(500 * time.Millisecond)
-			for _, target := range markedForDeletion {
-				slog.Info("Removing service from the cloud", "topic", target.Topic())
-
-				// FIXME: How to handle if the device is deregistered locally, but still exists in the cloud?
-				// Should it try to reconcile with the cloud to delete orphaned services?
-				// Delete service directly from Cumulocity using the local Cumulocity Proxy
-				target.CloudIdentity = tedgeClient.Target.CloudIdentity
-				if target.CloudIdentity != "" {
-					// Delay deleting the value
-					if _, err := tedgeClient.DeleteCumulocityManagedObject(target); err != nil {
-						slog.Warn("Failed to delete managed object.", "err", err)
-					}
-				}
+		if a.config.EnableMDNS {
+			if err := a.announceServices(items); err != nil {
+				slog.Warn("Could not update mDNS service announcements.", "err", err)
 			}
 		}
+
+		if err := a.UpdateProfiles(); err != nil {
+			slog.Warn("Could not update profiles.", "err", err)
+		}
+		a.status.recordReconcile(time.Now())
 	}
 
 	return nil
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func Test_StopTimeout(t *testing.T) {
+	application := &App{
+		shutdown: make(chan struct{}),
+	}
+	// Background task which never stops
+	application.wg.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, application.Stop(ctx, false), ErrShutdownTimeout)
+}
+
+func Test_Stop(t *testing.T) {
+	application := &App{
+		shutdown: make(chan struct{}),
+	}
+	application.wg.Add(1)
+	go func() {
+		<-application.shutdown
+		application.wg.Done()
+	}()
+	assert.NoError(t, application.Stop(context.Background(), false))
+}
+
+func Test_HasMainDevice(t *testing.T) {
+	a := &App{
+		client: &tedge.Client{Entities: map[string]any{}},
+		Device: tedge.NewTarget("te", "device/child01//"),
+	}
+	assert.False(t, a.HasMainDevice("tedge-agent"))
+
+	a.client.Entities["te/device/main/service/tedge-agent"] = map[string]any{}
+	assert.True(t, a.HasMainDevice("tedge-agent"))
+	assert.False(t, a.HasMainDevice("other"))
+
+	a.client.Entities["te/device/main//"] = map[string]any{}
+	assert.True(t, a.HasMainDevice(""))
+}
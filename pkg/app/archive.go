@@ -0,0 +1,85 @@
+package app
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/archive"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Topic of the retained archive record of a removed container
+func (a *App) archiveTopic(name string) string {
+	return tedge.GetTopic(a.client.Target, "archive", name)
+}
+
+// Keep a record of the containers which disappeared since the last (complete) update, with the time
+// they were last seen and their final state. Expired records are pruned
+func (a *App) archiveRemoved(items []container.TedgeContainer) {
+	containerArchive := a.config.Archive
+	if containerArchive == nil {
+		return
+	}
+	now := time.Now()
+
+	current := make(map[string]container.TedgeContainer, len(items))
+	for _, item := range items {
+		current[item.Name] = item
+
+		// A container with the same name was created again
+		if removed, err := containerArchive.Remove(item.Name); removed {
+			if err != nil {
+				slog.Warn("Could not save container archive.", "err", err)
+			}
+			a.clearArchiveRecord(item.Name)
+		}
+	}
+
+	// The first update only provides the baseline
+	if a.lastSeen != nil {
+		for name, item := range a.lastSeen {
+			if _, ok := current[name]; ok {
+				continue
+			}
+			record := archive.Record{
+				Name:        name,
+				ServiceType: item.ServiceType,
+				ContainerID: item.Container.Id,
+				Image:       item.Container.Image,
+				State:       item.Container.State,
+				Status:      item.Container.Status,
+				LastSeen:    a.lastSeenAt,
+				RemovedAt:   now,
+			}
+			slog.Info("Archiving removed container.", "name", name, "lastSeen", record.LastSeen)
+			if err := containerArchive.Add(record); err != nil {
+				slog.Warn("Could not save container archive.", "err", err)
+			}
+			if a.config.PublishArchive {
+				if err := a.client.Publish(a.archiveTopic(name), 1, true, mustMarshalJSON(record)); err != nil {
+					slog.Warn("Could not publish archive record.", "name", name, "err", err)
+				}
+			}
+		}
+	}
+	a.lastSeen = current
+	a.lastSeenAt = now
+
+	expired, err := containerArchive.Prune(now)
+	if err != nil {
+		slog.Warn("Could not save container archive.", "err", err)
+	}
+	for _, record := range expired {
+		a.clearArchiveRecord(record.Name)
+	}
+}
+
+func (a *App) clearArchiveRecord(name string) {
+	if !a.config.PublishArchive {
+		return
+	}
+	if err := a.client.Publish(a.archiveTopic(name), 1, true, ""); err != nil {
+		slog.Warn("Could not clear archive record.", "name", name, "err", err)
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Event type published once the bootstrap registered all of the existing containers
+var EventTypeBootstrap = "container_bootstrap"
+
+// State of the staged bootstrap, which only runs once on the first full update
+type bootstrapState struct {
+	mutex  sync.Mutex
+	active bool
+	done   bool
+}
+
+// Start the bootstrap, and return false if it already ran
+func (s *bootstrapState) Begin() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done || s.active {
+		return false
+	}
+	s.active = true
+	return true
+}
+
+func (s *bootstrapState) Finish() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.active = false
+	s.done = true
+}
+
+// Check if the bootstrap is in progress
+func (s *bootstrapState) Active() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.active
+}
+
+// Service which is registered by the bootstrap
+type bootstrapService struct {
+	Name   string
+	Type   string
+	Status string
+}
+
+// Get the services which are not registered yet
+func pendingServices(services []container.TedgeContainer, projects []container.ProjectHealth, stacks []container.Stack, device tedge.Target, existingServices map[string]struct{}) []bootstrapService {
+	pending := make([]bootstrapService, 0)
+	add := func(name string, serviceType string, status string) {
+		if _, ok := existingServices[device.Service(name).Topic()]; ok {
+			return
+		}
+		pending = append(pending, bootstrapService{Name: name, Type: serviceType, Status: status})
+	}
+	for _, item := range services {
+		add(item.Name, item.ServiceType, item.Status)
+	}
+	for _, project := range projects {
+		add(project.Name, container.ContainerGroupType, project.Status)
+	}
+	for _, stack := range stacks {
+		add(stack.Name, container.ContainerStackType, stack.Status)
+	}
+	return pending
+}
+
+// Split the services into chunks of the given size
+func chunkServices(services []bootstrapService, size int) [][]bootstrapService {
+	chunks := make([][]bootstrapService, 0, (len(services)+size-1)/size)
+	for start := 0; start < len(services); start += size {
+		end := min(start+size, len(services))
+		chunks = append(chunks, services[start:end])
+	}
+	return chunks
+}
+
+// Register the existing containers in chunks when there are more unregistered services than
+// the chunk size (e.g. a new install on a device which already runs hundreds of containers),
+// so the broker and the cloud are not overloaded by the registration messages.
+// The registered services are added to the existing services, so they are not registered again
+func (a *App) bootstrap(services []container.TedgeContainer, projects []container.ProjectHealth, stacks []container.Stack, existingServices map[string]struct{}) {
+	if a.config.BootstrapChunkSize <= 0 || !a.bootstrapState.Begin() {
+		return
+	}
+	defer a.bootstrapState.Finish()
+
+	pending := pendingServices(services, projects, stacks, *a.Device, existingServices)
+	if len(pending) <= a.config.BootstrapChunkSize {
+		return
+	}
+
+	chunks := chunkServices(pending, a.config.BootstrapChunkSize)
+	slog.Info("Bootstrapping services.", "total", len(pending), "chunks", len(chunks), "interval", a.config.BootstrapInterval)
+	startedAt := time.Now()
+	for i, chunk := range chunks {
+		if i > 0 {
+			select {
+			case <-a.shutdown:
+				slog.Info("Stopping bootstrap.", "registered", i*a.config.BootstrapChunkSize, "total", len(pending))
+				return
+			case <-time.After(a.config.BootstrapInterval):
+			}
+		}
+
+		slog.Info("Registering bootstrap chunk.", "chunk", i+1, "chunks", len(chunks), "services", len(chunk))
+		healthMessages := make([]tedge.Message, 0, len(chunk))
+		for _, service := range chunk {
+			a.registerService(service.Name, service.Type, existingServices)
+			target := a.Device.Service(service.Name)
+			existingServices[target.Topic()] = struct{}{}
+
+			status := service.Status
+			if a.config.BootstrapInitialStatus != "" {
+				status = a.config.BootstrapInitialStatus
+			}
+			b, err := json.Marshal(a.client.Clock.SetTime(map[string]any{
+				"status": status,
+			}))
+			if err != nil {
+				slog.Warn("Could not marshal health message", "err", err)
+				continue
+			}
+			healthMessages = append(healthMessages, tedge.Message{Topic: tedge.GetHealthTopic(*target), Retained: true, Payload: b})
+		}
+		for _, result := range a.client.PublishBatch(healthMessages) {
+			if result.Err != nil {
+				slog.Error("Failed to update health status", "target", result.Topic, "err", result.Err)
+			}
+		}
+	}
+
+	duration := time.Since(startedAt)
+	slog.Info("Bootstrap completed.", "total", len(pending), "duration", duration)
+	b, err := json.Marshal(a.client.Clock.SetTime(map[string]any{
+		"text":     fmt.Sprintf("Bootstrap completed. Registered %d services", len(pending)),
+		"services": len(pending),
+		"chunks":   len(chunks),
+		"duration": duration.Seconds(),
+	}))
+	if err != nil {
+		slog.Warn("Could not marshal bootstrap event.", "err", err)
+		return
+	}
+	if err := a.client.Publish(tedge.GetTopic(*a.Device, "e", EventTypeBootstrap), 1, false, b); err != nil {
+		slog.Warn("Could not publish bootstrap event.", "err", err)
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func Test_pendingServices(t *testing.T) {
+	device := tedge.NewTarget("te", "device/main//")
+	services := []container.TedgeContainer{
+		{Name: "app1", ServiceType: container.ContainerType, Status: "up"},
+		{Name: "app2", ServiceType: container.ContainerType, Status: "down"},
+	}
+	projects := []container.ProjectHealth{{Name: "project1", Status: "up"}}
+	existing := map[string]struct{}{
+		device.Service("app1").Topic(): {},
+	}
+
+	pending := pendingServices(services, projects, nil, *device, existing)
+	assert.Equal(t, []bootstrapService{
+		{Name: "app2", Type: container.ContainerType, Status: "down"},
+		{Name: "project1", Type: container.ContainerGroupType, Status: "up"},
+	}, pending)
+}
+
+func Test_chunkServices(t *testing.T) {
+	services := []bootstrapService{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	chunks := chunkServices(services, 2)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, []bootstrapService{{Name: "e"}}, chunks[2])
+
+	assert.Len(t, chunkServices(services, 5), 1)
+	assert.Empty(t, chunkServices(nil, 5))
+}
+
+func Test_bootstrapState(t *testing.T) {
+	state := bootstrapState{}
+	assert.True(t, state.Begin())
+	assert.True(t, state.Active())
+	assert.False(t, state.Begin())
+	state.Finish()
+	assert.False(t, state.Active())
+	assert.False(t, state.Begin())
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Maximum difference between the time of an engine event and the container's start/finish time
+var catchUpTolerance = 2 * time.Second
+
+// State of a container as reported by the container engine
+type containerTimes struct {
+	ID         string
+	Image      string
+	Running    bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExitCode   int
+}
+
+// Missed state transition, which is published as the engine event it would have been
+type missedTransition struct {
+	Action     events.Action
+	Transition history.Transition
+}
+
+func latestTransition(recorded []history.Transition, action string) time.Time {
+	latest := time.Time{}
+	for _, item := range recorded {
+		if item.Action == action && item.Time.After(latest) {
+			latest = item.Time
+		}
+	}
+	return latest
+}
+
+// Compare the container's start and finish times with the recorded transitions, and return the
+// transitions which were missed (e.g. while the monitor was not running)
+func missedTransitions(recorded []history.Transition, state containerTimes, now time.Time) []missedTransition {
+	missed := make([]missedTransition, 0)
+
+	started := ContainerEventText[events.ActionStart]
+	if !state.StartedAt.IsZero() && state.StartedAt.After(latestTransition(recorded, started).Add(catchUpTolerance)) {
+		missed = append(missed, missedTransition{
+			Action: events.ActionStart,
+			Transition: history.Transition{
+				Time:        state.StartedAt,
+				Action:      started,
+				ContainerID: state.ID,
+				Image:       state.Image,
+			},
+		})
+	}
+
+	died := ContainerEventText[events.ActionDie]
+	if !state.Running && state.FinishedAt.After(state.StartedAt) && state.FinishedAt.After(latestTransition(recorded, died).Add(catchUpTolerance)) {
+		missed = append(missed, missedTransition{
+			Action: events.ActionDie,
+			Transition: history.Transition{
+				Time:        state.FinishedAt,
+				Action:      died,
+				ContainerID: state.ID,
+				Image:       state.Image,
+				ExitCode:    strconv.Itoa(state.ExitCode),
+			},
+		})
+	}
+
+	// Transitions in the future indicate that the clock has drifted, so they can't be trusted
+	valid := missed[:0]
+	for _, item := range missed {
+		if item.Transition.Time.After(now.Add(catchUpTolerance)) {
+			slog.Warn("Container transition is in the future, the clock might have drifted.", "container", state.ID, "action", item.Transition.Action, "time", item.Transition.Time, "now", now)
+			continue
+		}
+		valid = append(valid, item)
+	}
+	return valid
+}
+
+// Synthesize the engine events of container restarts which occurred while the monitor was not running,
+// so that the timeline in the cloud remains accurate. The recorded history is used to detect the missed events,
+// so only the primary application (which records the history) catches up
+func (a *App) catchUpTransitions(ctx context.Context, filterOptions container.FilterOptions) {
+	containerHistory := a.config.History
+	if containerHistory == nil || a.config.Secondary {
+		return
+	}
+	if containerHistory.Empty() {
+		// Nothing has been observed yet (e.g. first start), so it is not possible to tell what was missed
+		slog.Info("Skipping catch-up of missed container events as no transitions have been recorded yet.")
+		return
+	}
+
+	items, err := a.ContainerClient.List(ctx, filterOptions)
+	if err != nil {
+		slog.Warn("Could not list containers for the catch-up of missed events.", "err", err)
+		return
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		info, err := a.ContainerClient.Client.ContainerInspect(ctx, item.Container.Id)
+		if err != nil || info.State == nil {
+			slog.Warn("Could not inspect container.", "container", item.Container.Id, "err", err)
+			continue
+		}
+		startedAt, _ := time.Parse(time.RFC3339Nano, info.State.StartedAt)
+		finishedAt, _ := time.Parse(time.RFC3339Nano, info.State.FinishedAt)
+		state := containerTimes{
+			ID:         info.ID,
+			Image:      item.Container.Image,
+			Running:    info.State.Running,
+			StartedAt:  startedAt,
+			FinishedAt: finishedAt,
+			ExitCode:   info.State.ExitCode,
+		}
+
+		for _, missed := range missedTransitions(containerHistory.Get(item.Name, time.Time{}), state, now) {
+			slog.Info("Found missed container transition.", "name", item.Name, "action", missed.Transition.Action, "time", missed.Transition.Time)
+			if err := containerHistory.Record(item.Name, missed.Transition); err != nil {
+				slog.Warn("Could not record container state transition.", "name", item.Name, "err", err)
+			}
+			if !a.config.EnableEngineEvents {
+				continue
+			}
+			payload := map[string]any{
+				"text":        fmt.Sprintf("container %s (missed while the monitor was not running). name=%s, image=%s", missed.Transition.Action, item.Name, state.Image),
+				"time":        missed.Transition.Time.Format(time.RFC3339Nano),
+				"containerID": state.ID,
+				"missed":      true,
+			}
+			if missed.Transition.ExitCode != "" {
+				payload["exitCode"] = missed.Transition.ExitCode
+			}
+			if err := a.client.Publish(tedge.GetTopic(a.client.Target, "e", string(missed.Action)), 1, false, mustMarshalJSON(payload)); err != nil {
+				slog.Warn("Failed to publish missed container event.", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+)
+
+func Test_missedTransitions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	recorded := []history.Transition{
+		{Time: now.Add(-2 * time.Hour), Action: "started"},
+	}
+
+	// Start event was observed (within the tolerance)
+	missed := missedTransitions(recorded, containerTimes{
+		Running:   true,
+		StartedAt: now.Add(-2*time.Hour + 500*time.Millisecond),
+	}, now)
+	assert.Empty(t, missed)
+
+	// Container died and was restarted while the monitor was down
+	missed = missedTransitions(recorded, containerTimes{
+		Running:    false,
+		StartedAt:  now.Add(-1 * time.Hour),
+		FinishedAt: now.Add(-10 * time.Minute),
+		ExitCode:   137,
+	}, now)
+	assert.Len(t, missed, 2)
+	assert.Equal(t, events.ActionStart, missed[0].Action)
+	assert.Equal(t, events.ActionDie, missed[1].Action)
+	assert.Equal(t, "137", missed[1].Transition.ExitCode)
+
+	// Transitions in the future are ignored
+	missed = missedTransitions(recorded, containerTimes{
+		Running:   true,
+		StartedAt: now.Add(time.Hour),
+	}, now)
+	assert.Empty(t, missed)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+var OperationContainerCheckpoint = "container_checkpoint"
+var OperationContainerRestore = "container_restore"
+
+type CheckpointCommand struct {
+	Name string `json:"name"`
+
+	// Checkpoint name. Optional when creating a checkpoint, and the generated name is returned
+	CheckpointID string `json:"checkpointId,omitempty"`
+	LeaveRunning bool   `json:"leaveRunning,omitempty"`
+}
+
+// Declare the checkpoint and restore command capabilities (experimental) and listen for the commands
+func (a *App) SubscribeCheckpoints() error {
+	if !a.config.EnableCheckpoints {
+		return nil
+	}
+	target := a.client.Target
+	operations := []string{OperationContainerCheckpoint, OperationContainerRestore}
+	if a.config.ReadOnly {
+		// Remove any previously declared capabilities
+		slog.Info("Read-only mode is enabled, so checkpoint commands are disabled.")
+		for _, operation := range operations {
+			if err := a.client.RemoveCommandCapability(target, operation); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := a.ContainerClient.CheckpointsSupported(context.Background()); err != nil {
+		slog.Warn("Container checkpoints are not available.", "err", err)
+	}
+
+	for _, operation := range operations {
+		if err := a.client.HandleCommands(target, operation, a.commandOptions(), a.handleCheckpointCommand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) handleCheckpointCommand(command *tedge.Command) error {
+	cmd := CheckpointCommand{}
+	if err := command.Decode(&cmd); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	opts := container.CheckpointOptions{
+		ID:           cmd.CheckpointID,
+		Dir:          a.config.CheckpointDir,
+		LeaveRunning: cmd.LeaveRunning,
+	}
+
+	action := audit.ActionCheckpoint
+	var err error
+	if command.Operation == OperationContainerRestore {
+		action = audit.ActionRestore
+		err = a.ContainerClient.RestoreCheckpoint(ctx, cmd.Name, opts)
+	} else {
+		cmd.CheckpointID, err = a.ContainerClient.CreateCheckpoint(ctx, cmd.Name, opts)
+		if err == nil {
+			command.Set("checkpointId", cmd.CheckpointID)
+		}
+	}
+	a.config.Audit.Record(audit.Entry{
+		Action:      action,
+		Type:        container.ContainerType,
+		Name:        cmd.Name,
+		Version:     cmd.CheckpointID,
+		OperationID: command.ID,
+		Initiator:   command.Operation,
+	}, err)
+	return err
+}
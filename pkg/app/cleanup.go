@@ -0,0 +1,317 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Check if removing the stale services would remove more than the allowed ratio of the registered services.
+// A ratio of 0 or more than 1 disables the check
+func exceedsRemovalLimit(stale int, registered int, maxRatio float64) bool {
+	if stale == 0 || registered == 0 || maxRatio <= 0 || maxRatio >= 1 {
+		return false
+	}
+	return float64(stale)/float64(registered) > maxRatio
+}
+
+// Schedule the confirmation of the stale services using a second sample of the containers after the
+// confirm delay, so that the worker is not blocked in the meantime. The confirmed services are removed
+// by the worker. Only one confirmation is pending at a time, as the next update checks the services again
+func (a *App) scheduleStaleConfirm(stale map[string]struct{}, entities map[string]any, filterOptions container.FilterOptions) {
+	if !a.staleConfirmPending.CompareAndSwap(false, true) {
+		slog.Info("Confirmation of stale services is already pending.")
+		return
+	}
+	time.AfterFunc(a.config.StaleConfirmDelay, func() {
+		defer a.staleConfirmPending.Store(false)
+		select {
+		case <-a.shutdown:
+			return
+		default:
+		}
+
+		targets := make([]tedge.Target, 0)
+		for topic := range a.confirmStaleServices(stale, filterOptions) {
+			target, err := tedge.NewTargetFromTopic(topic)
+			if err != nil {
+				slog.Warn("Invalid topic structure", "err", err)
+				continue
+			}
+			targets = append(targets, *target)
+		}
+		if remove := a.previewStaleServices(targets, entities); len(remove) > 0 {
+			a.enqueue(NewRemoveServicesAction(remove))
+		}
+	})
+}
+
+// Confirm the stale services using a second sample of the containers.
+// The engine can transiently return an empty list (e.g. when it is restarting), so
+// nothing is removed if the engine is not reachable or the containers can't be listed
+func (a *App) confirmStaleServices(stale map[string]struct{}, filterOptions container.FilterOptions) map[string]struct{} {
+	ctx := context.Background()
+	if status := a.ContainerClient.GetEngineStatus(ctx); status.Status != "up" {
+		slog.Warn("Container engine is not available. Skipping removal of stale services.", "err", status.Error)
+		return map[string]struct{}{}
+	}
+
+	items, err := a.ContainerClient.List(ctx, filterOptions)
+	if err != nil {
+		slog.Warn("Could not get second sample of containers. Skipping removal of stale services.", "err", err)
+		return map[string]struct{}{}
+	}
+
+	confirmed := a.withoutActiveServices(stale, items)
+	slog.Info("Confirmed stale services.", "first", len(stale), "second", len(confirmed))
+	return confirmed
+}
+
+// Get the stale services which are not used by any of the containers, either by the container, compose project or stack name
+func (a *App) withoutActiveServices(stale map[string]struct{}, items []container.TedgeContainer) map[string]struct{} {
+	out := make(map[string]struct{}, len(stale))
+	for topic := range stale {
+		out[topic] = struct{}{}
+	}
+	for _, item := range items {
+		for _, name := range []string{item.Name, item.Container.ProjectName, item.Container.StackName} {
+			if name != "" {
+				delete(out, a.Device.Service(name).Topic())
+			}
+		}
+	}
+	return out
+}
+
+// Check if a stale service is excluded from the cleanup. The patterns are matched against the service name and topic
+func excludedFromCleanup(name string, topic string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		p, err := regexp.Compile(pattern)
+		if err != nil {
+			slog.Warn("Invalid cleanup exclude pattern.", "pattern", pattern, "err", err)
+			continue
+		}
+		if (name != "" && p.MatchString(name)) || p.MatchString(topic) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Log what the stale service cleanup would remove, and return the services which should be removed.
+// Services matching an exclude pattern are kept, and nothing is removed in dry run mode
+func (a *App) previewStaleServices(targets []tedge.Target, entities map[string]any) []tedge.Target {
+	if len(targets) == 0 {
+		return targets
+	}
+	remove := make([]tedge.Target, 0, len(targets))
+	for _, target := range targets {
+		topic := target.Topic()
+		name, _ := a.Device.ServiceName(topic)
+		serviceType := ""
+		if payload, ok := entities[topic].(map[string]any); ok {
+			serviceType, _ = payload["type"].(string)
+		}
+		if pattern, ok := excludedFromCleanup(name, topic, a.config.CleanupExclude); ok {
+			slog.Info("Excluding service from stale cleanup.", "topic", topic, "name", name, "type", serviceType, "pattern", pattern)
+			continue
+		}
+		slog.Info("Stale service will be removed.", "topic", topic, "name", name, "type", serviceType, "deleteFromCloud", a.config.DeleteFromCloud, "dryRun", a.config.CleanupDryRun)
+		remove = append(remove, target)
+	}
+	if a.config.CleanupDryRun {
+		slog.Warn("Stale service cleanup is in dry run mode, no services are removed.", "stale", len(remove), "excluded", len(targets)-len(remove))
+		return nil
+	}
+	return remove
+}
+
+// Deregister the given services and delete them from the cloud, and return the services which were removed.
+// Services are only marked as removed if a retention period is configured
+func (a *App) removeServices(targets []tedge.Target) []tedge.Target {
+	markedForDeletion := make([]tedge.Target, 0, len(targets))
+	for _, target := range targets {
+		if a.config.DeleteRetention > 0 && !a.tombstoneExpired(target) {
+			continue
+		}
+
+		slog.Info("Removing service", "topic", target.Topic())
+		if a.debounce != nil {
+			a.debounce.Remove(target.Topic())
+		}
+		a.cloudTags.Remove(target.Topic())
+		for _, result := range a.client.PublishBatch(a.clearTwinFields(target)) {
+			if result.Err != nil {
+				slog.Warn("Could not clear twin field.", "topic", result.Topic, "err", result.Err)
+			}
+		}
+		if err := a.client.DeregisterEntity(target, "twin/container", "twin/tombstone"); err != nil {
+			slog.Warn("Failed to deregister entity.", "err", err)
+		}
+		if a.config.EnableHomeAssistant {
+			a.removeDiscovery(target)
+		}
+
+		// mark targets for deletion from the cloud, but don't delete them yet to give time
+		// for thin-edge.io to process the status updates
+		markedForDeletion = append(markedForDeletion, target)
+	}
+
+	if len(markedForDeletion) > 0 && a.config.DeleteFromCloud {
+		a.deleteFromCloud(markedForDeletion)
+	}
+	return markedForDeletion
+}
+
+// Type of the event which summarizes the deletion of stale services from the cloud
+var CloudCleanupEventType = "cloud_cleanup"
+
+// Progress of the deletion of stale services from the cloud
+type cloudCleanupProgress struct {
+	mutex   sync.Mutex
+	total   int
+	done    int
+	deleted int
+	missing int
+	failed  int
+}
+
+// Record the result of a deletion, and return the number of processed services
+func (p *cloudCleanupProgress) record(deleted bool, err error) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done++
+	switch {
+	case err != nil:
+		p.failed++
+	case deleted:
+		p.deleted++
+	default:
+		p.missing++
+	}
+	return p.done
+}
+
+// Log the progress after every n-th service (e.g. every 10%), so large cleanups can be followed
+func progressInterval(total int) int {
+	return max(total/10, 10)
+}
+
+// Delete the given targets from the cloud.
+// The deletion waits for the grace period first to give thin-edge.io time to process the
+// deregistration messages, then the managed objects are deleted with limited concurrency.
+// An event summarizing the deletion is published once all targets have been processed
+func (a *App) deleteFromCloud(targets []tedge.Target) {
+	cloudIdentity := a.client.Target.CloudIdentity
+	if cloudIdentity == "" {
+		return
+	}
+
+	time.Sleep(a.config.DeleteGracePeriod)
+
+	concurrency := a.config.DeleteConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	started := time.Now()
+	progress := &cloudCleanupProgress{total: len(targets)}
+	interval := progressInterval(len(targets))
+	slog.Info("Deleting stale services from the cloud.", "total", len(targets), "concurrency", concurrency)
+
+	jobs := make(chan tedge.Target, len(targets))
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				slog.Info("Removing service from the cloud", "topic", target.Topic())
+
+				// FIXME: How to handle if the device is deregistered locally, but still exists in the cloud?
+				// Should it try to reconcile with the cloud to delete orphaned services?
+				// Delete service directly from Cumulocity using the local Cumulocity Proxy
+				target.CloudIdentity = cloudIdentity
+				deleted, err := a.client.DeleteCumulocityManagedObjectWithRetry(target, a.config.DeleteRetries, time.Second)
+				if err != nil {
+					slog.Warn("Failed to delete managed object.", "err", err)
+				}
+				if done := progress.record(deleted, err); done%interval == 0 && done < progress.total {
+					slog.Info("Cloud deletion progress.", "done", done, "total", progress.total)
+				}
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		jobs <- target
+	}
+	close(jobs)
+	wg.Wait()
+
+	a.publishCloudCleanupEvent(progress, time.Since(started))
+}
+
+func (a *App) publishCloudCleanupEvent(progress *cloudCleanupProgress, duration time.Duration) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	slog.Info("Deleted stale services from the cloud.", "total", progress.total, "deleted", progress.deleted, "missing", progress.missing, "failed", progress.failed, "duration", duration)
+
+	payload := map[string]any{
+		"text":     fmt.Sprintf("Deleted %d stale services from the cloud. total=%d, failed=%d", progress.deleted, progress.total, progress.failed),
+		"total":    progress.total,
+		"deleted":  progress.deleted,
+		"missing":  progress.missing,
+		"failed":   progress.failed,
+		"duration": duration.Round(time.Millisecond).String(),
+	}
+	topic := tedge.GetTopic(a.client.Target, "e", CloudCleanupEventType)
+	if err := a.client.Publish(topic, 1, false, mustMarshalJSON(a.client.Clock.SetTime(payload))); err != nil {
+		slog.Warn("Could not publish cloud cleanup event.", "topic", topic, "err", err)
+	}
+}
+
+// Check if the tombstone of a stale service has expired so that it can be deleted.
+// If the service does not have a tombstone yet, then it is marked as removed
+func (a *App) tombstoneExpired(target tedge.Target) bool {
+	topic := target.Topic()
+	removedAt, ok := a.tombstones.Get(topic)
+	if ok {
+		if time.Since(removedAt) < a.config.DeleteRetention {
+			slog.Debug("Service is marked as removed but still within the retention period.", "topic", topic, "removedAt", removedAt)
+			return false
+		}
+		slog.Info("Service retention period has expired.", "topic", topic, "removedAt", removedAt)
+		if _, err := a.tombstones.Remove(topic); err != nil {
+			slog.Warn("Could not save tombstones.", "err", err)
+		}
+		return true
+	}
+
+	removedAt = time.Now()
+	slog.Info("Marking service as removed.", "topic", topic, "retention", a.config.DeleteRetention)
+	if err := a.tombstones.Add(topic, removedAt); err != nil {
+		slog.Warn("Could not save tombstones.", "err", err)
+	}
+
+	payload := a.client.Clock.SetTime(map[string]any{
+		"status": "removed",
+	})
+	if err := a.client.Publish(tedge.GetHealthTopic(target), 1, true, mustMarshalJSON(payload)); err != nil {
+		slog.Warn("Could not publish removed status.", "err", err)
+	}
+	tombstone := map[string]any{
+		"removedAt": removedAt.Format(time.RFC3339),
+		"deleteAt":  removedAt.Add(a.config.DeleteRetention).Format(time.RFC3339),
+	}
+	if err := a.client.Publish(tedge.GetTopic(target, "twin", "tombstone"), 1, true, mustMarshalJSON(tombstone)); err != nil {
+		slog.Warn("Could not publish tombstone.", "err", err)
+	}
+	return false
+}
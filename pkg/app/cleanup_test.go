@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func Test_ExceedsRemovalLimit(t *testing.T) {
+	assert.False(t, exceedsRemovalLimit(0, 10, 0.5))
+	assert.False(t, exceedsRemovalLimit(5, 10, 0.5))
+	assert.True(t, exceedsRemovalLimit(6, 10, 0.5))
+	assert.True(t, exceedsRemovalLimit(10, 10, 0.5))
+
+	// disabled
+	assert.False(t, exceedsRemovalLimit(10, 10, 0))
+	assert.False(t, exceedsRemovalLimit(10, 10, 1))
+}
+
+func Test_previewStaleServices(t *testing.T) {
+	device := tedge.NewTarget("te", "device/main//")
+	a := &App{
+		Device: device,
+		config: Config{
+			CleanupExclude: []string{"^tedge-", "/service/legacy$"},
+		},
+	}
+	targets := []tedge.Target{
+		*device.Service("app"),
+		*device.Service("tedge-mapper-c8y"),
+		*device.Service("legacy"),
+	}
+	entities := map[string]any{
+		device.Service("app").Topic(): map[string]any{"type": "container"},
+	}
+
+	removed := a.previewStaleServices(targets, entities)
+	assert.Equal(t, []tedge.Target{*device.Service("app")}, removed)
+
+	a.config.CleanupDryRun = true
+	assert.Empty(t, a.previewStaleServices(targets, entities))
+}
+
+func Test_cloudCleanupProgress(t *testing.T) {
+	progress := &cloudCleanupProgress{total: 3}
+	assert.Equal(t, 1, progress.record(true, nil))
+	assert.Equal(t, 2, progress.record(false, nil))
+	assert.Equal(t, 3, progress.record(false, assert.AnError))
+	assert.Equal(t, 1, progress.deleted)
+	assert.Equal(t, 1, progress.missing)
+	assert.Equal(t, 1, progress.failed)
+
+	assert.Equal(t, 10, progressInterval(20))
+	assert.Equal(t, 50, progressInterval(500))
+}
+
+func Test_withoutActiveServices(t *testing.T) {
+	device := tedge.NewTarget("te", "device/main//")
+	a := &App{Device: device}
+	registered := map[string]struct{}{
+		device.Service("app").Topic():     {},
+		device.Service("project").Topic(): {},
+		device.Service("stack").Topic():   {},
+		device.Service("removed").Topic(): {},
+	}
+	items := []container.TedgeContainer{
+		{Name: "app"},
+		{Name: "project-web-1", Container: container.Container{ProjectName: "project"}},
+		{Name: "stack_web.1", Container: container.Container{StackName: "stack"}},
+	}
+
+	stale := a.withoutActiveServices(registered, items)
+	assert.Equal(t, map[string]struct{}{device.Service("removed").Topic(): {}}, stale)
+	assert.Len(t, registered, 4)
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Fragment which marks the Cumulocity managed objects of the container services
+var DefaultCloudTagMarker = "c8y_ContainerService"
+
+// Fragment which includes the compose project of the service, e.g. to build a dynamic group per project
+var CloudTagProjectFragment = "c8y_ContainerProject"
+
+// Attempts to tag a service, as the managed object is created asynchronously after the registration
+var (
+	cloudTagRetries = 5
+	cloudTagDelay   = 5 * time.Second
+)
+
+// Fragments which have been added to the managed object of each service (by topic)
+type cloudTagState struct {
+	mutex   sync.Mutex
+	tagged  map[string]map[string]any
+	pending map[string]struct{}
+}
+
+// Mark a service as being tagged with the given fragments. Returns false if the service
+// is already tagged with the same fragments, or if tagging is already in progress
+func (s *cloudTagState) start(topic string, fragments map[string]any) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tagged == nil {
+		s.tagged = make(map[string]map[string]any)
+		s.pending = make(map[string]struct{})
+	}
+	if _, ok := s.pending[topic]; ok {
+		return false
+	}
+	if existing, ok := s.tagged[topic]; ok && string(mustMarshalJSON(existing)) == string(mustMarshalJSON(fragments)) {
+		return false
+	}
+	s.pending[topic] = struct{}{}
+	return true
+}
+
+func (s *cloudTagState) done(topic string, fragments map[string]any, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pending, topic)
+	if err == nil {
+		s.tagged[topic] = fragments
+	}
+}
+
+func (s *cloudTagState) Remove(topic string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.tagged, topic)
+}
+
+// Get the fragments which are added to the managed object of a service
+func cloudTagFragments(marker string, serviceType string, project string) map[string]any {
+	fragments := map[string]any{
+		marker: map[string]any{
+			"type": serviceType,
+		},
+		// a null fragment is removed, e.g. if the container no longer belongs to a project
+		CloudTagProjectFragment: nil,
+	}
+	if project != "" {
+		fragments[CloudTagProjectFragment] = map[string]any{
+			"name": project,
+		}
+	}
+	return fragments
+}
+
+// Add the marker fragments to the Cumulocity managed objects of the services, so dynamic groups
+// and dashboards can be built from the container metadata. Services are only tagged once, unless their fragments change
+func (a *App) tagCloudServices(services []container.TedgeContainer, projects []container.ProjectHealth, stacks []container.Stack) {
+	if !a.config.EnableCloudTags {
+		return
+	}
+	marker := a.config.CloudTagMarker
+	if marker == "" {
+		marker = DefaultCloudTagMarker
+	}
+	tags := make(map[string]map[string]any)
+	for _, item := range services {
+		tags[item.Name] = cloudTagFragments(marker, item.ServiceType, item.Container.ProjectName)
+	}
+	for _, project := range projects {
+		tags[project.Name] = cloudTagFragments(marker, container.ContainerGroupType, project.Name)
+	}
+	for _, stack := range stacks {
+		tags[stack.Name] = cloudTagFragments(marker, container.ContainerStackType, "")
+	}
+
+	for name, fragments := range tags {
+		target := *a.Device.Service(name)
+		if !a.cloudTags.start(target.Topic(), fragments) {
+			continue
+		}
+		go func(target tedge.Target, fragments map[string]any) {
+			err := a.updateCloudFragments(target, fragments)
+			if err != nil {
+				slog.Warn("Could not tag service in the cloud.", "topic", target.Topic(), "err", err)
+			} else {
+				slog.Info("Tagged service in the cloud.", "topic", target.Topic(), "fragments", fragments)
+			}
+			a.cloudTags.done(target.Topic(), fragments, err)
+		}(target, fragments)
+	}
+}
+
+// Update the managed object of a service, and retry while it has not been created yet
+func (a *App) updateCloudFragments(target tedge.Target, fragments map[string]any) error {
+	target.CloudIdentity = a.client.Target.CloudIdentity
+	for attempt := 1; ; attempt++ {
+		err := a.client.UpdateCumulocityManagedObject(target, fragments)
+		if err == nil || attempt >= cloudTagRetries {
+			return err
+		}
+		if !errors.Is(err, tedge.ErrCloudNotFound) && !tedge.IsRetryable(err) {
+			return err
+		}
+		select {
+		case <-a.shutdown:
+			return err
+		case <-time.After(cloudTagDelay * time.Duration(attempt)):
+		}
+	}
+}
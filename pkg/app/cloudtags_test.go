@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cloudTagState(t *testing.T) {
+	state := cloudTagState{}
+	topic := "te/device/main/service/app"
+	fragments := cloudTagFragments(DefaultCloudTagMarker, "container", "shop")
+	assert.Equal(t, map[string]any{"name": "shop"}, fragments[CloudTagProjectFragment])
+
+	assert.True(t, state.start(topic, fragments))
+	// already in progress
+	assert.False(t, state.start(topic, fragments))
+	state.done(topic, fragments, nil)
+	assert.False(t, state.start(topic, cloudTagFragments(DefaultCloudTagMarker, "container", "shop")))
+
+	// the project changed
+	changed := cloudTagFragments(DefaultCloudTagMarker, "container", "")
+	assert.Nil(t, changed[CloudTagProjectFragment])
+	assert.True(t, state.start(topic, changed))
+	state.done(topic, changed, assert.AnError)
+	assert.True(t, state.start(topic, changed))
+}
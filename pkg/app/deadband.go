@@ -0,0 +1,95 @@
+package app
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Deadband of a measurement series. A value is only published if its change (compared to the last
+// published value) exceeds all of the configured thresholds
+type Deadband struct {
+	// Minimum relative change in percent. 0 = disabled
+	Percent float64 `mapstructure:"percent"`
+
+	// Minimum absolute change. 0 = disabled
+	Absolute float64 `mapstructure:"absolute"`
+
+	// Publish the value after the given duration even if it did not change significantly. 0 = disabled
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+type publishedValue struct {
+	Value float64
+	Time  time.Time
+}
+
+// Check if a change from the last published value is significant
+func (d Deadband) significant(last publishedValue, value float64, now time.Time) bool {
+	if d.MaxAge > 0 && now.Sub(last.Time) >= d.MaxAge {
+		return true
+	}
+	change := math.Abs(value - last.Value)
+	if change == 0 {
+		return false
+	}
+	if d.Absolute > 0 && change <= d.Absolute {
+		return false
+	}
+	if d.Percent > 0 && last.Value != 0 && change/math.Abs(last.Value)*100 <= d.Percent {
+		return false
+	}
+	return true
+}
+
+// Filter which drops measurement values which did not change significantly since they were last published
+type deadbandFilter struct {
+	Series map[string]Deadband
+
+	mutex     sync.Mutex
+	published map[string]map[string]publishedValue
+}
+
+func newDeadbandFilter(series map[string]Deadband) *deadbandFilter {
+	return &deadbandFilter{
+		Series:    series,
+		published: make(map[string]map[string]publishedValue),
+	}
+}
+
+// Get the values of the given source (e.g. a measurement topic) which should be published.
+// The series without a deadband are always published
+func (f *deadbandFilter) Filter(source string, values map[string]container.LowPrecisionFloat, now time.Time) map[string]container.LowPrecisionFloat {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	published, ok := f.published[source]
+	if !ok {
+		published = make(map[string]publishedValue)
+		f.published[source] = published
+	}
+
+	out := make(map[string]container.LowPrecisionFloat, len(values))
+	for name, value := range values {
+		if deadband, ok := f.Series[name]; ok {
+			if last, ok := published[name]; ok && !deadband.significant(last, value.Value, now) {
+				continue
+			}
+		}
+		published[name] = publishedValue{
+			Value: value.Value,
+			Time:  now,
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// Forget the published values of a source, e.g. when the container was removed
+func (f *deadbandFilter) Remove(source string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.published, source)
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_deadbandFilter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	filter := newDeadbandFilter(map[string]Deadband{
+		"memory": {Percent: 5, MaxAge: time.Hour},
+	})
+	values := func(cpu, memory float64) map[string]container.LowPrecisionFloat {
+		return map[string]container.LowPrecisionFloat{
+			"cpu":    container.NewLowerPrecisionFloat64(cpu, 2),
+			"memory": container.NewLowerPrecisionFloat64(memory, 2),
+		}
+	}
+
+	// First values are always published
+	assert.Len(t, filter.Filter("abc", values(1, 40), now), 2)
+
+	// Memory changed by less than 5%
+	out := filter.Filter("abc", values(2, 41), now.Add(time.Minute))
+	assert.Contains(t, out, "cpu")
+	assert.NotContains(t, out, "memory")
+
+	// Memory changed by more than 5% compared to the last published value
+	assert.Contains(t, filter.Filter("abc", values(2, 42.5), now.Add(2*time.Minute)), "memory")
+
+	// Published after max age even without a significant change
+	assert.Contains(t, filter.Filter("abc", values(2, 42.5), now.Add(2*time.Minute+time.Hour)), "memory")
+
+	// Independent sources
+	assert.Contains(t, filter.Filter("def", values(2, 42.5), now), "memory")
+}
+
+func Test_Deadband_significant(t *testing.T) {
+	now := time.Now()
+	last := publishedValue{Value: 10, Time: now}
+	deadband := Deadband{Percent: 5, Absolute: 1}
+	assert.False(t, deadband.significant(last, 10, now))
+	assert.False(t, deadband.significant(last, 10.8, now))
+	assert.True(t, deadband.significant(last, 11.5, now))
+	assert.True(t, deadband.significant(publishedValue{Value: 0, Time: now}, 1.5, now))
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Label which overrides the status debounce window of a container, e.g. "2m" or "0s" to disable it
+var LabelStatusDebounce = "tedge.status.debounce"
+
+type debounceEntry struct {
+	published string
+	pending   string
+	since     time.Time
+	scheduled bool
+}
+
+// Debounce of the service statuses. A status change is only published once the new status
+// persisted for the debounce window, so brief restarts don't flip the service down and up
+type statusDebouncer struct {
+	Window time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*debounceEntry
+}
+
+func newStatusDebouncer(window time.Duration) *statusDebouncer {
+	return &statusDebouncer{
+		Window:  window,
+		entries: make(map[string]*debounceEntry),
+	}
+}
+
+// Check if the status of a service should be published. If not, the remaining duration of the
+// debounce window is returned, after which the status should be checked again
+func (d *statusDebouncer) Check(key string, status string, window time.Duration, now time.Time) (bool, time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	entry, ok := d.entries[key]
+	if !ok {
+		// Nothing has been published yet, so there is nothing to flip
+		d.entries[key] = &debounceEntry{published: status}
+		return true, 0
+	}
+	if status == entry.published {
+		entry.pending = ""
+		return true, 0
+	}
+	if status != entry.pending {
+		entry.pending = status
+		entry.since = now
+	}
+	if elapsed := now.Sub(entry.since); elapsed < window {
+		return false, window - elapsed
+	}
+	entry.published = status
+	entry.pending = ""
+	return true, 0
+}
+
+// Mark a recheck of the service as scheduled. Returns false if a recheck is already scheduled
+func (d *statusDebouncer) schedule(key string, scheduled bool) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	entry, ok := d.entries[key]
+	if !ok || (scheduled && entry.scheduled) {
+		return false
+	}
+	entry.scheduled = scheduled
+	return true
+}
+
+func (d *statusDebouncer) Remove(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.entries, key)
+}
+
+// Get the debounce window of a container, which can be overridden by a label
+func (d *statusDebouncer) window(labels map[string]string) time.Duration {
+	if value, ok := labels[LabelStatusDebounce]; ok {
+		window, err := time.ParseDuration(value)
+		if err == nil {
+			return window
+		}
+		slog.Warn("Invalid status debounce label.", "label", LabelStatusDebounce, "value", value, "err", err)
+	}
+	return d.Window
+}
+
+// Check if the status of a service should be published. A status change which is suppressed is checked
+// again (using the given filter) at the end of the debounce window, so a persistent change is still published
+func (a *App) debounceStatus(topic string, status string, labels map[string]string, recheck container.FilterOptions) bool {
+	if a.debounce == nil {
+		return true
+	}
+	publish, wait := a.debounce.Check(topic, status, a.debounce.window(labels), time.Now())
+	if publish {
+		return true
+	}
+	slog.Info("Delaying service status change.", "topic", topic, "status", status, "wait", wait)
+	if a.debounce.schedule(topic, true) {
+		time.AfterFunc(wait, func() {
+			a.debounce.schedule(topic, false)
+			a.enqueue(NewTargetedUpdateAction(recheck))
+		})
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_statusDebouncer(t *testing.T) {
+	d := newStatusDebouncer(30 * time.Second)
+	now := time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+	window := d.window(nil)
+
+	publish, _ := d.Check("te/device/main/service/app", "up", window, now)
+	assert.True(t, publish)
+
+	// brief restart
+	publish, wait := d.Check("te/device/main/service/app", "down", window, now.Add(time.Second))
+	assert.False(t, publish)
+	assert.Equal(t, 30*time.Second, wait)
+	publish, _ = d.Check("te/device/main/service/app", "up", window, now.Add(5*time.Second))
+	assert.True(t, publish)
+
+	// persistent change
+	publish, _ = d.Check("te/device/main/service/app", "down", window, now.Add(10*time.Second))
+	assert.False(t, publish)
+	publish, wait = d.Check("te/device/main/service/app", "down", window, now.Add(30*time.Second))
+	assert.False(t, publish)
+	assert.Equal(t, 10*time.Second, wait)
+	publish, _ = d.Check("te/device/main/service/app", "down", window, now.Add(40*time.Second))
+	assert.True(t, publish)
+
+	// label override
+	assert.Equal(t, time.Duration(0), d.window(map[string]string{LabelStatusDebounce: "0s"}))
+	assert.Equal(t, 30*time.Second, d.window(map[string]string{LabelStatusDebounce: "invalid"}))
+	publish, _ = d.Check("te/device/main/service/app", "up", 0, now.Add(41*time.Second))
+	assert.True(t, publish)
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Event type used to publish the changes of the containers between update cycles
+var EventTypeContainerChanges = "container_changes"
+
+// Values of a container which are compared between update cycles
+type containerSnapshot struct {
+	Image       string
+	ImageDigest string
+	State       string
+}
+
+type ContainerChange struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type ContainerDiff struct {
+	Added   []string          `json:"added"`
+	Removed []string          `json:"removed"`
+	Changed []ContainerChange `json:"changed"`
+}
+
+func (d ContainerDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Human readable summary of the changes
+func (d ContainerDiff) Text() string {
+	parts := make([]string, 0, 3)
+	if len(d.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Added, ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(d.Removed, ", "))
+	}
+	if len(d.Changed) > 0 {
+		changes := make([]string, 0, len(d.Changed))
+		for _, change := range d.Changed {
+			changes = append(changes, fmt.Sprintf("%s %s %s->%s", change.Name, change.Field, change.Old, change.New))
+		}
+		parts = append(parts, "changed: "+strings.Join(changes, ", "))
+	}
+	return "Containers changed. " + strings.Join(parts, "; ")
+}
+
+func newContainerSnapshots(items []container.TedgeContainer) map[string]containerSnapshot {
+	snapshots := make(map[string]containerSnapshot, len(items))
+	for _, item := range items {
+		snapshots[item.Name] = containerSnapshot{
+			Image:       item.Container.Image,
+			ImageDigest: item.Container.ImageDigest,
+			State:       item.Container.State,
+		}
+	}
+	return snapshots
+}
+
+// Compare the containers of two update cycles
+func diffContainers(previous map[string]containerSnapshot, current map[string]containerSnapshot) ContainerDiff {
+	diff := ContainerDiff{
+		Added:   make([]string, 0),
+		Removed: make([]string, 0),
+		Changed: make([]ContainerChange, 0),
+	}
+	for name, curr := range current {
+		prev, ok := previous[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+		fields := []struct {
+			name     string
+			old, new string
+		}{
+			{"image", prev.Image, curr.Image},
+			{"imageDigest", prev.ImageDigest, curr.ImageDigest},
+			{"state", prev.State, curr.State},
+		}
+		for _, field := range fields {
+			if field.old != field.new {
+				diff.Changed = append(diff.Changed, ContainerChange{
+					Name:  name,
+					Field: field.name,
+					Old:   field.old,
+					New:   field.new,
+				})
+			}
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		if diff.Changed[i].Name == diff.Changed[j].Name {
+			return diff.Changed[i].Field < diff.Changed[j].Field
+		}
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+	return diff
+}
+
+// Publish the changes since the previous update cycle as a single event.
+// The first update cycle is only used as the baseline
+func (a *App) publishContainerChanges(items []container.TedgeContainer) {
+	current := newContainerSnapshots(items)
+	previous := a.snapshots
+	a.snapshots = current
+	if previous == nil {
+		return
+	}
+
+	diff := diffContainers(previous, current)
+	if diff.IsEmpty() {
+		return
+	}
+	payload := make(map[string]any)
+	if err := json.Unmarshal(mustMarshalJSON(diff), &payload); err != nil {
+		slog.Warn("Could not marshal container changes.", "err", err)
+		return
+	}
+	payload["text"] = diff.Text()
+	b, err := json.Marshal(a.client.Clock.SetTime(payload))
+	if err != nil {
+		slog.Warn("Could not marshal container changes.", "err", err)
+		return
+	}
+	topic := tedge.GetTopic(*a.Device, "e", EventTypeContainerChanges)
+	slog.Info("Publishing container changes.", "topic", topic, "payload", b)
+	if err := a.client.Publish(topic, 1, false, b); err != nil {
+		slog.Warn("Could not publish container changes.", "err", err)
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffContainers(t *testing.T) {
+	previous := map[string]containerSnapshot{
+		"app1": {Image: "nginx:1.26", State: "running"},
+		"app2": {Image: "redis:7", State: "running"},
+		"app3": {Image: "mosquitto:2", State: "running"},
+	}
+	current := map[string]containerSnapshot{
+		"app1": {Image: "nginx:1.27", State: "exited"},
+		"app2": {Image: "redis:7", State: "running"},
+		"app4": {Image: "grafana:11", State: "running"},
+	}
+
+	diff := diffContainers(previous, current)
+	assert.Equal(t, []string{"app4"}, diff.Added)
+	assert.Equal(t, []string{"app3"}, diff.Removed)
+	assert.Equal(t, []ContainerChange{
+		{Name: "app1", Field: "image", Old: "nginx:1.26", New: "nginx:1.27"},
+		{Name: "app1", Field: "state", Old: "running", New: "exited"},
+	}, diff.Changed)
+	assert.Equal(t, "Containers changed. added: app4; removed: app3; changed: app1 image nginx:1.26->nginx:1.27, app1 state running->exited", diff.Text())
+
+	assert.True(t, diffContainers(current, current).IsEmpty())
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+type enrichment struct {
+	fields map[string]any
+	time   time.Time
+}
+
+// Additional twin fields of each container (by id), which are cached as the enrichers can be expensive
+type enrichmentCache struct {
+	mutex   sync.Mutex
+	entries map[string]enrichment
+}
+
+func (c *enrichmentCache) Get(id string, maxAge time.Duration, now time.Time) (map[string]any, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[id]
+	if !ok || now.Sub(entry.time) >= maxAge {
+		return nil, false
+	}
+	return entry.fields, true
+}
+
+func (c *enrichmentCache) Set(id string, fields map[string]any, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]enrichment)
+	}
+	c.entries[id] = enrichment{fields: fields, time: now}
+}
+
+func (c *enrichmentCache) Remove(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, id)
+}
+
+// Get the additional twin fields of a container from the enrichers. The fields of later
+// enrichers take precedence, and the result is reused until the enrich interval has passed
+func (a *App) enrich(item container.TedgeContainer) map[string]any {
+	if len(a.config.Enrichers) == 0 {
+		return nil
+	}
+	now := time.Now()
+	if fields, ok := a.enrichments.Get(item.Container.Id, a.config.EnrichInterval, now); ok {
+		return fields
+	}
+
+	fields := make(map[string]any)
+	for _, enricher := range a.config.Enrichers {
+		ctx, cancel := context.WithTimeout(context.Background(), a.config.EnrichTimeout)
+		values, err := enricher.Enrich(ctx, item)
+		cancel()
+		if err != nil {
+			slog.Warn("Could not enrich container twin.", "container", item.Name, "enricher", enricher.Name(), "err", err)
+			continue
+		}
+		for k, v := range values {
+			fields[k] = v
+		}
+	}
+	a.enrichments.Set(item.Container.Id, fields, now)
+	return fields
+}
+
+// Get the twin payload of a container, including the additional fields of the enrichers.
+// The built-in fields take precedence over the additional fields
+func (a *App) containerTwin(item container.TedgeContainer) ([]byte, error) {
+	payload, err := json.Marshal(item.Container)
+	if err != nil {
+		return nil, err
+	}
+	fields := a.enrich(item)
+	if len(fields) == 0 {
+		return payload, nil
+	}
+
+	twin := make(map[string]any)
+	if err := json.Unmarshal(payload, &twin); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if _, exists := twin[k]; exists {
+			slog.Debug("Ignoring additional twin field which conflicts with a built-in field.", "container", item.Name, "field", k)
+			continue
+		}
+		twin[k] = v
+	}
+	return json.Marshal(twin)
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/enrich"
+)
+
+type testEnricher struct {
+	calls  int
+	fields map[string]any
+}
+
+func (e *testEnricher) Name() string {
+	return "test"
+}
+
+func (e *testEnricher) Enrich(ctx context.Context, item container.TedgeContainer) (map[string]any, error) {
+	e.calls++
+	return e.fields, nil
+}
+
+func Test_containerTwin(t *testing.T) {
+	enricher := &testEnricher{fields: map[string]any{"appVersion": "1.2.3", "image": "ignored"}}
+	a := &App{
+		config: Config{
+			Enrichers:      []enrich.Enricher{enricher},
+			EnrichInterval: time.Minute,
+			EnrichTimeout:  time.Second,
+		},
+	}
+	item := container.TedgeContainer{Name: "app", Container: container.Container{Id: "1", Image: "nginx"}}
+
+	b, err := a.containerTwin(item)
+	assert.NoError(t, err)
+	twin := make(map[string]any)
+	assert.NoError(t, json.Unmarshal(b, &twin))
+	assert.Equal(t, "1.2.3", twin["appVersion"])
+	assert.Equal(t, "nginx", twin["image"])
+
+	// cached until the interval has passed
+	_, err = a.containerTwin(item)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, enricher.calls)
+}
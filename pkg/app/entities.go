@@ -0,0 +1,45 @@
+package app
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Duration without further entity changes before the state is updated, so a burst of
+// registrations (e.g. when the broker's retained messages are received) only causes a single update
+var entityChangeQuietPeriod = 5 * time.Second
+
+// Update the container state when the container services are registered or removed externally,
+// e.g. a service is deregistered manually, so the service of an existing container is registered again.
+// The changes caused by the monitor itself result in at most one additional update
+func (a *App) watchEntities() {
+	defer a.wg.Done()
+	timer := time.NewTimer(entityChangeQuietPeriod)
+	timer.Stop()
+	pending := false
+	for {
+		select {
+		case <-a.shutdown:
+			timer.Stop()
+			return
+		case change := <-a.client.EntityChanges():
+			switch change.Type {
+			case container.ContainerType, container.ContainerGroupType, container.ContainerStackType:
+			default:
+				continue
+			}
+			slog.Debug("Container service changed.", "topic", change.Topic, "removed", change.Removed)
+			pending = true
+			timer.Reset(entityChangeQuietPeriod)
+		case <-timer.C:
+			if !pending {
+				continue
+			}
+			pending = false
+			slog.Info("Container services changed, updating the container state.")
+			go a.enqueue(NewUpdateAllAction(container.FilterOptions{}))
+		}
+	}
+}
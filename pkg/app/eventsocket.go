@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/eventsocket"
+)
+
+// Forward a container lifecycle event to the local consumers (if enabled)
+func (a *App) forwardEvent(evt events.Message) {
+	if a.eventSocket == nil {
+		return
+	}
+	action, ok := ContainerEventText[evt.Action]
+	if !ok {
+		return
+	}
+	a.eventSocket.Publish(eventsocket.Event{
+		Time:     time.Unix(0, evt.TimeNano).UTC(),
+		Action:   action,
+		ID:       evt.Actor.ID,
+		Name:     evt.Actor.Attributes["name"],
+		Image:    evt.Actor.Attributes["image"],
+		Project:  evt.Actor.Attributes["com.docker.compose.project"],
+		Service:  evt.Actor.Attributes["com.docker.compose.service"],
+		ExitCode: evt.Actor.Attributes["exitCode"],
+	})
+}
+
+// Serve the container lifecycle events to local consumers until the context is cancelled (if enabled)
+func (a *App) ServeEventSocket(ctx context.Context) error {
+	if a.eventSocket == nil {
+		return nil
+	}
+	return a.eventSocket.ListenAndServe(ctx)
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/exporter"
+)
+
+func newMetricsSample(item container.TedgeContainer, stats *container.ContainerTelemetryMessage) exporter.Sample {
+	return exporter.Sample{
+		Name:    item.Name,
+		Project: item.Container.ProjectName,
+		Fields: map[string]float64{
+			"cpu":    stats.Container.Cpu.Value,
+			"memory": stats.Container.Memory.Value,
+			"netio":  stats.Container.NetIO.Value,
+		},
+		Time: time.Now(),
+	}
+}
+
+// Send the container metrics to the additional metrics backends
+func (a *App) exportMetrics(samples []exporter.Sample) {
+	if len(samples) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, exp := range a.config.Exporters {
+		if err := exp.Export(ctx, samples); err != nil {
+			slog.Warn("Could not export metrics.", "err", err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/firewall"
+)
+
+// Refresh the firewall rules of a container installed by the sm-plugin when it is started, as the
+// engine can assign different random host ports on each start
+func (a *App) refreshFirewall(evt events.Message) {
+	if a.config.Firewall == nil || evt.Action != events.ActionStart {
+		return
+	}
+	name := evt.Actor.Attributes["name"]
+	if name == "" || !strings.EqualFold(evt.Actor.Attributes[container.LabelManaged], "true") {
+		return
+	}
+	go func() {
+		if err := firewall.AllowContainer(context.Background(), a.config.Firewall, a.ContainerClient, name); err != nil {
+			slog.Warn("Could not refresh firewall rules.", "container", name, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Heartbeat of the monitor, which includes when the container state was last updated successfully
+type Heartbeat struct {
+	// Time of the last full update which completed successfully. nil = no update has completed since the monitor started
+	LastReconcile *time.Time `json:"lastReconcile"`
+
+	// Seconds since the last successful full update (or since the monitor started)
+	Age int64 `json:"age"`
+}
+
+func (a *App) getHeartbeat(now time.Time) Heartbeat {
+	heartbeat := Heartbeat{
+		LastReconcile: a.status.getLastReconcile(),
+	}
+	since := a.startedAt
+	if heartbeat.LastReconcile != nil {
+		since = *heartbeat.LastReconcile
+	}
+	heartbeat.Age = int64(math.Max(0, now.Sub(since).Seconds()))
+	return heartbeat
+}
+
+// Publish the time since the last successful full update as a measurement and twin fragment (reconcile) of
+// the monitor's service, so fleet monitoring can detect monitors which are running but fail to update the state
+func (a *App) PublishHeartbeat(now time.Time) error {
+	heartbeat := a.getHeartbeat(now)
+
+	measurement := a.client.Clock.SetTime(map[string]any{
+		"reconcile": map[string]any{
+			"age": heartbeat.Age,
+		},
+	})
+	topic := tedge.GetTopic(a.client.Target, "m", "reconcile")
+	slog.Debug("Publishing heartbeat.", "topic", topic, "age", heartbeat.Age)
+	if err := a.client.Publish(topic, 1, false, mustMarshalJSON(measurement)); err != nil {
+		return err
+	}
+	return a.client.Publish(tedge.GetTopic(a.client.Target, "twin", "reconcile"), 1, true, mustMarshalJSON(heartbeat))
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getHeartbeat(t *testing.T) {
+	started := time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+	a := &App{startedAt: started}
+
+	// No successful update yet, so the age is the time since the monitor started
+	heartbeat := a.getHeartbeat(started.Add(90 * time.Second))
+	assert.Nil(t, heartbeat.LastReconcile)
+	assert.Equal(t, int64(90), heartbeat.Age)
+
+	a.status.recordReconcile(started.Add(time.Minute))
+	heartbeat = a.getHeartbeat(started.Add(90 * time.Second))
+	assert.Equal(t, started.Add(time.Minute), *heartbeat.LastReconcile)
+	assert.Equal(t, int64(30), heartbeat.Age)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+var OperationContainerHistory = "container_history"
+
+type HistoryCommand struct {
+	Name string `json:"name"`
+
+	// Only include transitions since the given time (RFC3339). Optional
+	Since string `json:"since,omitempty"`
+}
+
+// Record a container state transition from an engine event
+func (a *App) recordTransition(evt events.Message) {
+	if a.config.History == nil || a.config.Secondary {
+		return
+	}
+	action, ok := ContainerEventText[evt.Action]
+	name := evt.Actor.Attributes["name"]
+	if !ok || name == "" {
+		return
+	}
+	err := a.config.History.Record(name, history.Transition{
+		Time:        time.Unix(0, evt.TimeNano),
+		Action:      action,
+		ContainerID: evt.Actor.ID,
+		Image:       evt.Actor.Attributes["image"],
+		ExitCode:    evt.Actor.Attributes["exitCode"],
+	})
+	if err != nil {
+		slog.Warn("Could not record container state transition.", "name", name, "err", err)
+	}
+}
+
+// Declare the history command capability and listen for history queries
+func (a *App) SubscribeHistory() error {
+	if a.config.History == nil {
+		return nil
+	}
+	return a.client.HandleCommands(a.client.Target, OperationContainerHistory, tedge.CommandOptions{}, a.handleHistoryCommand)
+}
+
+func (a *App) handleHistoryCommand(command *tedge.Command) error {
+	cmd := HistoryCommand{}
+	if err := command.Decode(&cmd); err != nil {
+		return err
+	}
+	since := time.Time{}
+	if cmd.Since != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, cmd.Since); err != nil {
+			return fmt.Errorf("invalid since value, expected RFC3339 format. err=%w", err)
+		}
+	}
+	command.Set("history", a.config.History.Get(cmd.Name, since))
+	return nil
+}
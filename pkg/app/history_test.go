@@ -0,0 +1,36 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+)
+
+func Test_recordTransition(t *testing.T) {
+	containerHistory, err := history.Load(filepath.Join(t.TempDir(), "history.json"), 10)
+	assert.NoError(t, err)
+
+	evt := events.Message{
+		Action:   events.ActionStart,
+		TimeNano: time.Now().UnixNano(),
+		Actor: events.Actor{
+			ID:         "abc",
+			Attributes: map[string]string{"name": "app", "image": "nginx"},
+		},
+	}
+
+	// The history is shared by the topic roots, so only the primary application records the transitions
+	primary := &App{config: Config{History: containerHistory}}
+	secondary := &App{config: Config{History: containerHistory, Secondary: true}}
+	secondary.recordTransition(evt)
+	assert.True(t, containerHistory.Empty())
+
+	primary.recordTransition(evt)
+	transitions := containerHistory.Get("app", time.Time{})
+	assert.Len(t, transitions, 1)
+	assert.Equal(t, "started", transitions[0].Action)
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"log/slog"
+	"path"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/homeassistant"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func (a *App) homeAssistantOptions() homeassistant.Options {
+	nodeID := a.Device.CloudIdentity
+	if nodeID == "" {
+		nodeID = a.Device.TopicID
+	}
+	return homeassistant.Options{
+		Prefix:            a.config.HomeAssistantPrefix,
+		NodeID:            nodeID,
+		AvailabilityTopic: tedge.GetHealthTopic(a.client.Target),
+	}
+}
+
+// Publish the Home Assistant discovery messages. The entities use the existing health and measurement topics as their state
+func (a *App) publishDiscovery(services []container.TedgeContainer, projects []container.ProjectHealth, stacks []container.Stack) {
+	items := make([]homeassistant.Service, 0, len(services)+len(projects)+len(stacks))
+	for _, item := range services {
+		target := a.Device.Service(item.Name)
+		service := homeassistant.Service{
+			Name:        item.Name,
+			HealthTopic: tedge.GetHealthTopic(*target),
+		}
+		if a.config.EnableMetrics {
+			service.MetricsTopic = tedge.GetTopic(*target, "m", "resource_usage")
+		}
+		items = append(items, service)
+	}
+	for _, project := range projects {
+		items = append(items, homeassistant.Service{
+			Name:        project.Name,
+			HealthTopic: tedge.GetHealthTopic(*a.Device.Service(project.Name)),
+		})
+	}
+	for _, stack := range stacks {
+		items = append(items, homeassistant.Service{
+			Name:        stack.Name,
+			HealthTopic: tedge.GetHealthTopic(*a.Device.Service(stack.Name)),
+		})
+	}
+
+	opts := a.homeAssistantOptions()
+	for _, item := range items {
+		messages, err := opts.Discovery(item)
+		if err != nil {
+			slog.Warn("Could not create Home Assistant discovery messages.", "name", item.Name, "err", err)
+			continue
+		}
+		for _, message := range messages {
+			if err := a.client.Publish(message.Topic, 1, true, message.Payload); err != nil {
+				slog.Warn("Could not publish Home Assistant discovery message.", "topic", message.Topic, "err", err)
+			}
+		}
+	}
+}
+
+// Remove the Home Assistant entities of a service
+func (a *App) removeDiscovery(target tedge.Target) {
+	for _, topic := range a.homeAssistantOptions().RemovalTopics(path.Base(target.TopicID)) {
+		if err := a.client.Publish(topic, 1, true, ""); err != nil {
+			slog.Warn("Could not remove Home Assistant discovery message.", "topic", topic, "err", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/startup"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Name of the file (in the state directory) which caches the device's Cumulocity external id
+var CloudIdentityFile = "cloud_identity.json"
+
+type cachedCloudIdentity struct {
+	ExternalID string    `json:"externalId"`
+	Time       time.Time `json:"time"`
+}
+
+// Read the cached external id. An empty string is returned if the cache does not exist
+func readCloudIdentity(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	cached := cachedCloudIdentity{}
+	if err := json.Unmarshal(b, &cached); err != nil {
+		slog.Warn("Ignoring invalid cloud identity cache.", "path", path, "err", err)
+		return ""
+	}
+	return cached.ExternalID
+}
+
+func writeCloudIdentity(path string, externalID string) error {
+	b, err := json.Marshal(cachedCloudIdentity{
+		ExternalID: externalID,
+		Time:       time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Strategy which looks up the device's external id from the Cumulocity user (retried until it is successful)
+func cloudIdentityLookup(client *tedge.Client, onResult func(externalID string)) startup.Strategy {
+	return startup.Strategy{
+		Name:     "cumulocity-external-id",
+		Interval: 10 * time.Second,
+		Check: func(ctx context.Context) error {
+			currentUser, _, err := client.CumulocityClient.User.GetCurrentUser(ctx)
+			if err != nil {
+				return err
+			}
+			onResult(strings.TrimPrefix(currentUser.Username, "device_"))
+			return nil
+		},
+	}
+}
+
+// Resolve the device's external id. The cached id is used if available, so the startup does not block
+// while the cloud is unreachable, and the id is re-validated in the background
+func (a *App) resolveCloudIdentity() error {
+	path := filepath.Join(a.config.StateDir, CloudIdentityFile)
+	save := func(externalID string) {
+		if err := writeCloudIdentity(path, externalID); err != nil {
+			slog.Warn("Could not cache cloud identity.", "path", path, "err", err)
+		}
+	}
+
+	if cached := readCloudIdentity(path); cached != "" {
+		slog.Info("Using cached Cumulocity ExternalID", "value", cached)
+		a.client.Target.CloudIdentity = cached
+		a.Device.CloudIdentity = cached
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-a.shutdown
+			cancel()
+		}()
+		go func() {
+			lookup := cloudIdentityLookup(a.client, func(externalID string) {
+				if externalID == cached {
+					slog.Info("Validated cached Cumulocity ExternalID", "value", externalID)
+					return
+				}
+				slog.Warn("Cached Cumulocity ExternalID is outdated.", "old", cached, "new", externalID)
+				a.client.Target.CloudIdentity = externalID
+				a.Device.CloudIdentity = externalID
+				save(externalID)
+			})
+			_ = lookup.Wait(ctx)
+		}()
+		return nil
+	}
+
+	lookup := cloudIdentityLookup(a.client, func(externalID string) {
+		a.client.Target.CloudIdentity = externalID
+		a.Device.CloudIdentity = externalID
+		slog.Info("Found Cumulocity ExternalID", "value", externalID)
+		save(externalID)
+	})
+	return lookup.Wait(context.Background())
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cloudIdentityCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", CloudIdentityFile)
+	assert.Equal(t, "", readCloudIdentity(path))
+
+	assert.NoError(t, writeCloudIdentity(path, "rpi4-d83add90fe56"))
+	assert.Equal(t, "rpi4-d83add90fe56", readCloudIdentity(path))
+
+	assert.NoError(t, os.WriteFile(path, []byte("invalid"), 0644))
+	assert.Equal(t, "", readCloudIdentity(path))
+}
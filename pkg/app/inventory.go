@@ -0,0 +1,53 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Last published network and volume inventories (by twin fragment), so that the twin is only updated when they change
+type inventoryState struct {
+	mutex     sync.Mutex
+	published map[string][]byte
+}
+
+// Publish the inventory of the networks and volumes to the device's twin (networks and volumes fragments)
+func (a *App) UpdateInventory() error {
+	if !a.config.EnableInventory {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	inventory, err := a.ContainerClient.GetInventory(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.inventory.mutex.Lock()
+	defer a.inventory.mutex.Unlock()
+	if a.inventory.published == nil {
+		a.inventory.published = make(map[string][]byte)
+	}
+	fragments := map[string]any{
+		"networks": inventory.Networks,
+		"volumes":  inventory.Volumes,
+	}
+	for fragment, value := range fragments {
+		payload := mustMarshalJSON(value)
+		if bytes.Equal(payload, a.inventory.published[fragment]) {
+			continue
+		}
+		topic := tedge.GetTopic(*a.Device, "twin", fragment)
+		slog.Info("Publishing engine inventory.", "topic", topic, "size", len(payload))
+		if err := a.client.Publish(topic, 1, true, payload); err != nil {
+			return err
+		}
+		a.inventory.published[fragment] = payload
+	}
+	return nil
+}
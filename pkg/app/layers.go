@@ -0,0 +1,44 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Last published image layer report, so that the twin is only updated when the report changes
+type layerReportState struct {
+	mutex     sync.Mutex
+	published []byte
+}
+
+// Publish the report of the shared and unique image layer sizes to the device's twin (imageLayers fragment)
+func (a *App) UpdateLayerReport() error {
+	if !a.config.EnableLayerReport {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	report, err := a.ContainerClient.GetLayerReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.layerReport.mutex.Lock()
+	defer a.layerReport.mutex.Unlock()
+	payload := mustMarshalJSON(report)
+	if bytes.Equal(payload, a.layerReport.published) {
+		return nil
+	}
+	topic := tedge.GetTopic(*a.Device, "twin", "imageLayers")
+	slog.Info("Publishing image layer report.", "topic", topic, "images", report.Count, "layersSize", report.LayersSize)
+	if err := a.client.Publish(topic, 1, true, payload); err != nil {
+		return err
+	}
+	a.layerReport.published = payload
+	return nil
+}
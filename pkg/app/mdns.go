@@ -0,0 +1,74 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/mdns"
+)
+
+// Labels used to opt-in a container to be announced via mDNS
+const (
+	LabelMDNSType = "tedge.mdns.type"
+	LabelMDNSPort = "tedge.mdns.port"
+	LabelMDNSName = "tedge.mdns.name"
+	LabelMDNSTXT  = "tedge.mdns.txt"
+)
+
+// Get the mDNS service of a container from its labels. The announced port is the host port
+// which is published for the container port given by the label, or the first published port
+func getMDNSService(item container.TedgeContainer) (mdns.Service, bool) {
+	labels := item.Container.Labels
+	serviceType := labels[LabelMDNSType]
+	if serviceType == "" || item.Status != "up" {
+		return mdns.Service{}, false
+	}
+	protocol := "tcp"
+	if strings.HasSuffix(serviceType, "._udp") {
+		protocol = "udp"
+	}
+
+	privatePort, _ := strconv.Atoi(labels[LabelMDNSPort])
+	hostPort := 0
+	for _, port := range item.Container.PublishedPorts {
+		if port.PublicPort == 0 || port.Type != protocol {
+			continue
+		}
+		if privatePort == 0 || int(port.PrivatePort) == privatePort {
+			hostPort = int(port.PublicPort)
+			break
+		}
+	}
+	if hostPort == 0 {
+		return mdns.Service{}, false
+	}
+
+	name := labels[LabelMDNSName]
+	if name == "" {
+		name = item.Name + " on %h"
+	}
+	txt := make([]string, 0)
+	for _, record := range strings.Split(labels[LabelMDNSTXT], ",") {
+		if record = strings.TrimSpace(record); record != "" {
+			txt = append(txt, record)
+		}
+	}
+	return mdns.Service{
+		Name: name,
+		Type: serviceType,
+		Port: hostPort,
+		TXT:  txt,
+	}, true
+}
+
+// Announce the containers which have opted-in via labels, and remove the announcements of any other containers
+func (a *App) announceServices(items []container.TedgeContainer) error {
+	services := make(map[string]mdns.Service)
+	for _, item := range items {
+		if service, ok := getMDNSService(item); ok {
+			services[item.Name] = service
+		}
+	}
+	return mdns.NewAnnouncer(a.config.MDNSServicesDir).Sync(services)
+}
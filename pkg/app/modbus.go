@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Label used to assign a fixed status register to a container, e.g. tedge.modbus.index=5 => register 105
+const LabelModbusIndex = "tedge.modbus.index"
+
+// Modbus register map
+const (
+	ModbusRegisterTotal = 0
+	ModbusRegisterUp    = 1
+	ModbusRegisterDown  = 2
+
+	// Status of the containers with the index label (1 = up, 0 = down or missing)
+	ModbusRegisterStatusOffset = 100
+)
+
+func getModbusRegisters(items []container.TedgeContainer) map[uint16]uint16 {
+	registers := map[uint16]uint16{
+		ModbusRegisterTotal: 0,
+		ModbusRegisterUp:    0,
+		ModbusRegisterDown:  0,
+	}
+	for _, item := range items {
+		up := item.Status == "up"
+		registers[ModbusRegisterTotal]++
+		if up {
+			registers[ModbusRegisterUp]++
+		} else {
+			registers[ModbusRegisterDown]++
+		}
+
+		index, err := strconv.ParseUint(item.Container.Labels[LabelModbusIndex], 10, 16)
+		if err != nil || index > 0xFFFF-ModbusRegisterStatusOffset {
+			continue
+		}
+		if up {
+			registers[uint16(ModbusRegisterStatusOffset+index)] = 1
+		}
+	}
+	return registers
+}
+
+// Serve the container states via Modbus TCP until the context is cancelled (if enabled)
+func (a *App) ServeModbus(ctx context.Context) error {
+	if a.modbus == nil {
+		return nil
+	}
+	return a.modbus.ListenAndServe(ctx)
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_getModbusRegisters(t *testing.T) {
+	registers := getModbusRegisters([]container.TedgeContainer{
+		{Name: "app1", Status: "up", Container: container.Container{Labels: map[string]string{LabelModbusIndex: "1"}}},
+		{Name: "app2", Status: "down", Container: container.Container{Labels: map[string]string{LabelModbusIndex: "2"}}},
+		{Name: "app3", Status: "up", Container: container.Container{Labels: map[string]string{LabelModbusIndex: "invalid"}}},
+	})
+	assert.Equal(t, map[uint16]uint16{
+		ModbusRegisterTotal: 3,
+		ModbusRegisterUp:    2,
+		ModbusRegisterDown:  1,
+		101:                 1,
+	}, registers)
+}
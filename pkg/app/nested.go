@@ -0,0 +1,85 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Containers which run their own thin-edge.io services (e.g. a child device's tedge-agent)
+// are marked using the following labels
+var (
+	// Set to "true" to mark the container as a nested thin-edge.io instance which uses the default topic id (device/<name>//)
+	LabelNested = "tedge.nested"
+
+	// Topic id of the entity which is registered by the nested thin-edge.io instance, e.g. device/child01//
+	LabelNestedTopicID = "tedge.nested.topic_id"
+)
+
+// Nested thin-edge.io instance running inside a container
+type NestedEntity struct {
+	Name        string `json:"name"`
+	TopicID     string `json:"topicId"`
+	ContainerID string `json:"containerId"`
+}
+
+// Get the topic id of the entity registered by the nested thin-edge.io instance running inside the container
+func getNestedTopicID(item container.TedgeContainer) (string, bool) {
+	if topicID := strings.Trim(item.Container.Labels[LabelNestedTopicID], "/ "); topicID != "" {
+		// Normalize to the 4 segment format, e.g. device/child01//
+		parts := strings.Split(topicID, "/")
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		return strings.Join(parts, "/"), true
+	}
+	if strings.EqualFold(item.Container.Labels[LabelNested], "true") {
+		return fmt.Sprintf("device/%s//", item.Name), true
+	}
+	return "", false
+}
+
+// Link the nested thin-edge.io instances with the containers they run in. Nested containers are not registered
+// as services, as the entity is already registered by the nested instance, instead the container information is
+// published to the nested entity's twin, and the device's twin lists all nested entities
+func (a *App) publishNested(items []container.TedgeContainer, complete bool) {
+	entities := make([]NestedEntity, 0, len(items))
+	for _, item := range items {
+		topicID, _ := getNestedTopicID(item)
+		entities = append(entities, NestedEntity{
+			Name:        item.Name,
+			TopicID:     topicID,
+			ContainerID: item.Container.Id,
+		})
+
+		payload := make(map[string]any)
+		if err := json.Unmarshal(mustMarshalJSON(item.Container), &payload); err != nil {
+			slog.Warn("Could not marshal nested container status", "err", err)
+			continue
+		}
+		payload["name"] = item.Name
+		payload["status"] = item.Status
+		payload["host"] = a.Device.TopicID
+
+		target := tedge.Target{RootPrefix: a.Device.RootPrefix, TopicID: topicID}
+		topic := tedge.GetTopic(target, "twin", "container")
+		slog.Info("Publishing nested container status", "topic", topic)
+		if err := a.client.Publish(topic, 1, true, mustMarshalJSON(payload)); err != nil {
+			slog.Error("Could not publish nested container status", "err", err)
+		}
+	}
+
+	// The list can only be published when all containers were read
+	if !complete {
+		return
+	}
+	topic := tedge.GetTopic(*a.Device, "twin", "nested_tedge")
+	slog.Info("Publishing nested thin-edge.io entities", "topic", topic, "total", len(entities))
+	if err := a.client.Publish(topic, 1, true, mustMarshalJSON(entities)); err != nil {
+		slog.Error("Could not publish nested thin-edge.io entities", "err", err)
+	}
+}
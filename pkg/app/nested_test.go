@@ -0,0 +1,28 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_getNestedTopicID(t *testing.T) {
+	newContainer := func(labels map[string]string) container.TedgeContainer {
+		return container.TedgeContainer{
+			Name:      "child01",
+			Container: container.Container{Labels: labels},
+		}
+	}
+
+	_, ok := getNestedTopicID(newContainer(map[string]string{}))
+	assert.False(t, ok)
+
+	topicID, ok := getNestedTopicID(newContainer(map[string]string{LabelNested: "true"}))
+	assert.True(t, ok)
+	assert.Equal(t, "device/child01//", topicID)
+
+	topicID, ok = getNestedTopicID(newContainer(map[string]string{LabelNestedTopicID: "device/gateway"}))
+	assert.True(t, ok)
+	assert.Equal(t, "device/gateway//", topicID)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Port published by a container on the host
+type HealthPort struct {
+	Port          uint16 `json:"port"`
+	ContainerPort uint16 `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// Add the container's IP address and published ports to the health payload, so that other services
+// can locate the container without access to the container engine
+func addHealthNetworkInfo(payload map[string]any, item container.Container) map[string]any {
+	if item.IPAddress != "" {
+		payload["ipAddress"] = item.IPAddress
+	}
+
+	// The engine publishes each port on both the IPv4 and IPv6 addresses
+	seen := make(map[HealthPort]bool)
+	ports := make([]HealthPort, 0, len(item.PublishedPorts))
+	for _, p := range item.PublishedPorts {
+		if p.PublicPort == 0 {
+			continue
+		}
+		port := HealthPort{
+			Port:          p.PublicPort,
+			ContainerPort: p.PrivatePort,
+			Protocol:      p.Type,
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) > 0 {
+		payload["ports"] = ports
+	}
+	return payload
+}
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_addHealthNetworkInfo(t *testing.T) {
+	payload := addHealthNetworkInfo(map[string]any{"status": "up"}, container.Container{
+		IPAddress: "172.18.0.3",
+		PublishedPorts: []types.Port{
+			{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+			{IP: "::", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+			{PrivatePort: 443, Type: "tcp"},
+		},
+	})
+	assert.Equal(t, "172.18.0.3", payload["ipAddress"])
+	assert.Equal(t, []HealthPort{{Port: 8080, ContainerPort: 80, Protocol: "tcp"}}, payload["ports"])
+
+	payload = addHealthNetworkInfo(map[string]any{"status": "down"}, container.Container{})
+	assert.NotContains(t, payload, "ipAddress")
+	assert.NotContains(t, payload, "ports")
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Version and capabilities of the monitor, which are published as a twin fragment (plugin) of its service
+type PluginInfo struct {
+	Version  string          `json:"version"`
+	Branch   string          `json:"branch,omitempty"`
+	Engine   PluginEngine    `json:"engine"`
+	Features map[string]bool `json:"features"`
+}
+
+// Container engine backend used by the monitor
+type PluginEngine struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Host    string `json:"host,omitempty"`
+}
+
+// Get the feature flags of the monitor, so it can be queried which capabilities are enabled on a device
+func (a *App) features() map[string]bool {
+	return map[string]bool{
+		"readOnly":        a.config.ReadOnly,
+		"metrics":         a.config.EnableMetrics,
+		"engineEvents":    a.config.EnableEngineEvents,
+		"changeEvents":    a.config.EnableChangeEvents,
+		"deleteFromCloud": a.config.DeleteFromCloud,
+		"cloudTags":       a.config.EnableCloudTags,
+		"provenance":      a.config.EnableProvenance,
+		"summary":         a.config.EnableSummary,
+		"layerReport":     a.config.EnableLayerReport,
+		"inventory":       a.config.EnableInventory,
+		"probes":          a.config.EnableProbes,
+		"restart":         a.config.EnableRestart,
+		"scheduler":       a.config.EnableScheduler,
+		"engineService":   a.config.EnableEngineService,
+		"profiles":        a.config.EnableProfiles,
+		"checkpoints":     a.config.EnableCheckpoints,
+		"projectHealth":   a.config.EnableProjectHealth,
+		"projectMetrics":  a.config.EnableProjectMetrics,
+		"projectVersions": a.config.EnableProjectVersionChanges,
+		"bootstrap":       a.config.BootstrapChunkSize > 0,
+		"homeAssistant":   a.config.EnableHomeAssistant,
+		"modbus":          a.config.EnableModbus,
+		"eventSocket":     a.config.EnableEventSocket,
+		"mdns":            a.config.EnableMDNS,
+		"history":         a.config.History != nil,
+		"archive":         a.config.Archive != nil,
+		"bridge":          a.config.Bridge != nil,
+		"exporters":       len(a.config.Exporters) > 0,
+		"commandSigning":  a.config.CommandVerifier != nil,
+	}
+}
+
+// Publish the version, container engine backend and enabled features of the monitor on its service twin
+func (a *App) PublishPluginInfo() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status := a.ContainerClient.GetEngineStatus(ctx)
+
+	info := PluginInfo{
+		Version: a.config.BuildVersion,
+		Branch:  a.config.BuildBranch,
+		Engine: PluginEngine{
+			Name:    status.Name,
+			Version: status.ServerVersion,
+			Host:    status.Host,
+		},
+		Features: a.features(),
+	}
+	topic := tedge.GetTopic(a.client.Target, "twin", "plugin")
+	slog.Info("Publishing plugin information.", "topic", topic, "version", info.Version, "engine", info.Engine.Name)
+	return a.client.Publish(topic, 1, true, mustMarshalJSON(info))
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Readiness probe of the containers whose name matches the pattern.
+// The probe label of a container (see container.LabelProbe) takes precedence over the rules
+type ProbeRule struct {
+	// Regular expression which is matched against the container name
+	Name  string `mapstructure:"name"`
+	Probe string `mapstructure:"probe"`
+}
+
+// Result of the latest readiness probe of a container
+type ProbeResult struct {
+	Probe  string    `json:"probe"`
+	Ready  bool      `json:"ready"`
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"-"`
+}
+
+// Latest probe results by container id
+type probeResults struct {
+	mutex   sync.Mutex
+	entries map[string]ProbeResult
+}
+
+// Store the result of a probe, and return true if the readiness of the container changed
+func (p *probeResults) Set(id string, result ProbeResult) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.entries == nil {
+		p.entries = make(map[string]ProbeResult)
+	}
+	previous, ok := p.entries[id]
+	p.entries[id] = result
+	return !ok || previous.Ready != result.Ready
+}
+
+func (p *probeResults) Get(id string) (ProbeResult, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	result, ok := p.entries[id]
+	return result, ok
+}
+
+func (p *probeResults) Remove(id string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.entries, id)
+}
+
+// Remove the results of the containers which are not included in the given ids
+func (p *probeResults) Retain(ids map[string]struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for id := range p.entries {
+		if _, ok := ids[id]; !ok {
+			delete(p.entries, id)
+		}
+	}
+}
+
+// Get the probe definition of a container from its label, or the first matching rule
+func probeDefinition(item container.TedgeContainer, rules []ProbeRule) string {
+	if value := item.Container.Labels[container.LabelProbe]; value != "" {
+		return value
+	}
+	for _, rule := range rules {
+		p, err := regexp.Compile(rule.Name)
+		if err != nil {
+			slog.Warn("Invalid probe rule name pattern.", "pattern", rule.Name, "err", err)
+			continue
+		}
+		if p.MatchString(item.Container.Name) {
+			return rule.Probe
+		}
+	}
+	return ""
+}
+
+// Run the readiness probes of the running containers which don't have a built-in health check.
+// A targeted update is requested for the containers whose readiness changed, so the new status is published.
+// complete is true if the items include all containers, so the results of other containers can be removed
+func (a *App) runProbes(items []container.TedgeContainer, complete bool) {
+	if !a.config.EnableProbes {
+		return
+	}
+	probed := make(map[string]struct{})
+	changed := make([]string, 0)
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, item := range items {
+		definition := probeDefinition(item, a.config.ProbeRules)
+		if item.Status != "up" || definition == "" || container.HasHealthcheck(item) {
+			// A stale result would mark the container as down after it was (re)started
+			a.probes.Remove(item.Container.Id)
+			continue
+		}
+		probed[item.Container.Id] = struct{}{}
+
+		wg.Add(1)
+		go func(item container.TedgeContainer) {
+			defer wg.Done()
+			result := ProbeResult{
+				Probe: definition,
+				Ready: true,
+				Time:  time.Now(),
+			}
+			probe, err := container.ParseProbe(definition)
+			if err == nil {
+				err = a.ContainerClient.RunProbe(context.Background(), item, probe, a.config.ProbeTimeout)
+			}
+			if err != nil {
+				slog.Info("Container is not ready.", "container", item.Name, "probe", definition, "err", err)
+				result.Ready = false
+				result.Reason = err.Error()
+			}
+			if a.probes.Set(item.Container.Id, result) {
+				mutex.Lock()
+				changed = append(changed, item.Container.Id)
+				mutex.Unlock()
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	if complete {
+		a.probes.Retain(probed)
+	}
+
+	if len(changed) > 0 {
+		slog.Info("Readiness of containers changed.", "containers", changed)
+		go a.enqueue(NewTargetedUpdateAction(container.FilterOptions{
+			IDs: changed,
+		}))
+	}
+}
+
+// Mark the running containers whose latest probe failed as down
+func (a *App) applyProbeResults(items []container.TedgeContainer) {
+	if !a.config.EnableProbes {
+		return
+	}
+	for i, item := range items {
+		if item.Status != "up" {
+			continue
+		}
+		if result, ok := a.probes.Get(item.Container.Id); ok && !result.Ready {
+			items[i].Status = "down"
+		}
+	}
+}
+
+// Add the result of the container's readiness probe to the health payload
+func (a *App) addHealthProbeResult(payload map[string]any, item container.TedgeContainer) map[string]any {
+	if !a.config.EnableProbes {
+		return payload
+	}
+	if result, ok := a.probes.Get(item.Container.Id); ok {
+		payload["probe"] = result
+	}
+	return payload
+}
+
+// Forget the probe result of a container which was (re)started or removed, so a stale
+// result does not mark the container as down until it is probed again
+func (a *App) resetProbeResult(evt events.Message) {
+	switch evt.Action {
+	case events.ActionStart, events.ActionRestart, events.ActionDie, events.ActionDestroy, events.ActionRemove:
+		a.probes.Remove(evt.Actor.ID)
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_applyProbeResults(t *testing.T) {
+	a := &App{
+		config: Config{
+			EnableProbes: true,
+			ProbeRules: []ProbeRule{
+				{Name: "^db$", Probe: "tcp://:5432"},
+			},
+		},
+	}
+	db := container.TedgeContainer{
+		Status:    "up",
+		Container: container.Container{Id: "1", Name: "db"},
+	}
+	api := container.TedgeContainer{
+		Status:    "up",
+		Container: container.Container{Id: "2", Name: "api", Labels: map[string]string{container.LabelProbe: "http://:8080/health"}},
+	}
+	assert.Equal(t, "tcp://:5432", probeDefinition(db, a.config.ProbeRules))
+	assert.Equal(t, "http://:8080/health", probeDefinition(api, a.config.ProbeRules))
+
+	assert.True(t, a.probes.Set("1", ProbeResult{Ready: false, Reason: "connection refused"}))
+	assert.True(t, a.probes.Set("2", ProbeResult{Ready: true}))
+	assert.False(t, a.probes.Set("2", ProbeResult{Ready: true}))
+
+	items := []container.TedgeContainer{db, api}
+	a.applyProbeResults(items)
+	assert.Equal(t, "down", items[0].Status)
+	assert.Equal(t, "up", items[1].Status)
+
+	a.probes.Retain(map[string]struct{}{"2": {}})
+	_, ok := a.probes.Get("1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+var OperationContainerProfile = "container_profile"
+
+type ProfileAction string
+
+const (
+	ProfileActionActivate   ProfileAction = "activate"
+	ProfileActionDeactivate ProfileAction = "deactivate"
+)
+
+type ProfileCommand struct {
+	Name   string        `json:"name"`
+	Action ProfileAction `json:"action"`
+}
+
+// Declare the profile command capability and listen for profile commands
+func (a *App) SubscribeProfiles() error {
+	if !a.config.EnableProfiles {
+		return nil
+	}
+	target := a.client.Target
+	if a.config.ReadOnly {
+		// Remove any previously declared capability
+		slog.Info("Read-only mode is enabled, so profile commands are disabled.")
+		return a.client.RemoveCommandCapability(target, OperationContainerProfile)
+	}
+	return a.client.HandleCommands(target, OperationContainerProfile, a.commandOptions(), a.handleProfileCommand)
+}
+
+func (a *App) handleProfileCommand(command *tedge.Command) error {
+	cmd := ProfileCommand{}
+	if err := command.Decode(&cmd); err != nil {
+		return err
+	}
+	err := a.runProfileCommand(cmd)
+	action := audit.ActionStart
+	if cmd.Action == ProfileActionDeactivate {
+		action = audit.ActionStop
+	}
+	a.config.Audit.Record(audit.Entry{
+		Action:      action,
+		Type:        container.ContainerGroupType,
+		Name:        cmd.Name,
+		OperationID: command.ID,
+		Initiator:   OperationContainerProfile,
+		Images:      a.ContainerClient.GetContainerImageDigests(context.Background(), container.ProjectFilter(cmd.Name)),
+	}, err)
+
+	if err := a.UpdateProfiles(); err != nil {
+		slog.Warn("Could not update profiles.", "err", err)
+	}
+	return err
+}
+
+func (a *App) runProfileCommand(cmd ProfileCommand) error {
+	profile, err := container.FindProfile(a.config.ProfilesDir, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	out := &bytes.Buffer{}
+	switch cmd.Action {
+	case ProfileActionActivate:
+		err = a.ContainerClient.ActivateProfile(ctx, out, *profile)
+	case ProfileActionDeactivate:
+		err = a.ContainerClient.DeactivateProfile(ctx, out, *profile)
+	default:
+		return fmt.Errorf("invalid profile action. action=%s", cmd.Action)
+	}
+	slog.Info("Profile command output.", "name", cmd.Name, "action", cmd.Action, "output", out.String())
+	return err
+}
+
+// Publish the available and active profiles to the digital twin
+func (a *App) UpdateProfiles() error {
+	if !a.config.EnableProfiles {
+		return nil
+	}
+	profiles, err := a.ContainerClient.GetProfiles(context.Background(), a.config.ProfilesDir)
+	if err != nil {
+		return err
+	}
+	active := make([]string, 0)
+	for _, profile := range profiles {
+		if profile.Active {
+			active = append(active, profile.Name)
+		}
+	}
+	payload, err := json.Marshal(map[string]any{
+		"active":   active,
+		"profiles": profiles,
+	})
+	if err != nil {
+		return err
+	}
+	topic := tedge.GetTopic(a.client.Target, "twin", "container_profiles")
+	slog.Info("Publishing profiles", "topic", topic, "payload", payload)
+	return a.client.Publish(topic, 1, true, payload)
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/exporter"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Latest metrics of the members of each compose project. The members are collected independently
+// (e.g. when only recently restarted containers are collected), so the aggregate is calculated
+// from the latest sample of each member
+type projectMetrics struct {
+	// Ignore the samples of members which were not collected within the given duration, e.g. removed containers
+	MaxAge time.Duration
+
+	mutex    sync.Mutex
+	projects map[string]map[string]exporter.Sample
+}
+
+func newProjectMetrics(maxAge time.Duration) *projectMetrics {
+	return &projectMetrics{
+		MaxAge:   maxAge,
+		projects: make(map[string]map[string]exporter.Sample),
+	}
+}
+
+// Store the samples of project members, and return the names of the affected projects
+func (p *projectMetrics) Add(samples []exporter.Sample) []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	names := make([]string, 0)
+	for _, sample := range samples {
+		if sample.Project == "" {
+			continue
+		}
+		members, ok := p.projects[sample.Project]
+		if !ok {
+			members = make(map[string]exporter.Sample)
+			p.projects[sample.Project] = members
+		}
+		if !slices.Contains(names, sample.Project) {
+			names = append(names, sample.Project)
+		}
+		members[sample.Name] = sample
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sum the latest metrics of the project's members
+func (p *projectMetrics) Aggregate(project string, now time.Time) (map[string]container.LowPrecisionFloat, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	totals := map[string]float64{}
+	count := 0
+	for name, sample := range p.projects[project] {
+		if p.MaxAge > 0 && now.Sub(sample.Time) > p.MaxAge {
+			delete(p.projects[project], name)
+			continue
+		}
+		count++
+		for field, value := range sample.Fields {
+			totals[field] += value
+		}
+	}
+	if count == 0 {
+		delete(p.projects, project)
+	}
+
+	out := make(map[string]container.LowPrecisionFloat, len(totals))
+	for field, value := range totals {
+		precision := 2
+		if field == "netio" {
+			precision = 0
+		}
+		out[field] = container.NewLowerPrecisionFloat64(value, precision)
+	}
+	return out, count
+}
+
+// Publish the aggregated metrics of each compose project as measurements of the project's service
+func (a *App) publishProjectMetrics(samples []exporter.Sample) {
+	if a.projectMetrics == nil {
+		return
+	}
+	now := time.Now()
+	for _, project := range a.projectMetrics.Add(samples) {
+		totals, count := a.projectMetrics.Aggregate(project, now)
+		if count == 0 {
+			continue
+		}
+		payload, err := json.Marshal(map[string]any{
+			"project": totals,
+		})
+		if err != nil {
+			continue
+		}
+		topic := tedge.GetTopic(*a.Device.Service(project), "m", "resource_usage")
+		slog.Info("Publish compose project stats.", "topic", topic, "containers", count, "payload", payload)
+		if err := a.client.Publish(topic, 1, false, payload); err != nil {
+			slog.Warn("Failed to publish compose project stats.", "topic", topic, "err", err)
+		}
+	}
+}
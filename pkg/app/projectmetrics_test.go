@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/exporter"
+)
+
+func Test_projectMetrics(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metrics := newProjectMetrics(10 * time.Minute)
+
+	projects := metrics.Add([]exporter.Sample{
+		{Name: "app-web-1", Project: "app", Fields: map[string]float64{"cpu": 1.5, "memory": 10, "netio": 100}, Time: now},
+		{Name: "app-db-1", Project: "app", Fields: map[string]float64{"cpu": 2.5, "memory": 20, "netio": 50}, Time: now.Add(-20 * time.Minute)},
+		{Name: "standalone", Fields: map[string]float64{"cpu": 1}, Time: now},
+	})
+	assert.Equal(t, []string{"app"}, projects)
+
+	totals, count := metrics.Aggregate("app", now)
+	assert.Equal(t, 1, count, "outdated members are ignored")
+	assert.Equal(t, 1.5, totals["cpu"].Value)
+
+	// Only the collected member is updated
+	metrics.Add([]exporter.Sample{
+		{Name: "app-db-1", Project: "app", Fields: map[string]float64{"cpu": 2.5, "memory": 20, "netio": 50}, Time: now},
+	})
+	totals, count = metrics.Aggregate("app", now)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 4.0, totals["cpu"].Value)
+	assert.Equal(t, 30.0, totals["memory"].Value)
+	assert.Equal(t, 150.0, totals["netio"].Value)
+}
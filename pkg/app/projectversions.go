@@ -0,0 +1,171 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Event type used to publish the image changes of the compose projects
+var EventTypeProjectChanged = "container_project_changed"
+
+// Images of the services of each compose project, e.g. project => service => image
+type projectVersions map[string]map[string]string
+
+func newProjectVersions(items []container.TedgeContainer) projectVersions {
+	versions := make(projectVersions)
+	for project, containers := range container.GroupByProject(items) {
+		services := make(map[string]string, len(containers))
+		for _, item := range containers {
+			name := item.Container.ServiceName
+			if name == "" {
+				name = item.Name
+			}
+			services[name] = item.Container.Image
+		}
+		versions[project] = services
+	}
+	return versions
+}
+
+type ProjectChange struct {
+	Project string `json:"project"`
+	Service string `json:"service,omitempty"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// Compare the service images of the compose projects. Projects which were added or removed
+// are reported without a service
+func diffProjectVersions(previous projectVersions, current projectVersions) []ProjectChange {
+	changes := make([]ProjectChange, 0)
+	for project, services := range current {
+		prev, ok := previous[project]
+		if !ok {
+			changes = append(changes, ProjectChange{Project: project, New: "added"})
+			continue
+		}
+		for service, image := range services {
+			if prev[service] != image {
+				changes = append(changes, ProjectChange{Project: project, Service: service, Old: prev[service], New: image})
+			}
+		}
+		for service, image := range prev {
+			if _, ok := services[service]; !ok {
+				changes = append(changes, ProjectChange{Project: project, Service: service, Old: image})
+			}
+		}
+	}
+	for project := range previous {
+		if _, ok := current[project]; !ok {
+			changes = append(changes, ProjectChange{Project: project, Old: "removed"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Project == changes[j].Project {
+			return changes[i].Service < changes[j].Service
+		}
+		return changes[i].Project < changes[j].Project
+	})
+	return changes
+}
+
+// Track the versions of the compose projects. Compose recreates the containers of a project one
+// at a time, so the changes are only reported once the projects did not change for the quiet period
+type projectVersionTracker struct {
+	QuietPeriod time.Duration
+
+	mutex     sync.Mutex
+	published projectVersions
+	latest    projectVersions
+	timer     *time.Timer
+}
+
+// Record the current versions, and call onChange with the changes since the last reported
+// versions once the quiet period elapsed. The first observation is only used as the baseline
+func (t *projectVersionTracker) Observe(current projectVersions, onChange func([]ProjectChange)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.published == nil {
+		t.published = current
+		t.latest = current
+		return
+	}
+	if len(diffProjectVersions(t.latest, current)) == 0 && t.timer != nil {
+		return
+	}
+	t.latest = current
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if len(diffProjectVersions(t.published, current)) == 0 {
+		return
+	}
+	t.timer = time.AfterFunc(t.QuietPeriod, func() {
+		t.mutex.Lock()
+		changes := diffProjectVersions(t.published, t.latest)
+		t.published = t.latest
+		t.timer = nil
+		t.mutex.Unlock()
+		if len(changes) > 0 {
+			onChange(changes)
+		}
+	})
+}
+
+// Human readable summary of the changes
+func projectChangesText(changes []ProjectChange) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		switch {
+		case change.Service == "" && change.New != "":
+			parts = append(parts, change.Project+" "+change.New)
+		case change.Service == "":
+			parts = append(parts, change.Project+" "+change.Old)
+		default:
+			parts = append(parts, fmt.Sprintf("%s@%s %s->%s", change.Project, change.Service, change.Old, change.New))
+		}
+	}
+	return "Compose projects changed. " + strings.Join(parts, ", ")
+}
+
+// Detect compose projects whose images were changed outside of thin-edge.io (e.g. docker compose pull && up)
+func (a *App) checkProjectVersions(items []container.TedgeContainer) {
+	if a.projectVersions == nil {
+		return
+	}
+	a.projectVersions.Observe(newProjectVersions(items), a.publishProjectChanges)
+}
+
+// Publish the changes of the compose projects as an event, and refresh the software list so
+// the cloud's inventory reflects the new versions
+func (a *App) publishProjectChanges(changes []ProjectChange) {
+	payload := map[string]any{
+		"text":    projectChangesText(changes),
+		"changes": changes,
+	}
+	b, err := json.Marshal(a.client.Clock.SetTime(payload))
+	if err != nil {
+		slog.Warn("Could not marshal compose project changes.", "err", err)
+		return
+	}
+	topic := tedge.GetTopic(*a.Device, "e", EventTypeProjectChanged)
+	slog.Info("Publishing compose project changes.", "topic", topic, "payload", b)
+	if err := a.client.Publish(topic, 1, false, b); err != nil {
+		slog.Warn("Could not publish compose project changes.", "err", err)
+	}
+
+	cmdTopic := tedge.GetTopic(*a.Device, "cmd", "software_list", fmt.Sprintf("%s-%d", a.config.ServiceName, time.Now().UnixNano()))
+	slog.Info("Requesting a software list refresh.", "topic", cmdTopic)
+	if err := a.client.Publish(cmdTopic, 1, true, `{"status":"init"}`); err != nil {
+		slog.Warn("Could not request a software list refresh.", "err", err)
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffProjectVersions(t *testing.T) {
+	previous := projectVersions{
+		"app1": {"web": "nginx:1.25", "db": "postgres:15"},
+		"app2": {"web": "httpd:2.4"},
+	}
+	current := projectVersions{
+		"app1": {"web": "nginx:1.26", "cache": "redis:7"},
+		"app3": {"web": "caddy:2"},
+	}
+	changes := diffProjectVersions(previous, current)
+	assert.Equal(t, []ProjectChange{
+		{Project: "app1", Service: "cache", New: "redis:7"},
+		{Project: "app1", Service: "db", Old: "postgres:15"},
+		{Project: "app1", Service: "web", Old: "nginx:1.25", New: "nginx:1.26"},
+		{Project: "app2", Old: "removed"},
+		{Project: "app3", New: "added"},
+	}, changes)
+	assert.Equal(t, "Compose projects changed. app1@cache ->redis:7, app1@db postgres:15->, app1@web nginx:1.25->nginx:1.26, app2 removed, app3 added", projectChangesText(changes))
+
+	assert.Empty(t, diffProjectVersions(current, current))
+}
+
+func Test_projectVersionTracker(t *testing.T) {
+	tracker := &projectVersionTracker{QuietPeriod: 20 * time.Millisecond}
+	published := make(chan []ProjectChange, 2)
+	onChange := func(changes []ProjectChange) {
+		published <- changes
+	}
+
+	// baseline
+	tracker.Observe(projectVersions{"app1": {"web": "nginx:1.25", "db": "postgres:15"}}, onChange)
+
+	// compose recreates the containers one at a time
+	tracker.Observe(projectVersions{"app1": {"db": "postgres:15"}}, onChange)
+	tracker.Observe(projectVersions{"app1": {"web": "nginx:1.26", "db": "postgres:15"}}, onChange)
+
+	select {
+	case changes := <-published:
+		assert.Equal(t, []ProjectChange{{Project: "app1", Service: "web", Old: "nginx:1.25", New: "nginx:1.26"}}, changes)
+	case <-time.After(time.Second):
+		t.Fatal("changes were not published")
+	}
+
+	// a change which is reverted within the quiet period is not reported
+	tracker.Observe(projectVersions{"app1": {"db": "postgres:15"}}, onChange)
+	tracker.Observe(projectVersions{"app1": {"web": "nginx:1.26", "db": "postgres:15"}}, onChange)
+	select {
+	case changes := <-published:
+		t.Fatalf("unexpected changes: %v", changes)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
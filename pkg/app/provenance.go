@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Publish the provenance (builder, source repository and revision) of the containers' images to the twin.
+// The provenance is cached per image as it can't change
+func (a *App) publishProvenance(items []container.TedgeContainer) {
+	a.provenanceMutex.Lock()
+	defer a.provenanceMutex.Unlock()
+	if a.provenance == nil {
+		a.provenance = make(map[string]*container.Provenance)
+	}
+	for _, item := range items {
+		imageID := item.Container.ImageID
+		if imageID == "" {
+			continue
+		}
+		provenance, cached := a.provenance[imageID]
+		if !cached {
+			value, ok, err := a.ContainerClient.GetImageProvenance(context.Background(), imageID)
+			if err != nil {
+				slog.Warn("Could not read image provenance.", "image", item.Container.Image, "err", err)
+				continue
+			}
+			if ok {
+				provenance = &value
+			}
+			a.provenance[imageID] = provenance
+		}
+		if provenance == nil {
+			continue
+		}
+
+		topic := tedge.GetTopic(*a.Device.Service(item.Name), "twin", "provenance")
+		if err := a.client.Publish(topic, 1, true, mustMarshalJSON(provenance)); err != nil {
+			slog.Warn("Could not publish image provenance.", "topic", topic, "err", err)
+		}
+	}
+}
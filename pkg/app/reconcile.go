@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Actions which are reported for each stale service by the reconciliation
+var (
+	ReconcileActionExcluded    = "excluded"
+	ReconcileActionWouldRemove = "would_remove"
+	ReconcileActionMarked      = "marked"
+	ReconcileActionRemoved     = "removed"
+)
+
+// Stale service which was found by the reconciliation
+type ReconcileResult struct {
+	Topic   string `json:"topic"`
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Action  string `json:"action"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Report of a reconciliation of the registered services with the containers
+type ReconcileReport struct {
+	Root            string            `json:"root"`
+	Registered      int               `json:"registered"`
+	Containers      int               `json:"containers"`
+	DryRun          bool              `json:"dryRun"`
+	DeleteFromCloud bool              `json:"deleteFromCloud"`
+	Stale           []ReconcileResult `json:"stale"`
+}
+
+// Reconcile the registered services with the containers on demand, and remove the stale services
+// (and delete them from the cloud if enabled). A service is only stale if no container uses its name,
+// compose project or stack name. In dry run mode, the stale services are only reported
+func (a *App) Reconcile(dryRun bool) (ReconcileReport, error) {
+	report := ReconcileReport{
+		DryRun:          dryRun,
+		DeleteFromCloud: a.config.DeleteFromCloud,
+		Stale:           make([]ReconcileResult, 0),
+	}
+
+	ctx := context.Background()
+	if status := a.ContainerClient.GetEngineStatus(ctx); status.Status != "up" {
+		return report, fmt.Errorf("%w, stale services can't be detected. err=%s", container.ErrEngineUnavailable, status.Error)
+	}
+
+	entities, err := a.client.GetEntities()
+	if err != nil {
+		return report, err
+	}
+	registered := make(map[string]struct{})
+	for topic, v := range entities {
+		switch v.(map[string]any)["type"] {
+		case container.ContainerType, container.ContainerGroupType, container.ContainerStackType:
+			registered[topic] = struct{}{}
+		}
+	}
+	report.Registered = len(registered)
+
+	result, err := a.ContainerClient.ListBounded(ctx, container.FilterOptions{})
+	if err != nil {
+		return report, err
+	}
+	if result.Truncated {
+		return report, fmt.Errorf("container list is truncated, stale services can't be detected. containers=%d", len(result.Items))
+	}
+	report.Containers = len(result.Items)
+
+	stale := a.withoutActiveServices(registered, result.Items)
+	newStale := 0
+	for topic := range stale {
+		if _, ok := a.tombstones.Get(topic); !ok {
+			newStale++
+		}
+	}
+	if exceedsRemovalLimit(newStale, len(registered), a.config.StaleMaxRemovalRatio) {
+		slog.Warn("Removal of stale services exceeds the allowed limit. Confirming with a second sample.", "stale", newStale, "registered", len(registered))
+		stale = a.confirmStaleServices(stale, container.FilterOptions{})
+	}
+
+	topics := make([]string, 0, len(stale))
+	for topic := range stale {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	targets := make([]tedge.Target, 0, len(topics))
+	for _, topic := range topics {
+		item := ReconcileResult{
+			Topic:  topic,
+			Action: ReconcileActionWouldRemove,
+		}
+		item.Name, _ = a.Device.ServiceName(topic)
+		if payload, ok := entities[topic].(map[string]any); ok {
+			item.Type, _ = payload["type"].(string)
+		}
+		if pattern, ok := excludedFromCleanup(item.Name, topic, a.config.CleanupExclude); ok {
+			item.Action = ReconcileActionExcluded
+			item.Pattern = pattern
+		} else if target, err := tedge.NewTargetFromTopic(topic); err != nil {
+			slog.Warn("Invalid topic structure", "err", err)
+			continue
+		} else {
+			targets = append(targets, *target)
+		}
+		slog.Info("Found stale service.", "topic", topic, "name", item.Name, "type", item.Type, "action", item.Action)
+		report.Stale = append(report.Stale, item)
+	}
+
+	if dryRun || len(targets) == 0 {
+		return report, nil
+	}
+
+	removed := make(map[string]struct{})
+	for _, target := range a.removeServices(targets) {
+		removed[target.Topic()] = struct{}{}
+	}
+	for i, item := range report.Stale {
+		if item.Action != ReconcileActionWouldRemove {
+			continue
+		}
+		if _, ok := removed[item.Topic]; ok {
+			report.Stale[i].Action = ReconcileActionRemoved
+		} else {
+			// A retention period is configured, so the service is only marked as removed
+			report.Stale[i].Action = ReconcileActionMarked
+		}
+	}
+	return report, nil
+}
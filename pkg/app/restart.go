@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+var OperationContainerRestart = "container_restart"
+
+type RestartCommand struct {
+	Name string `json:"name"`
+}
+
+// Result of restarting a single container as part of a restart command
+type RestartResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Declare the restart command capability and listen for restart commands
+func (a *App) SubscribeRestart() error {
+	if !a.config.EnableRestart {
+		return nil
+	}
+	target := a.client.Target
+	if a.config.ReadOnly {
+		// Remove any previously declared capability
+		slog.Info("Read-only mode is enabled, so restart commands are disabled.")
+		return a.client.RemoveCommandCapability(target, OperationContainerRestart)
+	}
+	return a.client.HandleCommands(target, OperationContainerRestart, a.commandOptions(), a.handleRestartCommand)
+}
+
+// Restart a container followed by the containers which declared that they have to be restarted with it
+// (see container.LabelRestartWith). The result of each restart is included in the command's results
+func (a *App) handleRestartCommand(command *tedge.Command) error {
+	cmd := RestartCommand{}
+	if err := command.Decode(&cmd); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	items, err := a.ContainerClient.List(ctx, container.FilterOptions{})
+	if err != nil {
+		return err
+	}
+	order, err := container.RestartOrder(items, cmd.Name)
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string, len(items))
+	for _, item := range items {
+		ids[item.Container.Name] = item.Container.Id
+	}
+
+	results := make([]RestartResult, 0, len(order))
+	failed := make([]string, 0)
+	for _, name := range order {
+		result := RestartResult{
+			Name:   name,
+			Status: tedge.CommandStatusSuccessful,
+		}
+		if len(failed) > 0 && name != cmd.Name {
+			// The dependencies might not be in a usable state, so don't restart the remaining dependents
+			result.Status = "skipped"
+			result.Reason = "restart of a previous container failed"
+			results = append(results, result)
+			continue
+		}
+		err := a.ContainerClient.RestartContainer(ctx, ids[name])
+		a.config.Audit.Record(audit.Entry{
+			Action:      audit.ActionRestart,
+			Type:        container.ContainerType,
+			Name:        name,
+			OperationID: command.ID,
+			Initiator:   OperationContainerRestart,
+		}, err)
+		if err != nil {
+			result.Status = tedge.CommandStatusFailed
+			result.Reason = err.Error()
+			failed = append(failed, name)
+		}
+		results = append(results, result)
+	}
+	command.Set("results", results)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not restart containers. failed=%s", strings.Join(failed, ","))
+	}
+	slog.Info("Restarted containers.", "name", cmd.Name, "containers", order)
+	return nil
+}
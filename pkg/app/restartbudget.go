@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Alarm type (suffixed with the container name) which is raised when a container exceeds its restart budget
+var RestartBudgetAlarmType = "container_restart_budget_exceeded_"
+
+// Number of times each container (by id) was started within the window. Containers which
+// are started more often than allowed are crash looping
+type restartBudget struct {
+	Max    int
+	Window time.Duration
+
+	mutex    sync.Mutex
+	starts   map[string][]time.Time
+	exceeded map[string]struct{}
+}
+
+func newRestartBudget(max int, window time.Duration) *restartBudget {
+	return &restartBudget{
+		Max:      max,
+		Window:   window,
+		starts:   make(map[string][]time.Time),
+		exceeded: make(map[string]struct{}),
+	}
+}
+
+// Record a start of a container, and return the number of starts within the window
+// and true if the container exceeded the budget (only the first time)
+func (b *restartBudget) Record(id string, now time.Time) (int, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	starts := make([]time.Time, 0, len(b.starts[id])+1)
+	for _, t := range b.starts[id] {
+		if now.Sub(t) < b.Window {
+			starts = append(starts, t)
+		}
+	}
+	starts = append(starts, now)
+	b.starts[id] = starts
+
+	if len(starts) <= b.Max {
+		return len(starts), false
+	}
+	if _, ok := b.exceeded[id]; ok {
+		return len(starts), false
+	}
+	b.exceeded[id] = struct{}{}
+	return len(starts), true
+}
+
+// Forget a container, and return true if it had exceeded the budget
+func (b *restartBudget) Remove(id string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, exceeded := b.exceeded[id]
+	delete(b.starts, id)
+	delete(b.exceeded, id)
+	return exceeded
+}
+
+// Track the container starts, and stop restarting the containers which exceed the restart budget
+func (a *App) checkRestartBudget(evt events.Message) {
+	if a.restartBudget == nil {
+		return
+	}
+	switch evt.Action {
+	case events.ActionStart:
+		starts, exceeded := a.restartBudget.Record(evt.Actor.ID, time.Now())
+		if exceeded {
+			go a.enforceRestartBudget(evt.Actor.ID, evt.Actor.Attributes["name"], starts)
+		}
+	case events.ActionDestroy, events.ActionRemove:
+		if a.restartBudget.Remove(evt.Actor.ID) {
+			// A reinstalled container starts with a new budget
+			a.publishRestartBudgetAlarm(evt.Actor.Attributes["name"], nil)
+		}
+	}
+}
+
+// Disable the restart policy of a crash looping container and raise an alarm. The container stays
+// stopped after it exits the next time, until it is started again (e.g. by reinstalling it)
+func (a *App) enforceRestartBudget(id string, name string, starts int) {
+	slog.Warn("Container exceeded the restart budget.", "container", name, "starts", starts, "max", a.restartBudget.Max, "window", a.restartBudget.Window)
+
+	var err error
+	if a.config.ReadOnly {
+		slog.Warn("Read-only mode is enabled, so the restart policy is not changed.", "container", name)
+	} else {
+		err = a.ContainerClient.DisableRestart(context.Background(), id)
+		a.config.Audit.Record(audit.Entry{
+			Action:    audit.ActionUpdate,
+			Type:      container.ContainerType,
+			Name:      name,
+			Initiator: "restart_budget",
+		}, err)
+		if err != nil {
+			slog.Warn("Could not disable the restart policy.", "container", name, "err", err)
+		}
+	}
+
+	a.publishRestartBudgetAlarm(name, map[string]any{
+		"containerID":     id,
+		"starts":          starts,
+		"max":             a.restartBudget.Max,
+		"window":          a.restartBudget.Window.String(),
+		"restartDisabled": !a.config.ReadOnly && err == nil,
+	})
+}
+
+// Raise the restart budget alarm of a container, or clear it if the details are nil
+func (a *App) publishRestartBudgetAlarm(name string, details map[string]any) {
+	topic := tedge.GetAlarmTopic(*a.Device, RestartBudgetAlarmType+name)
+	payload := []byte{}
+	if details != nil {
+		var err error
+		text := fmt.Sprintf("Container exceeded the restart budget. name=%s, starts=%v, window=%v", name, details["starts"], details["window"])
+		payload, err = tedge.PayloadAlarm(a.client.Clock, details, text, tedge.AlarmSeverityMajor)
+		if err != nil {
+			slog.Warn("Could not marshal alarm payload.", "err", err)
+			return
+		}
+	}
+	if err := a.client.Publish(topic, 1, true, payload); err != nil {
+		slog.Warn("Could not publish restart budget alarm.", "topic", topic, "err", err)
+	}
+}
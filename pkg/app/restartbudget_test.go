@@ -0,0 +1,38 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_restartBudget(t *testing.T) {
+	budget := newRestartBudget(2, 10*time.Minute)
+	now := time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+
+	starts, exceeded := budget.Record("1", now)
+	assert.Equal(t, 1, starts)
+	assert.False(t, exceeded)
+	_, exceeded = budget.Record("1", now.Add(time.Minute))
+	assert.False(t, exceeded)
+
+	starts, exceeded = budget.Record("1", now.Add(2*time.Minute))
+	assert.Equal(t, 3, starts)
+	assert.True(t, exceeded)
+
+	// only reported once
+	_, exceeded = budget.Record("1", now.Add(3*time.Minute))
+	assert.False(t, exceeded)
+
+	// starts outside of the window are not counted
+	starts, _ = budget.Record("2", now)
+	assert.Equal(t, 1, starts)
+	starts, _ = budget.Record("2", now.Add(11*time.Minute))
+	assert.Equal(t, 1, starts)
+
+	assert.True(t, budget.Remove("1"))
+	assert.False(t, budget.Remove("2"))
+	starts, _ = budget.Record("1", now.Add(4*time.Minute))
+	assert.Equal(t, 1, starts)
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/schedule"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Labels which define when a container is started and stopped (cron expressions), e.g. "0 8 * * 1-5"
+var (
+	LabelScheduleStart = "tedge.schedule.start"
+	LabelScheduleStop  = "tedge.schedule.stop"
+)
+
+// Type of the event which is published when the scheduler starts or stops a container
+var ScheduleEventType = "container_schedule"
+
+// Start and stop schedule of the containers whose name matches the pattern.
+// The schedule labels of a container take precedence over the rules
+type ScheduleRule struct {
+	// Regular expression which is matched against the container name
+	Name  string `mapstructure:"name"`
+	Start string `mapstructure:"start"`
+	Stop  string `mapstructure:"stop"`
+}
+
+// Parsed cron expressions, as the expressions are evaluated every minute
+type cronCache struct {
+	mutex   sync.Mutex
+	entries map[string]*schedule.Cron
+}
+
+func (c *cronCache) get(expr string) (*schedule.Cron, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if cron, ok := c.entries[expr]; ok {
+		return cron, nil
+	}
+	cron, err := schedule.ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*schedule.Cron)
+	}
+	c.entries[expr] = cron
+	return cron, nil
+}
+
+// Get the start and stop expressions of a container from its labels, or the first matching rule
+func scheduleExpressions(item container.TedgeContainer, rules []ScheduleRule) (string, string) {
+	start := item.Container.Labels[LabelScheduleStart]
+	stop := item.Container.Labels[LabelScheduleStop]
+	if start != "" || stop != "" {
+		return start, stop
+	}
+	for _, rule := range rules {
+		p, err := regexp.Compile(rule.Name)
+		if err != nil {
+			slog.Warn("Invalid schedule rule name pattern.", "pattern", rule.Name, "err", err)
+			continue
+		}
+		if p.MatchString(item.Container.Name) {
+			return rule.Start, rule.Stop
+		}
+	}
+	return "", ""
+}
+
+func (a *App) scheduleMatches(expr string, now time.Time) (bool, error) {
+	if expr == "" {
+		return false, nil
+	}
+	cron, err := a.crons.get(expr)
+	if err != nil {
+		return false, err
+	}
+	return cron.Matches(now), nil
+}
+
+// Get the scheduled action (start or stop) of a container at the given time. The stop schedule wins
+// if both match. No action is returned if the container is already in the desired state
+func (a *App) scheduledAction(item container.TedgeContainer, now time.Time) (string, error) {
+	start, stop := scheduleExpressions(item, a.config.ScheduleRules)
+	startMatches, err := a.scheduleMatches(start, now)
+	if err != nil {
+		return "", err
+	}
+	stopMatches, err := a.scheduleMatches(stop, now)
+	if err != nil {
+		return "", err
+	}
+	running := item.Container.State == "running"
+	switch {
+	case stopMatches && running:
+		return audit.ActionStop, nil
+	case startMatches && !stopMatches && !running:
+		return audit.ActionStart, nil
+	}
+	return "", nil
+}
+
+// Start and stop the containers whose schedule matches the given time (to the minute)
+func (a *App) RunSchedule(now time.Time) error {
+	if !a.config.EnableScheduler || a.config.ReadOnly {
+		return nil
+	}
+	ctx := context.Background()
+	items, err := a.ContainerClient.List(ctx, container.FilterOptions{
+		Types: []string{container.ContainerType, container.ContainerGroupType},
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		action, err := a.scheduledAction(item, now)
+		if err != nil {
+			slog.Warn("Invalid container schedule.", "name", item.Container.Name, "err", err)
+			continue
+		}
+		if action == "" {
+			continue
+		}
+
+		if action == audit.ActionStart {
+			err = a.ContainerClient.StartContainer(ctx, item.Container.Id)
+		} else {
+			err = a.ContainerClient.StopContainer(ctx, item.Container.Id)
+		}
+		a.config.Audit.Record(audit.Entry{
+			Action:    action,
+			Type:      item.ServiceType,
+			Name:      item.Container.Name,
+			Initiator: "schedule",
+		}, err)
+		a.publishScheduleEvent(item, action, err)
+	}
+	return nil
+}
+
+func (a *App) publishScheduleEvent(item container.TedgeContainer, action string, actionErr error) {
+	payload := map[string]any{
+		"text":   fmt.Sprintf("Scheduled container %s. name=%s", action, item.Container.Name),
+		"name":   item.Container.Name,
+		"action": action,
+	}
+	if actionErr != nil {
+		slog.Warn("Scheduled container action failed.", "name", item.Container.Name, "action", action, "err", actionErr)
+		payload["text"] = fmt.Sprintf("Scheduled container %s failed. name=%s", action, item.Container.Name)
+		payload["error"] = actionErr.Error()
+	}
+	topic := tedge.GetTopic(a.client.Target, "e", ScheduleEventType)
+	if err := a.client.Publish(topic, 1, false, mustMarshalJSON(a.client.Clock.SetTime(payload))); err != nil {
+		slog.Warn("Could not publish schedule event.", "topic", topic, "err", err)
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_scheduledAction(t *testing.T) {
+	a := &App{
+		config: Config{
+			ScheduleRules: []ScheduleRule{
+				{Name: "^camera", Start: "0 8 * * 1-5", Stop: "0 18 * * 1-5"},
+			},
+		},
+	}
+	camera := container.TedgeContainer{
+		Container: container.Container{Name: "camera-analytics", State: "exited"},
+	}
+
+	// 2024-09-02 is a Monday
+	morning := time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2024, 9, 2, 18, 0, 0, 0, time.UTC)
+
+	action, err := a.scheduledAction(camera, morning)
+	assert.NoError(t, err)
+	assert.Equal(t, audit.ActionStart, action)
+
+	action, _ = a.scheduledAction(camera, evening)
+	assert.Equal(t, "", action, "already stopped")
+
+	camera.Container.State = "running"
+	action, _ = a.scheduledAction(camera, evening)
+	assert.Equal(t, audit.ActionStop, action)
+
+	// Labels take precedence over the rules
+	camera.Container.Labels = map[string]string{LabelScheduleStop: "30 12 * * *"}
+	action, _ = a.scheduledAction(camera, evening)
+	assert.Equal(t, "", action)
+	action, _ = a.scheduledAction(camera, time.Date(2024, 9, 2, 12, 30, 0, 0, time.UTC))
+	assert.Equal(t, audit.ActionStop, action)
+
+	camera.Container.Labels = map[string]string{LabelScheduleStart: "invalid"}
+	_, err = a.scheduledAction(camera, evening)
+	assert.Error(t, err)
+}
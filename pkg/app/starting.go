@@ -0,0 +1,71 @@
+package app
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Container states which are reported using the starting status (instead of down) during the grace period
+var startingStates = map[string]struct{}{
+	"created":    {},
+	"restarting": {},
+}
+
+// Time when each container (by id) was first seen in a starting state
+type startingTracker struct {
+	mutex sync.Mutex
+	since map[string]time.Time
+}
+
+// Record that a container is in a starting state, and return when it was first seen in a starting state
+func (s *startingTracker) Seen(id string, now time.Time) (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.since == nil {
+		s.since = make(map[string]time.Time)
+	}
+	if since, ok := s.since[id]; ok {
+		return since, false
+	}
+	s.since[id] = now
+	return now, true
+}
+
+func (s *startingTracker) Remove(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.since, id)
+}
+
+// Report the containers which are created or restarting using the starting status, so slow boots don't
+// raise false alerts. The containers are reported as down once they have been starting for longer than
+// the grace period. Containers which are not starting are removed from the tracker
+func (a *App) applyStartingStatus(items []container.TedgeContainer, now time.Time) {
+	if a.config.StartingStatus == "" {
+		return
+	}
+	for i, item := range items {
+		if _, ok := startingStates[item.Container.State]; !ok {
+			a.starting.Remove(item.Container.Id)
+			continue
+		}
+		since, first := a.starting.Seen(item.Container.Id, now)
+		if now.Sub(since) >= a.config.StartingGracePeriod {
+			continue
+		}
+		items[i].Status = a.config.StartingStatus
+		if first {
+			// Check the container again once the grace period is over, so it is reported as down if it is still starting
+			id := item.Container.Id
+			slog.Info("Container is starting.", "container", item.Name, "state", item.Container.State, "gracePeriod", a.config.StartingGracePeriod)
+			time.AfterFunc(a.config.StartingGracePeriod, func() {
+				a.enqueue(NewTargetedUpdateAction(container.FilterOptions{
+					IDs: []string{id},
+				}))
+			})
+		}
+	}
+}
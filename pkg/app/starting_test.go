@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_applyStartingStatus(t *testing.T) {
+	a := &App{
+		config: Config{
+			StartingStatus:      "starting",
+			StartingGracePeriod: 2 * time.Minute,
+		},
+	}
+	now := time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+	newItems := func(state string) []container.TedgeContainer {
+		return []container.TedgeContainer{{
+			Status:    container.ConvertToTedgeStatus(state),
+			Container: container.Container{Id: "1", Name: "app", State: state},
+		}}
+	}
+
+	items := newItems("restarting")
+	a.applyStartingStatus(items, now)
+	assert.Equal(t, "starting", items[0].Status)
+
+	items = newItems("restarting")
+	a.applyStartingStatus(items, now.Add(2*time.Minute))
+	assert.Equal(t, "down", items[0].Status)
+
+	// the grace period starts again once the container was running
+	a.applyStartingStatus(newItems("running"), now.Add(3*time.Minute))
+	items = newItems("created")
+	a.applyStartingStatus(items, now.Add(4*time.Minute))
+	assert.Equal(t, "starting", items[0].Status)
+}
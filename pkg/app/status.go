@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Health of a connection, e.g. to the MQTT broker or the container engine
+type ConnectionStatus struct {
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ErrorStatus struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Status of the monitor of a single topic root, which can be assessed without MQTT access
+type Status struct {
+	Root    string            `json:"root"`
+	Service string            `json:"service"`
+	MQTT    ConnectionStatus  `json:"mqtt"`
+	Bridge  *ConnectionStatus `json:"bridge,omitempty"`
+	Engine  ConnectionStatus  `json:"engine"`
+
+	Entities   int `json:"entities"`
+	Containers int `json:"containers"`
+
+	// Update requests which have not been processed yet
+	QueuedRequests int `json:"queuedRequests"`
+	// Services which are marked as removed and are waiting to be deleted
+	PendingRemovals int `json:"pendingRemovals"`
+
+	LastUpdate *time.Time   `json:"lastUpdate,omitempty"`
+	LastError  *ErrorStatus `json:"lastError,omitempty"`
+
+	// Time of the last full update which completed successfully
+	LastReconcile *time.Time `json:"lastReconcile,omitempty"`
+}
+
+// StatusFile is written periodically by the run command
+type StatusFile struct {
+	Time      time.Time `json:"time"`
+	PID       int       `json:"pid"`
+	Instances []Status  `json:"instances"`
+}
+
+// Counters which are updated by the background worker
+type statusTracker struct {
+	mutex      sync.Mutex
+	queued     int
+	containers int
+	lastUpdate *time.Time
+	lastError  *ErrorStatus
+
+	lastReconcile *time.Time
+}
+
+func (s *statusTracker) addQueued(delta int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queued += delta
+}
+
+func (s *statusTracker) recordUpdate(containers int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	s.containers = containers
+	s.lastUpdate = &now
+}
+
+func (s *statusTracker) recordReconcile(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastReconcile = &now
+}
+
+func (s *statusTracker) getLastReconcile() *time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastReconcile
+}
+
+func (s *statusTracker) recordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastError = &ErrorStatus{
+		Time:    time.Now(),
+		Message: err.Error(),
+	}
+}
+
+// Queue a request for the background workers of the request's lane
+func (a *App) enqueue(request ActionRequest) {
+	a.status.addQueued(1)
+	if request.Lane == LaneTargeted {
+		a.targetedRequests <- request
+		return
+	}
+	a.fullRequests <- request
+}
+
+// Get the current status of the application
+func (a *App) Status(ctx context.Context) Status {
+	status := Status{
+		Root:    a.Device.RootPrefix,
+		Service: a.config.ServiceName,
+		MQTT: ConnectionStatus{
+			Connected: a.client.Client.IsConnectionOpen(),
+		},
+	}
+
+	if a.config.Bridge != nil {
+		status.Bridge = &ConnectionStatus{
+			Connected: a.config.Bridge.Client.IsConnectionOpen(),
+		}
+	}
+
+	engineStatus := a.ContainerClient.GetEngineStatus(ctx)
+	status.Engine = ConnectionStatus{
+		Connected: engineStatus.Status == "up",
+		Error:     engineStatus.Error,
+	}
+
+	if entities, err := a.client.GetEntities(); err == nil {
+		status.Entities = len(entities)
+	}
+	status.PendingRemovals = a.tombstones.Len()
+
+	a.status.mutex.Lock()
+	defer a.status.mutex.Unlock()
+	status.Containers = a.status.containers
+	status.QueuedRequests = a.status.queued
+	status.LastUpdate = a.status.lastUpdate
+	status.LastError = a.status.lastError
+	status.LastReconcile = a.status.lastReconcile
+	return status
+}
+
+// Write the status of the applications to a file. The file is replaced atomically
+// so that readers never see a partially written file
+func WriteStatusFile(ctx context.Context, path string, applications []*App) error {
+	file := StatusFile{
+		Time:      time.Now(),
+		PID:       os.Getpid(),
+		Instances: make([]Status, 0, len(applications)),
+	}
+	for _, application := range applications {
+		file.Instances = append(file.Instances, application.Status(ctx))
+	}
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_statusTracker(t *testing.T) {
+	tracker := statusTracker{}
+	tracker.addQueued(1)
+	tracker.addQueued(1)
+	tracker.addQueued(-1)
+	assert.Equal(t, 1, tracker.queued)
+
+	tracker.recordError(nil)
+	assert.Nil(t, tracker.lastError)
+	tracker.recordError(errors.New("engine is not reachable"))
+	assert.Equal(t, "engine is not reachable", tracker.lastError.Message)
+
+	tracker.recordUpdate(3)
+	assert.Equal(t, 3, tracker.containers)
+	assert.NotNil(t, tracker.lastUpdate)
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+type ContainerCounts struct {
+	Total int `json:"total"`
+	Up    int `json:"up"`
+	Down  int `json:"down"`
+}
+
+func (c *ContainerCounts) add(status string) {
+	c.Total++
+	if status == "up" {
+		c.Up++
+	} else {
+		c.Down++
+	}
+}
+
+// Summary of the container states which is published to the device's twin
+type ContainerSummary struct {
+	ContainerCounts
+	ByProject map[string]ContainerCounts `json:"byProject"`
+}
+
+// Last known state of a container which is included in the summary
+type summaryEntry struct {
+	Project string
+	Status  string
+}
+
+// Container states used to calculate the summary. Partial updates only update the included containers
+type containerSummaryState struct {
+	mutex     sync.Mutex
+	entries   map[string]summaryEntry
+	published []byte
+}
+
+func (s *containerSummaryState) update(items []container.TedgeContainer, complete bool) {
+	if complete || s.entries == nil {
+		s.entries = make(map[string]summaryEntry, len(items))
+	}
+	for _, item := range items {
+		s.entries[item.Container.Id] = summaryEntry{
+			Project: item.Container.ProjectName,
+			Status:  item.Status,
+		}
+	}
+}
+
+func (s *containerSummaryState) summary() ContainerSummary {
+	summary := ContainerSummary{
+		ByProject: make(map[string]ContainerCounts),
+	}
+	for _, entry := range s.entries {
+		summary.add(entry.Status)
+		if entry.Project != "" {
+			counts := summary.ByProject[entry.Project]
+			counts.add(entry.Status)
+			summary.ByProject[entry.Project] = counts
+		}
+	}
+	return summary
+}
+
+// Publish the container summary to the device's twin if it changed since it was last published.
+// The summary is only published once all containers were read
+func (a *App) publishSummary(items []container.TedgeContainer, complete bool) {
+	a.summary.mutex.Lock()
+	defer a.summary.mutex.Unlock()
+	if !complete && a.summary.entries == nil {
+		return
+	}
+	a.summary.update(items, complete)
+
+	payload := mustMarshalJSON(a.summary.summary())
+	if bytes.Equal(payload, a.summary.published) {
+		return
+	}
+	topic := tedge.GetTopic(*a.Device, "twin", "containerSummary")
+	slog.Info("Publishing container summary.", "topic", topic, "payload", payload)
+	if err := a.client.Publish(topic, 1, true, payload); err != nil {
+		slog.Warn("Could not publish container summary.", "err", err)
+		return
+	}
+	a.summary.published = payload
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func newSummaryItem(id string, project string, status string) container.TedgeContainer {
+	return container.TedgeContainer{
+		Status: status,
+		Container: container.Container{
+			Id:          id,
+			ProjectName: project,
+		},
+	}
+}
+
+func Test_containerSummaryState(t *testing.T) {
+	state := containerSummaryState{}
+	state.update([]container.TedgeContainer{
+		newSummaryItem("1", "app", "up"),
+		newSummaryItem("2", "app", "down"),
+		newSummaryItem("3", "", "up"),
+	}, true)
+
+	summary := state.summary()
+	assert.Equal(t, ContainerCounts{Total: 3, Up: 2, Down: 1}, summary.ContainerCounts)
+	assert.Equal(t, ContainerCounts{Total: 2, Up: 1, Down: 1}, summary.ByProject["app"])
+
+	// Partial updates only replace the state of the included containers
+	state.update([]container.TedgeContainer{newSummaryItem("2", "app", "up")}, false)
+	summary = state.summary()
+	assert.Equal(t, ContainerCounts{Total: 3, Up: 3, Down: 0}, summary.ContainerCounts)
+
+	// Complete updates drop removed containers
+	state.update([]container.TedgeContainer{newSummaryItem("3", "", "up")}, true)
+	summary = state.summary()
+	assert.Equal(t, ContainerCounts{Total: 1, Up: 1, Down: 0}, summary.ContainerCounts)
+	assert.Empty(t, summary.ByProject)
+	assert.JSONEq(t, `{"total":1,"up":1,"down":0,"byProject":{}}`, string(mustMarshalJSON(summary)))
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Tombstones record when a service was marked as removed, so that it can be
+// hard deleted after the retention period. The tombstones are persisted so that
+// the retention period is not reset when the monitor is restarted
+type Tombstones struct {
+	path  string
+	mutex sync.Mutex
+	items map[string]time.Time
+}
+
+func NewTombstones(path string) *Tombstones {
+	t := &Tombstones{
+		path:  path,
+		items: make(map[string]time.Time),
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &t.items)
+	}
+	return t
+}
+
+// Get the time when the service was marked as removed
+func (t *Tombstones) Get(topic string) (time.Time, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	v, ok := t.items[topic]
+	return v, ok
+}
+
+func (t *Tombstones) Add(topic string, removedAt time.Time) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.items[topic] = removedAt
+	return t.save()
+}
+
+// Remove a tombstone. Returns true if the tombstone existed
+func (t *Tombstones) Remove(topic string) (bool, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.items[topic]; !ok {
+		return false, nil
+	}
+	delete(t.items, topic)
+	return true, t.save()
+}
+
+// Number of services which are marked as removed
+func (t *Tombstones) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.items)
+}
+
+func (t *Tombstones) save() error {
+	if t.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(t.items)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, b, 0644)
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Controls how the container twin information is published
+type TwinMode string
+
+const (
+	// Publish the container information as a single fragment (twin/container)
+	TwinModeFragment TwinMode = "fragment"
+
+	// Publish each property as a separate topic (twin/container/<field>), e.g. twin/container/image
+	TwinModeFields TwinMode = "fields"
+)
+
+// Fields which have been published for each service (by topic) in the fields twin mode,
+// so the topics of the fields which are no longer included can be cleared
+type twinFields struct {
+	mutex  sync.Mutex
+	fields map[string]map[string]struct{}
+}
+
+// Store the published fields of a service, and return the previously published fields which are no
+// longer included. first is true if the service's fields have not been published before
+func (t *twinFields) Set(topic string, fields []string) (removed []string, first bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.fields == nil {
+		t.fields = make(map[string]map[string]struct{})
+	}
+	previous, ok := t.fields[topic]
+	current := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		current[field] = struct{}{}
+	}
+	for field := range previous {
+		if _, exists := current[field]; !exists {
+			removed = append(removed, field)
+		}
+	}
+	sort.Strings(removed)
+	t.fields[topic] = current
+	return removed, !ok
+}
+
+// Forget a service, and return its published fields
+func (t *twinFields) Remove(topic string) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fields := make([]string, 0, len(t.fields[topic]))
+	for field := range t.fields[topic] {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	delete(t.fields, topic)
+	return fields
+}
+
+// Get the messages which publish the container twin of a service according to the twin mode
+func (a *App) twinMessages(target tedge.Target, payload []byte) ([]tedge.Message, error) {
+	topic := tedge.GetTopic(target, "twin", "container")
+	if a.config.TwinMode != TwinModeFields {
+		return []tedge.Message{{Topic: topic, Retained: true, Payload: payload}}, nil
+	}
+
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]tedge.Message, 0, len(fields)+1)
+	removed, first := a.twinFields.Set(target.Topic(), fields)
+	if first {
+		// Clear the fragment which was published in the fragment mode
+		messages = append(messages, tedge.Message{Topic: topic, Retained: true, Payload: ""})
+	}
+	for _, field := range fields {
+		messages = append(messages, tedge.Message{Topic: topic + "/" + field, Retained: true, Payload: []byte(values[field])})
+	}
+	for _, field := range removed {
+		messages = append(messages, tedge.Message{Topic: topic + "/" + field, Retained: true, Payload: ""})
+	}
+	return messages, nil
+}
+
+// Get the messages which clear the twin field topics of a removed service
+func (a *App) clearTwinFields(target tedge.Target) []tedge.Message {
+	topic := tedge.GetTopic(target, "twin", "container")
+	fields := a.twinFields.Remove(target.Topic())
+	messages := make([]tedge.Message, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, tedge.Message{Topic: topic + "/" + field, Retained: true, Payload: ""})
+	}
+	return messages
+}
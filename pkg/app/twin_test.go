@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func Test_twinMessages(t *testing.T) {
+	device := tedge.NewTarget("te", "device/main//")
+	target := *device.Service("app")
+	topic := tedge.GetTopic(target, "twin", "container")
+
+	a := &App{config: Config{TwinMode: TwinModeFragment}}
+	messages, err := a.twinMessages(target, []byte(`{"image":"nginx","state":"running"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []tedge.Message{{Topic: topic, Retained: true, Payload: []byte(`{"image":"nginx","state":"running"}`)}}, messages)
+
+	a = &App{config: Config{TwinMode: TwinModeFields}}
+	messages, err = a.twinMessages(target, []byte(`{"image":"nginx","state":"running"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []tedge.Message{
+		{Topic: topic, Retained: true, Payload: ""},
+		{Topic: topic + "/image", Retained: true, Payload: []byte(`"nginx"`)},
+		{Topic: topic + "/state", Retained: true, Payload: []byte(`"running"`)},
+	}, messages)
+
+	// fields which are no longer included are cleared
+	messages, err = a.twinMessages(target, []byte(`{"image":"nginx"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []tedge.Message{
+		{Topic: topic + "/image", Retained: true, Payload: []byte(`"nginx"`)},
+		{Topic: topic + "/state", Retained: true, Payload: ""},
+	}, messages)
+
+	assert.Equal(t, []tedge.Message{
+		{Topic: topic + "/image", Retained: true, Payload: ""},
+	}, a.clearTwinFields(target))
+	assert.Empty(t, a.clearTwinFields(target))
+}
@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default duration that the records of removed containers are kept
+var DefaultRetention = 7 * 24 * time.Hour
+
+// Last known state of a container which has been removed
+type Record struct {
+	Name        string    `json:"name"`
+	ServiceType string    `json:"serviceType"`
+	ContainerID string    `json:"containerId,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	State       string    `json:"state,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	LastSeen    time.Time `json:"lastSeen"`
+	RemovedAt   time.Time `json:"removedAt"`
+}
+
+// Archive of the containers which have been removed, which is persisted to a file.
+// The records are kept for the retention period
+type Archive struct {
+	Path      string        `json:"-"`
+	Retention time.Duration `json:"-"`
+
+	mu      sync.Mutex
+	Records map[string]Record `json:"records"`
+}
+
+// Load the archive from a file. An empty archive is returned if the file does not exist
+func Load(path string, retention time.Duration) (*Archive, error) {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	a := &Archive{
+		Path:      path,
+		Retention: retention,
+		Records:   make(map[string]Record),
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return a, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, a); err != nil {
+		return nil, err
+	}
+	if a.Records == nil {
+		a.Records = make(map[string]Record)
+	}
+	return a, nil
+}
+
+// Add the record of a removed container. An existing record of a container with the same name is replaced
+func (a *Archive) Add(record Record) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Records[record.Name] = record
+	return a.save()
+}
+
+// Remove the record of a container, e.g. when a container with the same name is created again.
+// Returns true if the record existed
+func (a *Archive) Remove(name string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.Records[name]; !ok {
+		return false, nil
+	}
+	delete(a.Records, name)
+	return true, a.save()
+}
+
+// Get the record of a removed container
+func (a *Archive) Get(name string) (Record, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	record, ok := a.Records[name]
+	return record, ok
+}
+
+// List the records (most recently removed first) which have not expired
+func (a *Archive) List(now time.Time) []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Record, 0, len(a.Records))
+	for _, record := range a.Records {
+		if now.Sub(record.RemovedAt) < a.Retention {
+			out = append(out, record)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].RemovedAt.After(out[j].RemovedAt)
+	})
+	return out
+}
+
+// Prune the records which are older than the retention period. The expired records are returned
+func (a *Archive) Prune(now time.Time) ([]Record, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	expired := make([]Record, 0)
+	for name, record := range a.Records {
+		if now.Sub(record.RemovedAt) >= a.Retention {
+			expired = append(expired, record)
+			delete(a.Records, name)
+		}
+	}
+	if len(expired) == 0 {
+		return expired, nil
+	}
+	return expired, a.save()
+}
+
+// Write the archive to a temporary file first so a partially written file is never read
+func (a *Archive) save() error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.Path), 0755); err != nil {
+		return err
+	}
+	tmp := a.Path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.Path)
+}
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Archive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	a, err := Load(path, time.Hour)
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	assert.NoError(t, a.Add(Record{Name: "app1", State: "exited", LastSeen: now.Add(-2 * time.Hour), RemovedAt: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, a.Add(Record{Name: "app2", State: "running", LastSeen: now.Add(-time.Minute), RemovedAt: now}))
+
+	// Expired records are not listed
+	items := a.List(now)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "app2", items[0].Name)
+
+	// The archive is persisted
+	loaded, err := Load(path, time.Hour)
+	assert.NoError(t, err)
+	record, ok := loaded.Get("app2")
+	assert.True(t, ok)
+	assert.Equal(t, "running", record.State)
+
+	expired, err := loaded.Prune(now)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, "app1", expired[0].Name)
+
+	removed, err := loaded.Remove("app2")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.Empty(t, loaded.List(now))
+}
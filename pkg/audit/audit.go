@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Mutating actions which are recorded
+const (
+	ActionInstall    = "install"
+	ActionRemove     = "remove"
+	ActionStart      = "start"
+	ActionStop       = "stop"
+	ActionRestart    = "restart"
+	ActionPrune      = "prune"
+	ActionCheckpoint = "checkpoint"
+	ActionRestore    = "restore"
+	ActionUpdate     = "update"
+)
+
+const (
+	OutcomeSuccessful = "successful"
+	OutcomeFailed     = "failed"
+)
+
+// Record of a mutating action
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Type        string    `json:"type"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version,omitempty"`
+	OperationID string    `json:"operationId,omitempty"`
+	Initiator   string    `json:"initiator,omitempty"`
+	Images      []string  `json:"images,omitempty"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Type of the thin-edge.io event used when forwarding the entries
+var EventType = "container_audit"
+
+// Convert the entry to a thin-edge.io event payload. The time is excluded as it is
+// set according to the configured time mode
+func (e Entry) Event() map[string]any {
+	payload := map[string]any{
+		"text":    fmt.Sprintf("Container %s %s. type=%s, name=%s", e.Action, e.Outcome, e.Type, e.Name),
+		"action":  e.Action,
+		"type":    e.Type,
+		"name":    e.Name,
+		"outcome": e.Outcome,
+	}
+	optional := map[string]string{
+		"version":     e.Version,
+		"operationId": e.OperationID,
+		"initiator":   e.Initiator,
+		"error":       e.Error,
+	}
+	for key, value := range optional {
+		if value != "" {
+			payload[key] = value
+		}
+	}
+	if len(e.Images) > 0 {
+		payload["images"] = e.Images
+	}
+	return payload
+}
+
+// Append-only audit log, where each entry is written as a single line of JSON.
+// The log is rotated once it exceeds the maximum size, so it does not grow forever on devices with limited storage.
+// Entries can optionally be forwarded (e.g. published as thin-edge.io events)
+type Logger struct {
+	Path string
+
+	// Maximum size (in bytes) of the log before it is rotated. 0 = unlimited
+	MaxSize int64
+
+	// Number of rotated logs which are kept, e.g. audit.log.1 (newest) to audit.log.<n> (oldest).
+	// 0 = all rotated logs are kept, so entries are only deleted if explicitly configured
+	MaxBackups int
+
+	// Optional function called for each entry after it has been written to the log
+	Forward func(entry Entry) error
+
+	mu sync.Mutex
+}
+
+func NewLogger(path string, maxSize int64, maxBackups int) *Logger {
+	return &Logger{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
+}
+
+// Record the result of an action. Errors are only logged, as auditing must not
+// change the outcome of the action. A nil logger does nothing
+func (l *Logger) Record(entry Entry, actionErr error) {
+	if l == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Outcome = OutcomeSuccessful
+	if actionErr != nil {
+		entry.Outcome = OutcomeFailed
+		entry.Error = actionErr.Error()
+	}
+
+	if err := l.append(entry); err != nil {
+		slog.Warn("Could not write audit log entry.", "path", l.Path, "err", err)
+	}
+	if l.Forward != nil {
+		if err := l.Forward(entry); err != nil {
+			slog.Warn("Could not forward audit log entry.", "err", err)
+		}
+	}
+}
+
+func (l *Logger) append(entry Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	if err := l.rotate(int64(len(b) + 1)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func (l *Logger) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", l.Path, i)
+}
+
+// Rotate the log if writing the given number of bytes would exceed the maximum size.
+// The oldest rotated log is only removed if the number of backups is limited
+func (l *Logger) rotate(size int64) error {
+	if l.MaxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || info.Size()+size <= l.MaxSize {
+		return nil
+	}
+
+	backups := l.MaxBackups
+	if backups <= 0 {
+		// Keep all of the rotated logs
+		backups = 1
+		for {
+			if _, err := os.Stat(l.backupPath(backups)); errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			backups++
+		}
+	} else if err := os.Remove(l.backupPath(backups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := backups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(l.Path, l.backupPath(1))
+}
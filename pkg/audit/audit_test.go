@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	logger := NewLogger(path, 0, 0)
+	forwarded := make([]Entry, 0)
+	logger.Forward = func(entry Entry) error {
+		forwarded = append(forwarded, entry)
+		return nil
+	}
+
+	logger.Record(Entry{Action: ActionInstall, Type: "container", Name: "app1", Images: []string{"nginx@sha256:abc"}}, nil)
+	logger.Record(Entry{Action: ActionRemove, Type: "container", Name: "app2"}, errors.New("not found"))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := Entry{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	assert.Len(t, entries, 2)
+	assert.Equal(t, OutcomeSuccessful, entries[0].Outcome)
+	assert.Equal(t, []string{"nginx@sha256:abc"}, entries[0].Images)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, OutcomeFailed, entries[1].Outcome)
+	assert.Equal(t, "not found", entries[1].Error)
+	assert.Len(t, forwarded, 2)
+}
+
+func Test_RecordNilLogger(t *testing.T) {
+	var logger *Logger
+	logger.Record(Entry{Action: ActionPrune}, nil)
+}
+
+func Test_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger := NewLogger(path, 200, 2)
+
+	for i := 0; i < 10; i++ {
+		logger.Record(Entry{Action: ActionInstall, Type: "container", Name: "app1"}, nil)
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		assert.NoError(t, err, p)
+		assert.LessOrEqual(t, info.Size(), int64(200), p)
+	}
+	assert.NoFileExists(t, path+".3")
+}
+
+func Test_RotateKeepsAllBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger := NewLogger(path, 200, 0)
+
+	for i := 0; i < 10; i++ {
+		logger.Record(Entry{Action: ActionInstall, Type: "container", Name: "app1", Version: strconv.Itoa(i)}, nil)
+	}
+
+	// No entries are lost
+	entries := 0
+	paths, _ := filepath.Glob(path + "*")
+	assert.Greater(t, len(paths), 3)
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		entries += strings.Count(string(b), "\n")
+	}
+	assert.Equal(t, 10, entries)
+
+	// The oldest entry is in the oldest backup
+	b, _ := os.ReadFile(fmt.Sprintf("%s.%d", path, len(paths)-1))
+	assert.Contains(t, string(b), `"version":"0"`)
+}
@@ -0,0 +1,170 @@
+package bridge
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Templates used to map the thin-edge.io topics to the topics of the external broker.
+// The following placeholders are supported: {{root}}, {{device}}, {{name}} and {{type}} (measurement type).
+// Messages are not mirrored if the template is empty
+type TopicTemplates struct {
+	Registration string
+	Health       string
+	Measurement  string
+}
+
+type Config struct {
+	Host     string
+	Port     uint16
+	ClientID string
+	Username string
+	Password string
+
+	// TLS
+	UseTLS   bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	Topics TopicTemplates
+}
+
+// Mirror the container state (registrations, health and measurements) to a secondary MQTT broker,
+// e.g. a local SCADA broker, which is not connected to thin-edge.io
+type Bridge struct {
+	Client mqtt.Client
+	Topics TopicTemplates
+}
+
+func newTLSConfig(config Config) (*tls.Config, error) {
+	rootCAs, _ := x509.SystemCertPool()
+	if rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if config.CAFile != "" {
+		pemCerts, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs.AppendCertsFromPEM(pemCerts)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs: rootCAs,
+	}
+	if config.CertFile != "" && config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func NewBridge(config Config) (*Bridge, error) {
+	opts := mqtt.NewClientOptions()
+	if config.UseTLS {
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		opts.AddBroker(fmt.Sprintf("ssl://%s:%d", config.Host, config.Port))
+		opts.SetTLSConfig(tlsConfig)
+	} else {
+		opts.AddBroker(fmt.Sprintf("tcp://%s:%d", config.Host, config.Port))
+	}
+	opts.SetClientID(config.ClientID)
+	opts.SetUsername(config.Username)
+	opts.SetPassword(config.Password)
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetKeepAlive(60 * time.Second)
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		slog.Info("Bridge MQTT Client is connected.", "host", config.Host, "port", config.Port)
+	})
+	opts.SetConnectionLostHandler(func(c mqtt.Client, err error) {
+		slog.Info("Bridge MQTT Client is disconnected.", "err", err)
+	})
+
+	return &Bridge{
+		Client: mqtt.NewClient(opts),
+		Topics: config.Topics,
+	}, nil
+}
+
+// Connect to the external broker. The connection is retried in the background if the broker is not reachable
+func (b *Bridge) Connect() error {
+	tok := b.Client.Connect()
+	if !tok.WaitTimeout(5 * time.Second) {
+		slog.Warn("Bridge MQTT Client could not connect yet, retrying in the background.")
+		return nil
+	}
+	return tok.Error()
+}
+
+func (b *Bridge) Disconnect() {
+	b.Client.Disconnect(250)
+}
+
+// Map a thin-edge.io topic, e.g. te/device/main/service/app1/status/health, to the external topic
+func (t TopicTemplates) Map(topic string) (string, bool) {
+	parts := strings.Split(topic, "/")
+	if len(parts) < 5 {
+		return "", false
+	}
+
+	template := ""
+	measurementType := ""
+	channel := parts[5:]
+	switch {
+	case len(channel) == 0:
+		template = t.Registration
+	case len(channel) == 2 && channel[0] == "status" && channel[1] == "health":
+		template = t.Health
+	case len(channel) <= 2 && channel[0] == "m":
+		template = t.Measurement
+		if len(channel) == 2 {
+			measurementType = channel[1]
+		}
+	}
+	if template == "" {
+		return "", false
+	}
+
+	replacer := strings.NewReplacer(
+		"{{root}}", parts[0],
+		"{{device}}", parts[2],
+		"{{name}}", parts[4],
+		"{{type}}", measurementType,
+	)
+	return strings.TrimRight(replacer.Replace(template), "/"), true
+}
+
+// Mirror a message which was published to thin-edge.io. Messages which don't match any of the topic templates are ignored
+func (b *Bridge) Mirror(topic string, retained bool, payload []byte) {
+	mapped, ok := b.Topics.Map(topic)
+	if !ok {
+		return
+	}
+	if !b.Client.IsConnectionOpen() {
+		slog.Debug("Bridge MQTT Client is not connected, so the message is not mirrored.", "topic", mapped)
+		return
+	}
+	tok := b.Client.Publish(mapped, 1, retained, payload)
+	if !tok.WaitTimeout(100 * time.Millisecond) {
+		slog.Warn("Timeout whilst mirroring message.", "topic", mapped)
+		return
+	}
+	if err := tok.Error(); err != nil {
+		slog.Warn("Could not mirror message.", "topic", mapped, "err", err)
+	}
+}
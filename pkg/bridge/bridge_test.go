@@ -0,0 +1,38 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TopicTemplatesMap(t *testing.T) {
+	templates := TopicTemplates{
+		Registration: "scada/{{device}}/{{name}}",
+		Health:       "scada/{{device}}/{{name}}/health",
+		Measurement:  "scada/{{device}}/{{name}}/metrics/{{type}}",
+	}
+
+	cases := []struct {
+		topic    string
+		expected string
+		ok       bool
+	}{
+		{"te/device/main/service/app1", "scada/main/app1", true},
+		{"te/device/main/service/app1/status/health", "scada/main/app1/health", true},
+		{"te/device/main/service/app1/m/resource_usage", "scada/main/app1/metrics/resource_usage", true},
+		{"te/device/main/service/app1/m/", "scada/main/app1/metrics", true},
+		{"te/device/main/service/app1/twin/container", "", false},
+		{"te/device/main/service/app1/e/container_audit", "", false},
+		{"te/device/main", "", false},
+	}
+	for _, c := range cases {
+		mapped, ok := templates.Map(c.topic)
+		assert.Equal(t, c.ok, ok, c.topic)
+		assert.Equal(t, c.expected, mapped, c.topic)
+	}
+
+	// Empty templates are not mirrored
+	_, ok := TopicTemplates{}.Map("te/device/main/service/app1")
+	assert.False(t, ok)
+}
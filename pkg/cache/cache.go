@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
+)
+
+// Extension of the file which stores the checksum of a cache entry
+const checksumExt = ".sha256"
+
+// Prefix of temporary directories which are not cache entries yet
+const tmpPrefix = ".tmp-"
+
+// The content does not match the expected checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Cache of downloaded artifacts (e.g. compose bundles or image tarballs).
+// Each entry is verified against its SHA256 checksum before being used, and the
+// least recently used entries are removed when the cache exceeds the maximum size
+type Cache struct {
+	Dir string
+
+	// Maximum size of the cache in bytes. 0 = unlimited
+	MaxSize int64
+}
+
+type Entry struct {
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+func NewCache(dir string, maxSize int64) *Cache {
+	return &Cache{
+		Dir:     dir,
+		MaxSize: maxSize,
+	}
+}
+
+// Path of the cache entry for the given key
+func (c *Cache) Path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Get the path to a cache entry. Entries which fail the integrity check are removed
+func (c *Cache) Get(key string) (string, bool) {
+	return c.GetWithChecksum(key, "")
+}
+
+// Get the path to a cache entry whose checksum must match the expected checksum (e.g. the checksum
+// of an artifact given by the module version). Entries which fail the integrity check are removed.
+// An empty expected checksum only checks the entry against the checksum recorded when it was added
+func (c *Cache) GetWithChecksum(key string, expected string) (string, bool) {
+	path := c.Path(key)
+	if !utils.PathExists(path) {
+		return "", false
+	}
+
+	recorded, err := os.ReadFile(path + checksumExt)
+	if err != nil {
+		slog.Warn("Cache entry does not have a checksum, so it will be removed.", "path", path)
+		c.remove(path)
+		return "", false
+	}
+	if expected == "" {
+		expected = strings.TrimSpace(string(recorded))
+	}
+	actual, err := Checksum(path)
+	if err != nil || actual != strings.TrimSpace(string(recorded)) || !strings.EqualFold(actual, expected) {
+		slog.Warn("Cache entry failed the integrity check, so it will be removed.", "path", path, "expected", expected, "actual", actual, "err", err)
+		c.remove(path)
+		return "", false
+	}
+
+	// Mark as recently used
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		slog.Warn("Could not update cache entry modification time.", "path", path, "err", err)
+	}
+	return path, true
+}
+
+// Create a temporary directory within the cache, so that content can be moved into
+// the cache once it is complete
+func (c *Cache) TempDir() (string, error) {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(c.Dir, tmpPrefix+"*")
+}
+
+// Add a file or directory to the cache, replacing any existing entry.
+// The source must be located in the cache directory (see TempDir)
+func (c *Cache) Put(key string, src string) (string, error) {
+	return c.PutWithChecksum(key, src, "")
+}
+
+// Add a file or directory to the cache if its checksum matches the expected checksum, e.g. the checksum
+// of an artifact given by the module version. The source is removed if the checksum does not match.
+// An empty expected checksum accepts any content
+func (c *Cache) PutWithChecksum(key string, src string, expected string) (string, error) {
+	path := c.Path(key)
+	checksum, err := Checksum(src)
+	if err != nil {
+		return "", err
+	}
+	if expected != "" && !strings.EqualFold(checksum, expected) {
+		c.remove(src)
+		return "", fmt.Errorf("%w. key=%s, expected=%s, actual=%s", ErrChecksumMismatch, key, expected, checksum)
+	}
+
+	c.remove(path)
+	if err := os.Rename(src, path); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path+checksumExt, []byte(checksum+"\n"), 0644); err != nil {
+		c.remove(path)
+		return "", err
+	}
+	slog.Info("Added cache entry.", "key", key, "path", path, "sha256", checksum)
+
+	if err := c.Evict(path); err != nil {
+		slog.Warn("Could not evict cache entries.", "err", err)
+	}
+	return path, nil
+}
+
+// List the cache entries, ordered from the least to the most recently used
+func (c *Cache) List() ([]Entry, error) {
+	items, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Entry{}, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		if strings.HasSuffix(item.Name(), checksumExt) || strings.HasPrefix(item.Name(), tmpPrefix) {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.Dir, item.Name())
+		entries = append(entries, Entry{
+			Path:     path,
+			Size:     diskUsage(path),
+			LastUsed: info.ModTime(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Remove the least recently used entries until the cache is within the maximum size.
+// The given paths are never removed (e.g. an entry which is about to be used)
+func (c *Cache) Evict(keep ...string) error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	total := int64(0)
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	for _, entry := range entries {
+		if total <= c.MaxSize {
+			break
+		}
+		if isKept(entry.Path, keep) {
+			continue
+		}
+		slog.Info("Evicting cache entry.", "path", entry.Path, "size", entry.Size)
+		c.remove(entry.Path)
+		total -= entry.Size
+	}
+	return nil
+}
+
+// Remove all cache entries and return the number of bytes which were reclaimed
+func (c *Cache) Purge() (int64, error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+	reclaimed := int64(0)
+	for _, entry := range entries {
+		c.remove(entry.Path)
+		reclaimed += entry.Size
+	}
+	return reclaimed, nil
+}
+
+func (c *Cache) remove(path string) {
+	if err := os.RemoveAll(path); err != nil {
+		slog.Warn("Could not remove cache entry.", "path", path, "err", err)
+	}
+	_ = os.Remove(path + checksumExt)
+}
+
+func isKept(path string, keep []string) bool {
+	for _, p := range keep {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func diskUsage(path string) int64 {
+	size := int64(0)
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// Calculate the SHA256 checksum of a file, or of all files (including their relative paths) in a directory.
+// The checksum of a file is the plain SHA256 of its content, so it can be compared with the checksum of an artifact
+func Checksum(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if info.Mode().IsRegular() {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(h, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func putFile(t *testing.T, c *Cache, key string, contents string) string {
+	dir, err := c.TempDir()
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(contents), 0644))
+	path, err := c.Put(key, dir)
+	assert.NoError(t, err)
+	return path
+}
+
+func Test_CacheIntegrity(t *testing.T) {
+	c := NewCache(t.TempDir(), 0)
+	path := putFile(t, c, "app", "services: {}")
+
+	got, ok := c.Get("app")
+	assert.True(t, ok)
+	assert.Equal(t, path, got)
+
+	// Modified entries are removed
+	assert.NoError(t, os.WriteFile(filepath.Join(path, "docker-compose.yaml"), []byte("services: {modified}"), 0644))
+	_, ok = c.Get("app")
+	assert.False(t, ok)
+	assert.NoDirExists(t, path)
+}
+
+func Test_CacheEvictLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(t.TempDir(), 25)
+	first := putFile(t, c, "first", "0123456789")
+	second := putFile(t, c, "second", "0123456789")
+	old := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(first, old, old))
+	assert.NoError(t, os.Chtimes(second, old.Add(time.Minute), old.Add(time.Minute)))
+
+	// Using an entry marks it as recently used
+	_, ok := c.Get("first")
+	assert.True(t, ok)
+
+	putFile(t, c, "third", "0123456789")
+	_, ok = c.Get("second")
+	assert.False(t, ok)
+	_, ok = c.Get("first")
+	assert.True(t, ok)
+	_, ok = c.Get("third")
+	assert.True(t, ok)
+
+	reclaimed, err := c.Purge()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), reclaimed)
+	entries, err := c.List()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func Test_CacheExpectedChecksum(t *testing.T) {
+	c := NewCache(t.TempDir(), 0)
+	hash := sha256.Sum256([]byte("image tarball"))
+	expected := hex.EncodeToString(hash[:])
+
+	// Tampered content is not added to the cache
+	dir, err := c.TempDir()
+	assert.NoError(t, err)
+	tampered := filepath.Join(dir, "app.tar")
+	assert.NoError(t, os.WriteFile(tampered, []byte("tampered tarball"), 0644))
+	_, err = c.PutWithChecksum("app", tampered, expected)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, tampered)
+	_, ok := c.Get("app")
+	assert.False(t, ok)
+
+	file := filepath.Join(dir, "app.tar")
+	assert.NoError(t, os.WriteFile(file, []byte("image tarball"), 0644))
+	path, err := c.PutWithChecksum("app", file, expected)
+	assert.NoError(t, err)
+	got, ok := c.GetWithChecksum("app", expected)
+	assert.True(t, ok)
+	assert.Equal(t, path, got)
+
+	// Entries whose content and recorded checksum were both modified are removed
+	assert.NoError(t, os.WriteFile(path, []byte("tampered tarball"), 0644))
+	checksum, err := Checksum(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path+checksumExt, []byte(checksum+"\n"), 0644))
+	_, ok = c.GetWithChecksum("app", expected)
+	assert.False(t, ok)
+	assert.NoFileExists(t, path)
+}
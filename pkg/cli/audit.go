@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Default initiator of the actions when called as a software management plugin
+var InitiatorSoftwareManagement = "software-management"
+
+func (c *Cli) GetAuditLogPath() string {
+	if path := viper.GetString("monitor.audit.path"); path != "" {
+		return path
+	}
+	return filepath.Join(c.GetStateDir(), "audit.log")
+}
+
+// ID of the operation which triggered the action (if provided), e.g. via the CONTAINER_OPERATION_ID env variable
+func (c *Cli) GetOperationID() string {
+	return viper.GetString("operation_id")
+}
+
+// Who/what triggered the action, e.g. via the CONTAINER_INITIATOR env variable
+func (c *Cli) GetInitiator() string {
+	if initiator := viper.GetString("initiator"); initiator != "" {
+		return initiator
+	}
+	return "cli"
+}
+
+// Get the audit logger. Returns nil if auditing is disabled
+func (c *Cli) GetAuditLogger() *audit.Logger {
+	if !viper.GetBool("monitor.audit.enabled") {
+		return nil
+	}
+	maxSize, err := units.FromHumanSize(viper.GetString("monitor.audit.max_size"))
+	if err != nil {
+		slog.Warn("Invalid audit log max size, so the audit log will not be rotated.", "value", viper.GetString("monitor.audit.max_size"), "err", err)
+		maxSize = 0
+	}
+	logger := audit.NewLogger(c.GetAuditLogPath(), maxSize, viper.GetInt("monitor.audit.max_backups"))
+	if viper.GetBool("monitor.audit.events") {
+		logger.Forward = func(entry audit.Entry) error {
+			b, err := json.Marshal(tedge.NewClock(c.GetTimeMode()).SetTime(entry.Event()))
+			if err != nil {
+				return err
+			}
+			topic := tedge.GetTopic(c.GetDeviceTarget(), "e", audit.EventType)
+			return c.Publish("audit#"+entry.Name, topic, false, b)
+		}
+	}
+	return logger
+}
+
+// Record a mutating action in the audit log
+func (c *Cli) Audit(entry audit.Entry, err error) {
+	if entry.OperationID == "" {
+		entry.OperationID = c.GetOperationID()
+	}
+	if entry.Initiator == "" {
+		entry.Initiator = c.GetInitiator()
+	}
+	c.GetAuditLogger().Record(entry, err)
+}
@@ -1,15 +1,31 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/app"
+	"github.com/thin-edge/tedge-container-plugin/pkg/archive"
+	"github.com/thin-edge/tedge-container-plugin/pkg/bridge"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cache"
 	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/enrich"
+	"github.com/thin-edge/tedge-container-plugin/pkg/exporter"
+	"github.com/thin-edge/tedge-container-plugin/pkg/firewall"
+	"github.com/thin-edge/tedge-container-plugin/pkg/history"
+	"github.com/thin-edge/tedge-container-plugin/pkg/lease"
+	"github.com/thin-edge/tedge-container-plugin/pkg/signature"
 	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
 	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
 )
@@ -20,12 +36,53 @@ type SilentError error
 
 type Cli struct {
 	ConfigFile string
+
+	// Build information of the binary
+	BuildVersion string
+	BuildBranch  string
 }
 
 func (c *Cli) OnInit() {
 
 	// Set shared config
+	viper.SetDefault("filter.limit", 5000)
+	viper.SetDefault("client.c8y.host", "127.0.0.1")
+	viper.SetDefault("client.c8y.port", 8001)
 	viper.SetDefault("container.network", "tedge")
+	viper.SetDefault("container.network_options.ipv6", false)
+	viper.SetDefault("container.network_options.subnets", []string{})
+	viper.SetDefault("container.network_options.gateways", []string{})
+	viper.SetDefault("container.network_options.mtu", 0)
+	viper.SetDefault("container.artifacts.file_dir", "")
+	viper.SetDefault("container.artifacts.require_checksum", false)
+	viper.SetDefault("container.pull_failure_alarm", true)
+	viper.SetDefault("container.pull_failure_threshold", 3)
+	viper.SetDefault("container.firewall.enabled", false)
+	viper.SetDefault("container.firewall.backend", string(firewall.BackendIPTables))
+	viper.SetDefault("container.firewall.table", "")
+	viper.SetDefault("container.firewall.chain", "")
+	viper.SetDefault("container.firewall.ipv6", true)
+	viper.SetDefault("container.checkpoint.dir", "")
+	viper.SetDefault("container.protected.names", []string{})
+	viper.SetDefault("container.protected.labels", []string{container.DefaultProtectedLabel})
+	viper.SetDefault("monitor.cache.max_size", "1GB")
+	viper.SetDefault("monitor.install.max_bandwidth", "0")
+	viper.SetDefault("monitor.install.maintenance_window.schedule", "")
+	viper.SetDefault("monitor.install.maintenance_window.duration", "1h")
+	viper.SetDefault("monitor.read_only", false)
+	viper.SetDefault("monitor.history.enabled", true)
+	viper.SetDefault("monitor.history.size", history.DefaultSize)
+	viper.SetDefault("monitor.history.path", "")
+	viper.SetDefault("monitor.archive.enabled", true)
+	viper.SetDefault("monitor.archive.retention", "168h")
+	viper.SetDefault("monitor.archive.path", "")
+	viper.SetDefault("monitor.archive.publish", false)
+	viper.SetDefault("monitor.audit.enabled", true)
+	viper.SetDefault("monitor.audit.path", "")
+	viper.SetDefault("monitor.audit.events", false)
+	viper.SetDefault("monitor.audit.max_size", "1MB")
+	viper.SetDefault("monitor.audit.max_backups", 0)
+	viper.SetDefault("operation_id", "")
 
 	if c.ConfigFile != "" && utils.PathExists(c.ConfigFile) {
 		// Use config file from the flag.
@@ -47,13 +104,35 @@ func (c *Cli) OnInit() {
 		}
 	}
 
-	viper.SetEnvPrefix("CONTAINER")
+	viper.SetEnvPrefix(EnvPrefix)
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	if err := viper.ReadInConfig(); err == nil {
 		slog.Info("Using config file", "path", viper.ConfigFileUsed())
 	}
+
+	if err := applyProfile(); err != nil {
+		slog.Error("Could not apply configuration profile.", "err", err)
+	}
+	applyRenamedKeys()
+
+	c.applyProxySettings()
+}
+
+// Apply the proxy settings from the configuration to the process environment,
+// as the settings are not always available in the environment which the process is started from
+func (c *Cli) applyProxySettings() {
+	httpProxy := viper.GetString("proxy.http")
+	httpsProxy := viper.GetString("proxy.https")
+	noProxy := viper.GetString("proxy.no_proxy")
+	if httpProxy == "" && httpsProxy == "" && noProxy == "" {
+		return
+	}
+	slog.Info("Using proxy settings from configuration.", "http", utils.RedactURL(httpProxy), "https", utils.RedactURL(httpsProxy), "no_proxy", noProxy)
+	if err := utils.SetProxyEnv(httpProxy, httpsProxy, noProxy); err != nil {
+		slog.Warn("Could not set proxy environment variables.", "err", err)
+	}
 }
 
 func (c *Cli) GetString(key string) string {
@@ -68,7 +147,11 @@ func (c *Cli) PrintConfig() {
 	keys := viper.AllKeys()
 	sort.Strings(keys)
 	for _, key := range keys {
-		slog.Info("setting", "item", fmt.Sprintf("%s=%v", key, viper.Get(key)))
+		value := viper.Get(key)
+		if key == "proxy.http" || key == "proxy.https" {
+			value = utils.RedactURL(viper.GetString(key))
+		}
+		slog.Info("setting", "item", fmt.Sprintf("%s=%v", key, value))
 	}
 }
 
@@ -92,8 +175,40 @@ func (c *Cli) GetTopicRoot() string {
 	return viper.GetString("topic_root")
 }
 
+// Get all topic roots which the container state is published under. The primary topic root is always first
+func (c *Cli) GetTopicRoots() []string {
+	roots := []string{c.GetTopicRoot()}
+	for _, root := range getExpandedStringSlice("topic_roots") {
+		if root = strings.TrimSpace(root); root != "" && !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
 func (c *Cli) GetTopicID() string {
-	return viper.GetString("topic_id")
+	topicID := viper.GetString("topic_id")
+	if normalized, err := tedge.NormalizeTopicID(topicID); err == nil {
+		return normalized
+	}
+	return topicID
+}
+
+// Get the template of the topic identifiers of the device's services, e.g. {0}/{1}/service/{name}
+func (c *Cli) GetServiceTopicTemplate() string {
+	return viper.GetString("topic_service_id")
+}
+
+// Check that the device's topic identifier and the service template are valid
+func (c *Cli) CheckTopicID() error {
+	topicID := viper.GetString("topic_id")
+	if _, err := tedge.NormalizeTopicID(topicID); err != nil {
+		return NewExitCodeError(ExitCodeUsage, err)
+	}
+	if err := tedge.ValidateServiceTemplate(c.GetServiceTopicTemplate(), c.GetTopicID()); err != nil {
+		return NewExitCodeError(ExitCodeUsage, err)
+	}
+	return nil
 }
 
 func (c *Cli) GetDeviceID() string {
@@ -108,10 +223,561 @@ func (c *Cli) EngineEventsEnabled() bool {
 	return viper.GetBool("events.enabled")
 }
 
+func (c *Cli) ChangeEventsEnabled() bool {
+	return viper.GetBool("events.changes")
+}
+
+// Check if the container's IP address and published ports should be included in the health messages
+func (c *Cli) HealthNetworkInfoEnabled() bool {
+	return viper.GetBool("health.network")
+}
+
+// Check if marker fragments should be added to the Cumulocity managed objects of the container services
+func (c *Cli) CloudTagsEnabled() bool {
+	return viper.GetBool("monitor.cloud_tags.enabled")
+}
+
+func (c *Cli) GetCloudTagMarker() string {
+	return viper.GetString("monitor.cloud_tags.marker")
+}
+
+// Check if the provenance of the containers' images should be published to the twin
+func (c *Cli) ProvenanceEnabled() bool {
+	return viper.GetBool("monitor.provenance.enabled")
+}
+
+// Get the number of workers which process targeted update requests (e.g. triggered by container events)
+// concurrently. Full updates are always processed one at a time
+func (c *Cli) GetUpdateWorkers() int {
+	workers := viper.GetInt("monitor.update.workers")
+	if workers < 1 {
+		slog.Warn("monitor.update.workers is lower than allowed limit.", "old", workers, "new", 1)
+		workers = 1
+	}
+	return workers
+}
+
+// Get the maximum duration to wait for the background tasks to stop when shutting down
+func (c *Cli) GetShutdownTimeout() time.Duration {
+	timeout := viper.GetDuration("monitor.shutdown.timeout")
+	if timeout <= 0 {
+		timeout = 20 * time.Second
+	}
+	return timeout
+}
+
+// Check if the container counts should be published to the device's twin
+func (c *Cli) SummaryEnabled() bool {
+	return viper.GetBool("monitor.summary.enabled")
+}
+
+// Check if the inventory of the networks and volumes should be published to the device's twin
+func (c *Cli) InventoryEnabled() bool {
+	return viper.GetBool("monitor.inventory.enabled")
+}
+
+func (c *Cli) GetInventoryInterval() time.Duration {
+	interval := viper.GetDuration("monitor.inventory.interval")
+	if interval < time.Minute {
+		slog.Warn("monitor.inventory.interval is lower than allowed limit.", "old", interval, "new", time.Minute)
+		interval = time.Minute
+	}
+	return interval
+}
+
+// Check if the image layer report should be published to the device's twin
+func (c *Cli) LayerReportEnabled() bool {
+	return viper.GetBool("monitor.layers.enabled")
+}
+
+func (c *Cli) GetLayerReportInterval() time.Duration {
+	interval := viper.GetDuration("monitor.layers.interval")
+	if interval < time.Minute {
+		slog.Warn("monitor.layers.interval is lower than allowed limit.", "old", interval, "new", time.Minute)
+		interval = time.Minute
+	}
+	return interval
+}
+
+// Check if the readiness probes of the containers should be run
+func (c *Cli) ProbesEnabled() bool {
+	return viper.GetBool("monitor.probes.enabled")
+}
+
+// Get the readiness probes of the containers which don't define the probe via a label
+func (c *Cli) GetProbeRules() []app.ProbeRule {
+	rules := make([]app.ProbeRule, 0)
+	if err := unmarshalKey("monitor.probes.rules", &rules); err != nil {
+		slog.Warn("Invalid container probe rules.", "err", err)
+		return nil
+	}
+	return rules
+}
+
+func (c *Cli) GetProbeTimeout() time.Duration {
+	timeout := viper.GetDuration("monitor.probes.timeout")
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return timeout
+}
+
+// Check if the container_restart command is supported
+func (c *Cli) RestartEnabled() bool {
+	return viper.GetBool("monitor.restart.enabled")
+}
+
+// Check if containers should be started and stopped according to their schedule
+func (c *Cli) SchedulerEnabled() bool {
+	return viper.GetBool("monitor.scheduler.enabled")
+}
+
+// Get the start and stop schedules of the containers which don't define the schedule via labels
+func (c *Cli) GetScheduleRules() []app.ScheduleRule {
+	rules := make([]app.ScheduleRule, 0)
+	if err := unmarshalKey("monitor.scheduler.rules", &rules); err != nil {
+		slog.Warn("Invalid container schedule rules.", "err", err)
+		return nil
+	}
+	return rules
+}
+
 func (c *Cli) DeleteFromCloud() bool {
 	return viper.GetBool("delete_from_cloud.enabled")
 }
 
+func (c *Cli) EngineServiceEnabled() bool {
+	return viper.GetBool("monitor.engine.enabled")
+}
+
+func (c *Cli) GetEngineServiceName() string {
+	return viper.GetString("monitor.engine.service_name")
+}
+
+func (c *Cli) GetEngineCheckInterval() time.Duration {
+	interval := viper.GetDuration("monitor.engine.interval")
+	if interval < 10*time.Second {
+		slog.Warn("monitor.engine.interval is lower than allowed limit.", "old", interval, "new", 10*time.Second)
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+func (c *Cli) ProfilesEnabled() bool {
+	return viper.GetBool("monitor.profiles.enabled")
+}
+
+func (c *Cli) GetProfilesDir() string {
+	return viper.GetString("monitor.profiles.dir")
+}
+
+func (c *Cli) HomeAssistantEnabled() bool {
+	return viper.GetBool("homeassistant.enabled")
+}
+
+func (c *Cli) GetHomeAssistantPrefix() string {
+	return viper.GetString("homeassistant.discovery_prefix")
+}
+
+// Check if the container lifecycle events should be forwarded to local consumers
+func (c *Cli) EventSocketEnabled() bool {
+	return viper.GetBool("monitor.event_socket.enabled")
+}
+
+func (c *Cli) GetEventSocketPath() string {
+	return viper.GetString("monitor.event_socket.path")
+}
+
+// Get the type of the event socket, either socket (unix socket) or fifo (named pipe)
+func (c *Cli) GetEventSocketType() string {
+	return viper.GetString("monitor.event_socket.type")
+}
+
+func (c *Cli) GetEventSocketBuffer() int {
+	return viper.GetInt("monitor.event_socket.buffer")
+}
+
+func (c *Cli) ModbusEnabled() bool {
+	return viper.GetBool("monitor.modbus.enabled")
+}
+
+func (c *Cli) GetModbusAddress() string {
+	return viper.GetString("monitor.modbus.address")
+}
+
+func (c *Cli) MDNSEnabled() bool {
+	return viper.GetBool("monitor.mdns.enabled")
+}
+
+func (c *Cli) GetMDNSServicesDir() string {
+	return viper.GetString("monitor.mdns.services_dir")
+}
+
+func (c *Cli) ProjectHealthEnabled() bool {
+	return viper.GetBool("monitor.compose.health.enabled")
+}
+
+func (c *Cli) GetProjectHealthOptions() container.ProjectHealthOptions {
+	return container.ProjectHealthOptions{
+		Policy:        container.HealthPolicy(viper.GetString("monitor.compose.health.policy")),
+		Quorum:        viper.GetFloat64("monitor.compose.health.quorum"),
+		CriticalLabel: viper.GetString("monitor.compose.health.critical_label"),
+	}
+}
+
+// Check if the aggregated metrics of each compose project should be published on the project's service
+func (c *Cli) ProjectMetricsEnabled() bool {
+	return viper.GetBool("monitor.compose.metrics.enabled")
+}
+
+// Check if changes of the compose projects' images should be published and refresh the software list
+func (c *Cli) ProjectVersionChangesEnabled() bool {
+	return viper.GetBool("monitor.compose.version_changes.enabled")
+}
+
+func (c *Cli) GetProjectVersionQuietPeriod() time.Duration {
+	return viper.GetDuration("monitor.compose.version_changes.quiet_period")
+}
+
+// Get how the container twin is published, either as a single fragment or as separate topics per field
+func (c *Cli) GetTwinMode() string {
+	return viper.GetString("monitor.twin.mode")
+}
+
+func (c *Cli) GetComposeGroupMode() string {
+	return viper.GetString("monitor.compose.group_mode")
+}
+
+func (c *Cli) GetDeleteGracePeriod() time.Duration {
+	return viper.GetDuration("delete_from_cloud.grace_period")
+}
+
+func (c *Cli) GetDeleteConcurrency() int {
+	return viper.GetInt("delete_from_cloud.concurrency")
+}
+
+func (c *Cli) GetDeleteRetries() int {
+	return viper.GetInt("delete_from_cloud.retries")
+}
+
+func (c *Cli) GetDeleteRetention() time.Duration {
+	return viper.GetDuration("delete_from_cloud.retention")
+}
+
+func (c *Cli) GetStateDir() string {
+	return viper.GetString("state_dir")
+}
+
+// Get the tls settings of the private registries (monitor.registries.<host>.*) by registry host.
+// The host can contain dots (which viper treats as nested keys), so the settings are grouped by their field name
+func (c *Cli) GetRegistries() map[string]container.RegistryTLS {
+	registries := make(map[string]container.RegistryTLS)
+	if _, ok := viper.Get("monitor.registries").(string); ok {
+		// json value set via the environment
+		if err := unmarshalKey("monitor.registries", &registries); err != nil {
+			slog.Warn("Invalid registries configuration.", "err", err)
+			return nil
+		}
+		return registries
+	}
+
+	prefix := "monitor.registries."
+	hosts := make(map[string]*viper.Viper)
+	for _, key := range viper.AllKeys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		host, field, ok := cutLast(strings.TrimPrefix(key, prefix), ".")
+		if !ok {
+			continue
+		}
+		if _, exists := hosts[host]; !exists {
+			hosts[host] = viper.New()
+		}
+		hosts[host].Set(field, viper.Get(key))
+	}
+	for host, values := range hosts {
+		cfg := container.RegistryTLS{}
+		if err := values.Unmarshal(&cfg); err != nil {
+			slog.Warn("Invalid registry configuration.", "registry", host, "err", err)
+			continue
+		}
+		registries[host] = cfg
+	}
+	return registries
+}
+
+// Get the mirror of each upstream registry (monitor.mirrors.<host>), e.g. docker.io => harbor.local/dockerhub
+func (c *Cli) GetMirrors() map[string]string {
+	mirrors := make(map[string]string)
+	if _, ok := viper.Get("monitor.mirrors").(string); ok {
+		// json value set via the environment
+		if err := unmarshalKey("monitor.mirrors", &mirrors); err != nil {
+			slog.Warn("Invalid mirrors configuration.", "err", err)
+			return nil
+		}
+		return mirrors
+	}
+
+	// The host can contain dots, so it is the remainder of the key
+	prefix := "monitor.mirrors."
+	for _, key := range viper.AllKeys() {
+		if strings.HasPrefix(key, prefix) {
+			if mirror := viper.GetString(key); mirror != "" {
+				mirrors[strings.TrimPrefix(key, prefix)] = mirror
+			}
+		}
+	}
+	return mirrors
+}
+
+// Split a string at the last occurrence of the separator
+func cutLast(s string, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Get the maximum bandwidth (bytes per second) used to download artifacts (e.g. image tarballs). 0 = unlimited
+func (c *Cli) GetMaxDownloadBandwidth() int64 {
+	value := viper.GetString("monitor.install.max_bandwidth")
+	if value == "" || value == "0" {
+		return 0
+	}
+	maxBandwidth, err := units.FromHumanSize(value)
+	if err != nil {
+		slog.Warn("Invalid max bandwidth, so the bandwidth will not be limited.", "value", value, "err", err)
+		return 0
+	}
+	return maxBandwidth
+}
+
+// Get the consecutive pull failures of each image, which are persisted in the state directory
+func (c *Cli) GetPullFailures() *container.PullFailures {
+	return container.NewPullFailures(filepath.Join(c.GetStateDir(), "pull_failures.json"))
+}
+
+// Get the number of consecutive pull failures of an image after which an alarm is raised. 0 = disabled
+func (c *Cli) GetPullFailureThreshold() int {
+	if !viper.GetBool("container.pull_failure_alarm") {
+		return 0
+	}
+	return max(viper.GetInt("container.pull_failure_threshold"), 1)
+}
+
+// Get the path of the file which stores the history of the container state transitions
+func (c *Cli) GetHistoryPath() string {
+	if path := viper.GetString("monitor.history.path"); path != "" {
+		return path
+	}
+	return filepath.Join(c.GetStateDir(), "history.json")
+}
+
+// Get the history of the container state transitions. Returns nil if it is disabled
+func (c *Cli) GetHistory() (*history.History, error) {
+	if !viper.GetBool("monitor.history.enabled") {
+		return nil, nil
+	}
+	return history.Load(c.GetHistoryPath(), viper.GetInt("monitor.history.size"))
+}
+
+func (c *Cli) GetArchivePath() string {
+	if path := viper.GetString("monitor.archive.path"); path != "" {
+		return path
+	}
+	return filepath.Join(c.GetStateDir(), "archive.json")
+}
+
+// Get the archive of the removed containers. Returns nil if it is disabled
+func (c *Cli) GetArchive() (*archive.Archive, error) {
+	if !viper.GetBool("monitor.archive.enabled") {
+		return nil, nil
+	}
+	return archive.Load(c.GetArchivePath(), viper.GetDuration("monitor.archive.retention"))
+}
+
+// Check if the archive records should be published as retained messages
+func (c *Cli) PublishArchive() bool {
+	return viper.GetBool("monitor.archive.publish")
+}
+
+// Get the additional backends (e.g. statsd or influx) which the container metrics are sent to
+func (c *Cli) GetMetricsExporters() []exporter.Exporter {
+	configs := make([]exporter.Config, 0)
+	if err := unmarshalKey("monitor.metrics.exporters", &configs); err != nil {
+		slog.Warn("Invalid metrics exporters configuration.", "err", err)
+		return nil
+	}
+	return exporter.NewExporters(configs)
+}
+
+// Get the enrichers which compute additional twin fields of each container. nil = disabled
+func (c *Cli) GetEnrichers() []enrich.Enricher {
+	if !viper.GetBool("monitor.enrich.enabled") {
+		return nil
+	}
+	enrichers, err := enrich.Load(viper.GetString("monitor.enrich.dir"))
+	if err != nil {
+		slog.Warn("Could not load enrichers.", "dir", viper.GetString("monitor.enrich.dir"), "err", err)
+		return nil
+	}
+	return enrichers
+}
+
+func (c *Cli) GetEnrichInterval() time.Duration {
+	return viper.GetDuration("monitor.enrich.interval")
+}
+
+func (c *Cli) GetEnrichTimeout() time.Duration {
+	timeout := viper.GetDuration("monitor.enrich.timeout")
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return timeout
+}
+
+// Get the deadband of each measurement series (e.g. memory). Series without a deadband are always published
+func (c *Cli) GetMetricsDeadband() map[string]app.Deadband {
+	deadbands := make(map[string]app.Deadband)
+	if err := unmarshalKey("metrics.deadband", &deadbands); err != nil {
+		slog.Warn("Invalid metrics deadband configuration.", "err", err)
+		return nil
+	}
+	return deadbands
+}
+
+// Get the cache of downloaded artifacts (compose bundles and image tarballs)
+func (c *Cli) GetCache() *cache.Cache {
+	maxSize, err := units.FromHumanSize(viper.GetString("monitor.cache.max_size"))
+	if err != nil {
+		slog.Warn("Invalid cache max size, so the cache size will not be limited.", "value", viper.GetString("monitor.cache.max_size"), "err", err)
+		maxSize = 0
+	}
+	return cache.NewCache(filepath.Join(c.GetStateDir(), "cache"), maxSize)
+}
+
+// Get the patterns (regular expressions) of the services which are excluded from the stale cleanup
+func (c *Cli) GetCleanupExclude() []string {
+	patterns := make([]string, 0)
+	for _, pattern := range viper.GetStringSlice("monitor.cleanup.exclude") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			slog.Warn("Ignoring invalid cleanup exclude pattern.", "pattern", pattern, "err", err)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// Check if the stale cleanup should only log the services which would be removed
+func (c *Cli) CleanupDryRun() bool {
+	return viper.GetBool("monitor.cleanup.dry_run")
+}
+
+func (c *Cli) GetStaleMaxRemovalRatio() float64 {
+	return viper.GetFloat64("monitor.stale.max_removal_ratio")
+}
+
+func (c *Cli) GetStaleConfirmDelay() time.Duration {
+	return viper.GetDuration("monitor.stale.confirm_delay")
+}
+
+func (c *Cli) StatusFileEnabled() bool {
+	return viper.GetBool("monitor.status.enabled")
+}
+
+// Path of the machine-readable status file which is written by the run command
+func (c *Cli) GetStatusFilePath() string {
+	return viper.GetString("monitor.status.path")
+}
+
+func (c *Cli) GetStatusFileInterval() time.Duration {
+	interval := viper.GetDuration("monitor.status.interval")
+	if interval < time.Second {
+		slog.Warn("monitor.status.interval is lower than allowed limit.", "old", interval, "new", time.Second)
+		interval = time.Second
+	}
+	return interval
+}
+
+// Duration which a service status change has to persist for before it is published. 0 = disabled
+func (c *Cli) GetStatusDebounce() time.Duration {
+	return max(viper.GetDuration("monitor.status.debounce"), 0)
+}
+
+// Status of the containers which are created or restarting. Empty = the containers are reported as down
+func (c *Cli) GetStartingStatus() string {
+	return viper.GetString("monitor.status.starting")
+}
+
+// Duration after which a container which is still starting is reported as down
+func (c *Cli) GetStartingGracePeriod() time.Duration {
+	return max(viper.GetDuration("monitor.status.starting_grace_period"), 0)
+}
+
+// Get the maximum number of starts of a container within the restart budget window. 0 = disabled
+func (c *Cli) GetRestartBudgetMax() int {
+	if !viper.GetBool("monitor.restart_budget.enabled") {
+		return 0
+	}
+	return viper.GetInt("monitor.restart_budget.max_starts")
+}
+
+func (c *Cli) GetRestartBudgetWindow() time.Duration {
+	return viper.GetDuration("monitor.restart_budget.window")
+}
+
+// Get the number of services which are registered at once when bootstrapping the existing containers. 0 = disabled
+func (c *Cli) GetBootstrapChunkSize() int {
+	if !viper.GetBool("monitor.bootstrap.enabled") {
+		return 0
+	}
+	return viper.GetInt("monitor.bootstrap.chunk_size")
+}
+
+func (c *Cli) GetBootstrapInterval() time.Duration {
+	return viper.GetDuration("monitor.bootstrap.interval")
+}
+
+// Check if the metrics collection should be skipped while bootstrapping
+func (c *Cli) BootstrapDeferMetrics() bool {
+	return viper.GetBool("monitor.bootstrap.defer_metrics")
+}
+
+func (c *Cli) GetBootstrapInitialStatus() string {
+	return viper.GetString("monitor.bootstrap.initial_status")
+}
+
+// Check if the heartbeat (time since the last successful full update) should be published
+func (c *Cli) HeartbeatEnabled() bool {
+	return viper.GetBool("monitor.heartbeat.enabled")
+}
+
+func (c *Cli) GetHeartbeatInterval() time.Duration {
+	interval := viper.GetDuration("monitor.heartbeat.interval")
+	if interval < 10*time.Second {
+		slog.Warn("monitor.heartbeat.interval is lower than allowed limit.", "old", interval, "new", 10*time.Second)
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+// Check if the run command should wait for the given dependency (broker, engine or device) before starting
+func (c *Cli) StartupWaitEnabled(name string) bool {
+	return viper.GetBool("startup." + name + ".enabled")
+}
+
+// Maximum duration to wait for the given dependency. 0 = wait forever
+func (c *Cli) GetStartupWaitTimeout(name string) time.Duration {
+	return viper.GetDuration("startup." + name + ".timeout")
+}
+
+// Service which is registered by thin-edge.io once the main device is available
+func (c *Cli) GetStartupDeviceService() string {
+	return viper.GetString("startup.device.service")
+}
+
 func (c *Cli) GetMQTTHost() string {
 	return viper.GetString("client.mqtt.host")
 }
@@ -120,6 +786,76 @@ func (c *Cli) GetSharedContainerNetwork() string {
 	return viper.GetString("container.network")
 }
 
+// Check if the checkpoint and restore commands (experimental) are enabled
+func (c *Cli) CheckpointsEnabled() bool {
+	return viper.GetBool("monitor.checkpoint.enabled")
+}
+
+// Custom directory used to store container checkpoints. Empty = use the container engine's default
+func (c *Cli) GetCheckpointDir() string {
+	return viper.GetString("container.checkpoint.dir")
+}
+
+func (c *Cli) GetSharedNetworkOptions() container.NetworkOptions {
+	return container.NetworkOptions{
+		EnableIPv6: viper.GetBool("container.network_options.ipv6"),
+		Subnets:    getExpandedStringSlice("container.network_options.subnets"),
+		Gateways:   getExpandedStringSlice("container.network_options.gateways"),
+		MTU:        viper.GetInt("container.network_options.mtu"),
+	}
+}
+
+// Get the bridge which mirrors the container state to a secondary broker. Returns nil if it is disabled
+func (c *Cli) GetBridge() (*bridge.Bridge, error) {
+	if !viper.GetBool("bridge.enabled") {
+		return nil, nil
+	}
+	clientID := viper.GetString("bridge.client_id")
+	if clientID == "" {
+		clientID = c.GetServiceName() + "#bridge"
+	}
+	return bridge.NewBridge(bridge.Config{
+		Host:     viper.GetString("bridge.host"),
+		Port:     uint16(viper.GetInt("bridge.port")),
+		ClientID: clientID,
+		Username: viper.GetString("bridge.username"),
+		Password: viper.GetString("bridge.password"),
+		UseTLS:   viper.GetBool("bridge.tls.enabled"),
+		CAFile:   viper.GetString("bridge.tls.ca_file"),
+		CertFile: viper.GetString("bridge.tls.cert_file"),
+		KeyFile:  viper.GetString("bridge.tls.key_file"),
+		Topics: bridge.TopicTemplates{
+			Registration: viper.GetString("bridge.topics.registration"),
+			Health:       viper.GetString("bridge.topics.health"),
+			Measurement:  viper.GetString("bridge.topics.measurement"),
+		},
+	})
+}
+
+// Get the verifier used to check the signature of remote commands. Returns nil if verification is disabled
+func (c *Cli) GetCommandVerifier() (*signature.Verifier, error) {
+	if !viper.GetBool("monitor.commands.verify.enabled") {
+		return nil, nil
+	}
+	return signature.NewVerifier(
+		viper.GetString("monitor.commands.verify.hmac_key_file"),
+		viper.GetString("monitor.commands.verify.public_key_file"),
+	)
+}
+
+// Get the firewall which manages the rules for the published ports. Returns nil if it is disabled
+func (c *Cli) GetFirewall() (*firewall.Firewall, error) {
+	if !viper.GetBool("container.firewall.enabled") {
+		return nil, nil
+	}
+	return firewall.NewFirewall(
+		firewall.Backend(viper.GetString("container.firewall.backend")),
+		viper.GetString("container.firewall.table"),
+		viper.GetString("container.firewall.chain"),
+		viper.GetBool("container.firewall.ipv6"),
+	)
+}
+
 func (c *Cli) GetMetricsInterval() time.Duration {
 	interval := viper.GetDuration("metrics.interval")
 	if interval < 60*time.Second {
@@ -129,6 +865,27 @@ func (c *Cli) GetMetricsInterval() time.Duration {
 	return interval
 }
 
+func (c *Cli) AdaptiveMetricsEnabled() bool {
+	return viper.GetBool("metrics.adaptive.enabled")
+}
+
+// Get the metrics interval of containers which recently restarted or became unhealthy. 0 = disabled
+func (c *Cli) GetAdaptiveMetricsInterval() time.Duration {
+	if !c.AdaptiveMetricsEnabled() {
+		return 0
+	}
+	interval := viper.GetDuration("metrics.adaptive.interval")
+	if interval < 10*time.Second {
+		slog.Warn("metrics.adaptive.interval is lower than allowed limit.", "old", interval, "new", 10*time.Second)
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+func (c *Cli) GetAdaptiveMetricsHold() time.Duration {
+	return viper.GetDuration("metrics.adaptive.hold")
+}
+
 func (c *Cli) GetMQTTPort() uint16 {
 	v := viper.GetUint16("client.mqtt.port")
 	if v == 0 {
@@ -145,18 +902,84 @@ func (c *Cli) GetCumulocityHost() string {
 }
 
 func (c *Cli) GetCumulocityPort() uint16 {
-	v := viper.GetUint16("monitor.c8y.proxy.client..port")
+	return viper.GetUint16("client.c8y.port")
+}
+
+// Get a local copy of the artifact referenced by a module version url (http(s):// or file://), and verify its checksum.
+// Artifacts are downloaded using the certificates of the thin-edge.io file transfer service.
+// ok is false if the module version is not an artifact url
+func (c *Cli) FetchArtifact(ctx context.Context, version string) (path string, cleanup func(), ok bool, err error) {
+	artifact, ok, err := container.ParseArtifact(version)
+	if !ok || err != nil {
+		return "", func() {}, ok, err
+	}
+	client := tedge.NewFileTransferClient(c.GetFileTransferHost(), c.GetFileTransferPort(), c.GetTedgeClientConfig())
+	policy := container.ArtifactPolicy{
+		FileDir:         viper.GetString("container.artifacts.file_dir"),
+		RequireChecksum: viper.GetBool("container.artifacts.require_checksum"),
+	}
+	maxBandwidth := c.GetMaxDownloadBandwidth()
+	download := func(ctx context.Context, url string, w io.Writer) error {
+		return client.Download(ctx, url, container.NewThrottledWriter(ctx, w, maxBandwidth))
+	}
+	path, cleanup, err = container.FetchArtifact(ctx, artifact, policy, os.TempDir(), c.GetCache(), download)
+	return path, cleanup, true, err
+}
+
+// Host of the thin-edge.io file transfer service
+func (c *Cli) GetFileTransferHost() string {
+	return viper.GetString("client.http.host")
+}
+
+func (c *Cli) GetFileTransferPort() uint16 {
+	v := viper.GetUint16("client.http.port")
 	if v == 0 {
-		return 8001
+		return 8000
 	}
 	return v
 }
 
+func (c *Cli) GetTedgeClientConfig() *tedge.ClientConfig {
+	return &tedge.ClientConfig{
+		MqttHost: c.GetMQTTHost(),
+		MqttPort: c.GetMQTTPort(),
+		C8yHost:  c.GetCumulocityHost(),
+		C8yPort:  c.GetCumulocityPort(),
+		CertFile: c.GetCertificateFile(),
+		KeyFile:  c.GetKeyFile(),
+		CAFile:   c.GetCAFile(),
+		TimeMode: c.GetTimeMode(),
+	}
+}
+
+func (c *Cli) GetTimeMode() tedge.TimeMode {
+	return tedge.TimeMode(viper.GetString("time.mode"))
+}
+
+// Publish a single MQTT message from a short lived command
+func (c *Cli) Publish(name string, topic string, retained bool, payload []byte) error {
+	clientID := fmt.Sprintf("%s#%s", c.GetServiceName(), name)
+	return tedge.PublishOnce(clientID, c.GetTedgeClientConfig(), topic, retained, payload)
+}
+
+// Get the instance lease which ensures that only one monitor is active. nil = disabled
+func (c *Cli) GetLease() *lease.Lease {
+	if !viper.GetBool("monitor.lease.enabled") {
+		return nil
+	}
+	id := lease.InstanceID()
+	device := c.GetDeviceTarget()
+	topic := tedge.GetTopic(*device.Service(c.GetServiceName()), "lease")
+	opts := tedge.NewMQTTClientOptions(fmt.Sprintf("%s#lease#%s", c.GetServiceName(), id), c.GetTedgeClientConfig())
+	return lease.NewLease(opts, topic, id, viper.GetDuration("monitor.lease.duration"), viper.GetDuration("monitor.lease.max_wait"))
+}
+
 func (c *Cli) GetDeviceTarget() tedge.Target {
 	return tedge.Target{
-		RootPrefix:    c.GetTopicRoot(),
-		TopicID:       c.GetTopicID(),
-		CloudIdentity: c.GetDeviceID(),
+		RootPrefix:      c.GetTopicRoot(),
+		TopicID:         c.GetTopicID(),
+		CloudIdentity:   c.GetDeviceID(),
+		ServiceTemplate: c.GetServiceTopicTemplate(),
 	}
 }
 
@@ -174,9 +997,42 @@ func (c *Cli) GetFilterOptions() container.FilterOptions {
 		Names:            getExpandedStringSlice("filter.include.names"),
 		IDs:              getExpandedStringSlice("filter.include.ids"),
 		Labels:           getExpandedStringSlice("filter.include.labels"),
+		States:           getExpandedStringSlice("filter.include.states"),
 		Types:            getExpandedStringSlice("filter.include.types"),
 		ExcludeNames:     getExpandedStringSlice("filter.exclude.names"),
 		ExcludeWithLabel: getExpandedStringSlice("filter.exclude.labels"),
+		ExcludeIDs:       getExpandedStringSlice("filter.exclude.ids"),
+		ExcludeDefaults:  viper.GetBool("filter.exclude.defaults"),
+		Limit:            viper.GetInt("filter.limit"),
 	}
 	return options
 }
+
+func (c *Cli) GetDefaultImageOptions() container.DefaultImageOptions {
+	return container.DefaultImageOptions{
+		Policy:   container.DefaultImagePolicy(strings.ToLower(viper.GetString("container.default_image.policy"))),
+		Template: viper.GetString("container.default_image.template"),
+	}
+}
+
+// Get the grace period for containers to stop before they are killed when they are removed or replaced.
+// 0 = container's stop timeout (engine default)
+func (c *Cli) GetStopTimeout() time.Duration {
+	return viper.GetDuration("container.stop_timeout")
+}
+
+// Get the user settings of the containers created by the plugin
+func (c *Cli) GetUserOptions() container.UserOptions {
+	return container.UserOptions{
+		User:       viper.GetString("container.security.user"),
+		UsernsMode: viper.GetString("container.security.userns_mode"),
+		ForbidRoot: viper.GetBool("container.security.forbid_root"),
+	}
+}
+
+func (c *Cli) GetProtectionOptions() container.ProtectionOptions {
+	return container.ProtectionOptions{
+		Names:  getExpandedStringSlice("container.protected.names"),
+		Labels: getExpandedStringSlice("container.protected.labels"),
+	}
+}
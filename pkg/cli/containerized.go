@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"log/slog"
+	"net"
+	"slices"
+
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Settings of the thin-edge.io services which refer to the host's loopback address by default
+var loopbackHostKeys = []string{
+	"client.mqtt.host",
+	"client.c8y.host",
+	"client.http.host",
+}
+
+// Check if the monitor is running as a container, where the engine socket is mounted into the container
+func (c *Cli) Containerized() bool {
+	return viper.GetBool("monitor.containerized.enabled")
+}
+
+// Check if the container of the monitor itself should be excluded from the monitored containers
+func (c *Cli) ExcludeSelf() bool {
+	return viper.GetBool("monitor.containerized.exclude_self")
+}
+
+// Get the address which is used to reach the host's services (e.g. the MQTT broker) from the container
+func (c *Cli) GetContainerizedGateway() string {
+	return viper.GetString("monitor.containerized.gateway")
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Adjust the settings to the container which the monitor is running in. The host's services are
+// not reachable via the loopback address unless the container uses the host's network namespace,
+// so the gateway address is used instead. self is nil if the container could not be found
+func (c *Cli) ApplyContainerized(self *container.SelfContainer) {
+	if self == nil {
+		slog.Warn("Could not find the container of the monitor, so the container is not excluded and the host address is not detected.")
+	} else {
+		slog.Info("Running as a container.", "id", self.ID, "name", self.Name, "host", self.EngineHostname, "hostNetwork", self.HostNetwork, "hostPID", self.HostPID)
+		if !self.HostPID {
+			slog.Info("The container does not share the host's pid namespace, so the reported process ids are only valid inside the container.")
+		}
+		if c.ExcludeSelf() {
+			ids := getExpandedStringSlice("filter.exclude.ids")
+			if !slices.Contains(ids, self.ID) {
+				viper.Set("filter.exclude.ids", append(ids, self.ID))
+			}
+		}
+		if self.HostNetwork {
+			return
+		}
+	}
+
+	gateway := c.GetContainerizedGateway()
+	if gateway == "" && self != nil {
+		gateway = self.Gateway
+	}
+	if gateway == "" {
+		if self != nil {
+			slog.Warn("Could not detect the host address. The host's services are not reachable via the loopback address from the container, so use the host network or set monitor.containerized.gateway.")
+		}
+		return
+	}
+	for _, key := range loopbackHostKeys {
+		if !isLoopbackHost(viper.GetString(key)) {
+			continue
+		}
+		slog.Info("Using the gateway address to reach the host's service.", "key", key, "host", gateway)
+		viper.Set(key, gateway)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+var ErrUnknownEngine = errors.New("unknown container engine")
+
+// Sockets of the well-known container engines which can be selected by name (e.g. --engine podman-user)
+func knownEngines() map[string]string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return map[string]string{
+		"docker":      "unix:///var/run/docker.sock",
+		"docker-user": "unix://" + filepath.Join(runtimeDir, "docker.sock"),
+		"podman":      "unix:///run/podman/podman.sock",
+		"podman-user": "unix://" + filepath.Join(runtimeDir, "podman", "podman.sock"),
+	}
+}
+
+// Get the address of the container engine which is selected via the --host or --engine flags
+// (or the engine.host and engine.name settings). An empty string is returned if no engine is selected
+func (c *Cli) GetEngineHost() (string, error) {
+	if host := viper.GetString("engine.host"); host != "" {
+		return host, nil
+	}
+	name := viper.GetString("engine.name")
+	if name == "" {
+		return "", nil
+	}
+	engines := knownEngines()
+	host, ok := engines[name]
+	if !ok {
+		names := make([]string, 0, len(engines))
+		for k := range engines {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("%w. name=%s, expected one of %v", ErrUnknownEngine, name, names)
+	}
+	return host, nil
+}
+
+// Use the selected container engine for the current invocation. The engine is selected by setting
+// DOCKER_HOST, so it is also used by the engine's cli tools (e.g. docker compose) which are called by the commands
+func ApplyEngineHost() error {
+	c := Cli{}
+	host, err := c.GetEngineHost()
+	if err != nil {
+		return NewExitCodeError(ExitCodeUsage, err)
+	}
+	if host == "" {
+		return nil
+	}
+	if os.Getenv("DOCKER_HOST") == host {
+		return nil
+	}
+	slog.Debug("Using container engine.", "host", host)
+	return os.Setenv("DOCKER_HOST", host)
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetEngineHost(t *testing.T) {
+	defer viper.Reset()
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	c := &Cli{}
+
+	host, err := c.GetEngineHost()
+	assert.NoError(t, err)
+	assert.Equal(t, "", host)
+
+	viper.Set("engine.name", "podman-user")
+	host, err = c.GetEngineHost()
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///run/user/1000/podman/podman.sock", host)
+
+	// the address takes precedence
+	viper.Set("engine.host", "tcp://127.0.0.1:2375")
+	host, err = c.GetEngineHost()
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://127.0.0.1:2375", host)
+
+	viper.Set("engine.host", "")
+	viper.Set("engine.name", "containerd")
+	_, err = c.GetEngineHost()
+	assert.True(t, errors.Is(err, ErrUnknownEngine))
+}
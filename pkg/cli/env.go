@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Prefix of the environment variables which can be used to set any configuration key
+const EnvPrefix = "CONTAINER"
+
+// Settings which were renamed to use consistent (snake_case) key names (new key -> old key).
+// The old keys are still read from the configuration file and environment, but are deprecated
+var renamedKeys = map[string]string{
+	"client.c8y.port":                    "monitor.c8y.proxy.client..port",
+	"container.always_pull":              "container.alwaysPull",
+	"container.pull_failure_alarm":       "container.pullFailureAlarm",
+	"container.default_image.policy":     "container.defaultImage.policy",
+	"container.default_image.template":   "container.defaultImage.template",
+	"container.network_options.ipv6":     "container.networkOptions.ipv6",
+	"container.network_options.subnets":  "container.networkOptions.subnets",
+	"container.network_options.gateways": "container.networkOptions.gateways",
+	"container.network_options.mtu":      "container.networkOptions.mtu",
+	"container.remove_image":             "container.removeImage",
+	"container.remove_network":           "container.removeNetwork",
+	"container.prune_images":             "container.pruneImages",
+	"container.prune_networks":           "container.pruneNetworks",
+	"container.update_list.concurrency":  "container.updateList.concurrency",
+}
+
+// Get the name of the environment variable which sets the given configuration key,
+// e.g. container.network_options.mtu => CONTAINER_CONTAINER_NETWORK_OPTIONS_MTU
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Use the value of a deprecated key when it is set. The value is used as the default of the new key,
+// so the new key still takes precedence when set via the configuration file, environment or a flag
+func applyRenamedKeys() {
+	for key, oldKey := range renamedKeys {
+		value := viper.Get(oldKey)
+		if value == nil {
+			continue
+		}
+		slog.Warn("Configuration key is deprecated, please use the new key instead.", "key", oldKey, "new", key, "env", EnvName(key))
+		viper.SetDefault(key, value)
+	}
+}
+
+// Decode a structured setting into out. The nested keys of tables are read individually, so they
+// can be overridden by their environment variables. Settings which can't be expressed as individual
+// environment variables (e.g. a list of tables) can also be set to a json string
+func unmarshalKey(key string, out any) error {
+	switch value := viper.Get(key).(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+		var raw any
+		if err := json.Unmarshal([]byte(value), &raw); err != nil {
+			return fmt.Errorf("invalid json value of %s. %w", EnvName(key), err)
+		}
+		// decode the same way as the configuration file, e.g. durations given as strings
+		decoded := viper.New()
+		decoded.Set("value", raw)
+		return decoded.UnmarshalKey("value", out)
+	case map[string]any:
+		prefix := strings.ToLower(key) + "."
+		nested := viper.New()
+		for _, k := range viper.AllKeys() {
+			if strings.HasPrefix(k, prefix) {
+				nested.Set(strings.TrimPrefix(k, prefix), viper.Get(k))
+			}
+		}
+		return nested.Unmarshal(out)
+	}
+	return viper.UnmarshalKey(key, out)
+}
+
+// Effective value of a configuration key and the environment variable which sets it
+type EnvSetting struct {
+	Key   string `json:"key"`
+	Env   string `json:"env"`
+	Value any    `json:"value"`
+}
+
+// Get the effective value of all known configuration keys, including the environment variable
+// which can be used to set each key. Profiles and deprecated keys are not included
+func (c *Cli) GetEnvSettings() []EnvSetting {
+	deprecated := make(map[string]struct{}, len(renamedKeys))
+	for _, oldKey := range renamedKeys {
+		deprecated[strings.ToLower(oldKey)] = struct{}{}
+	}
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	settings := make([]EnvSetting, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, "profiles.") {
+			continue
+		}
+		if _, ok := deprecated[key]; ok {
+			continue
+		}
+		settings = append(settings, EnvSetting{
+			Key:   key,
+			Env:   EnvName(key),
+			Value: viper.Get(key),
+		})
+	}
+	return settings
+}
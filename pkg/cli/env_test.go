@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/app"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_EnvName(t *testing.T) {
+	assert.Equal(t, "CONTAINER_CLIENT_C8Y_PORT", EnvName("client.c8y.port"))
+	assert.Equal(t, "CONTAINER_CONTAINER_NETWORK_OPTIONS_MTU", EnvName("container.network_options.mtu"))
+}
+
+func Test_applyRenamedKeys(t *testing.T) {
+	defer viper.Reset()
+	viper.SetDefault("container.always_pull", false)
+	viper.SetDefault("container.remove_network", true)
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[container]
+alwaysPull = true
+removeNetwork = false
+remove_network = true
+`))
+	assert.NoError(t, err)
+
+	applyRenamedKeys()
+	assert.True(t, viper.GetBool("container.always_pull"))
+	assert.True(t, viper.GetBool("container.remove_network"))
+
+	keys := make([]string, 0)
+	for _, setting := range (&Cli{}).GetEnvSettings() {
+		keys = append(keys, setting.Key)
+	}
+	assert.Contains(t, keys, "container.always_pull")
+	assert.NotContains(t, keys, "container.alwayspull")
+}
+
+func Test_PackagedConfigUsesCurrentKeys(t *testing.T) {
+	defer viper.Reset()
+	viper.SetConfigFile("../../packaging/config.toml")
+	assert.NoError(t, viper.ReadInConfig())
+
+	for key, oldKey := range renamedKeys {
+		assert.False(t, viper.InConfig(oldKey), "deprecated key %s is used instead of %s", oldKey, key)
+	}
+}
+
+func Test_unmarshalKey(t *testing.T) {
+	defer viper.Reset()
+	t.Setenv("CONTAINER_METRICS_DEADBAND_MEMORY_PERCENT", "10")
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[metrics.deadband.memory]
+percent = 5
+max_age = "1h"
+`))
+	assert.NoError(t, err)
+
+	deadbands := make(map[string]app.Deadband)
+	assert.NoError(t, unmarshalKey("metrics.deadband", &deadbands))
+	assert.Equal(t, 10.0, deadbands["memory"].Percent)
+	assert.Equal(t, "1h0m0s", deadbands["memory"].MaxAge.String())
+
+	t.Setenv("CONTAINER_METRICS_DEADBAND", `{"cpu":{"absolute":2,"max_age":"30m"}}`)
+	deadbands = make(map[string]app.Deadband)
+	assert.NoError(t, unmarshalKey("metrics.deadband", &deadbands))
+	assert.Equal(t, 2.0, deadbands["cpu"].Absolute)
+	assert.Equal(t, "30m0s", deadbands["cpu"].MaxAge.String())
+	assert.NotContains(t, deadbands, "memory")
+}
+
+func Test_GetRegistries(t *testing.T) {
+	defer viper.Reset()
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[monitor.registries."registry.local:5000"]
+ca_file = "/etc/ssl/plant-ca.crt"
+insecure_skip_verify = true
+
+[monitor.registries.localhost]
+cert_file = "/etc/ssl/client.crt"
+`))
+	assert.NoError(t, err)
+
+	registries := (&Cli{}).GetRegistries()
+	assert.Equal(t, map[string]container.RegistryTLS{
+		"registry.local:5000": {CAFile: "/etc/ssl/plant-ca.crt", InsecureSkipVerify: true},
+		"localhost":           {CertFile: "/etc/ssl/client.crt"},
+	}, registries)
+}
+
+func Test_GetMirrors(t *testing.T) {
+	defer viper.Reset()
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[monitor.mirrors]
+"docker.io" = "harbor.local/dockerhub"
+"ghcr.io" = ""
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"docker.io": "harbor.local/dockerhub"}, (&Cli{}).GetMirrors())
+
+	t.Setenv("CONTAINER_MONITOR_MIRRORS", `{"quay.io":"harbor.local/quay"}`)
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	assert.Equal(t, map[string]string{"quay.io": "harbor.local/quay"}, (&Cli{}).GetMirrors())
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Exit codes used by the software management plugin commands.
+// See https://thin-edge.github.io/thin-edge.io/extend/software-management/
+const (
+	ExitCodeSuccess = 0
+
+	// The command arguments or input could not be interpreted
+	ExitCodeUsage = 1
+
+	// The command is not supported by the plugin. The tedge-agent falls back to
+	// calling install/remove for each module if update-list is not supported
+	ExitCodeNotSupported = 1
+
+	// The operation failed (e.g. the image could not be pulled or the container could not be created).
+	// The tedge-agent treats any code other than 0 and 1 as a failure of the operation
+	ExitCodeFailure = 2
+
+	// The requested module is not valid for the operation (e.g. it does not exist or is protected),
+	// or the operation is not allowed (read-only mode)
+	ExitCodeValidation = 3
+
+	// The container engine is not available, so the operation can be retried later
+	ExitCodeEngineUnavailable = 4
+)
+
+// Error with an explicit exit code
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+func NewExitCodeError(code int, err error) error {
+	return ExitCodeError{
+		Code: code,
+		Err:  err,
+	}
+}
+
+// Get the exit code which should be used for the given error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	exitCodeErr := ExitCodeError{}
+	if errors.As(err, &exitCodeErr) {
+		return exitCodeErr.Code
+	}
+
+	// Exit codes of external commands (e.g. docker compose) are not passed through,
+	// as code 1 would be interpreted as a usage error by the tedge-agent
+	switch {
+	case errors.Is(err, container.ErrEngineUnavailable):
+		return ExitCodeEngineUnavailable
+	case errors.Is(err, container.ErrNotFound), errors.Is(err, container.ErrProtected), errors.Is(err, container.ErrInvalid), errors.Is(err, container.ErrUnsupported), errors.Is(err, ErrReadOnly):
+		return ExitCodeValidation
+	default:
+		return ExitCodeFailure
+	}
+}
+
+// Mark any argument or flag errors of the command (and its subcommands) as usage errors
+func SetUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return NewExitCodeError(ExitCodeUsage, err)
+	})
+	if cmd.Args != nil {
+		validateArgs := cmd.Args
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validateArgs(c, args); err != nil {
+				return NewExitCodeError(ExitCodeUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, subcommand := range cmd.Commands() {
+		SetUsageErrors(subcommand)
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_ExitCode(t *testing.T) {
+	// Exit code 1 of an external command must not be reported as a usage error
+	cmdErr := exec.Command("sh", "-c", "exit 1").Run()
+	assert.Error(t, cmdErr)
+
+	testcases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: ExitCodeSuccess},
+		{name: "usage", err: NewExitCodeError(ExitCodeUsage, errors.New("invalid argument")), expected: ExitCodeUsage},
+		{name: "not supported", err: NewExitCodeError(ExitCodeNotSupported, errors.New("update-list is not supported")), expected: ExitCodeNotSupported},
+		{name: "failure", err: errors.New("failed to create container"), expected: ExitCodeFailure},
+		{name: "external command", err: fmt.Errorf("docker compose failed: %w", cmdErr), expected: ExitCodeFailure},
+		{name: "not found", err: fmt.Errorf("container %w. name=app", container.ErrNotFound), expected: ExitCodeValidation},
+		{name: "protected", err: fmt.Errorf("container is %w", container.ErrProtected), expected: ExitCodeValidation},
+		{name: "invalid", err: fmt.Errorf("%w container name", container.ErrInvalid), expected: ExitCodeValidation},
+		{name: "unsupported", err: fmt.Errorf("checkpoints are %w", container.ErrUnsupported), expected: ExitCodeValidation},
+		{name: "read-only", err: fmt.Errorf("install is %w", ErrReadOnly), expected: ExitCodeValidation},
+		{name: "engine unavailable", err: fmt.Errorf("%w: connection refused", container.ErrEngineUnavailable), expected: ExitCodeEngineUnavailable},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ExitCode(tc.err))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/audit"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func (c *Cli) GetPruneOptions() container.PruneOptions {
+	return container.PruneOptions{
+		Images:        c.GetBool("container.prune_images"),
+		Networks:      c.GetBool("container.prune_networks"),
+		SharedNetwork: c.GetSharedContainerNetwork(),
+	}
+}
+
+// Run the prune policy after an install/remove operation, and publish the reclaimed space as an event
+func (c *Cli) Finalize(ctx context.Context, name string) error {
+	opts := c.GetPruneOptions()
+	if !opts.Images && !opts.Networks {
+		return nil
+	}
+	cli, err := container.NewContainerClient()
+	if err != nil {
+		return err
+	}
+	result, err := cli.Prune(ctx, opts)
+	c.Audit(audit.Entry{
+		Action: audit.ActionPrune,
+		Type:   name,
+		Name:   name,
+	}, err)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"text":            fmt.Sprintf("Pruned unused resources. images=%d, networks=%d, reclaimed=%s", result.ImagesDeleted, result.NetworksDeleted, result.HumanSpaceReclaimed()),
+		"imagesDeleted":   result.ImagesDeleted,
+		"networksDeleted": result.NetworksDeleted,
+		"spaceReclaimed":  result.SpaceReclaimed,
+	}
+	tedge.NewClock(c.GetTimeMode()).SetTime(payload)
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	topic := tedge.GetTopic(c.GetDeviceTarget(), "e", "container_prune")
+	if err := c.Publish(name+"#finalize", topic, false, b); err != nil {
+		// non critical error
+		slog.Warn("Could not publish prune event.", "err", err)
+	}
+	return nil
+}
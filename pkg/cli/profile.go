@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/viper"
+)
+
+var ErrUnknownProfile = errors.New("configuration profile does not exist")
+
+// Get the name of the selected configuration profile. The profile is selected via the --profile flag,
+// the CONTAINER_PROFILE environment variable or the profile setting in the configuration file
+func (c *Cli) GetProfile() string {
+	return viper.GetString("profile")
+}
+
+// Apply the settings of the selected configuration profile (profiles.<name>) on top of the configuration file,
+// so that one configuration file can serve different device roles. Environment variables and flags still
+// take precedence over the profile's settings
+func applyProfile() error {
+	name := viper.GetString("profile")
+	if name == "" {
+		return nil
+	}
+	settings := viper.GetStringMap("profiles." + name)
+	if len(settings) == 0 {
+		return fmt.Errorf("%w. profile=%s", ErrUnknownProfile, name)
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	slog.Info("Using configuration profile.", "profile", name)
+	return nil
+}
+
+// Check if the selected configuration profile exists
+func CheckProfile() error {
+	name := viper.GetString("profile")
+	if name != "" && len(viper.GetStringMap("profiles."+name)) == 0 {
+		return NewExitCodeError(ExitCodeUsage, fmt.Errorf("%w. profile=%s", ErrUnknownProfile, name))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyProfile(t *testing.T) {
+	defer viper.Reset()
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[metrics]
+enabled = true
+interval = "300s"
+
+[profiles.kiosk.metrics]
+enabled = false
+
+[profiles.gateway.metrics]
+interval = "60s"
+`))
+	assert.NoError(t, err)
+
+	viper.Set("profile", "gateway")
+	assert.NoError(t, CheckProfile())
+	assert.NoError(t, applyProfile())
+	assert.Equal(t, "60s", viper.GetString("metrics.interval"))
+	assert.True(t, viper.GetBool("metrics.enabled"))
+
+	viper.Set("profile", "unknown")
+	assert.ErrorIs(t, CheckProfile(), ErrUnknownProfile)
+	assert.Equal(t, ExitCodeUsage, ExitCode(CheckProfile()))
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Annotation which marks a command as mutating (e.g. install or remove), so it is disabled in read-only mode
+const AnnotationMutating = "tedge-container.mutating"
+
+var ErrReadOnly = errors.New("read-only mode is enabled")
+
+// Check if the plugin should only observe and report, and not modify any containers
+func (c *Cli) ReadOnly() bool {
+	return viper.GetBool("monitor.read_only")
+}
+
+// Annotations used to mark a command as mutating
+func MutatingAnnotations() map[string]string {
+	return map[string]string{
+		AnnotationMutating: "true",
+	}
+}
+
+// Reject mutating commands when read-only mode is enabled
+func CheckReadOnly(cmd *cobra.Command) error {
+	if viper.GetBool("monitor.read_only") && cmd.Annotations[AnnotationMutating] == "true" {
+		return fmt.Errorf("%w, so the command is not allowed. cmd=%s", ErrReadOnly, cmd.CommandPath())
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckReadOnly(t *testing.T) {
+	mutating := &cobra.Command{Use: "install", Annotations: MutatingAnnotations()}
+	readOnly := &cobra.Command{Use: "list"}
+
+	viper.Set("monitor.read_only", false)
+	assert.NoError(t, CheckReadOnly(mutating))
+	assert.NoError(t, CheckReadOnly(readOnly))
+
+	viper.Set("monitor.read_only", true)
+	defer viper.Set("monitor.read_only", false)
+	err := CheckReadOnly(mutating)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Equal(t, ExitCodeValidation, ExitCode(err))
+	assert.NoError(t, CheckReadOnly(readOnly))
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thin-edge/tedge-container-plugin/pkg/schedule"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+// Get the maintenance window in which installs are allowed. Returns nil if installs are always allowed
+func (c *Cli) GetMaintenanceWindow() (*schedule.Window, error) {
+	expr := viper.GetString("monitor.install.maintenance_window.schedule")
+	if expr == "" {
+		return nil, nil
+	}
+	return schedule.NewWindow(expr, viper.GetDuration("monitor.install.maintenance_window.duration"))
+}
+
+// Wait until the maintenance window (if configured) before applying an install.
+// The operation stays in the executing state while waiting, and an event is published when the install starts
+func (c *Cli) WaitForMaintenanceWindow(ctx context.Context, name string) error {
+	window, err := c.GetMaintenanceWindow()
+	if err != nil || window == nil {
+		return err
+	}
+
+	now := time.Now()
+	start, ok := window.Next(now)
+	if !ok {
+		return fmt.Errorf("no maintenance window found. schedule=%s", window.Start.Expression)
+	}
+
+	deferred := start.After(now)
+	if deferred {
+		slog.Info("Deferring install until the next maintenance window.", "name", name, "start", start.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(start))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	payload := map[string]any{
+		"text":     fmt.Sprintf("Starting install in maintenance window. name=%s, deferred=%v", name, deferred),
+		"name":     name,
+		"deferred": deferred,
+	}
+	tedge.NewClock(c.GetTimeMode()).SetTime(payload)
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	topic := tedge.GetTopic(c.GetDeviceTarget(), "e", "container_install_started")
+	if err := c.Publish("install#"+name, topic, false, b); err != nil {
+		// non critical error
+		slog.Warn("Could not publish install started event.", "err", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/cache"
+)
+
+// Artifact (e.g. an image tarball or compose file) which is referenced by a module version url.
+// The expected checksum can be given in the url fragment, e.g. https://example.com/app.tar#sha256=<hex>
+type Artifact struct {
+	URL    *url.URL
+	SHA256 string
+}
+
+// Parse a module version which refers to an artifact (http://, https:// or file:// url).
+// Returns false if the version is not an artifact url
+func ParseArtifact(version string) (Artifact, bool, error) {
+	scheme, _, found := strings.Cut(version, "://")
+	if !found {
+		return Artifact{}, false, nil
+	}
+	switch scheme {
+	case "http", "https", "file":
+	default:
+		return Artifact{}, false, nil
+	}
+	u, err := url.Parse(version)
+	if err != nil {
+		return Artifact{}, true, fmt.Errorf("%w artifact url. url=%s, err=%s", ErrInvalid, version, err)
+	}
+	artifact := Artifact{URL: u}
+	if u.Fragment != "" {
+		algorithm, checksum, _ := strings.Cut(u.Fragment, "=")
+		if algorithm != "sha256" || len(checksum) != sha256.Size*2 {
+			return Artifact{}, true, fmt.Errorf("%w artifact checksum, expected #sha256=<hex>. url=%s", ErrInvalid, version)
+		}
+		artifact.SHA256 = strings.ToLower(checksum)
+		u.Fragment = ""
+	}
+	return artifact, true, nil
+}
+
+// Restricts the artifacts which can be fetched, as the module versions are provided by the cloud
+type ArtifactPolicy struct {
+	// Directory which file:// artifacts must be located in. Empty = file:// artifacts are not allowed
+	FileDir string
+
+	// Require a checksum for all artifacts. Artifacts which are downloaded via plain http always require one
+	RequireChecksum bool
+}
+
+// Check that the artifact is allowed by the policy, and return the local path of file:// artifacts
+// (with the symlinks resolved, so they can't point outside of the allowed directory)
+func (p ArtifactPolicy) Check(a Artifact) (string, error) {
+	if a.SHA256 == "" && (p.RequireChecksum || a.URL.Scheme == "http") {
+		return "", fmt.Errorf("%w artifact url, a checksum is required (#sha256=<hex>). url=%s", ErrInvalid, a.URL.Redacted())
+	}
+	if a.URL.Scheme != "file" {
+		return "", nil
+	}
+	if p.FileDir == "" {
+		return "", fmt.Errorf("%w artifact url, file urls are not allowed. url=%s", ErrInvalid, a.URL.String())
+	}
+	dir, err := filepath.EvalSymlinks(p.FileDir)
+	if err != nil {
+		return "", fmt.Errorf("%w artifact directory. dir=%s, err=%w", ErrInvalid, p.FileDir, err)
+	}
+	file, err := filepath.EvalSymlinks(filepath.Clean(a.URL.Path))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(dir, file); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w artifact url, the file is not located in the allowed directory. url=%s, dir=%s", ErrInvalid, a.URL.String(), p.FileDir)
+	}
+	return file, nil
+}
+
+// Check if a module version refers to an artifact rather than an image
+func IsArtifact(version string) bool {
+	_, ok, _ := ParseArtifact(version)
+	return ok
+}
+
+// Name of the artifact's file
+func (a Artifact) Name() string {
+	return path.Base(a.URL.Path)
+}
+
+// Check the checksum of a file if the artifact defines one
+func (a Artifact) Verify(p string) error {
+	if a.SHA256 == "" {
+		return nil
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != a.SHA256 {
+		return fmt.Errorf("%w artifact checksum. expected=%s, actual=%s", ErrInvalid, a.SHA256, actual)
+	}
+	return nil
+}
+
+// Get a local copy of an artifact which is allowed by the policy, and verify its checksum. Files (file://) are used in place,
+// other artifacts are downloaded to the given directory. The returned cleanup function removes the downloaded file.
+// Artifacts with a checksum are immutable, so they are kept in the cache (if given) and only downloaded once
+func FetchArtifact(ctx context.Context, a Artifact, policy ArtifactPolicy, dir string, artifactCache *cache.Cache, download func(ctx context.Context, url string, w io.Writer) error) (string, func(), error) {
+	noop := func() {}
+	p, err := policy.Check(a)
+	if err != nil {
+		return "", noop, err
+	}
+	if a.URL.Scheme == "file" {
+		if err := a.Verify(p); err != nil {
+			return "", noop, err
+		}
+		return p, noop, nil
+	}
+
+	cached := artifactCache != nil && a.SHA256 != ""
+	key := "sha256:" + a.SHA256
+	if cached {
+		if p, ok := artifactCache.GetWithChecksum(key, a.SHA256); ok {
+			slog.Info("Using cached artifact.", "url", a.URL.Redacted(), "path", p)
+			return p, noop, nil
+		}
+		tmpDir, err := artifactCache.TempDir()
+		if err != nil {
+			return "", noop, err
+		}
+		defer os.RemoveAll(tmpDir)
+		dir = tmpDir
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", noop, err
+	}
+	file, err := os.CreateTemp(dir, "artifact-*-"+a.Name())
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() {
+		_ = os.Remove(file.Name())
+	}
+	slog.Info("Downloading artifact.", "url", a.URL.Redacted(), "path", file.Name())
+	err = download(ctx, a.URL.String(), file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && !cached {
+		err = a.Verify(file.Name())
+	}
+	if err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	if cached {
+		// The downloaded bytes are checked against the expected checksum before they are added to the cache
+		p, err := artifactCache.PutWithChecksum(key, file.Name(), a.SHA256)
+		if errors.Is(err, cache.ErrChecksumMismatch) {
+			return "", noop, fmt.Errorf("%w artifact checksum. %s", ErrInvalid, err)
+		}
+		if err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		return p, noop, nil
+	}
+	return file.Name(), cleanup, nil
+}
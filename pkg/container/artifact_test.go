@@ -0,0 +1,170 @@
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cache"
+)
+
+func Test_ParseArtifact(t *testing.T) {
+	_, ok, err := ParseArtifact("docker.io/library/nginx:latest")
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	assert.False(t, IsArtifact("oci://ghcr.io/org/app:1.0.0"))
+
+	checksum := hex.EncodeToString(make([]byte, sha256.Size))
+	artifact, ok, err := ParseArtifact("https://example.com/files/app.tar#sha256=" + checksum)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/files/app.tar", artifact.URL.String())
+	assert.Equal(t, checksum, artifact.SHA256)
+	assert.Equal(t, "app.tar", artifact.Name())
+
+	_, ok, err = ParseArtifact("file:///tmp/app.tar#md5=abc")
+	assert.True(t, ok)
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func Test_FetchArtifact(t *testing.T) {
+	contents := []byte("services: {}\n")
+	hash := sha256.Sum256(contents)
+	checksum := hex.EncodeToString(hash[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(contents)
+	}))
+	defer server.Close()
+	download := func(ctx context.Context, url string, w io.Writer) error {
+		resp, err := http.Get(url)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+	dir := t.TempDir()
+
+	policy := ArtifactPolicy{FileDir: dir}
+
+	artifact, _, _ := ParseArtifact(server.URL + "/docker-compose.yaml#sha256=" + checksum)
+	p, cleanup, err := FetchArtifact(context.Background(), artifact, policy, dir, nil, download)
+	assert.NoError(t, err)
+	b, _ := os.ReadFile(p)
+	assert.Equal(t, contents, b)
+	cleanup()
+	assert.NoFileExists(t, p)
+
+	// checksum mismatch
+	artifact.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	_, _, err = FetchArtifact(context.Background(), artifact, policy, dir, nil, download)
+	assert.True(t, errors.Is(err, ErrInvalid))
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	// local files are used in place
+	local := filepath.Join(dir, "docker-compose.yaml")
+	assert.NoError(t, os.WriteFile(local, contents, 0644))
+	artifact, _, _ = ParseArtifact("file://" + local + "#sha256=" + checksum)
+	p, _, err = FetchArtifact(context.Background(), artifact, policy, dir, nil, download)
+	assert.NoError(t, err)
+	assert.Equal(t, local, p)
+}
+
+func Test_FetchArtifactCached(t *testing.T) {
+	contents := []byte("image tarball")
+	hash := sha256.Sum256(contents)
+	checksum := hex.EncodeToString(hash[:])
+
+	downloads := 0
+	download := func(ctx context.Context, url string, w io.Writer) error {
+		downloads++
+		_, err := w.Write(contents)
+		return err
+	}
+	artifactCache := cache.NewCache(filepath.Join(t.TempDir(), "cache"), 0)
+	policy := ArtifactPolicy{}
+
+	artifact, _, _ := ParseArtifact("https://example.com/app.tar#sha256=" + checksum)
+	p, cleanup, err := FetchArtifact(context.Background(), artifact, policy, t.TempDir(), artifactCache, download)
+	assert.NoError(t, err)
+	cleanup()
+	b, _ := os.ReadFile(p)
+	assert.Equal(t, contents, b)
+
+	// The artifact is only downloaded once, as it is identified by its checksum
+	p2, _, err := FetchArtifact(context.Background(), artifact, policy, t.TempDir(), artifactCache, download)
+	assert.NoError(t, err)
+	assert.Equal(t, p, p2)
+	assert.Equal(t, 1, downloads)
+
+	// Artifacts without a checksum are not cached
+	artifact, _, _ = ParseArtifact("https://example.com/app.tar")
+	p, cleanup, err = FetchArtifact(context.Background(), artifact, policy, t.TempDir(), artifactCache, download)
+	assert.NoError(t, err)
+	cleanup()
+	assert.NoFileExists(t, p)
+	assert.Equal(t, 2, downloads)
+}
+
+func Test_FetchArtifactTampered(t *testing.T) {
+	hash := sha256.Sum256([]byte("image tarball"))
+	checksum := hex.EncodeToString(hash[:])
+	download := func(ctx context.Context, url string, w io.Writer) error {
+		_, err := w.Write([]byte("tampered tarball"))
+		return err
+	}
+	artifactCache := cache.NewCache(filepath.Join(t.TempDir(), "cache"), 0)
+
+	// The install fails and the tampered artifact is not cached
+	artifact, _, _ := ParseArtifact("https://example.com/app.tar#sha256=" + checksum)
+	_, _, err := FetchArtifact(context.Background(), artifact, ArtifactPolicy{}, t.TempDir(), artifactCache, download)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, ok := artifactCache.Get("sha256:" + checksum)
+	assert.False(t, ok)
+	entries, err := artifactCache.List()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func Test_ArtifactPolicy(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "artifacts")
+	assert.NoError(t, os.Mkdir(dir, 0755))
+	local := filepath.Join(dir, "app.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("image"), 0644))
+	outside := filepath.Join(base, "secret")
+	assert.NoError(t, os.WriteFile(outside, []byte("secret"), 0600))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+	checksum := "#sha256=" + hex.EncodeToString(make([]byte, sha256.Size))
+
+	check := func(policy ArtifactPolicy, version string) error {
+		artifact, ok, err := ParseArtifact(version)
+		assert.True(t, ok, version)
+		assert.NoError(t, err, version)
+		_, err = policy.Check(artifact)
+		return err
+	}
+	policy := ArtifactPolicy{FileDir: dir}
+
+	assert.NoError(t, check(policy, "https://example.com/app.tar"))
+	assert.NoError(t, check(policy, "http://example.com/app.tar"+checksum))
+	assert.ErrorIs(t, check(policy, "http://example.com/app.tar"), ErrInvalid)
+	assert.ErrorIs(t, check(ArtifactPolicy{FileDir: dir, RequireChecksum: true}, "https://example.com/app.tar"), ErrInvalid)
+
+	assert.NoError(t, check(policy, "file://"+local))
+	assert.ErrorIs(t, check(policy, "file://"+outside), ErrInvalid)
+	assert.ErrorIs(t, check(policy, "file://"+dir+"/../secret"), ErrInvalid)
+	assert.ErrorIs(t, check(policy, "file://"+filepath.Join(dir, "link")), ErrInvalid)
+	assert.ErrorIs(t, check(ArtifactPolicy{}, "file://"+local), ErrInvalid)
+}
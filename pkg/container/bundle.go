@@ -0,0 +1,228 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cache"
+	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
+)
+
+type BundleSourceType string
+
+const (
+	// Compose bundle stored in a git repository, e.g. git+https://github.com/org/repo.git#v1.0.0
+	BundleSourceGit BundleSourceType = "git"
+
+	// Compose bundle stored as an OCI artifact, e.g. oci://ghcr.io/org/app:1.0.0
+	BundleSourceOCI BundleSourceType = "oci"
+)
+
+// File written to a fetched bundle which records where it was fetched from
+const bundleInfoFile = ".tedge-bundle"
+
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Git revisions (branch, tag or commit) which can be fetched from a bundle source
+var validRevision = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+
+// scp-like ssh url of a git repository, e.g. git@github.com:org/repo.git
+var scpLikeURL = regexp.MustCompile(`^[a-zA-Z0-9._-]+@[a-zA-Z0-9.-]+:[^:]`)
+
+// Remote location of a compose bundle which is referenced by a container-group module version
+type BundleSource struct {
+	Type BundleSourceType
+
+	// Repository url or artifact reference
+	URL string
+
+	// Git branch, tag or commit. Only used by git sources
+	Revision string
+}
+
+// Parse a module version which refers to a remote compose bundle.
+// Returns false if the version is not a bundle reference
+func ParseBundleSource(version string) (BundleSource, bool) {
+	switch {
+	case strings.HasPrefix(version, "git+"):
+		url, revision, _ := strings.Cut(strings.TrimPrefix(version, "git+"), "#")
+		return BundleSource{
+			Type:     BundleSourceGit,
+			URL:      url,
+			Revision: revision,
+		}, url != ""
+	case strings.HasPrefix(version, "oci://"):
+		ref := strings.TrimPrefix(version, "oci://")
+		return BundleSource{
+			Type: BundleSourceOCI,
+			URL:  ref,
+		}, ref != ""
+	}
+	return BundleSource{}, false
+}
+
+func (s BundleSource) String() string {
+	switch s.Type {
+	case BundleSourceGit:
+		if s.Revision == "" {
+			return "git+" + s.URL
+		}
+		return "git+" + s.URL + "#" + s.Revision
+	default:
+		return "oci://" + s.URL
+	}
+}
+
+// Check that the source can be safely passed to git or oras. The values are provided by the cloud,
+// so they must not be interpreted as options, and git is only allowed to use the https and ssh transports
+// (e.g. not ext:: which runs arbitrary commands, or file:// which reads local repositories)
+func (s BundleSource) Validate() error {
+	if s.URL == "" || strings.HasPrefix(s.URL, "-") {
+		return fmt.Errorf("%w bundle url. url=%s", ErrInvalid, s.URL)
+	}
+	switch s.Type {
+	case BundleSourceGit:
+		if strings.Contains(s.URL, "::") {
+			return fmt.Errorf("%w bundle url. Remote helpers are not allowed. url=%s", ErrInvalid, s.URL)
+		}
+		if !strings.HasPrefix(s.URL, "https://") && !strings.HasPrefix(s.URL, "ssh://") && !scpLikeURL.MatchString(s.URL) {
+			return fmt.Errorf("%w bundle url. Only https and ssh urls are supported. url=%s", ErrInvalid, s.URL)
+		}
+		if s.Revision != "" && (!validRevision.MatchString(s.Revision) || strings.Contains(s.Revision, "..")) {
+			return fmt.Errorf("%w bundle revision. revision=%s", ErrInvalid, s.Revision)
+		}
+	case BundleSourceOCI:
+		if _, err := reference.ParseNormalizedNamed(s.URL); err != nil {
+			return fmt.Errorf("%w bundle reference. ref=%s, err=%w", ErrInvalid, s.URL, err)
+		}
+	default:
+		return fmt.Errorf("%w bundle source type. type=%s", ErrInvalid, s.Type)
+	}
+	return nil
+}
+
+// Check if the source refers to content which can't change, e.g. a git commit or an OCI digest.
+// Only immutable sources can be reused from the cache
+func (s BundleSource) Immutable() bool {
+	switch s.Type {
+	case BundleSourceGit:
+		return commitSHA.MatchString(s.Revision)
+	default:
+		return strings.Contains(s.URL, "@sha256:")
+	}
+}
+
+// Fetch a compose bundle to the cache and return the path to it.
+// Immutable sources are only fetched if they are not already in the cache.
+// The fetched content is verified against the requested commit or digest
+func FetchBundle(ctx context.Context, w io.Writer, src BundleSource, bundleCache *cache.Cache) (string, error) {
+	if err := src.Validate(); err != nil {
+		return "", err
+	}
+	if src.Immutable() {
+		if dir, ok := bundleCache.Get(src.String()); ok {
+			slog.Info("Using cached bundle.", "source", src.String(), "dir", dir)
+			return dir, nil
+		}
+	}
+
+	tmpDir, err := bundleCache.TempDir()
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	slog.Info("Fetching bundle.", "source", src.String())
+	var resolved string
+	switch src.Type {
+	case BundleSourceGit:
+		resolved, err = fetchGitBundle(ctx, w, src, tmpDir)
+	case BundleSourceOCI:
+		resolved, err = fetchOCIBundle(ctx, w, src, tmpDir)
+	default:
+		err = fmt.Errorf("%w bundle source type. type=%s", ErrInvalid, src.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+	slog.Info("Fetched bundle.", "source", src.String(), "resolved", resolved)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, bundleInfoFile), []byte(src.String()+"\n"+resolved+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return bundleCache.Put(src.String(), tmpDir)
+}
+
+func runCommand(ctx context.Context, w io.Writer, dir string, name string, args ...string) (string, error) {
+	if !utils.CommandExists(name) {
+		return "", fmt.Errorf("%s cli not found", name)
+	}
+	stdout := &bytes.Buffer{}
+	prog := exec.CommandContext(ctx, name, args...)
+	prog.Dir = dir
+	prog.Stdout = stdout
+	prog.Stderr = w
+	err := prog.Run()
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// Fetch a single revision of a git repository and return the commit sha
+func fetchGitBundle(ctx context.Context, w io.Writer, src BundleSource, dir string) (string, error) {
+	revision := src.Revision
+	if revision == "" {
+		revision = "HEAD"
+	}
+	commands := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "--", "origin", src.URL},
+		{"fetch", "--quiet", "--depth", "1", "--", "origin", revision},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	}
+	for _, args := range commands {
+		if _, err := runCommand(ctx, w, dir, "git", args...); err != nil {
+			return "", fmt.Errorf("git %s failed. err=%w", args[0], err)
+		}
+	}
+
+	commit, err := runCommand(ctx, w, dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if commitSHA.MatchString(src.Revision) && commit != src.Revision {
+		return "", fmt.Errorf("git commit does not match the requested revision. expected=%s, got=%s", src.Revision, commit)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// Pull an OCI artifact (using oras) and return its digest
+func fetchOCIBundle(ctx context.Context, w io.Writer, src BundleSource, dir string) (string, error) {
+	digest, err := runCommand(ctx, w, dir, "oras", "resolve", "--", src.URL)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve artifact. ref=%s, err=%w", src.URL, err)
+	}
+	if _, expected, found := strings.Cut(src.URL, "@"); found && digest != expected {
+		return "", fmt.Errorf("artifact digest does not match the requested digest. expected=%s, got=%s", expected, digest)
+	}
+
+	// Pull by digest so that the verified content is used even if the tag is moved in the meantime
+	name, _, _ := strings.Cut(src.URL, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	if _, err := runCommand(ctx, w, dir, "oras", "pull", "--output", dir, "--", name+"@"+digest); err != nil {
+		return "", fmt.Errorf("could not pull artifact. ref=%s, err=%w", src.URL, err)
+	}
+	return digest, nil
+}
@@ -0,0 +1,65 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseBundleSource(t *testing.T) {
+	src, ok := ParseBundleSource("git+https://github.com/org/app.git#v1.0.0")
+	assert.True(t, ok)
+	assert.Equal(t, BundleSourceGit, src.Type)
+	assert.Equal(t, "https://github.com/org/app.git", src.URL)
+	assert.Equal(t, "v1.0.0", src.Revision)
+	assert.False(t, src.Immutable())
+
+	src, ok = ParseBundleSource("git+https://github.com/org/app.git#0123456789abcdef0123456789abcdef01234567")
+	assert.True(t, ok)
+	assert.True(t, src.Immutable())
+
+	src, ok = ParseBundleSource("oci://ghcr.io/org/app@" + testDigest)
+	assert.True(t, ok)
+	assert.Equal(t, BundleSourceOCI, src.Type)
+	assert.Equal(t, "ghcr.io/org/app@"+testDigest, src.URL)
+	assert.True(t, src.Immutable())
+	assert.Equal(t, "oci://ghcr.io/org/app@"+testDigest, src.String())
+
+	for _, version := range []string{"", "1.0.0", "git+", "oci://"} {
+		_, ok := ParseBundleSource(version)
+		assert.False(t, ok, version)
+	}
+}
+
+func Test_BundleSourceValidate(t *testing.T) {
+	valid := []string{
+		"git+https://github.com/org/app.git#v1.0.0",
+		"git+ssh://git@github.com/org/app.git#release/1.x",
+		"git+git@github.com:org/app.git",
+		"oci://ghcr.io/org/app:1.0.0",
+		"oci://ghcr.io/org/app@" + testDigest,
+	}
+	for _, version := range valid {
+		src, ok := ParseBundleSource(version)
+		assert.True(t, ok, version)
+		assert.NoError(t, src.Validate(), version)
+	}
+
+	invalid := []string{
+		"git+https://github.com/org/app.git#--upload-pack=touch /tmp/pwned",
+		"git+https://github.com/org/app.git#-v",
+		"git+https://github.com/org/app.git#a..b",
+		"git+--upload-pack=touch /tmp/pwned",
+		"git+ext::sh -c touch% /tmp/pwned",
+		"git+file:///etc",
+		"git+/var/lib/repo",
+		"git+http://github.com/org/app.git",
+		"oci://--insecure",
+		"oci://ghcr.io/org/app:1.0.0 --insecure",
+	}
+	for _, version := range invalid {
+		src, ok := ParseBundleSource(version)
+		assert.True(t, ok, version)
+		assert.ErrorIs(t, src.Validate(), ErrInvalid, version)
+	}
+}
@@ -0,0 +1,106 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/api/types/checkpoint"
+	"github.com/docker/docker/api/types/container"
+)
+
+var checkpointIDRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Options used to create or restore a container checkpoint (CRIU)
+type CheckpointOptions struct {
+	// Checkpoint name. A name is generated when creating a checkpoint without a name
+	ID string
+
+	// Custom checkpoint directory. Empty = use the engine's default directory
+	Dir string
+
+	// Keep the container running after the checkpoint has been created
+	LeaveRunning bool
+}
+
+// Generate a checkpoint name from the container name and the current time
+func NewCheckpointID(name string, now time.Time) string {
+	return fmt.Sprintf("%s-%s", name, now.UTC().Format("20060102T150405Z"))
+}
+
+func validateCheckpointID(id string) error {
+	if !checkpointIDRegex.MatchString(id) {
+		return fmt.Errorf("%w checkpoint name. name=%s", ErrInvalid, id)
+	}
+	return nil
+}
+
+// Check if the container engine supports checkpoints, which requires the experimental features
+// to be enabled and CRIU to be installed
+func (c *ContainerClient) CheckpointsSupported(ctx context.Context) error {
+	ping, err := c.Client.Ping(ctx)
+	if err != nil {
+		return wrapEngineError(err)
+	}
+	if !ping.Experimental {
+		return fmt.Errorf("checkpoints are %w, the container engine's experimental features are not enabled", ErrUnsupported)
+	}
+	return nil
+}
+
+// Create a checkpoint of a running container. The container is stopped unless LeaveRunning is set.
+// The name of the checkpoint is returned
+func (c *ContainerClient) CreateCheckpoint(ctx context.Context, containerName string, opts CheckpointOptions) (string, error) {
+	if err := c.CheckpointsSupported(ctx); err != nil {
+		return "", err
+	}
+	if opts.ID == "" {
+		opts.ID = NewCheckpointID(containerName, time.Now())
+	}
+	if err := validateCheckpointID(opts.ID); err != nil {
+		return "", err
+	}
+	err := c.Client.CheckpointCreate(ctx, containerName, checkpoint.CreateOptions{
+		CheckpointID:  opts.ID,
+		CheckpointDir: opts.Dir,
+		Exit:          !opts.LeaveRunning,
+	})
+	if err != nil {
+		return "", wrapEngineError(err)
+	}
+	return opts.ID, nil
+}
+
+// Restore a stopped container from a checkpoint
+func (c *ContainerClient) RestoreCheckpoint(ctx context.Context, containerName string, opts CheckpointOptions) error {
+	if err := c.CheckpointsSupported(ctx); err != nil {
+		return err
+	}
+	if opts.ID == "" {
+		return fmt.Errorf("%w checkpoint name, a name is required to restore a container", ErrInvalid)
+	}
+	if err := validateCheckpointID(opts.ID); err != nil {
+		return err
+	}
+	err := c.Client.ContainerStart(ctx, containerName, container.StartOptions{
+		CheckpointID:  opts.ID,
+		CheckpointDir: opts.Dir,
+	})
+	return wrapEngineError(err)
+}
+
+// List the names of the checkpoints of a container
+func (c *ContainerClient) ListCheckpoints(ctx context.Context, containerName string, dir string) ([]string, error) {
+	items, err := c.Client.CheckpointList(ctx, containerName, checkpoint.ListOptions{
+		CheckpointDir: dir,
+	})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names, nil
+}
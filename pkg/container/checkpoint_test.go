@@ -0,0 +1,20 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewCheckpointID(t *testing.T) {
+	id := NewCheckpointID("app1", time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))
+	assert.Equal(t, "app1-20240501T123000Z", id)
+	assert.NoError(t, validateCheckpointID(id))
+}
+
+func Test_validateCheckpointID(t *testing.T) {
+	assert.NoError(t, validateCheckpointID("before-update.1"))
+	assert.ErrorIs(t, validateCheckpointID("../etc"), ErrInvalid)
+	assert.ErrorIs(t, validateCheckpointID(""), ErrInvalid)
+}
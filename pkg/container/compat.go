@@ -0,0 +1,115 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/versions"
+)
+
+// Minimum API versions of the optional engine features
+const (
+	// One-shot stats don't wait for a second sample (Docker 20.10)
+	MinAPIVersionOneShotStats = "1.41"
+
+	// Calculating the container sizes is slow on older engines and can cause the list to time out
+	MinAPIVersionContainerSize = "1.41"
+)
+
+// Optional features of the container engine, which are detected from the negotiated API version
+type EngineFeatures struct {
+	APIVersion    string `json:"apiVersion"`
+	OneShotStats  bool   `json:"oneShotStats"`
+	ContainerSize bool   `json:"containerSize"`
+}
+
+func NewEngineFeatures(apiVersion string) EngineFeatures {
+	return EngineFeatures{
+		APIVersion:    apiVersion,
+		OneShotStats:  versions.GreaterThanOrEqualTo(apiVersion, MinAPIVersionOneShotStats),
+		ContainerSize: versions.GreaterThanOrEqualTo(apiVersion, MinAPIVersionContainerSize),
+	}
+}
+
+// Get the features supported by the container engine. The API version is negotiated on the first call,
+// and the result is cached once the engine was reachable. If the engine is not reachable then
+// only the features supported by all engines are used
+func (c *ContainerClient) Features(ctx context.Context) EngineFeatures {
+	c.featuresMutex.Lock()
+	defer c.featuresMutex.Unlock()
+	if c.features != nil {
+		return *c.features
+	}
+
+	ping, err := c.Client.Ping(ctx)
+	if err != nil {
+		return EngineFeatures{}
+	}
+	c.Client.NegotiateAPIVersionPing(ping)
+	features := NewEngineFeatures(c.Client.ClientVersion())
+	slog.Info("Detected container engine features.", "apiVersion", features.APIVersion, "oneShotStats", features.OneShotStats, "containerSize", features.ContainerSize)
+	c.features = &features
+	return features
+}
+
+// Previous cpu usage of a container, used to calculate the cpu percentage from one-shot stats
+type cpuSample struct {
+	Total  uint64
+	System uint64
+}
+
+// Get the container stats without waiting for a second sample. The cpu usage is calculated using the
+// sample of the previous call, so false is returned if there is no previous sample or the engine
+// runs on windows
+func (c *ContainerClient) getStatsOneShot(ctx context.Context, containerID string) (StatsEntry, bool, error) {
+	response, err := c.Client.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		return StatsEntry{}, false, err
+	}
+	defer response.Body.Close()
+
+	v := &container.StatsResponse{}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return StatsEntry{}, false, err
+	}
+	if response.OSType == "windows" {
+		return StatsEntry{}, false, nil
+	}
+
+	c.featuresMutex.Lock()
+	if c.cpuSamples == nil {
+		c.cpuSamples = make(map[string]cpuSample)
+	}
+	previous, ok := c.cpuSamples[containerID]
+	c.cpuSamples[containerID] = cpuSample{
+		Total:  v.CPUStats.CPUUsage.TotalUsage,
+		System: v.CPUStats.SystemUsage,
+	}
+	c.featuresMutex.Unlock()
+
+	// The container was restarted or no previous sample exists
+	if !ok || previous.System >= v.CPUStats.SystemUsage || previous.Total > v.CPUStats.CPUUsage.TotalUsage {
+		return StatsEntry{}, false, nil
+	}
+
+	mem := calculateMemUsageUnixNoCache(v.MemoryStats)
+	memLimit := float64(v.MemoryStats.Limit)
+	blkRead, blkWrite := calculateBlockIO(v.BlkioStats)
+	netRx, netTx := calculateNetwork(v.Networks)
+	return StatsEntry{
+		Container:        containerID,
+		Name:             v.Name,
+		ID:               v.ID,
+		CPUPercentage:    calculateCPUPercentUnix(previous.Total, previous.System, v),
+		Memory:           mem,
+		MemoryPercentage: calculateMemPercentUnixNoCache(memLimit, mem),
+		MemoryLimit:      memLimit,
+		NetworkRx:        netRx,
+		NetworkTx:        netTx,
+		BlockRead:        float64(blkRead),
+		BlockWrite:       float64(blkWrite),
+		PidsCurrent:      v.PidsStats.Current,
+	}, true, nil
+}
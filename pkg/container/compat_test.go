@@ -0,0 +1,20 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewEngineFeatures(t *testing.T) {
+	// Docker 19.03
+	features := NewEngineFeatures("1.40")
+	assert.False(t, features.OneShotStats)
+	assert.False(t, features.ContainerSize)
+
+	// Docker 20.10 and newer
+	features = NewEngineFeatures("1.41")
+	assert.True(t, features.OneShotStats)
+	assert.True(t, features.ContainerSize)
+	assert.True(t, NewEngineFeatures("1.47").OneShotStats)
+}
@@ -12,6 +12,7 @@ import (
 	"os/exec"
 	"regexp"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +33,12 @@ import (
 var ContainerType string = "container"
 var ContainerGroupType string = "container-group"
 
+// Label added to containers which are installed by the sm-plugin
+var LabelManaged = "tedge.managed"
+
+// Directory where the container-group (compose) projects are installed to
+var ComposeBaseDir = "/var/tedge-container-plugin/compose"
+
 func NewJSONTime(t time.Time) JSONTime {
 	return JSONTime{
 		Time: t,
@@ -94,6 +101,7 @@ type Container struct {
 	Status      string   `json:"containerStatus,omitempty"`
 	CreatedAt   string   `json:"createdAt,omitempty"`
 	Image       string   `json:"image,omitempty"`
+	ImageDigest string   `json:"imageDigest,omitempty"`
 	Ports       string   `json:"ports,omitempty"`
 	NetworkIDs  []string `json:"-"`
 	Networks    string   `json:"networks,omitempty"`
@@ -102,12 +110,21 @@ type Container struct {
 	Command     string   `json:"command,omitempty"`
 	NetworkMode string   `json:"networkMode,omitempty"`
 
+	// Managed is true if the container was installed by thin-edge.io (via the sm-plugin)
+	Managed bool `json:"managed"`
+
 	// Only used for container groups
 	ServiceName string `json:"serviceName,omitempty"`
 	ProjectName string `json:"projectName,omitempty"`
 
+	// Only used for swarm stacks
+	StackName string `json:"stackName,omitempty"`
+
 	// Private values
-	Labels map[string]string `json:"-"`
+	Labels         map[string]string `json:"-"`
+	PublishedPorts []types.Port      `json:"-"`
+	IPAddress      string            `json:"-"`
+	ImageID        string            `json:"-"`
 }
 
 func NewContainerFromDockerContainer(item *types.Container) TedgeContainer {
@@ -117,19 +134,24 @@ func NewContainerFromDockerContainer(item *types.Container) TedgeContainer {
 		State:       item.State,
 		Status:      item.Status,
 		Image:       item.Image,
+		ImageID:     item.ImageID,
 		Command:     item.Command,
 		CreatedAt:   time.Unix(item.Created, 0).Format(time.RFC3339),
 		Ports:       FormatPorts(item.Ports),
 		NetworkMode: item.HostConfig.NetworkMode,
 		Labels:      item.Labels,
+
+		PublishedPorts: item.Ports,
 	}
 
-	// Mimic filesystem
-	srw := units.HumanSizeWithPrecision(float64(item.SizeRw), 3)
-	sv := units.HumanSizeWithPrecision(float64(item.SizeRootFs), 3)
-	container.Filesystem = srw
-	if item.SizeRootFs > 0 {
-		container.Filesystem = fmt.Sprintf("%s (virtual %s)", srw, sv)
+	// Mimic filesystem. The size is not available if the engine does not support it
+	if item.SizeRw > 0 || item.SizeRootFs > 0 {
+		srw := units.HumanSizeWithPrecision(float64(item.SizeRw), 3)
+		sv := units.HumanSizeWithPrecision(float64(item.SizeRootFs), 3)
+		container.Filesystem = srw
+		if item.SizeRootFs > 0 {
+			container.Filesystem = fmt.Sprintf("%s (virtual %s)", srw, sv)
+		}
 	}
 
 	if v, ok := item.Labels["com.docker.compose.project"]; ok {
@@ -140,11 +162,15 @@ func NewContainerFromDockerContainer(item *types.Container) TedgeContainer {
 		container.ServiceName = v
 	}
 
+	container.Managed = IsManaged(item.Labels)
+	container.ImageDigest = item.Labels[LabelImageDigest]
+
 	container.NetworkIDs = make([]string, 0)
 	if item.NetworkSettings != nil && len(item.NetworkSettings.Networks) > 0 {
 		for _, v := range item.NetworkSettings.Networks {
 			container.NetworkIDs = append(container.NetworkIDs, v.NetworkID)
 		}
+		container.IPAddress = primaryIPAddress(item.HostConfig.NetworkMode, item.NetworkSettings.Networks)
 	}
 
 	containerType := ContainerType
@@ -153,6 +179,13 @@ func NewContainerFromDockerContainer(item *types.Container) TedgeContainer {
 		containerType = ContainerGroupType
 	}
 
+	// A swarm task container belongs to a "container-stack"
+	if v, ok := item.Labels[LabelStackNamespace]; ok {
+		containerType = ContainerStackType
+		container.StackName = v
+		container.ServiceName = strings.TrimPrefix(item.Labels[LabelSwarmServiceName], v+"_")
+	}
+
 	return TedgeContainer{
 		Name: container.GetName(),
 		Time: JSONTime{
@@ -164,6 +197,26 @@ func NewContainerFromDockerContainer(item *types.Container) TedgeContainer {
 	}
 }
 
+// Check if a container was installed by thin-edge.io, either by the managed label
+// or by being a compose project which was installed by the container-group sm-plugin
+func IsManaged(labels map[string]string) bool {
+	if v, ok := labels[LabelManaged]; ok && strings.EqualFold(v, "true") {
+		return true
+	}
+	if v, ok := labels["com.docker.compose.project.working_dir"]; ok {
+		return strings.HasPrefix(v, ComposeBaseDir+"/")
+	}
+	return false
+}
+
+// Get the source of the container, either "managed" or "external"
+func (c *Container) Source() string {
+	if c.Managed {
+		return "managed"
+	}
+	return "external"
+}
+
 func (c *Container) GetName() string {
 	if c.ProjectName == "" {
 		return c.Name
@@ -180,6 +233,25 @@ func ConvertToTedgeStatus(v string) string {
 	}
 }
 
+// Get the IP address of the container in its primary network, which is the network given by the network mode,
+// otherwise the first network (sorted by name) which has an address
+func primaryIPAddress(networkMode string, networks map[string]*network.EndpointSettings) string {
+	if v, ok := networks[networkMode]; ok && v != nil && v.IPAddress != "" {
+		return v.IPAddress
+	}
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := networks[name]; v != nil && v.IPAddress != "" {
+			return v.IPAddress
+		}
+	}
+	return ""
+}
+
 func FormatPorts(values []types.Port) string {
 	formatted := make([]string, 0, len(values))
 	for _, port := range values {
@@ -202,6 +274,25 @@ func ConvertName(v []string) string {
 
 type ContainerClient struct {
 	Client *client.Client
+
+	// TLS settings of the private registries by host, e.g. registry.local:5000
+	Registries map[string]RegistryTLS
+
+	// Mirror of each upstream registry which images are pulled from, e.g. docker.io => harbor.local/dockerhub
+	Mirrors map[string]string
+
+	// Grace period for containers to stop (SIGTERM) before they are killed when they are removed.
+	// Containers which were created with a longer stop timeout use their own. 0 = container's stop timeout
+	StopTimeout time.Duration
+
+	// The client runs in a container which does not share the host's network namespace (e.g. the monitor
+	// is deployed as a container), so host ports can't be probed
+	Containerized bool
+
+	featuresMutex sync.Mutex
+	features      *EngineFeatures
+	cpuSamples    map[string]cpuSample
+	proxyCheck    sync.Once
 }
 
 func socketExists(p string) bool {
@@ -261,8 +352,11 @@ type LowPrecisionFloat struct {
 }
 
 func (l LowPrecisionFloat) MarshalJSON() ([]byte, error) {
-	s := fmt.Sprintf("%.*f", l.Digits, l.Value)
-	return []byte(s), nil
+	return []byte(l.String()), nil
+}
+
+func (l LowPrecisionFloat) String() string {
+	return fmt.Sprintf("%.*f", l.Digits, l.Value)
 }
 
 func NewLowerPrecisionFloat64(value float64, precision int) LowPrecisionFloat {
@@ -278,7 +372,37 @@ type ContainerStats struct {
 	NetIO  LowPrecisionFloat `json:"netio"`
 }
 
+// Get the stats as a map of series name to value
+func (s ContainerStats) Series() map[string]LowPrecisionFloat {
+	return map[string]LowPrecisionFloat{
+		"cpu":    s.Cpu,
+		"memory": s.Memory,
+		"netio":  s.NetIO,
+	}
+}
+
+// Get the container stats. If the engine supports one-shot stats, then the cpu usage is the average
+// since the previous call, otherwise the engine waits for a second sample to calculate the cpu usage
 func (c *ContainerClient) GetStats(ctx context.Context, containerID string) (*ContainerTelemetryMessage, error) {
+	s, err := c.GetStatsEntry(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return NewContainerTelemetryMessage(s), nil
+}
+
+// Get the raw container stats, which include the values which are not published (e.g. block io)
+func (c *ContainerClient) GetStatsEntry(ctx context.Context, containerID string) (StatsEntry, error) {
+	if c.Features(ctx).OneShotStats {
+		entry, ok, err := c.getStatsOneShot(ctx, containerID)
+		if err != nil {
+			slog.Debug("Could not get one-shot stats, falling back to regular stats.", "container", containerID, "err", err)
+		}
+		if ok && err == nil {
+			return entry, nil
+		}
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	containerStats := &Stats{
@@ -290,16 +414,18 @@ func (c *ContainerClient) GetStats(ctx context.Context, containerID string) (*Co
 	// Start collecting statistics
 	collect(ctx, containerStats, c.Client, false, &wg)
 	wg.Wait()
+	return containerStats.GetStatistics(), nil
+}
 
-	s := containerStats.GetStatistics()
-	stats := &ContainerTelemetryMessage{
+// Normalize the stats to the telemetry message which is published by the monitor
+func NewContainerTelemetryMessage(s StatsEntry) *ContainerTelemetryMessage {
+	return &ContainerTelemetryMessage{
 		Container: ContainerStats{
 			Cpu:    NewLowerPrecisionFloat64(s.CPUPercentage, 2),
 			Memory: NewLowerPrecisionFloat64(s.MemoryPercentage, 2),
 			NetIO:  NewLowerPrecisionFloat64(s.NetworkTx, 0),
 		},
 	}
-	return stats, nil
 }
 
 type FilterOptions struct {
@@ -307,16 +433,51 @@ type FilterOptions struct {
 	Labels []string
 	IDs    []string
 
+	// Only include containers in the given states, e.g. running or exited (evaluated by the engine)
+	States []string
+
+	// Maximum number of containers which are read, the most recently created containers are kept. 0 = unlimited
+	Limit int
+
 	// Client side filters
 	Types            []string
 	ExcludeNames     []string
 	ExcludeWithLabel []string
+
+	// Exclude the containers with the given ids (or id prefixes), e.g. the container of the monitor itself
+	ExcludeIDs []string
+
+	// Exclude the ephemeral containers of build and CI tools (see DefaultExcludeNames and DefaultExcludeLabels)
+	ExcludeDefaults bool
+}
+
+// Name patterns of ephemeral containers which are excluded by default, e.g. buildkit builders and the testcontainers reaper
+var DefaultExcludeNames = []string{
+	"^buildx_buildkit_",
+	"^testcontainers-ryuk-",
+}
+
+// Labels of ephemeral containers which are excluded by default, e.g. testcontainers and GitLab CI job containers
+var DefaultExcludeLabels = []string{
+	"org.testcontainers",
+	"com.gitlab.gitlab-runner.managed",
 }
 
 func (fo FilterOptions) IsEmpty() bool {
 	return len(fo.Names) == 0 && len(fo.Labels) == 0 && len(fo.IDs) == 0
 }
 
+// Add the default exclusions to the exclude filters (if enabled)
+func (fo FilterOptions) withDefaultExcludes() FilterOptions {
+	if !fo.ExcludeDefaults {
+		return fo
+	}
+	fo.ExcludeNames = slices.Concat(fo.ExcludeNames, DefaultExcludeNames)
+	fo.ExcludeWithLabel = slices.Concat(fo.ExcludeWithLabel, DefaultExcludeLabels)
+	fo.ExcludeDefaults = false
+	return fo
+}
+
 func (c *ContainerClient) GetContainer(ctx context.Context, containerID string) (*TedgeContainer, error) {
 	containers, err := c.List(ctx, FilterOptions{
 		IDs: []string{containerID},
@@ -325,22 +486,70 @@ func (c *ContainerClient) GetContainer(ctx context.Context, containerID string)
 		return nil, err
 	}
 	if len(containers) == 0 {
-		return nil, fmt.Errorf("container not found")
+		return nil, fmt.Errorf("container %w", ErrNotFound)
 	}
 	return &containers[0], nil
 }
 
+// Find a container by its thin-edge.io service name.
+// The service name of a container-group container is in the format <project>@<service>
+func (c *ContainerClient) FindByServiceName(ctx context.Context, name string) (*TedgeContainer, error) {
+	options := FilterOptions{
+		Names: []string{name},
+	}
+	if project, service, found := strings.Cut(name, "@"); found {
+		options = FilterOptions{
+			Labels: []string{
+				"com.docker.compose.project=" + project,
+				"com.docker.compose.service=" + service,
+			},
+		}
+	}
+	containers, err := c.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range containers {
+		if item.Name == name {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("container %w. name=%s", ErrNotFound, name)
+}
+
 // Stop and remove a container
 // Don't fail if the container does not exist
 func (c *ContainerClient) StopRemoveContainer(ctx context.Context, containerID string) error {
-	slog.Info("Stopping container.", "id", containerID)
-	err := c.Client.ContainerStop(ctx, containerID, container.StopOptions{})
+	return c.StopRemoveContainerWithTimeout(ctx, containerID, c.StopTimeout)
+}
+
+// Stop and remove a container, where the container is given the timeout (or its own stop timeout if it is longer)
+// to stop gracefully before it is killed. 0 = use the container's stop timeout
+func (c *ContainerClient) StopRemoveContainerWithTimeout(ctx context.Context, containerID string, timeout time.Duration) error {
+	ownTimeout := time.Duration(0)
+	if info, err := c.Client.ContainerInspect(ctx, containerID); err == nil && info.Config != nil && info.Config.StopTimeout != nil {
+		ownTimeout = time.Duration(*info.Config.StopTimeout) * time.Second
+	}
+	options, grace := ResolveStopTimeout(timeout, ownTimeout)
+
+	slog.Info("Stopping container.", "id", containerID, "timeout", grace)
+	stopCtx := ctx
+	if grace > 0 {
+		// Don't wait forever if the engine does not respond
+		var cancel context.CancelFunc
+		stopCtx, cancel = context.WithTimeout(ctx, grace+StopTimeoutMargin)
+		defer cancel()
+	}
+	err := c.Client.ContainerStop(stopCtx, containerID, options)
 	if err != nil {
 		if errdefs.IsNotFound(err) {
 			slog.Info("Container does not exist, so nothing to stop")
 			return nil
 		}
-		return err
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("container did not stop within the timeout. id=%s, timeout=%s, err=%w", containerID, grace, err)
+		}
+		return wrapEngineError(err)
 	}
 	slog.Info("Removing container.", "id", containerID)
 	err = c.Client.ContainerRemove(ctx, containerID, container.RemoveOptions{
@@ -353,15 +562,39 @@ func (c *ContainerClient) StopRemoveContainer(ctx context.Context, containerID s
 			return nil
 		}
 	}
-	return err
+	return wrapEngineError(err)
+}
+
+// Result of listing the containers
+type ListResult struct {
+	Items []TedgeContainer
+
+	// The list was cut off by the limit, so it does not include all containers
+	Truncated bool
 }
 
 func (c *ContainerClient) List(ctx context.Context, options FilterOptions) ([]TedgeContainer, error) {
+	result, err := c.ListBounded(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// List the containers matching the filter options. The number of containers which are read from the engine
+// is bounded by the limit, and a warning is logged if the list was truncated
+func (c *ContainerClient) ListBounded(ctx context.Context, options FilterOptions) (*ListResult, error) {
+	options = options.withDefaultExcludes()
+
 	// Filter for docker compose projects
 	listOptions := container.ListOptions{
-		Size: true,
+		Size: c.Features(ctx).ContainerSize,
 		All:  true,
 	}
+	if options.Limit > 0 {
+		// Read one additional container to detect if the list is truncated
+		listOptions.Limit = options.Limit + 1
+	}
 
 	filterValues := make([]filters.KeyValuePair, 0)
 
@@ -383,6 +616,14 @@ func (c *ContainerClient) List(ctx context.Context, options FilterOptions) ([]Te
 
 	// filterValues = append(filterValues, filters.Arg("label", "com.docker.compose.project"))
 
+	// Match by state
+	for _, state := range options.States {
+		filterValues = append(filterValues, filters.KeyValuePair{
+			Key:   "status",
+			Value: state,
+		})
+	}
+
 	// Match by label
 	for _, label := range options.Labels {
 		filterValues = append(filterValues, filters.KeyValuePair{
@@ -397,7 +638,13 @@ func (c *ContainerClient) List(ctx context.Context, options FilterOptions) ([]Te
 
 	containers, err := c.Client.ContainerList(ctx, listOptions)
 	if err != nil {
-		return nil, err
+		return nil, wrapEngineError(err)
+	}
+
+	truncated := options.Limit > 0 && len(containers) > options.Limit
+	if truncated {
+		slog.Warn("Number of containers exceeds the limit. Only the most recently created containers are included.", "limit", options.Limit)
+		containers = containers[:options.Limit]
 	}
 
 	// Pre-compile regular expressions
@@ -411,8 +658,11 @@ func (c *ContainerClient) List(ctx context.Context, options FilterOptions) ([]Te
 	}
 
 	items := make([]TedgeContainer, 0, len(containers))
-	for _, i := range containers {
-		item := NewContainerFromDockerContainer(&i)
+	for index := range containers {
+		item := NewContainerFromDockerContainer(&containers[index])
+
+		// Release the engine's representation as soon as it is converted
+		containers[index] = types.Container{}
 
 		// Apply client side filters
 		if len(options.Types) > 0 {
@@ -445,10 +695,19 @@ func (c *ContainerClient) List(ctx context.Context, options FilterOptions) ([]Te
 				continue
 			}
 		}
+
+		if len(options.ExcludeIDs) > 0 && slices.ContainsFunc(options.ExcludeIDs, func(id string) bool {
+			return strings.HasPrefix(item.Container.Id, id)
+		}) {
+			continue
+		}
 		items = append(items, item)
 	}
 
-	return items, nil
+	return &ListResult{
+		Items:     items,
+		Truncated: truncated,
+	}, nil
 }
 
 func (c *ContainerClient) MonitorEvents(ctx context.Context) (<-chan events.Message, <-chan error) {
@@ -531,15 +790,19 @@ func (c *ContainerClient) runComposeInContainer(ctx context.Context, projectName
 
 }
 
-// Create shared network
-func (c *ContainerClient) CreateSharedNetwork(ctx context.Context, name string) error {
+// Create shared network. The network options are only used if the network does not already exist
+func (c *ContainerClient) CreateSharedNetwork(ctx context.Context, name string, opts NetworkOptions) error {
 	netw, err := c.Client.NetworkInspect(ctx, name, network.InspectOptions{})
 	if err != nil {
 		if !errdefs.IsNotFound(err) {
 			return err
 		}
+		createOptions, err := opts.CreateOptions()
+		if err != nil {
+			return err
+		}
 		// Create network
-		netwResp, err := c.Client.NetworkCreate(ctx, name, network.CreateOptions{})
+		netwResp, err := c.Client.NetworkCreate(ctx, name, createOptions)
 		if err != nil {
 			return err
 		}
@@ -551,6 +814,11 @@ func (c *ContainerClient) CreateSharedNetwork(ctx context.Context, name string)
 	return nil
 }
 
+// Create a dedicated network of a container (using the default network options) if it does not already exist
+func (c *ContainerClient) CreateNetwork(ctx context.Context, name string) error {
+	return c.CreateSharedNetwork(ctx, name, NetworkOptions{})
+}
+
 func (c *ContainerClient) DockerCommand(args ...string) (string, []string, error) {
 	return prepareDockerCommand(args...)
 }
@@ -573,6 +841,19 @@ func (c *ContainerClient) ComposeUp(ctx context.Context, w io.Writer, projectNam
 	return nil
 }
 
+func (c *ContainerClient) composeDownInDir(w io.Writer, projectName string, workingDir string, extraArgs ...string) error {
+	command, args, err := prepareComposeCommand(append([]string{"down", "--remove-orphans"}, extraArgs...)...)
+	if err != nil {
+		return err
+	}
+	slog.Info("Stopping compose project.", "name", projectName, "dir", workingDir, "command", command, "args", strings.Join(args, " "))
+	prog := exec.Command(command, args...)
+	prog.Dir = workingDir
+	out, err := prog.Output()
+	fmt.Fprintf(w, "%s", out)
+	return err
+}
+
 func (c *ContainerClient) ComposeDown(ctx context.Context, w io.Writer, projectName string) error {
 	// TODO: Read setting from configuration
 	manualCleanup := false
@@ -598,16 +879,7 @@ func (c *ContainerClient) ComposeDown(ctx context.Context, w io.Writer, projectN
 
 	// Find
 	if workingDir != "" && utils.PathExists(workingDir) {
-		command, args, err := prepareComposeCommand("down", "--remove-orphans", "--volumes")
-		if err != nil {
-			return err
-		}
-		slog.Info("Stopping compose project.", "name", projectName, "dir", workingDir, "command", command, "args", strings.Join(args, " "))
-		prog := exec.Command(command, args...)
-		prog.Dir = workingDir
-		out, err := prog.Output()
-		fmt.Fprintf(w, "%s", out)
-
+		err := c.composeDownInDir(w, projectName, workingDir, "--volumes")
 		if err == nil {
 			slog.Info("Removing project directory.", "dir", workingDir)
 			if err := os.RemoveAll(workingDir); err != nil {
@@ -0,0 +1,85 @@
+package container
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Label which declares the containers (comma separated names) that a container has to be restarted with,
+// e.g. tedge.restart_with=db restarts the container after the db container was restarted
+var LabelRestartWith = "tedge.restart_with"
+
+// Get the names of the containers which the container has to be restarted with
+func restartDependencies(item TedgeContainer) []string {
+	dependencies := make([]string, 0)
+	for _, name := range strings.Split(item.Container.Labels[LabelRestartWith], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies
+}
+
+// Get the order in which the containers are restarted when the given container is restarted. The container is
+// restarted first, followed by its dependents (direct and indirect), where each dependent is restarted after
+// all of its dependencies
+func RestartOrder(items []TedgeContainer, name string) ([]string, error) {
+	dependents := make(map[string][]string)
+	dependencies := make(map[string][]string)
+	found := false
+	for _, item := range items {
+		if item.Container.Name == name {
+			found = true
+		}
+		for _, dependency := range restartDependencies(item) {
+			dependents[dependency] = append(dependents[dependency], item.Container.Name)
+			dependencies[item.Container.Name] = append(dependencies[item.Container.Name], dependency)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("container %w. name=%s", ErrNotFound, name)
+	}
+
+	// Find all containers which are (indirectly) restarted with the container
+	included := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if !included[dependent] {
+				included[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	// Order the containers so that the dependencies are restarted first. Only the dependencies
+	// which are restarted are considered
+	order := make([]string, 0, len(included))
+	for len(order) < len(included) {
+		ready := make([]string, 0)
+		for candidate := range included {
+			if slices.Contains(order, candidate) {
+				continue
+			}
+			pending := slices.ContainsFunc(dependencies[candidate], func(dependency string) bool {
+				return included[dependency] && !slices.Contains(order, dependency) && dependency != candidate
+			})
+			if !pending || candidate == name {
+				ready = append(ready, candidate)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, fmt.Errorf("%w restart dependencies, the dependencies contain a cycle. name=%s", ErrInvalid, name)
+		}
+		sort.Strings(ready)
+		if slices.Contains(ready, name) {
+			ready = []string{name}
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
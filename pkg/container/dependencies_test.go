@@ -0,0 +1,44 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDependentContainer(name string, restartWith string) TedgeContainer {
+	item := TedgeContainer{Container: Container{Name: name, Labels: map[string]string{}}}
+	if restartWith != "" {
+		item.Container.Labels[LabelRestartWith] = restartWith
+	}
+	return item
+}
+
+func Test_RestartOrder(t *testing.T) {
+	items := []TedgeContainer{
+		newDependentContainer("db", ""),
+		newDependentContainer("api", "db"),
+		newDependentContainer("worker", "api, db"),
+		newDependentContainer("cache", ""),
+		newDependentContainer("ui", "api"),
+	}
+
+	order, err := RestartOrder(items, "db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db", "api", "ui", "worker"}, order)
+
+	order, err = RestartOrder(items, "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cache"}, order)
+
+	_, err = RestartOrder(items, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	cycle := []TedgeContainer{
+		newDependentContainer("db", ""),
+		newDependentContainer("a", "db,b"),
+		newDependentContainer("b", "a"),
+	}
+	_, err = RestartOrder(cycle, "db")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,90 @@
+package container
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/distribution/reference"
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Labels used to record the image which was requested when installing a container
+var (
+	// Image reference as requested, e.g. nginx:1.27 or nginx@sha256:...
+	LabelImageRef = "tedge.image.ref"
+
+	// Repository digest of the image, e.g. nginx@sha256:...
+	LabelImageDigest = "tedge.image.digest"
+)
+
+// Get the repository digest of an image, e.g. nginx@sha256:...
+// An empty string is returned if the image does not have a repository digest, e.g. it was built or loaded locally
+func (c *ContainerClient) GetImageDigest(ctx context.Context, imageRef string) (string, error) {
+	info, _, err := c.Client.ImageInspectWithRaw(ctx, imageRef)
+	if err != nil {
+		return "", wrapEngineError(err)
+	}
+	return matchRepoDigest(imageRef, info.RepoDigests), nil
+}
+
+// Find the repository digest which matches the image reference.
+// If the image reference already contains a digest, then it is used as is
+func matchRepoDigest(imageRef string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return ""
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		if digested, err := reference.WithDigest(reference.TrimNamed(named), canonical.Digest()); err == nil {
+			return reference.FamiliarString(digested)
+		}
+	}
+	for _, value := range repoDigests {
+		digested, err := reference.ParseNormalizedNamed(value)
+		if err != nil {
+			continue
+		}
+		if digested.Name() == named.Name() {
+			return reference.FamiliarString(digested)
+		}
+	}
+	return ""
+}
+
+// Filter matching a container by its exact name
+func NameFilter(name string) filters.KeyValuePair {
+	return filters.Arg("name", "^/?"+regexp.QuoteMeta(name)+"$")
+}
+
+// Filter matching the containers of a compose project
+func ProjectFilter(projectName string) filters.KeyValuePair {
+	return filters.Arg("label", "com.docker.compose.project="+projectName)
+}
+
+// Get the image digests used by the containers matching the filter (e.g. a container name
+// or compose project label). The image id is used if the image digest was not recorded
+func (c *ContainerClient) GetContainerImageDigests(ctx context.Context, filter filters.KeyValuePair) []string {
+	digests := make([]string, 0)
+	items, err := c.Client.ContainerList(ctx, containerSDK.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filter),
+	})
+	if err != nil {
+		return digests
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		digest := item.Labels[LabelImageDigest]
+		if digest == "" {
+			digest = item.ImageID
+		}
+		if digest != "" && !seen[digest] {
+			seen[digest] = true
+			digests = append(digests, digest)
+		}
+	}
+	sort.Strings(digests)
+	return digests
+}
@@ -0,0 +1,22 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDigest = "sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac"
+
+func Test_MatchRepoDigest(t *testing.T) {
+	repoDigests := []string{
+		"registry.local/nginx@" + testDigest,
+		"nginx@" + testDigest,
+	}
+	assert.Equal(t, "nginx@"+testDigest, matchRepoDigest("nginx:1.27", repoDigests))
+	assert.Equal(t, "registry.local/nginx@"+testDigest, matchRepoDigest("registry.local/nginx:latest", repoDigests))
+	assert.Equal(t, "", matchRepoDigest("app:latest", repoDigests))
+
+	// Pinned references are used as is (without the tag)
+	assert.Equal(t, "nginx@"+testDigest, matchRepoDigest("nginx:1.27@"+testDigest, nil))
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+var ContainerEngineType string = "container-engine"
+
+type EngineStatus struct {
+	Status       string `json:"-"`
+	Host         string `json:"host"`
+	SocketExists bool   `json:"socketExists"`
+	APIVersion   string `json:"apiVersion,omitempty"`
+	// API version used by the client, which can be lower than the engine's API version
+	NegotiatedAPIVersion string          `json:"negotiatedApiVersion,omitempty"`
+	Features             *EngineFeatures `json:"features,omitempty"`
+	ServerVersion        string          `json:"serverVersion,omitempty"`
+	OSType               string          `json:"osType,omitempty"`
+	// Container engine backend, e.g. docker or podman
+	Name  string   `json:"name,omitempty"`
+	Error string   `json:"error,omitempty"`
+	Time  JSONTime `json:"-"`
+}
+
+// Check if the container engine is reachable.
+// The engine is considered "up" if the socket exists (for unix sockets) and it responds to a ping
+func (c *ContainerClient) GetEngineStatus(ctx context.Context) EngineStatus {
+	status := EngineStatus{
+		Status:       "down",
+		Host:         c.Client.DaemonHost(),
+		SocketExists: true,
+		Time:         NewJSONTime(time.Now()),
+	}
+
+	if strings.HasPrefix(status.Host, "unix://") {
+		status.SocketExists = socketExists(status.Host)
+		if !status.SocketExists {
+			status.Error = "socket does not exist"
+			return status
+		}
+	}
+
+	ping, err := c.Client.Ping(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Status = "up"
+	status.APIVersion = ping.APIVersion
+	status.OSType = ping.OSType
+
+	if version, err := c.Client.ServerVersion(ctx); err == nil {
+		status.ServerVersion = version.Version
+		status.Name = engineName(version)
+	}
+	features := c.Features(ctx)
+	status.NegotiatedAPIVersion = features.APIVersion
+	status.Features = &features
+	return status
+}
+
+// Get the name of the container engine backend from its version information, e.g. docker or podman
+func engineName(version types.Version) string {
+	names := []string{version.Platform.Name}
+	for _, component := range version.Components {
+		names = append(names, component.Name)
+	}
+	for _, name := range names {
+		if strings.Contains(strings.ToLower(name), "podman") {
+			return "podman"
+		}
+	}
+	return "docker"
+}
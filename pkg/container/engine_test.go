@@ -0,0 +1,17 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_engineName(t *testing.T) {
+	docker := types.Version{Components: []types.ComponentVersion{{Name: "Engine"}, {Name: "containerd"}}}
+	docker.Platform.Name = "Docker Engine - Community"
+	assert.Equal(t, "docker", engineName(docker))
+
+	podman := types.Version{Components: []types.ComponentVersion{{Name: "Podman Engine"}}}
+	assert.Equal(t, "podman", engineName(podman))
+}
@@ -0,0 +1,46 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+var (
+	// The container engine could not be reached (e.g. the daemon is not running)
+	ErrEngineUnavailable = errors.New("container engine is unavailable")
+
+	// The requested resource (e.g. container, image or profile) does not exist
+	ErrNotFound = errors.New("not found")
+
+	// The container is protected and can only be removed using --force
+	ErrProtected = errors.New("protected")
+
+	// The user provided input (e.g. container name or image reference) is not valid
+	ErrInvalid = errors.New("invalid")
+
+	// The container engine does not support the requested feature (e.g. checkpoints)
+	ErrUnsupported = errors.New("unsupported")
+)
+
+// Wrap an error returned by the container engine with one of the package's
+// error types so that callers can check it using errors.Is
+func wrapEngineError(err error) error {
+	if err == nil || errors.Is(err, ErrEngineUnavailable) || errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if client.IsErrConnectionFailed(err) {
+		return fmt.Errorf("%w: %w", ErrEngineUnavailable, err)
+	}
+	if errdefs.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
+}
+
+// Check if an error is temporary and the action can be retried later
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrEngineUnavailable)
+}
@@ -0,0 +1,21 @@
+package container
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WrapEngineError(t *testing.T) {
+	assert.Nil(t, wrapEngineError(nil))
+
+	err := wrapEngineError(errdefs.NotFound(errors.New("no such container: app")))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, IsRetryable(err))
+
+	err = wrapEngineError(errors.New("other error"))
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrEngineUnavailable)
+}
@@ -0,0 +1,158 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	FilterResultPass    = "pass"
+	FilterResultFail    = "fail"
+	FilterResultSkipped = "not configured"
+)
+
+// Result of a single filter rule
+type FilterStep struct {
+	Filter string   `json:"filter"`
+	Values []string `json:"values,omitempty"`
+	Result string   `json:"result"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// Explanation why a container is included or excluded by the filters
+type FilterExplanation struct {
+	Name        string       `json:"name"`
+	ID          string       `json:"id"`
+	ServiceType string       `json:"serviceType"`
+	Included    bool         `json:"included"`
+	Steps       []FilterStep `json:"steps"`
+}
+
+// Find a container by its name, service name or id (prefix) and explain how the filters are evaluated against it
+func (c *ContainerClient) ExplainFilter(ctx context.Context, nameOrID string, options FilterOptions) (*FilterExplanation, error) {
+	containers, err := c.Client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	for _, i := range containers {
+		item := NewContainerFromDockerContainer(&i)
+		if item.Container.Name == nameOrID || item.Name == nameOrID || (len(nameOrID) >= 4 && strings.HasPrefix(i.ID, nameOrID)) {
+			explanation := ExplainFilter(item, options)
+			return &explanation, nil
+		}
+	}
+	return nil, fmt.Errorf("container %w. name=%s", ErrNotFound, nameOrID)
+}
+
+// Evaluate the filters against a container, in the same order as they are applied when listing the containers.
+// The include filters (names, ids, labels and states) are evaluated by the container engine, the others are client side filters
+func ExplainFilter(item TedgeContainer, options FilterOptions) FilterExplanation {
+	options = options.withDefaultExcludes()
+	explanation := FilterExplanation{
+		Name:        item.Name,
+		ID:          item.Container.Id,
+		ServiceType: item.ServiceType,
+		Included:    true,
+	}
+	addStep := func(filter string, values []string, pass bool, reason string) {
+		step := FilterStep{
+			Filter: filter,
+			Values: values,
+			Result: FilterResultPass,
+			Reason: reason,
+		}
+		if len(values) == 0 {
+			step.Result = FilterResultSkipped
+		} else if !pass {
+			step.Result = FilterResultFail
+			explanation.Included = false
+		}
+		explanation.Steps = append(explanation.Steps, step)
+	}
+
+	// Include by name (any of the patterns). The engine matches the pattern against the container name
+	match := firstMatch(options.Names, func(pattern string) bool {
+		p, err := regexp.Compile(pattern)
+		return err == nil && p.MatchString("/"+item.Container.Name)
+	})
+	addStep("include.names", options.Names, match != "", matchReason(match, "container name does not match any pattern"))
+
+	// Include by id (any of the id prefixes)
+	match = firstMatch(options.IDs, func(value string) bool {
+		return strings.HasPrefix(item.Container.Id, value)
+	})
+	addStep("include.ids", options.IDs, match != "", matchReason(match, "container id does not match any id"))
+
+	// Include by label (all of the labels)
+	missing := ""
+	for _, label := range options.Labels {
+		key, value, hasValue := strings.Cut(label, "=")
+		actual, ok := item.Container.Labels[key]
+		if !ok || (hasValue && actual != value) {
+			missing = label
+			break
+		}
+	}
+	labelReason := "matched all labels"
+	if missing != "" {
+		labelReason = "container does not have the label. label=" + missing
+	}
+	addStep("include.labels", options.Labels, missing == "", labelReason)
+
+	// Include by state (any of the states)
+	stateReason := ""
+	if len(options.States) > 0 && !slices.Contains(options.States, item.Container.State) {
+		stateReason = "state is not included. state=" + item.Container.State
+	}
+	addStep("include.states", options.States, stateReason == "", stateReason)
+
+	// Include by type
+	typeReason := ""
+	if len(options.Types) > 0 && !slices.Contains(options.Types, item.ServiceType) {
+		typeReason = "type is not included. type=" + item.ServiceType
+	}
+	addStep("include.types", options.Types, typeReason == "", typeReason)
+
+	// Exclude by name
+	match = firstMatch(options.ExcludeNames, func(pattern string) bool {
+		p, err := regexp.Compile(pattern)
+		return err == nil && (p.MatchString(item.Container.Name) || p.MatchString(item.Name))
+	})
+	addStep("exclude.names", options.ExcludeNames, match == "", matchReason(match, ""))
+
+	// Exclude by label
+	match = firstMatch(options.ExcludeWithLabel, func(label string) bool {
+		_, ok := item.Container.Labels[label]
+		return ok
+	})
+	addStep("exclude.labels", options.ExcludeWithLabel, match == "", matchReason(match, ""))
+
+	// Exclude by id (any of the id prefixes)
+	match = firstMatch(options.ExcludeIDs, func(value string) bool {
+		return strings.HasPrefix(item.Container.Id, value)
+	})
+	addStep("exclude.ids", options.ExcludeIDs, match == "", matchReason(match, ""))
+
+	return explanation
+}
+
+func firstMatch(values []string, matches func(string) bool) string {
+	for _, value := range values {
+		if matches(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+func matchReason(match string, fallback string) string {
+	if match != "" {
+		return "matched " + match
+	}
+	return fallback
+}
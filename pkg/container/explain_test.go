@@ -0,0 +1,77 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExplainFilter(t *testing.T) {
+	item := TedgeContainer{
+		Name:        "app1",
+		ServiceType: ContainerType,
+		Container: Container{
+			Id:     "a1b2c3d4e5",
+			Name:   "app1",
+			Labels: map[string]string{"env": "prod"},
+		},
+	}
+
+	explanation := ExplainFilter(item, FilterOptions{
+		Names:  []string{"^/app"},
+		Labels: []string{"env=prod"},
+		Types:  []string{ContainerType},
+	})
+	assert.True(t, explanation.Included)
+	assert.Equal(t, FilterResultPass, explanation.Steps[0].Result)
+	assert.Equal(t, FilterResultSkipped, explanation.Steps[1].Result)
+
+	explanation = ExplainFilter(item, FilterOptions{
+		Labels:           []string{"env=dev"},
+		ExcludeWithLabel: []string{"env"},
+	})
+	assert.False(t, explanation.Included)
+	assert.Equal(t, FilterResultFail, explanation.Steps[2].Result)
+	assert.Equal(t, "container does not have the label. label=env=dev", explanation.Steps[2].Reason)
+	assert.Equal(t, FilterResultFail, explanation.Steps[6].Result)
+	assert.Equal(t, "matched env", explanation.Steps[6].Reason)
+
+	item.Container.State = "exited"
+	explanation = ExplainFilter(item, FilterOptions{
+		States: []string{"running", "paused"},
+	})
+	assert.False(t, explanation.Included)
+	assert.Equal(t, "include.states", explanation.Steps[3].Filter)
+	assert.Equal(t, "state is not included. state=exited", explanation.Steps[3].Reason)
+
+	explanation = ExplainFilter(item, FilterOptions{
+		ExcludeIDs: []string{"a1b2"},
+	})
+	assert.False(t, explanation.Included)
+	assert.Equal(t, "exclude.ids", explanation.Steps[7].Filter)
+	assert.Equal(t, FilterResultFail, explanation.Steps[7].Result)
+}
+
+func Test_ExplainFilterDefaultExcludes(t *testing.T) {
+	item := TedgeContainer{
+		Name:        "buildx_buildkit_builder0",
+		ServiceType: ContainerType,
+		Container: Container{
+			Id:   "b1c2d3e4",
+			Name: "buildx_buildkit_builder0",
+		},
+	}
+	assert.True(t, ExplainFilter(item, FilterOptions{}).Included)
+
+	explanation := ExplainFilter(item, FilterOptions{ExcludeDefaults: true})
+	assert.False(t, explanation.Included)
+	assert.Equal(t, "exclude.names", explanation.Steps[5].Filter)
+	assert.Equal(t, "matched ^buildx_buildkit_", explanation.Steps[5].Reason)
+
+	item.Name = "postgres"
+	item.Container.Name = "postgres"
+	item.Container.Labels = map[string]string{"org.testcontainers": "true"}
+	explanation = ExplainFilter(item, FilterOptions{ExcludeDefaults: true, ExcludeWithLabel: []string{"tedge.ignore"}})
+	assert.False(t, explanation.Included)
+	assert.Equal(t, "matched org.testcontainers", explanation.Steps[6].Reason)
+}
@@ -0,0 +1,67 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+type ExportType string
+
+const (
+	// Export the container's image (docker save format)
+	ExportImage ExportType = "image"
+
+	// Export the container's filesystem, including any changes made at runtime (docker export format)
+	ExportFilesystem ExportType = "filesystem"
+)
+
+// Details of an exported container
+type ExportInfo struct {
+	Name    string     `json:"name"`
+	Type    ExportType `json:"type"`
+	Image   string     `json:"image"`
+	ImageID string     `json:"imageId"`
+}
+
+// Export a container's image or filesystem as a tarball, e.g. to capture the exact binaries
+// which were involved in a field incident
+func (c *ContainerClient) Export(ctx context.Context, containerName string, exportType ExportType, w io.Writer) (*ExportInfo, error) {
+	info, err := c.Client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	export := &ExportInfo{
+		Name:    ConvertName([]string{info.Name}),
+		Type:    exportType,
+		Image:   info.Config.Image,
+		ImageID: info.Image,
+	}
+
+	var reader io.ReadCloser
+	switch exportType {
+	case ExportFilesystem:
+		reader, err = c.Client.ContainerExport(ctx, info.ID)
+	case ExportImage:
+		// Only use the image reference (which keeps the image's tags) if it still refers to
+		// the image used by the container, otherwise the exact image is saved by its id
+		ref := info.Image
+		if imageInfo, _, inspectErr := c.Client.ImageInspectWithRaw(ctx, info.Config.Image); inspectErr == nil && imageInfo.ID == info.Image {
+			ref = info.Config.Image
+		}
+		slog.Info("Saving container image.", "name", export.Name, "image", ref)
+		reader, err = c.Client.ImageSave(ctx, []string{ref})
+	default:
+		return nil, fmt.Errorf("%w export type. type=%s", ErrInvalid, exportType)
+	}
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
@@ -0,0 +1,131 @@
+package container
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type HealthPolicy string
+
+const (
+	// All member containers must be up
+	HealthPolicyAllUp HealthPolicy = "all-up"
+
+	// A minimum ratio of the member containers must be up
+	HealthPolicyQuorum HealthPolicy = "quorum"
+
+	// Only the containers marked as critical (via a label) must be up
+	HealthPolicyCritical HealthPolicy = "critical"
+)
+
+var DefaultCriticalLabel = "tedge.critical"
+
+type ProjectHealthOptions struct {
+	Policy HealthPolicy
+
+	// Minimum ratio (0-1) of containers which must be up when using the quorum policy
+	Quorum float64
+
+	// Label used to mark a container as critical
+	CriticalLabel string
+}
+
+type ProjectHealth struct {
+	Name     string   `json:"-"`
+	Status   string   `json:"status"`
+	Total    int      `json:"total"`
+	Up       int      `json:"up"`
+	Policy   string   `json:"policy"`
+	Critical []string `json:"critical,omitempty"`
+	Time     JSONTime `json:"-"`
+
+	// Containers belonging to the project
+	Members []TedgeContainer `json:"-"`
+}
+
+// Group containers by their compose project name.
+// Containers which don't belong to a project are ignored
+func GroupByProject(items []TedgeContainer) map[string][]TedgeContainer {
+	projects := make(map[string][]TedgeContainer)
+	for _, item := range items {
+		if item.Container.ProjectName == "" {
+			continue
+		}
+		projects[item.Container.ProjectName] = append(projects[item.Container.ProjectName], item)
+	}
+	return projects
+}
+
+func isCritical(item TedgeContainer, label string) bool {
+	if label == "" {
+		return false
+	}
+	value, ok := item.Container.Labels[label]
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "", "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Compute the aggregated health of a compose project from its member containers
+func AggregateProjectHealth(name string, members []TedgeContainer, opts ProjectHealthOptions) ProjectHealth {
+	policy := opts.Policy
+	if policy == "" {
+		policy = HealthPolicyAllUp
+	}
+
+	health := ProjectHealth{
+		Name:     name,
+		Total:    len(members),
+		Policy:   string(policy),
+		Critical: make([]string, 0),
+		Time:     NewJSONTime(time.Now()),
+		Members:  members,
+	}
+
+	criticalUp := true
+	for _, item := range members {
+		up := item.Status == "up"
+		if up {
+			health.Up++
+		}
+		if isCritical(item, opts.CriticalLabel) {
+			health.Critical = append(health.Critical, item.Name)
+			if !up {
+				criticalUp = false
+			}
+		}
+	}
+	sort.Strings(health.Critical)
+
+	healthy := false
+	switch policy {
+	case HealthPolicyQuorum:
+		quorum := opts.Quorum
+		if quorum <= 0 || quorum > 1 {
+			quorum = 0.5
+		}
+		healthy = health.Total > 0 && float64(health.Up)/float64(health.Total) >= quorum
+	case HealthPolicyCritical:
+		if len(health.Critical) == 0 {
+			// Fallback to all-up if no containers are marked as critical
+			healthy = health.Total > 0 && health.Up == health.Total
+		} else {
+			healthy = criticalUp
+		}
+	default:
+		healthy = health.Total > 0 && health.Up == health.Total
+	}
+
+	health.Status = "down"
+	if healthy {
+		health.Status = "up"
+	}
+	return health
+}
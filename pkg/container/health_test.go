@@ -0,0 +1,64 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProjectMember(name string, status string, labels map[string]string) TedgeContainer {
+	return TedgeContainer{
+		Name:   "app@" + name,
+		Status: status,
+		Container: Container{
+			ProjectName: "app",
+			ServiceName: name,
+			Labels:      labels,
+		},
+	}
+}
+
+func Test_AggregateProjectHealthAllUp(t *testing.T) {
+	members := []TedgeContainer{
+		newProjectMember("db", "up", nil),
+		newProjectMember("web", "down", nil),
+	}
+	health := AggregateProjectHealth("app", members, ProjectHealthOptions{})
+	assert.Equal(t, "down", health.Status)
+	assert.Equal(t, 2, health.Total)
+	assert.Equal(t, 1, health.Up)
+
+	members[1].Status = "up"
+	assert.Equal(t, "up", AggregateProjectHealth("app", members, ProjectHealthOptions{}).Status)
+}
+
+func Test_AggregateProjectHealthQuorum(t *testing.T) {
+	members := []TedgeContainer{
+		newProjectMember("a", "up", nil),
+		newProjectMember("b", "up", nil),
+		newProjectMember("c", "down", nil),
+	}
+	opts := ProjectHealthOptions{Policy: HealthPolicyQuorum, Quorum: 0.6}
+	assert.Equal(t, "up", AggregateProjectHealth("app", members, opts).Status)
+
+	opts.Quorum = 0.8
+	assert.Equal(t, "down", AggregateProjectHealth("app", members, opts).Status)
+}
+
+func Test_AggregateProjectHealthCritical(t *testing.T) {
+	members := []TedgeContainer{
+		newProjectMember("db", "up", map[string]string{DefaultCriticalLabel: "true"}),
+		newProjectMember("web", "down", nil),
+	}
+	opts := ProjectHealthOptions{Policy: HealthPolicyCritical, CriticalLabel: DefaultCriticalLabel}
+	health := AggregateProjectHealth("app", members, opts)
+	assert.Equal(t, "up", health.Status)
+	assert.Equal(t, []string{"app@db"}, health.Critical)
+
+	members[0].Status = "down"
+	assert.Equal(t, "down", AggregateProjectHealth("app", members, opts).Status)
+}
+
+func Test_AggregateProjectHealthEmpty(t *testing.T) {
+	assert.Equal(t, "down", AggregateProjectHealth("app", nil, ProjectHealthOptions{}).Status)
+}
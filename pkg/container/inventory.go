@@ -0,0 +1,190 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Maximum number of networks and volumes which are included in the inventory (sorted by name)
+var MaxInventoryItems = 100
+
+// Summary of a network of the container engine
+type NetworkSummary struct {
+	Name       string   `json:"name"`
+	Driver     string   `json:"driver"`
+	Subnets    []string `json:"subnets,omitempty"`
+	Containers int      `json:"containers"`
+}
+
+// Summary of a volume of the container engine. The size is -1 if the engine can't calculate it (e.g. non-local drivers).
+// The volume was last used when one of the containers which mount it was started or stopped
+type VolumeSummary struct {
+	Name       string     `json:"name"`
+	Driver     string     `json:"driver"`
+	Size       int64      `json:"size"`
+	Containers int64      `json:"containers"`
+	LastUsed   *time.Time `json:"lastUsed,omitempty"`
+}
+
+type NetworkInventory struct {
+	Count     int              `json:"count"`
+	Items     []NetworkSummary `json:"items"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+type VolumeInventory struct {
+	Count     int             `json:"count"`
+	Size      int64           `json:"size"`
+	Items     []VolumeSummary `json:"items"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// Inventory of the engine resources beyond the containers
+type Inventory struct {
+	Networks NetworkInventory
+	Volumes  VolumeInventory
+}
+
+// Create the network inventory. The containers are counted from the container list, as the
+// engine does not include the endpoints when listing the networks
+func NewNetworkInventory(networks []network.Summary, containers []types.Container, limit int) NetworkInventory {
+	counts := make(map[string]int)
+	for _, item := range containers {
+		if item.NetworkSettings == nil {
+			continue
+		}
+		for name := range item.NetworkSettings.Networks {
+			counts[name]++
+		}
+	}
+
+	inventory := NetworkInventory{
+		Count: len(networks),
+		Items: make([]NetworkSummary, 0, len(networks)),
+	}
+	for _, item := range networks {
+		summary := NetworkSummary{
+			Name:       item.Name,
+			Driver:     item.Driver,
+			Containers: counts[item.Name],
+		}
+		for _, config := range item.IPAM.Config {
+			if config.Subnet != "" {
+				summary.Subnets = append(summary.Subnets, config.Subnet)
+			}
+		}
+		inventory.Items = append(inventory.Items, summary)
+	}
+	sort.Slice(inventory.Items, func(i, j int) bool {
+		return inventory.Items[i].Name < inventory.Items[j].Name
+	})
+	if limit > 0 && len(inventory.Items) > limit {
+		inventory.Items = inventory.Items[:limit]
+		inventory.Truncated = true
+	}
+	return inventory
+}
+
+// Create the volume inventory. lastUsed is the time each volume (by name) was last used by a container
+func NewVolumeInventory(volumes []*volume.Volume, lastUsed map[string]time.Time, limit int) VolumeInventory {
+	inventory := VolumeInventory{
+		Items: make([]VolumeSummary, 0, len(volumes)),
+	}
+	for _, item := range volumes {
+		if item == nil {
+			continue
+		}
+		summary := VolumeSummary{
+			Name:   item.Name,
+			Driver: item.Driver,
+			Size:   -1,
+		}
+		if item.UsageData != nil {
+			summary.Size = item.UsageData.Size
+			summary.Containers = max(item.UsageData.RefCount, 0)
+		}
+		if summary.Size > 0 {
+			inventory.Size += summary.Size
+		}
+		if t, ok := lastUsed[item.Name]; ok {
+			summary.LastUsed = &t
+		}
+		inventory.Items = append(inventory.Items, summary)
+	}
+	inventory.Count = len(inventory.Items)
+	sort.Slice(inventory.Items, func(i, j int) bool {
+		return inventory.Items[i].Name < inventory.Items[j].Name
+	})
+	if limit > 0 && len(inventory.Items) > limit {
+		inventory.Items = inventory.Items[:limit]
+		inventory.Truncated = true
+	}
+	return inventory
+}
+
+// Get the time each volume was last used, which is the latest time that one of the containers
+// which mount the volume was started or stopped
+func (c *ContainerClient) getVolumesLastUsed(ctx context.Context, containers []types.Container) map[string]time.Time {
+	lastUsed := make(map[string]time.Time)
+	for _, item := range containers {
+		volumes := make([]string, 0, len(item.Mounts))
+		for _, m := range item.Mounts {
+			if m.Type == mount.TypeVolume && m.Name != "" {
+				volumes = append(volumes, m.Name)
+			}
+		}
+		if len(volumes) == 0 {
+			continue
+		}
+		details, err := c.Client.ContainerInspect(ctx, item.ID)
+		if err != nil || details.State == nil {
+			slog.Debug("Could not inspect container.", "id", item.ID, "err", err)
+			continue
+		}
+		used := time.Time{}
+		for _, value := range []string{details.State.StartedAt, details.State.FinishedAt} {
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil && t.After(used) {
+				used = t
+			}
+		}
+		if used.IsZero() {
+			continue
+		}
+		for _, name := range volumes {
+			if used.After(lastUsed[name]) {
+				lastUsed[name] = used.UTC()
+			}
+		}
+	}
+	return lastUsed
+}
+
+// Get the inventory of the networks and volumes
+func (c *ContainerClient) GetInventory(ctx context.Context) (*Inventory, error) {
+	containers, err := c.Client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	networks, err := c.Client.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	usage, err := c.Client.DiskUsage(ctx, types.DiskUsageOptions{
+		Types: []types.DiskUsageObject{types.VolumeObject},
+	})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	return &Inventory{
+		Networks: NewNetworkInventory(networks, containers, MaxInventoryItems),
+		Volumes:  NewVolumeInventory(usage.Volumes, c.getVolumesLastUsed(ctx, containers), MaxInventoryItems),
+	}, nil
+}
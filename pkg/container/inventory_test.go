@@ -0,0 +1,53 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewNetworkInventory(t *testing.T) {
+	networks := []network.Summary{
+		{Name: "tedge", Driver: "bridge", IPAM: network.IPAM{Config: []network.IPAMConfig{{Subnet: "172.20.0.0/16"}}}},
+		{Name: "bridge", Driver: "bridge", IPAM: network.IPAM{Config: []network.IPAMConfig{{Subnet: "172.17.0.0/16"}}}},
+		{Name: "host", Driver: "host"},
+	}
+	containers := []types.Container{
+		{NetworkSettings: &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{"tedge": {}}}},
+		{NetworkSettings: &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{"tedge": {}, "bridge": {}}}},
+		{},
+	}
+	inventory := NewNetworkInventory(networks, containers, 2)
+	assert.Equal(t, 3, inventory.Count)
+	assert.True(t, inventory.Truncated)
+	assert.Equal(t, []NetworkSummary{
+		{Name: "bridge", Driver: "bridge", Subnets: []string{"172.17.0.0/16"}, Containers: 1},
+		{Name: "host", Driver: "host"},
+	}, inventory.Items)
+
+	inventory = NewNetworkInventory(networks, containers, 0)
+	assert.False(t, inventory.Truncated)
+	assert.Equal(t, 2, inventory.Items[2].Containers)
+}
+
+func Test_NewVolumeInventory(t *testing.T) {
+	lastUsed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	volumes := []*volume.Volume{
+		{Name: "data", Driver: "local", UsageData: &volume.UsageData{Size: 2048, RefCount: 1}},
+		{Name: "cache", Driver: "local", UsageData: &volume.UsageData{Size: 1024, RefCount: 0}},
+		{Name: "remote", Driver: "nfs", UsageData: &volume.UsageData{Size: -1, RefCount: -1}},
+		nil,
+	}
+	inventory := NewVolumeInventory(volumes, map[string]time.Time{"data": lastUsed}, 0)
+	assert.Equal(t, 3, inventory.Count)
+	assert.Equal(t, int64(3072), inventory.Size)
+	assert.Equal(t, "cache", inventory.Items[0].Name)
+	assert.Nil(t, inventory.Items[0].LastUsed)
+	assert.Equal(t, &lastUsed, inventory.Items[1].LastUsed)
+	assert.Equal(t, int64(-1), inventory.Items[2].Size)
+	assert.Equal(t, int64(0), inventory.Items[2].Containers)
+}
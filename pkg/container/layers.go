@@ -0,0 +1,113 @@
+package container
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+)
+
+// Maximum number of images which are included in the layer report (the images with the largest unique size are kept)
+var MaxLayerReportImages = 25
+
+// Disk usage of an image, split into the layers shared with other images and the layers which are unique to the image
+type ImageLayerUsage struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	Size       int64  `json:"size"`
+	Shared     int64  `json:"shared"`
+	Unique     int64  `json:"unique"`
+	Containers int64  `json:"containers"`
+}
+
+// Report of the disk space used by the image layers, similar to "docker system df -v". The unique size of an
+// image is the space which is freed when the image is removed, or added when the image is pulled on a device
+// with the same shared layers
+type LayerReport struct {
+	// Disk space used by all image layers, where each layer is only counted once
+	LayersSize int64 `json:"layersSize"`
+
+	// Sum of the image sizes, where the shared layers are counted once per image
+	ImagesSize int64 `json:"imagesSize"`
+
+	// Disk space saved by sharing layers between images
+	SharedSavings int64 `json:"sharedSavings"`
+
+	// Total number of images
+	Count int `json:"count"`
+
+	// Images sorted by the unique size (largest first)
+	Images    []ImageLayerUsage `json:"images"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+func imageDisplayName(item *image.Summary) string {
+	for _, tag := range item.RepoTags {
+		if tag != "<none>:<none>" {
+			return tag
+		}
+	}
+	if len(item.RepoDigests) > 0 {
+		return item.RepoDigests[0]
+	}
+	return shortImageID(item.ID)
+}
+
+func shortImageID(id string) string {
+	if len(id) > 19 {
+		// sha256: prefix + 12 characters
+		return id[:19]
+	}
+	return id
+}
+
+// Create a layer report from the engine's disk usage. limit is the maximum number of images which are included, 0 = unlimited
+func NewLayerReport(usage types.DiskUsage, limit int) LayerReport {
+	report := LayerReport{
+		LayersSize: usage.LayersSize,
+		Images:     make([]ImageLayerUsage, 0, len(usage.Images)),
+	}
+	for _, item := range usage.Images {
+		if item == nil {
+			continue
+		}
+		// The shared size is -1 if it was not calculated
+		shared := max(item.SharedSize, 0)
+		report.ImagesSize += item.Size
+		report.Images = append(report.Images, ImageLayerUsage{
+			Name:       imageDisplayName(item),
+			ID:         shortImageID(item.ID),
+			Size:       item.Size,
+			Shared:     shared,
+			Unique:     item.Size - shared,
+			Containers: max(item.Containers, 0),
+		})
+	}
+	report.Count = len(report.Images)
+	report.SharedSavings = max(report.ImagesSize-report.LayersSize, 0)
+
+	sort.SliceStable(report.Images, func(i, j int) bool {
+		if report.Images[i].Unique != report.Images[j].Unique {
+			return report.Images[i].Unique > report.Images[j].Unique
+		}
+		return report.Images[i].Name < report.Images[j].Name
+	})
+	if limit > 0 && len(report.Images) > limit {
+		report.Images = report.Images[:limit]
+		report.Truncated = true
+	}
+	return report
+}
+
+// Get the report of the disk space used by the image layers
+func (c *ContainerClient) GetLayerReport(ctx context.Context) (*LayerReport, error) {
+	usage, err := c.Client.DiskUsage(ctx, types.DiskUsageOptions{
+		Types: []types.DiskUsageObject{types.ImageObject},
+	})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+	report := NewLayerReport(usage, MaxLayerReportImages)
+	return &report, nil
+}
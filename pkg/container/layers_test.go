@@ -0,0 +1,37 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewLayerReport(t *testing.T) {
+	usage := types.DiskUsage{
+		LayersSize: 250,
+		Images: []*image.Summary{
+			{ID: "sha256:aaaaaaaaaaaaaaaaaaaa", RepoTags: []string{"app1:1.0"}, Size: 120, SharedSize: 80, Containers: 1},
+			{ID: "sha256:bbbbbbbbbbbbbbbbbbbb", RepoTags: []string{"<none>:<none>"}, Size: 100, SharedSize: 80},
+			{ID: "sha256:cccccccccccccccccccc", RepoTags: []string{"app3:2.0"}, Size: 110, SharedSize: -1, Containers: -1},
+		},
+	}
+	report := NewLayerReport(usage, 2)
+	assert.Equal(t, int64(250), report.LayersSize)
+	assert.Equal(t, int64(330), report.ImagesSize)
+	assert.Equal(t, int64(80), report.SharedSavings)
+	assert.Equal(t, 3, report.Count)
+	assert.True(t, report.Truncated)
+	assert.Len(t, report.Images, 2)
+
+	assert.Equal(t, "app3:2.0", report.Images[0].Name)
+	assert.Equal(t, int64(110), report.Images[0].Unique)
+	assert.Equal(t, int64(0), report.Images[0].Containers)
+	assert.Equal(t, "app1:1.0", report.Images[1].Name)
+	assert.Equal(t, int64(40), report.Images[1].Unique)
+
+	report = NewLayerReport(usage, 0)
+	assert.False(t, report.Truncated)
+	assert.Equal(t, "sha256:bbbbbbbbbbbb", report.Images[2].Name)
+}
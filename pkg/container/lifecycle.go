@@ -0,0 +1,61 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Stop timeout which the engine uses if neither the container nor the request sets one
+var DefaultStopTimeout = 10 * time.Second
+
+// Additional time to wait for the engine's response after the stop timeout elapsed, e.g. to kill the container
+var StopTimeoutMargin = 30 * time.Second
+
+// Get the stop options for the requested timeout and the container's own stop timeout (0 = not set), and
+// the resulting grace period. The longer of the two timeouts is used, and a negative grace period waits forever
+func ResolveStopTimeout(timeout time.Duration, ownTimeout time.Duration) (container.StopOptions, time.Duration) {
+	if ownTimeout < 0 {
+		return container.StopOptions{}, ownTimeout
+	}
+	if timeout <= ownTimeout {
+		if ownTimeout == 0 {
+			return container.StopOptions{}, DefaultStopTimeout
+		}
+		return container.StopOptions{}, ownTimeout
+	}
+	seconds := int(math.Ceil(timeout.Seconds()))
+	return container.StopOptions{Timeout: &seconds}, time.Duration(seconds) * time.Second
+}
+
+// Start a stopped container
+func (c *ContainerClient) StartContainer(ctx context.Context, containerID string) error {
+	slog.Info("Starting container.", "id", containerID)
+	return wrapEngineError(c.Client.ContainerStart(ctx, containerID, container.StartOptions{}))
+}
+
+// Stop a running container using the container's stop timeout
+func (c *ContainerClient) StopContainer(ctx context.Context, containerID string) error {
+	slog.Info("Stopping container.", "id", containerID)
+	return wrapEngineError(c.Client.ContainerStop(ctx, containerID, container.StopOptions{}))
+}
+
+// Restart a container using the container's stop timeout
+func (c *ContainerClient) RestartContainer(ctx context.Context, containerID string) error {
+	slog.Info("Restarting container.", "id", containerID)
+	return wrapEngineError(c.Client.ContainerRestart(ctx, containerID, container.StopOptions{}))
+}
+
+// Disable the restart policy of a container, so it is not restarted by the engine when it exits
+func (c *ContainerClient) DisableRestart(ctx context.Context, containerID string) error {
+	slog.Info("Disabling container restart policy.", "id", containerID)
+	_, err := c.Client.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyDisabled,
+		},
+	})
+	return wrapEngineError(err)
+}
@@ -0,0 +1,34 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveStopTimeout(t *testing.T) {
+	// Engine default
+	options, grace := ResolveStopTimeout(0, 0)
+	assert.Nil(t, options.Timeout)
+	assert.Equal(t, DefaultStopTimeout, grace)
+
+	// Container's own stop timeout
+	options, grace = ResolveStopTimeout(0, 60*time.Second)
+	assert.Nil(t, options.Timeout)
+	assert.Equal(t, 60*time.Second, grace)
+
+	// The longer timeout is used
+	options, grace = ResolveStopTimeout(30*time.Second, 60*time.Second)
+	assert.Nil(t, options.Timeout)
+	assert.Equal(t, 60*time.Second, grace)
+
+	options, grace = ResolveStopTimeout(1500*time.Millisecond, 0)
+	assert.Equal(t, 2, *options.Timeout)
+	assert.Equal(t, 2*time.Second, grace)
+
+	// Wait forever
+	options, grace = ResolveStopTimeout(30*time.Second, -time.Second)
+	assert.Nil(t, options.Timeout)
+	assert.Less(t, grace, time.Duration(0))
+}
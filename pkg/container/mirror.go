@@ -0,0 +1,57 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+)
+
+// Get the image reference of the mirror of the image's registry, e.g. docker.io/library/nginx:latest
+// is pulled from harbor.local/dockerhub/library/nginx:latest. Returns false if the registry has no mirror.
+// References which include a digest are not mirrored, as the pulled image can only be tagged by name
+func MirrorRef(imageRef string, mirrors map[string]string) (string, bool, error) {
+	if len(mirrors) == 0 {
+		return "", false, nil
+	}
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", false, fmt.Errorf("%w image reference. %w", ErrInvalid, err)
+	}
+	mirror, ok := mirrors[reference.Domain(named)]
+	if !ok || mirror == "" {
+		return "", false, nil
+	}
+	if _, ok := named.(reference.Digested); ok {
+		slog.Info("Image reference includes a digest, so it is not pulled from the mirror.", "ref", imageRef, "mirror", mirror)
+		return "", false, nil
+	}
+
+	ref := strings.TrimSuffix(mirror, "/") + "/" + reference.Path(named)
+	if tagged, ok := reference.TagNameOnly(named).(reference.Tagged); ok {
+		ref += ":" + tagged.Tag()
+	}
+	mirrored, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", false, fmt.Errorf("%w mirror reference. mirror=%s. %w", ErrInvalid, mirror, err)
+	}
+	return mirrored.String(), true, nil
+}
+
+// Pull the image from the mirror of its registry, and tag it using the original reference so it
+// can be used as if it was pulled from the upstream registry. Returns false if the registry has no mirror
+func (c *ContainerClient) pullFromMirror(ctx context.Context, imageRef string, opts image.PullOptions, w io.Writer) (bool, error) {
+	mirrorRef, ok, err := MirrorRef(imageRef, c.Mirrors)
+	if err != nil || !ok {
+		return false, err
+	}
+	slog.Info("Pulling image from mirror.", "ref", imageRef, "mirror", mirrorRef)
+	if err := c.pullImage(ctx, mirrorRef, opts, w); err != nil {
+		return true, err
+	}
+	return true, wrapEngineError(c.Client.ImageTag(ctx, mirrorRef, imageRef))
+}
@@ -0,0 +1,37 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MirrorRef(t *testing.T) {
+	mirrors := map[string]string{
+		"docker.io":       "harbor.local/dockerhub/",
+		"ghcr.io":         "harbor.local:8443",
+		"registry.vendor": "",
+	}
+
+	ref, ok, err := MirrorRef("nginx", mirrors)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "harbor.local/dockerhub/library/nginx:latest", ref)
+
+	ref, ok, err = MirrorRef("ghcr.io/thin-edge/tedge:1.4.0", mirrors)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "harbor.local:8443/thin-edge/tedge:1.4.0", ref)
+
+	// no mirror
+	_, ok, err = MirrorRef("quay.io/app:1.0", mirrors)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, _ = MirrorRef("registry.vendor/app:1.0", mirrors)
+	assert.False(t, ok)
+
+	// digests are not mirrored
+	_, ok, err = MirrorRef("nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000", mirrors)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
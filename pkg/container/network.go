@@ -0,0 +1,74 @@
+package container
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+// Settings used when creating the shared network
+type NetworkOptions struct {
+	EnableIPv6 bool
+
+	// Subnets in CIDR format, e.g. 172.20.0.0/16 or fd00:20::/64
+	Subnets []string
+
+	// Gateway of each subnet (optional). The gateways are matched to the subnets by their position
+	Gateways []string
+
+	// Maximum transmission unit. 0 = engine default
+	MTU int
+}
+
+// Convert the settings to the network creation options
+func (o NetworkOptions) CreateOptions() (network.CreateOptions, error) {
+	opts := network.CreateOptions{
+		Labels: map[string]string{
+			LabelManaged: "true",
+		},
+	}
+
+	if len(o.Gateways) > len(o.Subnets) {
+		return opts, fmt.Errorf("%w network gateways, each gateway must have a subnet. subnets=%v, gateways=%v", ErrInvalid, o.Subnets, o.Gateways)
+	}
+
+	enableIPv6 := o.EnableIPv6
+	if len(o.Subnets) > 0 {
+		opts.IPAM = &network.IPAM{
+			Config: make([]network.IPAMConfig, 0, len(o.Subnets)),
+		}
+		for i, value := range o.Subnets {
+			subnet, err := netip.ParsePrefix(value)
+			if err != nil {
+				return opts, fmt.Errorf("%w network subnet. value=%s", ErrInvalid, value)
+			}
+			config := network.IPAMConfig{
+				Subnet: subnet.Masked().String(),
+			}
+			if i < len(o.Gateways) && o.Gateways[i] != "" {
+				gateway, err := netip.ParseAddr(o.Gateways[i])
+				if err != nil || !subnet.Contains(gateway) {
+					return opts, fmt.Errorf("%w network gateway, it must be an address within the subnet. gateway=%s, subnet=%s", ErrInvalid, o.Gateways[i], value)
+				}
+				config.Gateway = gateway.String()
+			}
+			// IPv6 must be enabled to use an IPv6 subnet
+			if subnet.Addr().Is6() {
+				enableIPv6 = true
+			}
+			opts.IPAM.Config = append(opts.IPAM.Config, config)
+		}
+	}
+	if enableIPv6 {
+		opts.EnableIPv6 = &enableIPv6
+	}
+
+	if o.MTU > 0 {
+		opts.Options = map[string]string{
+			"com.docker.network.driver.mtu": strconv.Itoa(o.MTU),
+		}
+	}
+	return opts, nil
+}
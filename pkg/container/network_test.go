@@ -0,0 +1,32 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NetworkCreateOptions(t *testing.T) {
+	opts, err := NetworkOptions{}.CreateOptions()
+	assert.NoError(t, err)
+	assert.Nil(t, opts.IPAM)
+	assert.Nil(t, opts.EnableIPv6)
+	assert.Equal(t, "true", opts.Labels[LabelManaged])
+
+	opts, err = NetworkOptions{
+		Subnets:  []string{"172.20.0.0/16", "fd00:20::/64"},
+		Gateways: []string{"172.20.0.1"},
+		MTU:      1400,
+	}.CreateOptions()
+	assert.NoError(t, err)
+	assert.Len(t, opts.IPAM.Config, 2)
+	assert.Equal(t, "172.20.0.1", opts.IPAM.Config[0].Gateway)
+	assert.Equal(t, "fd00:20::/64", opts.IPAM.Config[1].Subnet)
+	assert.True(t, *opts.EnableIPv6)
+	assert.Equal(t, "1400", opts.Options["com.docker.network.driver.mtu"])
+
+	_, err = NetworkOptions{Subnets: []string{"172.20.0.0/16"}, Gateways: []string{"10.0.0.1"}}.CreateOptions()
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = NetworkOptions{Subnets: []string{"invalid"}}.CreateOptions()
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,236 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// Container options (module metadata) which are applied when the container is created.
+// The options are provided as a json file instead of an image file, e.g.
+//
+//	{"image": "nginx:1.27", "restartPolicy": "on-failure", "restartMaxRetries": 5, "networks": ["app-backend"]}
+type ContainerOptions struct {
+	// Image to use if the module version is not set
+	Image string `json:"image,omitempty"`
+
+	// Restart policy: no, always, on-failure or unless-stopped. Defaults to always
+	RestartPolicy string `json:"restartPolicy,omitempty"`
+
+	// Maximum number of restarts when using the on-failure policy
+	RestartMaxRetries int `json:"restartMaxRetries,omitempty"`
+
+	// Override the image's HEALTHCHECK
+	HealthCheck *HealthCheckOptions `json:"healthcheck,omitempty"`
+
+	// Publish the container ports on fixed host ports, e.g. ["8080:80", "127.0.0.1:1883:1883/tcp"].
+	// All exposed ports are published on random host ports if no ports are given
+	Ports []string `json:"ports,omitempty"`
+
+	// Fixed IPv4/IPv6 address within the shared network. The engine only supports fixed addresses
+	// if the network was created with a subnet, and the address must be within the subnet
+	IPAddress   string `json:"ipAddress,omitempty"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
+
+	// Additional names which the container can be resolved by within the shared network
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Dedicated networks which the container is connected to in addition to the shared network, e.g. ["app-backend"].
+	// They are created by thin-edge.io if they don't exist, and removed together with the container once no other
+	// container is connected to them (container.remove_network)
+	Networks []string `json:"networks,omitempty"`
+
+	// User (name or uid[:gid]) which the container runs as, e.g. "1000:1000". Defaults to the image's user
+	User string `json:"user,omitempty"`
+
+	// User namespace mode, e.g. "host" (docker) or "auto" (podman). Defaults to the engine's default
+	UsernsMode string `json:"usernsMode,omitempty"`
+
+	// Grace period for the container to stop (SIGTERM) before it is killed, e.g. "60s". Defaults to container.stop_timeout
+	StopTimeout string `json:"stopTimeout,omitempty"`
+}
+
+type HealthCheckOptions struct {
+	// Command to run, e.g. ["CMD", "curl", "-f", "http://localhost"] or ["NONE"] to disable the image's healthcheck
+	Test        []string `json:"test,omitempty"`
+	Interval    string   `json:"interval,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+	StartPeriod string   `json:"startPeriod,omitempty"`
+	Retries     int      `json:"retries,omitempty"`
+}
+
+// Read the container options from a file. False is returned if the file does
+// not contain container options (e.g. it is an image file)
+func ReadContainerOptions(path string) (*ContainerOptions, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	// Only json objects are container options
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(512)
+	if !bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) {
+		return nil, false, nil
+	}
+
+	options := &ContainerOptions{}
+	if err := json.NewDecoder(reader).Decode(options); err != nil {
+		return nil, true, fmt.Errorf("%w container options. file=%s, err=%w", ErrInvalid, path, err)
+	}
+	return options, true, nil
+}
+
+func parseOptionalDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w %s. value=%s", ErrInvalid, name, value)
+	}
+	return d, nil
+}
+
+// Get the endpoint settings of the shared network
+func (o ContainerOptions) NetworkEndpoint(networkName string) (*network.EndpointSettings, error) {
+	endpoint := &network.EndpointSettings{
+		NetworkID: networkName,
+		Aliases:   o.Aliases,
+	}
+	for _, alias := range o.Aliases {
+		if err := ValidateHostname(alias); err != nil {
+			return nil, fmt.Errorf("%w network alias, it must be a valid hostname (RFC 1123). alias=%s", ErrInvalid, alias)
+		}
+	}
+	if o.IPAddress != "" || o.IPv6Address != "" {
+		ipv4, err := parseOptionalIP(o.IPAddress, false)
+		if err != nil {
+			return nil, err
+		}
+		ipv6, err := parseOptionalIP(o.IPv6Address, true)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: ipv4,
+			IPv6Address: ipv6,
+		}
+	}
+	return endpoint, nil
+}
+
+// Get the dedicated networks of the container. The shared network and the engine's builtin networks can't be used
+func (o ContainerOptions) DedicatedNetworks(sharedNetwork string) ([]string, error) {
+	networks := make([]string, 0, len(o.Networks))
+	for _, name := range o.Networks {
+		if err := ValidateContainerName(name); err != nil || name == sharedNetwork || slices.Contains(builtinNetworks, name) {
+			return nil, fmt.Errorf("%w network. Networks must be valid names and not the shared or a builtin network. network=%s", ErrInvalid, name)
+		}
+		if !slices.Contains(networks, name) {
+			networks = append(networks, name)
+		}
+	}
+	return networks, nil
+}
+
+func parseOptionalIP(value string, ipv6 bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil || addr.Is6() != ipv6 {
+		return "", fmt.Errorf("%w ip address. value=%s", ErrInvalid, value)
+	}
+	return addr.String(), nil
+}
+
+// Apply the options to the container configuration
+func (o ContainerOptions) Apply(config *containerSDK.Config, hostConfig *containerSDK.HostConfig) error {
+	hostConfig.RestartPolicy = containerSDK.RestartPolicy{
+		Name: containerSDK.RestartPolicyAlways,
+	}
+	if o.RestartPolicy != "" {
+		policy := containerSDK.RestartPolicyMode(o.RestartPolicy)
+		switch policy {
+		case containerSDK.RestartPolicyDisabled, containerSDK.RestartPolicyAlways, containerSDK.RestartPolicyUnlessStopped:
+			hostConfig.RestartPolicy.Name = policy
+		case containerSDK.RestartPolicyOnFailure:
+			hostConfig.RestartPolicy.Name = policy
+			hostConfig.RestartPolicy.MaximumRetryCount = o.RestartMaxRetries
+		default:
+			return fmt.Errorf("%w restart policy. Only no, always, on-failure or unless-stopped are supported. value=%s", ErrInvalid, o.RestartPolicy)
+		}
+	}
+
+	if len(o.Ports) > 0 {
+		exposed, bindings, err := ParsePortBindings(o.Ports)
+		if err != nil {
+			return err
+		}
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = exposed
+		} else {
+			for port := range exposed {
+				config.ExposedPorts[port] = struct{}{}
+			}
+		}
+		hostConfig.PortBindings = bindings
+		hostConfig.PublishAllPorts = false
+	}
+
+	if o.User != "" {
+		if err := ValidateUser(o.User); err != nil {
+			return err
+		}
+		config.User = o.User
+	}
+	if o.UsernsMode != "" {
+		hostConfig.UsernsMode = containerSDK.UsernsMode(o.UsernsMode)
+	}
+
+	stopTimeout, err := parseOptionalDuration("stop timeout", o.StopTimeout)
+	if err != nil {
+		return err
+	}
+	if stopTimeout < 0 {
+		return fmt.Errorf("%w stop timeout. The timeout must not be negative. value=%s", ErrInvalid, o.StopTimeout)
+	}
+	if stopTimeout > 0 {
+		seconds := int(math.Ceil(stopTimeout.Seconds()))
+		config.StopTimeout = &seconds
+	}
+
+	if o.HealthCheck != nil {
+		interval, err := parseOptionalDuration("healthcheck interval", o.HealthCheck.Interval)
+		if err != nil {
+			return err
+		}
+		timeout, err := parseOptionalDuration("healthcheck timeout", o.HealthCheck.Timeout)
+		if err != nil {
+			return err
+		}
+		startPeriod, err := parseOptionalDuration("healthcheck start period", o.HealthCheck.StartPeriod)
+		if err != nil {
+			return err
+		}
+		config.Healthcheck = &containerSDK.HealthConfig{
+			Test:        o.HealthCheck.Test,
+			Interval:    interval,
+			Timeout:     timeout,
+			StartPeriod: startPeriod,
+			Retries:     o.HealthCheck.Retries,
+		}
+	}
+	return nil
+}
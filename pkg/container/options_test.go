@@ -0,0 +1,93 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadContainerOptions(t *testing.T) {
+	dir := t.TempDir()
+	optionsFile := filepath.Join(dir, "options.json")
+	assert.NoError(t, os.WriteFile(optionsFile, []byte(`{"image": "nginx:1.27", "restartPolicy": "on-failure", "restartMaxRetries": 5}`), 0644))
+
+	options, ok, err := ReadContainerOptions(optionsFile)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "nginx:1.27", options.Image)
+
+	imageFile := filepath.Join(dir, "image.tar")
+	assert.NoError(t, os.WriteFile(imageFile, []byte("manifest.json\x00\x00"), 0644))
+	_, ok, err = ReadContainerOptions(imageFile)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func Test_ContainerOptionsNetworkEndpoint(t *testing.T) {
+	endpoint, err := ContainerOptions{}.NetworkEndpoint("tedge")
+	assert.NoError(t, err)
+	assert.Equal(t, "tedge", endpoint.NetworkID)
+	assert.Nil(t, endpoint.IPAMConfig)
+
+	endpoint, err = ContainerOptions{IPAddress: "172.20.0.10", Aliases: []string{"modbus"}}.NetworkEndpoint("tedge")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.20.0.10", endpoint.IPAMConfig.IPv4Address)
+	assert.Equal(t, []string{"modbus"}, endpoint.Aliases)
+
+	_, err = ContainerOptions{IPAddress: "fd00::10"}.NetworkEndpoint("tedge")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = ContainerOptions{Aliases: []string{"--bad"}}.NetworkEndpoint("tedge")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = ContainerOptions{Aliases: []string{"modbus_server"}}.NetworkEndpoint("tedge")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func Test_ContainerOptionsDedicatedNetworks(t *testing.T) {
+	networks, err := ContainerOptions{Networks: []string{"app-backend", "app-frontend", "app-backend"}}.DedicatedNetworks("tedge")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app-backend", "app-frontend"}, networks)
+
+	for _, name := range []string{"tedge", "host", "bridge", "none", "--bad"} {
+		_, err = ContainerOptions{Networks: []string{name}}.DedicatedNetworks("tedge")
+		assert.ErrorIs(t, err, ErrInvalid, name)
+	}
+}
+
+func Test_ContainerOptionsApply(t *testing.T) {
+	config := &containerSDK.Config{}
+	hostConfig := &containerSDK.HostConfig{}
+	assert.NoError(t, ContainerOptions{}.Apply(config, hostConfig))
+	assert.Equal(t, containerSDK.RestartPolicyAlways, hostConfig.RestartPolicy.Name)
+	assert.Nil(t, config.Healthcheck)
+
+	options := ContainerOptions{
+		RestartPolicy:     "on-failure",
+		RestartMaxRetries: 3,
+		HealthCheck: &HealthCheckOptions{
+			Test:     []string{"CMD", "true"},
+			Interval: "30s",
+			Retries:  2,
+		},
+	}
+	assert.NoError(t, options.Apply(config, hostConfig))
+	assert.Equal(t, containerSDK.RestartPolicyOnFailure, hostConfig.RestartPolicy.Name)
+	assert.Equal(t, 3, hostConfig.RestartPolicy.MaximumRetryCount)
+	assert.Equal(t, 30*time.Second, config.Healthcheck.Interval)
+
+	assert.ErrorIs(t, ContainerOptions{RestartPolicy: "sometimes"}.Apply(config, hostConfig), ErrInvalid)
+	assert.ErrorIs(t, ContainerOptions{HealthCheck: &HealthCheckOptions{Timeout: "10"}}.Apply(config, hostConfig), ErrInvalid)
+}
+
+func Test_ContainerOptionsApplyStopTimeout(t *testing.T) {
+	config := &containerSDK.Config{}
+	hostConfig := &containerSDK.HostConfig{}
+	assert.NoError(t, ContainerOptions{StopTimeout: "60s"}.Apply(config, hostConfig))
+	assert.Equal(t, 60, *config.StopTimeout)
+
+	assert.ErrorIs(t, ContainerOptions{StopTimeout: "1 minute"}.Apply(config, hostConfig), ErrInvalid)
+	assert.ErrorIs(t, ContainerOptions{StopTimeout: "-5s"}.Apply(config, hostConfig), ErrInvalid)
+}
@@ -0,0 +1,168 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// Published host port
+type HostPort struct {
+	IP       string
+	Port     int
+	Protocol string
+}
+
+func (p HostPort) String() string {
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(p.IP, strconv.Itoa(p.Port)), p.Protocol)
+}
+
+// Port conflict with another container or a host process
+type PortConflict struct {
+	Port HostPort
+
+	// Name of the container (or "host" for other processes) using the port
+	Service string
+}
+
+// Parse the port mappings, e.g. "8080:80", "127.0.0.1:8443:443/tcp"
+func ParsePortBindings(ports []string) (nat.PortSet, nat.PortMap, error) {
+	exposed, bindings, err := nat.ParsePortSpecs(ports)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w port mapping. err=%w", ErrInvalid, err)
+	}
+	return exposed, bindings, nil
+}
+
+// Get the host ports which are published by the port bindings. Ports which are
+// dynamically assigned by the engine (no host port) are ignored
+func GetHostPorts(bindings nat.PortMap) []HostPort {
+	hostPorts := make([]HostPort, 0)
+	for containerPort, portBindings := range bindings {
+		for _, binding := range portBindings {
+			start, end, err := nat.ParsePortRangeToInt(binding.HostPort)
+			if err != nil || start == 0 {
+				continue
+			}
+			for port := start; port <= end; port++ {
+				hostPorts = append(hostPorts, HostPort{
+					IP:       binding.HostIP,
+					Port:     port,
+					Protocol: containerPort.Proto(),
+				})
+			}
+		}
+	}
+	return hostPorts
+}
+
+func portsOverlap(a HostPort, b HostPort) bool {
+	if a.Port != b.Port || !strings.EqualFold(a.Protocol, b.Protocol) {
+		return false
+	}
+	return isAnyAddress(a.IP) || isAnyAddress(b.IP) || a.IP == b.IP
+}
+
+func isAnyAddress(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// Check if any of the host ports are already used by other containers or host processes.
+// The container with the given name is ignored, as it is replaced by the install.
+// Host processes are only detected if the client runs in the host's network namespace
+func (c *ContainerClient) CheckPortConflicts(ctx context.Context, containerName string, ports []HostPort) ([]PortConflict, error) {
+	conflicts := make([]PortConflict, 0)
+	if len(ports) == 0 {
+		return conflicts, nil
+	}
+
+	containers, err := c.Client.ContainerList(ctx, containerSDK.ListOptions{})
+	if err != nil {
+		return nil, wrapEngineError(err)
+	}
+
+	probeHost := !c.Containerized
+	if !probeHost {
+		slog.Warn("Running as a container, so only the ports published by other containers are checked for conflicts. Ports used by host processes are not detected.")
+	}
+
+	for _, port := range ports {
+		found := false
+		replaced := false
+		for _, item := range containers {
+			name := ConvertName(item.Names)
+			for _, p := range item.Ports {
+				used := HostPort{IP: p.IP, Port: int(p.PublicPort), Protocol: p.Type}
+				if p.PublicPort == 0 || !portsOverlap(port, used) {
+					continue
+				}
+				if name == containerName {
+					replaced = true
+					continue
+				}
+				conflicts = append(conflicts, PortConflict{Port: port, Service: name})
+				found = true
+				break
+			}
+			if found {
+				break
+			}
+		}
+
+		// The engine listens on the ports of the container being replaced
+		if probeHost && !found && !replaced && !hostPortAvailable(port) {
+			conflicts = append(conflicts, PortConflict{Port: port, Service: "host"})
+		}
+	}
+	return conflicts, nil
+}
+
+// Check if a host process is listening on the port by trying to use it
+func hostPortAvailable(port HostPort) bool {
+	address := net.JoinHostPort(port.IP, strconv.Itoa(port.Port))
+	if strings.EqualFold(port.Protocol, "udp") {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// Get the id of a container and the host ports it publishes, including the ports which were randomly
+// assigned by the engine. The ports are only known once the container has been started
+func (c *ContainerClient) GetPublishedPorts(ctx context.Context, containerName string) (string, []HostPort, error) {
+	info, err := c.Client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return "", nil, wrapEngineError(err)
+	}
+	if info.NetworkSettings == nil {
+		return info.ID, []HostPort{}, nil
+	}
+	return info.ID, GetHostPorts(info.NetworkSettings.Ports), nil
+}
+
+// Format the port conflicts as an error
+func PortConflictError(conflicts []PortConflict) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	items := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		items = append(items, fmt.Sprintf("%s (used by %s)", conflict.Port, conflict.Service))
+	}
+	return fmt.Errorf("%w port mapping, the host ports are already in use. ports=%s", ErrInvalid, strings.Join(items, ", "))
+}
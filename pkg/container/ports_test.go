@@ -0,0 +1,71 @@
+package container
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetHostPorts(t *testing.T) {
+	_, bindings, err := ParsePortBindings([]string{"8080:80", "127.0.0.1:5000-5001:5000-5001/udp", "9000"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []HostPort{
+		{IP: "", Port: 8080, Protocol: "tcp"},
+		{IP: "127.0.0.1", Port: 5000, Protocol: "udp"},
+		{IP: "127.0.0.1", Port: 5001, Protocol: "udp"},
+	}, GetHostPorts(bindings))
+
+	_, _, err = ParsePortBindings([]string{"abc:80"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func Test_PortsOverlap(t *testing.T) {
+	assert.True(t, portsOverlap(HostPort{Port: 80, Protocol: "tcp"}, HostPort{IP: "127.0.0.1", Port: 80, Protocol: "tcp"}))
+	assert.False(t, portsOverlap(HostPort{Port: 80, Protocol: "tcp"}, HostPort{Port: 80, Protocol: "udp"}))
+	assert.False(t, portsOverlap(HostPort{IP: "127.0.0.1", Port: 80, Protocol: "tcp"}, HostPort{IP: "10.0.0.1", Port: 80, Protocol: "tcp"}))
+}
+
+func Test_HostPortAvailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.False(t, hostPortAvailable(HostPort{IP: "127.0.0.1", Port: port, Protocol: "tcp"}))
+}
+
+func Test_CheckPortConflictsContainerized(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	hostPort := listener.Addr().(*net.TCPAddr).Port
+
+	// Engine with a container publishing port 8080
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"Id":"1","Names":["/web"],"Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"}]}]`))
+	}))
+	defer server.Close()
+	engine, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")), client.WithVersion("1.43"))
+	assert.NoError(t, err)
+
+	ports := []HostPort{
+		{Port: 8080, Protocol: "tcp"},
+		{IP: "127.0.0.1", Port: hostPort, Protocol: "tcp"},
+	}
+	c := &ContainerClient{Client: engine}
+	conflicts, err := c.CheckPortConflicts(context.Background(), "app", ports)
+	assert.NoError(t, err)
+	assert.Equal(t, []PortConflict{{Port: ports[0], Service: "web"}, {Port: ports[1], Service: "host"}}, conflicts)
+
+	// Host processes can't be detected from a container, but the ports of other containers are still checked
+	c.Containerized = true
+	conflicts, err = c.CheckPortConflicts(context.Background(), "app", ports)
+	assert.NoError(t, err)
+	assert.Equal(t, []PortConflict{{Port: ports[0], Service: "web"}}, conflicts)
+}
@@ -0,0 +1,167 @@
+package container
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Label which defines the readiness probe of a container, e.g. tcp://:5432, http://:8080/health or exec:pg_isready
+var LabelProbe = "tedge.probe"
+
+// Probe types
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeExec = "exec"
+)
+
+// Built-in health check state which is included in the container's status, e.g. "Up 5 minutes (healthy)"
+var healthcheckStatusRegex = regexp.MustCompile(`\((healthy|unhealthy|health: starting)\)`)
+
+// Readiness probe which is executed by the monitor (rather than by the container engine)
+type Probe struct {
+	Type string
+
+	// Address of tcp probes, or the url of http probes. An empty host is replaced by the container's address
+	Address string
+
+	// Command of exec probes, which is executed inside the container
+	Command []string
+}
+
+func (p Probe) String() string {
+	if p.Type == ProbeExec {
+		return ProbeExec + ":" + strings.Join(p.Command, " ")
+	}
+	return p.Address
+}
+
+// Parse a probe definition: tcp://[host]:port, http(s)://[host]:port/path or exec:<command>
+func ParseProbe(value string) (Probe, error) {
+	value = strings.TrimSpace(value)
+	if command, ok := strings.CutPrefix(value, ProbeExec+":"); ok {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return Probe{}, fmt.Errorf("%w probe, an exec probe requires a command. probe=%s", ErrInvalid, value)
+		}
+		return Probe{Type: ProbeExec, Command: args}, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return Probe{}, fmt.Errorf("%w probe. probe=%s, err=%s", ErrInvalid, value, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Port() == "" {
+			return Probe{}, fmt.Errorf("%w probe, a tcp probe requires a port. probe=%s", ErrInvalid, value)
+		}
+		return Probe{Type: ProbeTCP, Address: value}, nil
+	case "http", "https":
+		return Probe{Type: ProbeHTTP, Address: value}, nil
+	}
+	return Probe{}, fmt.Errorf("%w probe type, expected tcp://, http(s):// or exec:. probe=%s", ErrInvalid, value)
+}
+
+// Check if the container defines a built-in health check (HEALTHCHECK), in which case the engine's state is used
+func HasHealthcheck(item TedgeContainer) bool {
+	return healthcheckStatusRegex.MatchString(item.Container.Status)
+}
+
+// Get the address of a probe, where an empty host is replaced by the container's address
+func probeURL(p Probe, item TedgeContainer) (*url.URL, error) {
+	u, err := url.Parse(p.Address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		host := item.Container.IPAddress
+		if host == "" || container.NetworkMode(item.Container.NetworkMode).IsHost() {
+			host = "127.0.0.1"
+		}
+		u.Host = net.JoinHostPort(host, u.Port())
+	}
+	return u, nil
+}
+
+// Run a readiness probe against a container. An error is returned if the container is not ready
+func (c *ContainerClient) RunProbe(ctx context.Context, item TedgeContainer, p Probe, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch p.Type {
+	case ProbeTCP:
+		u, err := probeURL(p, item)
+		if err != nil {
+			return err
+		}
+		dialer := net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ProbeHTTP:
+		u, err := probeURL(p, item)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				// The probe checks the availability of the service, not its certificate
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected http status. code=%d", resp.StatusCode)
+		}
+		return nil
+
+	case ProbeExec:
+		exec, err := c.Client.ContainerExecCreate(ctx, item.Container.Id, container.ExecOptions{
+			Cmd: p.Command,
+		})
+		if err != nil {
+			return wrapEngineError(err)
+		}
+		if err := c.Client.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{Detach: true}); err != nil {
+			return wrapEngineError(err)
+		}
+		for {
+			info, err := c.Client.ContainerExecInspect(ctx, exec.ID)
+			if err != nil {
+				return wrapEngineError(err)
+			}
+			if !info.Running {
+				if info.ExitCode != 0 {
+					return fmt.Errorf("command failed. exit_code=%d", info.ExitCode)
+				}
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+	return fmt.Errorf("%w probe type. type=%s", ErrUnsupported, p.Type)
+}
@@ -0,0 +1,71 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseProbe(t *testing.T) {
+	probe, err := ParseProbe("tcp://:5432")
+	assert.NoError(t, err)
+	assert.Equal(t, ProbeTCP, probe.Type)
+
+	probe, err = ParseProbe("https://:8443/health")
+	assert.NoError(t, err)
+	assert.Equal(t, ProbeHTTP, probe.Type)
+
+	probe, err = ParseProbe("exec:pg_isready -U postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pg_isready", "-U", "postgres"}, probe.Command)
+	assert.Equal(t, "exec:pg_isready -U postgres", probe.String())
+
+	for _, value := range []string{"tcp://localhost", "exec:", "udp://:53", "localhost:80"} {
+		_, err := ParseProbe(value)
+		assert.True(t, errors.Is(err, ErrInvalid), value)
+	}
+}
+
+func Test_HasHealthcheck(t *testing.T) {
+	for status, expected := range map[string]bool{
+		"Up 5 minutes (healthy)":          true,
+		"Up 5 seconds (health: starting)": true,
+		"Up 2 hours (unhealthy)":          true,
+		"Up 2 hours":                      false,
+	} {
+		item := TedgeContainer{Container: Container{Status: status}}
+		assert.Equal(t, expected, HasHealthcheck(item), status)
+	}
+}
+
+func Test_RunProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	c := &ContainerClient{}
+	item := TedgeContainer{Container: Container{IPAddress: "127.0.0.1"}}
+	run := func(value string) error {
+		probe, err := ParseProbe(value)
+		assert.NoError(t, err)
+		return c.RunProbe(context.Background(), item, probe, time.Second)
+	}
+
+	assert.NoError(t, run("tcp://:"+port))
+	assert.NoError(t, run("http://:"+port+"/health"))
+	assert.Error(t, run("http://:"+port+"/other"))
+
+	server.Close()
+	assert.Error(t, run("tcp://:"+port))
+}
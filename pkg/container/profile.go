@@ -0,0 +1,106 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
+)
+
+var ComposeFileNames = []string{
+	"docker-compose.yaml",
+	"docker-compose.yml",
+	"compose.yaml",
+	"compose.yml",
+}
+
+// A profile is a pre-approved compose project which is stored locally on the device
+// and can be activated or deactivated on demand
+type Profile struct {
+	Name   string `json:"name"`
+	Dir    string `json:"-"`
+	Active bool   `json:"active"`
+}
+
+func hasComposeFile(dir string) bool {
+	for _, name := range ComposeFileNames {
+		if utils.PathExists(filepath.Join(dir, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// List the profiles in the given directory. Each profile is a sub directory containing a compose file
+func ListProfiles(dir string) ([]Profile, error) {
+	profiles := make([]Profile, 0)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		profileDir := filepath.Join(dir, entry.Name())
+		if !hasComposeFile(profileDir) {
+			continue
+		}
+		profiles = append(profiles, Profile{
+			Name: entry.Name(),
+			Dir:  profileDir,
+		})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// Find a profile by name. Only profiles which exist in the directory can be used
+func FindProfile(dir string, name string) (*Profile, error) {
+	profiles, err := ListProfiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, fmt.Errorf("profile %w. name=%s", ErrNotFound, name)
+}
+
+// Get the profiles and mark which ones are active (have containers)
+func (c *ContainerClient) GetProfiles(ctx context.Context, dir string) ([]Profile, error) {
+	profiles, err := ListProfiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, profile := range profiles {
+		items, err := c.List(ctx, FilterOptions{
+			Labels: []string{"com.docker.compose.project.working_dir=" + profile.Dir},
+		})
+		if err != nil {
+			return nil, err
+		}
+		profiles[i].Active = len(items) > 0
+	}
+	return profiles, nil
+}
+
+func (c *ContainerClient) ActivateProfile(ctx context.Context, w io.Writer, profile Profile) error {
+	return c.ComposeUp(ctx, w, profile.Name, profile.Dir)
+}
+
+// Deactivate a profile. Unlike removing a container-group, the profile's directory is kept
+func (c *ContainerClient) DeactivateProfile(ctx context.Context, w io.Writer, profile Profile) error {
+	return c.composeDownInDir(w, profile.Name, profile.Dir)
+}
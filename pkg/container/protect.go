@@ -0,0 +1,74 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/docker/docker/errdefs"
+)
+
+var DefaultProtectedLabel = "tedge.protected"
+
+// Containers which should not be removed by the sm-plugin (unless forced)
+type ProtectionOptions struct {
+	// Container name patterns (regular expressions)
+	Names []string
+
+	// Containers with any of the given labels
+	Labels []string
+}
+
+func (o ProtectionOptions) IsProtected(name string, labels map[string]string) bool {
+	for _, label := range o.Labels {
+		if _, ok := labels[label]; ok {
+			return true
+		}
+	}
+	for _, pattern := range o.Names {
+		p, err := regexp.Compile(pattern)
+		if err != nil {
+			slog.Warn("Invalid protected name regex pattern.", "pattern", pattern, "err", err)
+			continue
+		}
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a container is protected. A container which does not exist is not protected
+func (c *ContainerClient) CheckProtected(ctx context.Context, containerName string, opts ProtectionOptions) error {
+	info, err := c.Client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return wrapEngineError(err)
+	}
+	if opts.IsProtected(ConvertName([]string{info.Name}), info.Config.Labels) {
+		return fmt.Errorf("container is %w and can only be removed using --force. name=%s", ErrProtected, containerName)
+	}
+	return nil
+}
+
+// Check if any of the containers belonging to a compose project are protected
+func (c *ContainerClient) CheckProjectProtected(ctx context.Context, projectName string, opts ProtectionOptions) error {
+	if opts.IsProtected(projectName, nil) {
+		return fmt.Errorf("container-group is %w and can only be removed using --force. name=%s", ErrProtected, projectName)
+	}
+	items, err := c.List(ctx, FilterOptions{
+		Labels: []string{"com.docker.compose.project=" + projectName},
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if opts.IsProtected(item.Container.Name, item.Container.Labels) || opts.IsProtected(item.Name, nil) {
+			return fmt.Errorf("container-group contains a container which is %w and can only be removed using --force. name=%s, container=%s", ErrProtected, projectName, item.Name)
+		}
+	}
+	return nil
+}
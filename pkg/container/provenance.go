@@ -0,0 +1,60 @@
+package container
+
+import (
+	"context"
+)
+
+// Image labels which are checked (in order) for each provenance field. The OCI annotations are set
+// by most build pipelines, e.g. docker/metadata-action, and the label-schema labels are used by older builds
+var (
+	ProvenanceBuilderLabels  = []string{"tedge.provenance.builder", "org.opencontainers.image.builder"}
+	ProvenanceSourceLabels   = []string{"org.opencontainers.image.source", "org.label-schema.vcs-url"}
+	ProvenanceRevisionLabels = []string{"org.opencontainers.image.revision", "org.label-schema.vcs-ref"}
+	ProvenanceCreatedLabels  = []string{"org.opencontainers.image.created", "org.label-schema.build-date"}
+	ProvenanceVersionLabels  = []string{"org.opencontainers.image.version", "org.label-schema.version"}
+)
+
+// Provenance summary of an image, which allows tracing a running container back to its build pipeline
+type Provenance struct {
+	Builder  string `json:"builder,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Created  string `json:"created,omitempty"`
+	Version  string `json:"version,omitempty"`
+	ImageID  string `json:"imageId,omitempty"`
+}
+
+func firstLabel(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if v := labels[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Get the provenance from the image labels. false is returned if the image does not have any provenance metadata
+func ProvenanceFromLabels(labels map[string]string) (Provenance, bool) {
+	provenance := Provenance{
+		Builder:  firstLabel(labels, ProvenanceBuilderLabels),
+		Source:   firstLabel(labels, ProvenanceSourceLabels),
+		Revision: firstLabel(labels, ProvenanceRevisionLabels),
+		Created:  firstLabel(labels, ProvenanceCreatedLabels),
+		Version:  firstLabel(labels, ProvenanceVersionLabels),
+	}
+	return provenance, provenance != Provenance{}
+}
+
+// Get the provenance of an image
+func (c *ContainerClient) GetImageProvenance(ctx context.Context, imageID string) (Provenance, bool, error) {
+	info, _, err := c.Client.ImageInspectWithRaw(ctx, imageID)
+	if err != nil {
+		return Provenance{}, false, wrapEngineError(err)
+	}
+	if info.Config == nil {
+		return Provenance{}, false, nil
+	}
+	provenance, ok := ProvenanceFromLabels(info.Config.Labels)
+	provenance.ImageID = info.ID
+	return provenance, ok, nil
+}
@@ -0,0 +1,25 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProvenanceFromLabels(t *testing.T) {
+	provenance, ok := ProvenanceFromLabels(map[string]string{
+		"org.opencontainers.image.source":   "https://github.com/example/app",
+		"org.opencontainers.image.revision": "1a2b3c4",
+		"org.label-schema.vcs-ref":          "ignored",
+		"tedge.provenance.builder":          "github-actions",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, Provenance{
+		Builder:  "github-actions",
+		Source:   "https://github.com/example/app",
+		Revision: "1a2b3c4",
+	}, provenance)
+
+	_, ok = ProvenanceFromLabels(map[string]string{"maintainer": "someone"})
+	assert.False(t, ok)
+}
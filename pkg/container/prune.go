@@ -0,0 +1,78 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-units"
+)
+
+type PruneOptions struct {
+	// Remove dangling images
+	Images bool
+
+	// Remove unused networks which were created by thin-edge.io
+	Networks bool
+
+	// Network shared by all containers, which is never removed
+	SharedNetwork string
+}
+
+type PruneResult struct {
+	ImagesDeleted   int    `json:"imagesDeleted"`
+	NetworksDeleted int    `json:"networksDeleted"`
+	SpaceReclaimed  uint64 `json:"spaceReclaimed"`
+}
+
+func (r PruneResult) HumanSpaceReclaimed() string {
+	return units.HumanSizeWithPrecision(float64(r.SpaceReclaimed), 3)
+}
+
+// Prune unused resources according to the given policy
+func (c *ContainerClient) Prune(ctx context.Context, opts PruneOptions) (*PruneResult, error) {
+	result := &PruneResult{}
+	errs := make([]error, 0)
+
+	if opts.Images {
+		slog.Info("Pruning images")
+		resp, err := c.Client.ImagesPrune(ctx, filters.Args{})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, image := range resp.ImagesDeleted {
+				slog.Info("Deleted image.", "deleted", image.Deleted, "untagged", image.Untagged)
+			}
+			result.ImagesDeleted = len(resp.ImagesDeleted)
+			result.SpaceReclaimed += resp.SpaceReclaimed
+		}
+	}
+
+	if opts.Networks {
+		// The shared network is also created by thin-edge.io, so the networks are removed
+		// individually (rather than using NetworksPrune) to keep the shared network
+		slog.Info("Pruning networks")
+		networks, err := c.Client.NetworkList(ctx, network.ListOptions{
+			Filters: filters.NewArgs(filters.Arg("label", LabelManaged+"=true")),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, netw := range networks {
+			if netw.Name == opts.SharedNetwork {
+				continue
+			}
+			removed, err := c.removeUnusedNetwork(ctx, netw.Name)
+			if err != nil {
+				errs = append(errs, err)
+			} else if removed {
+				result.NetworksDeleted++
+			}
+		}
+	}
+
+	slog.Info("Reclaimed space.", "size", result.HumanSpaceReclaimed())
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,126 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/thin-edge/tedge-container-plugin/pkg/utils"
+)
+
+type PullFailureReason string
+
+const (
+	PullFailureAuth     PullFailureReason = "auth"
+	PullFailureNotFound PullFailureReason = "not_found"
+	PullFailureDisk     PullFailureReason = "disk"
+	PullFailureNetwork  PullFailureReason = "network"
+	PullFailureUnknown  PullFailureReason = "unknown"
+)
+
+// Categorize an image pull error so that the reason can be reported
+func CategorizePullError(err error) PullFailureReason {
+	if err == nil {
+		return ""
+	}
+
+	if errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) {
+		return PullFailureAuth
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return PullFailureNetwork
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "denied"), strings.Contains(msg, "authentication required"):
+		return PullFailureAuth
+	case strings.Contains(msg, "no space left"), strings.Contains(msg, "disk quota"):
+		return PullFailureDisk
+	case errdefs.IsNotFound(err), strings.Contains(msg, "manifest unknown"), strings.Contains(msg, "not found"):
+		return PullFailureNotFound
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such host"), strings.Contains(msg, "network is unreachable"), strings.Contains(msg, "tls handshake"):
+		return PullFailureNetwork
+	}
+	return PullFailureUnknown
+}
+
+type pullMessage struct {
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// Pull an image and write the progress to the given writer.
+// The engine reports some failures (e.g. disk full) within the progress stream
+// rather than as a response error, so the stream is checked for errors.
+//
+// The image is pulled from the mirror of its registry if configured, with a fallback to the upstream registry
+func (c *ContainerClient) ImagePull(ctx context.Context, imageRef string, opts image.PullOptions, w io.Writer) error {
+	mirrored, err := c.pullFromMirror(ctx, imageRef, opts, w)
+	if mirrored && err == nil {
+		return nil
+	}
+	if err != nil {
+		slog.Warn("Could not pull image from mirror, pulling from the upstream registry.", "ref", imageRef, "err", err)
+	}
+	return c.pullImage(ctx, imageRef, opts, w)
+}
+
+func (c *ContainerClient) pullImage(ctx context.Context, imageRef string, opts image.PullOptions, w io.Writer) error {
+	if err := c.configureRegistry(ctx, imageRef); err != nil {
+		return err
+	}
+	c.proxyCheck.Do(func() { c.checkEngineProxy(ctx) })
+	out, err := c.Client.ImagePull(ctx, imageRef, opts)
+	if err != nil {
+		return wrapEngineError(err)
+	}
+	defer out.Close()
+
+	decoder := json.NewDecoder(io.TeeReader(out, w))
+	for {
+		msg := pullMessage{}
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
+
+// Warn if a proxy is configured for the plugin but not for the container engine.
+// Images are pulled by the engine, which only uses its own proxy settings
+// (e.g. "proxies" in docker's daemon.json or the HTTP(S)_PROXY environment of the engine's service)
+func (c *ContainerClient) checkEngineProxy(ctx context.Context) {
+	httpsProxy := os.Getenv("HTTPS_PROXY")
+	httpProxy := os.Getenv("HTTP_PROXY")
+	if httpsProxy == "" && httpProxy == "" {
+		return
+	}
+	info, err := c.Client.Info(ctx)
+	if err != nil {
+		return
+	}
+	if info.HTTPSProxy == "" && info.HTTPProxy == "" {
+		slog.Warn("A proxy is configured but the container engine does not report one, so image pulls may fail. Configure the proxy in the engine's settings (e.g. daemon.json or a systemd drop-in).", "https_proxy", utils.RedactURL(httpsProxy), "http_proxy", utils.RedactURL(httpProxy))
+	}
+}
@@ -0,0 +1,70 @@
+package container
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Consecutive pull failures of each image, so that an alarm is only raised when pulls fail repeatedly.
+// The counters are persisted as each install is run by a separate process
+type PullFailures struct {
+	path  string
+	mutex sync.Mutex
+	items map[string]int
+}
+
+func NewPullFailures(path string) *PullFailures {
+	f := &PullFailures{
+		path:  path,
+		items: make(map[string]int),
+	}
+	f.load()
+	return f
+}
+
+// Record the result of an image pull, and return the number of consecutive failures
+// before and after the pull. A successful pull resets the counter
+func (f *PullFailures) Record(imageRef string, pullErr error) (int, int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// Other processes (e.g. a concurrent install) could have changed the counters
+	f.load()
+	previous := f.items[imageRef]
+	if pullErr == nil {
+		if previous == 0 {
+			return 0, 0, nil
+		}
+		delete(f.items, imageRef)
+		return previous, 0, f.save()
+	}
+	f.items[imageRef] = previous + 1
+	return previous, previous + 1, f.save()
+}
+
+func (f *PullFailures) load() {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return
+	}
+	items := make(map[string]int)
+	if err := json.Unmarshal(b, &items); err == nil {
+		f.items = items
+	}
+}
+
+func (f *PullFailures) save() error {
+	if f.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(f.items)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, b, 0644)
+}
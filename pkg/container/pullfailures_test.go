@@ -0,0 +1,37 @@
+package container
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PullFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pull_failures.json")
+	failures := NewPullFailures(path)
+	pullErr := errors.New("unauthorized")
+
+	_, count, err := failures.Record("nginx:latest", pullErr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// The counters are persisted
+	previous, count, err := NewPullFailures(path).Record("nginx:latest", pullErr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, previous)
+	assert.Equal(t, 2, count)
+
+	// Each image is counted separately
+	_, count, _ = failures.Record("redis:latest", pullErr)
+	assert.Equal(t, 1, count)
+
+	previous, count, err = NewPullFailures(path).Record("nginx:latest", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, previous)
+	assert.Equal(t, 0, count)
+
+	_, count, _ = NewPullFailures(path).Record("nginx:latest", pullErr)
+	assert.Equal(t, 1, count)
+}
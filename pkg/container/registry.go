@@ -0,0 +1,165 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// TLS settings of a private registry, e.g. a registry which uses an internal CA.
+// Images are pulled by the container engine, so the certificates are installed where the engine reads them from
+type RegistryTLS struct {
+	// CA bundle which is used to verify the registry's certificate
+	CAFile string `json:"ca_file,omitempty" mapstructure:"ca_file"`
+
+	// Client certificate and key used to authenticate with the registry
+	CertFile string `json:"cert_file,omitempty" mapstructure:"cert_file"`
+	KeyFile  string `json:"key_file,omitempty" mapstructure:"key_file"`
+
+	// Don't verify the registry's certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty" mapstructure:"insecure_skip_verify"`
+}
+
+// Directories which each container engine reads the registry certificates from (<dir>/<host>/ca.crt)
+var RegistryCertsDirs = map[string]string{
+	"docker": "/etc/docker/certs.d",
+	"podman": "/etc/containers/certs.d",
+}
+
+// Directory of the podman registry configuration drop-in files, which is used to skip the tls verification
+var RegistriesConfDir = "/etc/containers/registries.conf.d"
+
+// Get the registry host of an image reference, e.g. registry.local:5000
+func RegistryHost(imageRef string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("%w image reference. %w", ErrInvalid, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// Copy a file to the destination if the content changed. Returns true if the file was written
+func installFile(src string, dst string, perm os.FileMode) (bool, error) {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return false, err
+	}
+	if existing, err := os.ReadFile(dst); err == nil && bytes.Equal(existing, b) {
+		return false, nil
+	}
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, b, perm); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, dst)
+}
+
+// Install the certificates of a registry into the certs.d directory of the container engine
+func installRegistryCerts(certsDir string, host string, cfg RegistryTLS) error {
+	files := []struct {
+		src  string
+		name string
+		perm os.FileMode
+	}{
+		{cfg.CAFile, "ca.crt", 0644},
+		{cfg.CertFile, "client.cert", 0644},
+		{cfg.KeyFile, "client.key", 0600},
+	}
+	dir := filepath.Join(certsDir, host)
+	for _, file := range files {
+		if file.src == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		changed, err := installFile(file.src, filepath.Join(dir, file.name), file.perm)
+		if err != nil {
+			return fmt.Errorf("could not install registry certificate. registry=%s, file=%s. %w", host, file.src, err)
+		}
+		if changed {
+			slog.Info("Installed registry certificate.", "registry", host, "file", file.src, "path", filepath.Join(dir, file.name))
+		}
+	}
+	return nil
+}
+
+// Write a podman registry configuration which disables the tls verification of the registry
+func installInsecureRegistry(confDir string, host string) error {
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		return err
+	}
+	name := "tedge-container-" + strings.NewReplacer(":", "_", "/", "_").Replace(host) + ".conf"
+	path := filepath.Join(confDir, name)
+	content := fmt.Sprintf("[[registry]]\nlocation = %q\ninsecure = true\n", host)
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		return nil
+	}
+	slog.Info("Disabling tls verification of registry.", "registry", host, "path", path)
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// Apply the tls settings of the image's registry before the image is pulled
+func (c *ContainerClient) configureRegistry(ctx context.Context, imageRef string) error {
+	if len(c.Registries) == 0 {
+		return nil
+	}
+	host, err := RegistryHost(imageRef)
+	if err != nil {
+		return err
+	}
+	cfg, ok := c.Registries[host]
+	if !ok {
+		return nil
+	}
+	return c.applyRegistryTLS(ctx, host, cfg)
+}
+
+// Apply the tls settings of all registries, e.g. before a compose project pulls its images
+func (c *ContainerClient) ConfigureRegistries(ctx context.Context) error {
+	errs := make([]error, 0)
+	for host, cfg := range c.Registries {
+		errs = append(errs, c.applyRegistryTLS(ctx, host, cfg))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ContainerClient) applyRegistryTLS(ctx context.Context, host string, cfg RegistryTLS) error {
+	version, err := c.Client.ServerVersion(ctx)
+	if err != nil {
+		return wrapEngineError(err)
+	}
+	engine := engineName(version)
+	if err := installRegistryCerts(RegistryCertsDirs[engine], host, cfg); err != nil {
+		return err
+	}
+	if !cfg.InsecureSkipVerify {
+		return nil
+	}
+	if engine == "podman" {
+		return installInsecureRegistry(RegistriesConfDir, host)
+	}
+
+	// docker only allows insecure registries to be configured in the daemon configuration
+	info, err := c.Client.Info(ctx)
+	if err != nil {
+		return wrapEngineError(err)
+	}
+	insecure := false
+	if info.RegistryConfig != nil {
+		if index, ok := info.RegistryConfig.IndexConfigs[host]; ok && index != nil {
+			insecure = !index.Secure
+		}
+	}
+	if !insecure {
+		return fmt.Errorf("%w, skipping the tls verification of a registry must be configured in the docker daemon (insecure-registries). registry=%s", ErrUnsupported, host)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RegistryHost(t *testing.T) {
+	host, err := RegistryHost("registry.local:5000/app:1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.local:5000", host)
+
+	host, err = RegistryHost("nginx:latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io", host)
+
+	_, err = RegistryHost("INVALID")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func Test_installRegistryCerts(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "plant-ca.crt")
+	assert.NoError(t, os.WriteFile(ca, []byte("ca"), 0644))
+
+	certsDir := filepath.Join(dir, "certs.d")
+	assert.NoError(t, installRegistryCerts(certsDir, "registry.local:5000", RegistryTLS{CAFile: ca}))
+	b, err := os.ReadFile(filepath.Join(certsDir, "registry.local:5000", "ca.crt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ca", string(b))
+	assert.NoFileExists(t, filepath.Join(certsDir, "registry.local:5000", "client.key"))
+
+	err = installRegistryCerts(certsDir, "registry.local:5000", RegistryTLS{KeyFile: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+}
+
+func Test_installInsecureRegistry(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, installInsecureRegistry(dir, "registry.local:5000"))
+	b, err := os.ReadFile(filepath.Join(dir, "tedge-container-registry.local_5000.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[[registry]]\nlocation = \"registry.local:5000\"\ninsecure = true\n", string(b))
+}
@@ -0,0 +1,107 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+)
+
+type RemoveOptions struct {
+	// Remove the container's image if it is no longer used by any other container
+	RemoveImage bool
+
+	// Remove the networks (created by thin-edge.io) which the container was connected to,
+	// if they are no longer used by any other container
+	RemoveNetworks bool
+
+	// Network shared by all containers, which is never removed
+	SharedNetwork string
+}
+
+var builtinNetworks = []string{"bridge", "host", "none"}
+
+// Stop and remove a container and its associated resources
+// Don't fail if the container does not exist
+func (c *ContainerClient) RemoveContainer(ctx context.Context, containerName string, opts RemoveOptions) error {
+	info, err := c.Client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			slog.Info("Container does not exist, so nothing to remove")
+			return nil
+		}
+		return wrapEngineError(err)
+	}
+
+	if err := c.StopRemoveContainer(ctx, containerName); err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	if opts.RemoveImage {
+		errs = append(errs, c.removeUnusedImage(ctx, info.Image))
+	}
+
+	if opts.RemoveNetworks && info.NetworkSettings != nil {
+		for name := range info.NetworkSettings.Networks {
+			if name == opts.SharedNetwork || slices.Contains(builtinNetworks, name) {
+				continue
+			}
+			_, err := c.removeUnusedNetwork(ctx, name)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ContainerClient) removeUnusedImage(ctx context.Context, imageID string) error {
+	slog.Info("Removing image.", "id", imageID)
+	resp, err := c.Client.ImageRemove(ctx, imageID, image.RemoveOptions{
+		PruneChildren: true,
+	})
+	if err != nil {
+		if errdefs.IsConflict(err) {
+			slog.Info("Image is still being used, so it will not be removed.", "id", imageID)
+			return nil
+		}
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	for _, item := range resp {
+		slog.Info("Deleted image.", "deleted", item.Deleted, "untagged", item.Untagged)
+	}
+	return nil
+}
+
+// Remove a network created by thin-edge.io if no container is connected to it. Returns true if the network was removed
+func (c *ContainerClient) removeUnusedNetwork(ctx context.Context, name string) (bool, error) {
+	netw, err := c.Client.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !IsManaged(netw.Labels) {
+		slog.Info("Network was not created by thin-edge.io, so it will not be removed.", "name", name)
+		return false, nil
+	}
+	if len(netw.Containers) > 0 {
+		slog.Info("Network is still being used, so it will not be removed.", "name", name, "containers", len(netw.Containers))
+		return false, nil
+	}
+	slog.Info("Removing network.", "name", name, "id", netw.ID)
+	if err := c.Client.NetworkRemove(ctx, netw.ID); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
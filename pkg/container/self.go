@@ -0,0 +1,159 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Files which are created by the container engines inside each container
+var containerMarkerFiles = []string{
+	"/.dockerenv",
+	"/run/.containerenv",
+}
+
+// Container ids are referenced by the cgroup and mount paths, e.g. /docker/<id> or /containers/<id>/hostname
+var containerIDRegex = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+
+// Container which the current process is running in
+type SelfContainer struct {
+	ID   string
+	Name string
+
+	// The container shares the host's network namespace (--network host)
+	HostNetwork bool
+
+	// The container shares the host's pid namespace (--pid host)
+	HostPID bool
+
+	// Gateway of the container's network, which is usually the address of the host
+	Gateway string
+
+	// Name of the host which the container engine is running on
+	EngineHostname string
+}
+
+// Check if the current process is running inside a container
+func RunningInContainer() bool {
+	for _, p := range containerMarkerFiles {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the container engine's socket has been mounted (for unix sockets).
+// Other addresses (e.g. tcp://) are not checked as they don't require a mount
+func CheckSocketMount(addr string) error {
+	if !strings.HasPrefix(addr, "unix://") {
+		return nil
+	}
+	p := strings.TrimPrefix(addr, "unix://")
+	info, err := os.Stat(p)
+	if err != nil {
+		return fmt.Errorf("%w, the socket is not mounted into the container (e.g. -v %s:%s). host=%s", ErrEngineUnavailable, p, p, addr)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w, the path is not a socket (the socket might not have existed when the container was created). host=%s", ErrEngineUnavailable, addr)
+	}
+	return nil
+}
+
+// Parse the container ids from the contents of /proc/self/cgroup or /proc/self/mountinfo.
+// Only lines which contain the given substring are considered, e.g. to ignore the image layer ids
+func parseContainerIDs(r io.Reader, contains string) []string {
+	ids := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, contains) {
+			continue
+		}
+		for _, id := range containerIDRegex.FindAllString(line, -1) {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Get the possible ids of the container which the current process is running in.
+// The hostname is used as a fallback as it defaults to the short container id
+func selfContainerIDCandidates() []string {
+	candidates := make([]string, 0)
+	sources := []struct {
+		path     string
+		contains string
+	}{
+		// cgroup v1, e.g. 12:memory:/docker/<id>
+		{path: "/proc/self/cgroup", contains: ""},
+		// cgroup v2, e.g. /var/lib/docker/containers/<id>/hostname
+		{path: "/proc/self/mountinfo", contains: "/containers/"},
+	}
+	for _, source := range sources {
+		file, err := os.Open(source.path)
+		if err != nil {
+			continue
+		}
+		for _, id := range parseContainerIDs(file, source.contains) {
+			if !slices.Contains(candidates, id) {
+				candidates = append(candidates, id)
+			}
+		}
+		_ = file.Close()
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		candidates = append(candidates, hostname)
+	}
+	return candidates
+}
+
+// Find the container which the current process is running in. If the container can't be found, then the
+// engine socket belongs to a different engine (e.g. a docker-in-docker or remote engine) than the one running the process
+func (c *ContainerClient) Self(ctx context.Context) (*SelfContainer, error) {
+	for _, id := range selfContainerIDCandidates() {
+		item, err := c.Client.ContainerInspect(ctx, id)
+		if err != nil {
+			if err := wrapEngineError(err); errors.Is(err, ErrEngineUnavailable) {
+				return nil, err
+			}
+			continue
+		}
+		self := &SelfContainer{
+			ID:   item.ID,
+			Name: strings.TrimPrefix(item.Name, "/"),
+		}
+		if item.HostConfig != nil {
+			self.HostNetwork = item.HostConfig.NetworkMode.IsHost()
+			self.HostPID = item.HostConfig.PidMode.IsHost()
+		}
+		if item.NetworkSettings != nil {
+			// Use the gateway of the first network (sorted by name), so the result is stable
+			names := make([]string, 0, len(item.NetworkSettings.Networks))
+			for name := range item.NetworkSettings.Networks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if gateway := item.NetworkSettings.Networks[name].Gateway; gateway != "" {
+					self.Gateway = gateway
+					break
+				}
+			}
+		}
+		if info, err := c.Client.Info(ctx); err == nil {
+			self.EngineHostname = info.Name
+		}
+		return self, nil
+	}
+	return nil, fmt.Errorf("container of the current process %w, the engine socket might belong to a different host", ErrNotFound)
+}
@@ -0,0 +1,23 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseContainerIDs(t *testing.T) {
+	id := "3f4e8c1a9b0d2e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f"
+	layer := "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"
+
+	cgroupV1 := "12:memory:/docker/" + id + "\n11:cpu:/docker/" + id + "\n"
+	assert.Equal(t, []string{id}, parseContainerIDs(strings.NewReader(cgroupV1), ""))
+
+	mountinfo := "" +
+		"871 802 0:48 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/" + layer + "/diff\n" +
+		"893 871 254:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw\n"
+	assert.Equal(t, []string{id}, parseContainerIDs(strings.NewReader(mountinfo), "/containers/"))
+
+	assert.Empty(t, parseContainerIDs(strings.NewReader("0::/\n"), ""))
+}
@@ -0,0 +1,115 @@
+package container
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+var ContainerStackType string = "container-stack"
+
+const (
+	LabelStackNamespace   = "com.docker.stack.namespace"
+	LabelSwarmServiceName = "com.docker.swarm.service.name"
+)
+
+type StackService struct {
+	Name string `json:"name"`
+
+	// Desired number of replicas. Only set if it could be read from the swarm manager
+	Replicas *uint64 `json:"replicas,omitempty"`
+
+	// Number of task containers which are running
+	Running int `json:"running"`
+
+	// Number of task containers present on the device
+	Tasks int `json:"tasks"`
+}
+
+type Stack struct {
+	Name     string         `json:"stackName"`
+	Status   string         `json:"-"`
+	Services []StackService `json:"services"`
+	Time     JSONTime       `json:"-"`
+
+	// Task containers belonging to the stack
+	Members []TedgeContainer `json:"-"`
+}
+
+// Group swarm task containers by their stack namespace.
+// Containers which don't belong to a stack are ignored
+func GroupByStack(items []TedgeContainer) map[string][]TedgeContainer {
+	stacks := make(map[string][]TedgeContainer)
+	for _, item := range items {
+		if item.Container.StackName == "" {
+			continue
+		}
+		stacks[item.Container.StackName] = append(stacks[item.Container.StackName], item)
+	}
+	return stacks
+}
+
+// Create a stack from its task containers. The desired replicas per service are optional
+func NewStack(name string, members []TedgeContainer, replicas map[string]uint64) Stack {
+	services := make(map[string]*StackService)
+	for _, item := range members {
+		serviceName := item.Container.ServiceName
+		if _, ok := services[serviceName]; !ok {
+			services[serviceName] = &StackService{
+				Name: serviceName,
+			}
+			if v, ok := replicas[serviceName]; ok {
+				services[serviceName].Replicas = &v
+			}
+		}
+		services[serviceName].Tasks++
+		if item.Status == "up" {
+			services[serviceName].Running++
+		}
+	}
+
+	stack := Stack{
+		Name:     name,
+		Status:   "up",
+		Services: make([]StackService, 0, len(services)),
+		Time:     NewJSONTime(time.Now()),
+		Members:  members,
+	}
+	for _, service := range services {
+		if service.Running == 0 || (service.Replicas != nil && uint64(service.Running) < *service.Replicas) {
+			stack.Status = "down"
+		}
+		stack.Services = append(stack.Services, *service)
+	}
+	if len(stack.Services) == 0 {
+		stack.Status = "down"
+	}
+	sort.Slice(stack.Services, func(i, j int) bool {
+		return stack.Services[i].Name < stack.Services[j].Name
+	})
+	return stack
+}
+
+// Get the desired number of replicas for each service in a stack.
+// The swarm services can only be read from a manager node, so an empty map
+// is returned if the information is not available
+func (c *ContainerClient) GetStackReplicas(ctx context.Context, stackName string) map[string]uint64 {
+	replicas := make(map[string]uint64)
+	services, err := c.Client.ServiceList(ctx, types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelStackNamespace+"="+stackName)),
+	})
+	if err != nil {
+		return replicas
+	}
+	for _, service := range services {
+		if service.Spec.Mode.Replicated != nil && service.Spec.Mode.Replicated.Replicas != nil {
+			name := strings.TrimPrefix(service.Spec.Name, stackName+"_")
+			replicas[name] = *service.Spec.Mode.Replicated.Replicas
+		}
+	}
+	return replicas
+}
@@ -0,0 +1,53 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStackTask(service string, status string) TedgeContainer {
+	return TedgeContainer{
+		Status:      status,
+		ServiceType: ContainerStackType,
+		Container: Container{
+			StackName:   "mystack",
+			ServiceName: service,
+		},
+	}
+}
+
+func Test_NewStack(t *testing.T) {
+	members := []TedgeContainer{
+		newStackTask("web", "up"),
+		newStackTask("web", "up"),
+		newStackTask("db", "up"),
+	}
+	stack := NewStack("mystack", members, nil)
+	assert.Equal(t, "up", stack.Status)
+	assert.Len(t, stack.Services, 2)
+	assert.Equal(t, "db", stack.Services[0].Name)
+	assert.Equal(t, 2, stack.Services[1].Running)
+	assert.Nil(t, stack.Services[1].Replicas)
+}
+
+func Test_NewStackMissingReplicas(t *testing.T) {
+	members := []TedgeContainer{
+		newStackTask("web", "up"),
+		newStackTask("web", "down"),
+	}
+	stack := NewStack("mystack", members, map[string]uint64{"web": 2})
+	assert.Equal(t, "down", stack.Status)
+	assert.Equal(t, uint64(2), *stack.Services[0].Replicas)
+	assert.Equal(t, 2, stack.Services[0].Tasks)
+}
+
+func Test_GroupByStack(t *testing.T) {
+	items := []TedgeContainer{
+		newStackTask("web", "up"),
+		{Name: "standalone"},
+	}
+	stacks := GroupByStack(items)
+	assert.Len(t, stacks, 1)
+	assert.Len(t, stacks["mystack"], 1)
+}
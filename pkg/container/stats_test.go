@@ -0,0 +1,22 @@
+package container
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewContainerTelemetryMessage(t *testing.T) {
+	message := NewContainerTelemetryMessage(StatsEntry{
+		CPUPercentage:    12.3456,
+		MemoryPercentage: 45.678,
+		NetworkTx:        1024.6,
+		BlockRead:        4096,
+	})
+	assert.Equal(t, "12.35", message.Container.Cpu.String())
+
+	b, err := json.Marshal(message)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"container":{"cpu":12.35,"memory":45.68,"netio":1025}}`, string(b))
+}
@@ -0,0 +1,77 @@
+package container
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Writer which limits the average rate of the written bytes (token bucket). Writes are delayed
+// when they exceed the allowed rate, so a download which is written to it is slowed down
+// by the transport's flow control
+type throttledWriter struct {
+	ctx   context.Context
+	w     io.Writer
+	rate  int64
+	start time.Time
+	total int64
+}
+
+// Limit the bytes written to w to the given rate (bytes per second). 0 = unlimited
+func NewThrottledWriter(ctx context.Context, w io.Writer, rate int64) io.Writer {
+	if rate <= 0 {
+		return w
+	}
+	return &throttledWriter{
+		ctx:   ctx,
+		w:     w,
+		rate:  rate,
+		start: time.Now(),
+	}
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	// Write in small chunks so that large writes are spread over time
+	chunkSize := int(max(t.rate/10, 1))
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), chunkSize)]
+		n, err := t.w.Write(chunk)
+		written += n
+		t.total += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+		if err := sleepContext(t.ctx, throttleDelay(t.total, t.rate, time.Since(t.start))); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Get how long to wait after transferring the total bytes within the elapsed time to stay within the rate
+func throttleDelay(total int64, rate int64, elapsed time.Duration) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	expected := time.Duration(float64(total) / float64(rate) * float64(time.Second))
+	if elapsed < expected {
+		return expected - elapsed
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
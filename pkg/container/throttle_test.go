@@ -0,0 +1,32 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ThrottleDelay(t *testing.T) {
+	assert.Equal(t, 500*time.Millisecond, throttleDelay(500, 1000, 0))
+	assert.Equal(t, time.Duration(0), throttleDelay(500, 1000, time.Second))
+	assert.Equal(t, time.Second, throttleDelay(2000, 1000, time.Second))
+	assert.Equal(t, time.Duration(0), throttleDelay(1e9, 0, 0))
+}
+
+func Test_ThrottledWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewThrottledWriter(context.Background(), buf, 1000)
+
+	start := time.Now()
+	n, err := w.Write(make([]byte, 300))
+	assert.NoError(t, err)
+	assert.Equal(t, 300, n)
+	assert.Equal(t, 300, buf.Len())
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+
+	// Unlimited
+	assert.Equal(t, buf, NewThrottledWriter(context.Background(), buf, 0))
+}
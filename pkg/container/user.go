@@ -0,0 +1,103 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+)
+
+// User (name or uid) and optional group, e.g. 1000, 1000:1000 or app:app
+var userPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+(:[a-zA-Z0-9_.-]+)?$`)
+
+// User namespace modes which remap the container's root user to an unprivileged host user (podman)
+var remappedUsernsModes = []string{"auto", "keep-id", "nomap"}
+
+// Controls the user which the containers created by the plugin run as
+type UserOptions struct {
+	// User (name or uid[:gid]) for images which would otherwise run as root, e.g. "1000:1000". Empty = image's user
+	User string
+
+	// User namespace mode of the containers, e.g. "host" (docker) or "auto" (podman). Empty = engine default
+	UsernsMode string
+
+	// Refuse to create containers which would run as root (uid 0) on the host
+	ForbidRoot bool
+}
+
+// Check if the user is root, which is also the case when the user is not set
+func IsRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
+
+func ValidateUser(user string) error {
+	if user != "" && !userPattern.MatchString(user) {
+		return fmt.Errorf("%w user. Expected a name or uid with an optional group, e.g. 1000:1000. value=%s", ErrInvalid, user)
+	}
+	return nil
+}
+
+// Check if the container's user is root on the host. The user is not root on the host if the
+// user namespace is remapped by the engine (docker userns-remap or rootless engines), unless the
+// container opts out of the remapping using the host user namespace
+func RunsAsHostRoot(user string, usernsMode string, securityOptions []string) bool {
+	if !IsRootUser(user) {
+		return false
+	}
+	mode, _, _ := strings.Cut(usernsMode, ":")
+	if slices.Contains(remappedUsernsModes, mode) {
+		return false
+	}
+	if containerSDK.UsernsMode(usernsMode).IsHost() {
+		return true
+	}
+	remapped := slices.ContainsFunc(securityOptions, func(option string) bool {
+		return option == "name=userns" || option == "name=rootless"
+	})
+	return !remapped
+}
+
+// Apply the default user and user namespace mode to a container which is about to be created,
+// and check that it does not run as root on the host (if forbidden)
+func (c *ContainerClient) ApplyUserOptions(ctx context.Context, options UserOptions, config *containerSDK.Config, hostConfig *containerSDK.HostConfig) error {
+	if options == (UserOptions{}) {
+		return nil
+	}
+	user := config.User
+	if user == "" {
+		// Use the image's user if it is not overridden
+		imageInfo, _, err := c.Client.ImageInspectWithRaw(ctx, config.Image)
+		if err != nil {
+			return wrapEngineError(err)
+		}
+		if imageInfo.Config != nil {
+			user = imageInfo.Config.User
+		}
+	}
+	if options.User != "" && config.User == "" && IsRootUser(user) {
+		if err := ValidateUser(options.User); err != nil {
+			return err
+		}
+		config.User = options.User
+		user = options.User
+	}
+	if options.UsernsMode != "" && hostConfig.UsernsMode == "" {
+		hostConfig.UsernsMode = containerSDK.UsernsMode(options.UsernsMode)
+	}
+
+	if !options.ForbidRoot {
+		return nil
+	}
+	info, err := c.Client.Info(ctx)
+	if err != nil {
+		return wrapEngineError(err)
+	}
+	if RunsAsHostRoot(user, string(hostConfig.UsernsMode), info.SecurityOptions) {
+		return fmt.Errorf("%w user, the container would run as root on the host. Set a non-root user or enable user namespace remapping. image=%s", ErrInvalid, config.Image)
+	}
+	return nil
+}
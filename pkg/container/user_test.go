@@ -0,0 +1,49 @@
+package container
+
+import (
+	"testing"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsRootUser(t *testing.T) {
+	assert.True(t, IsRootUser(""))
+	assert.True(t, IsRootUser("root"))
+	assert.True(t, IsRootUser("0:1000"))
+	assert.False(t, IsRootUser("1000:1000"))
+	assert.False(t, IsRootUser("app"))
+}
+
+func Test_ValidateUser(t *testing.T) {
+	assert.NoError(t, ValidateUser(""))
+	assert.NoError(t, ValidateUser("1000:1000"))
+	assert.NoError(t, ValidateUser("app"))
+	assert.ErrorIs(t, ValidateUser("1000:1000:1"), ErrInvalid)
+	assert.ErrorIs(t, ValidateUser("app user"), ErrInvalid)
+}
+
+func Test_RunsAsHostRoot(t *testing.T) {
+	assert.True(t, RunsAsHostRoot("", "", []string{"name=seccomp,profile=builtin"}))
+	assert.False(t, RunsAsHostRoot("1000", "", nil))
+
+	// docker userns-remap and rootless engines
+	assert.False(t, RunsAsHostRoot("root", "", []string{"name=seccomp,profile=builtin", "name=userns"}))
+	assert.False(t, RunsAsHostRoot("", "", []string{"name=rootless"}))
+	assert.True(t, RunsAsHostRoot("", "host", []string{"name=userns"}))
+
+	// podman user namespace modes
+	assert.False(t, RunsAsHostRoot("", "auto", nil))
+	assert.False(t, RunsAsHostRoot("", "auto:size=65536", nil))
+	assert.False(t, RunsAsHostRoot("", "keep-id", nil))
+}
+
+func Test_ContainerOptionsApplyUser(t *testing.T) {
+	config := &containerSDK.Config{}
+	hostConfig := &containerSDK.HostConfig{}
+	assert.NoError(t, ContainerOptions{User: "1000:1000", UsernsMode: "host"}.Apply(config, hostConfig))
+	assert.Equal(t, "1000:1000", config.User)
+	assert.Equal(t, containerSDK.UsernsMode("host"), hostConfig.UsernsMode)
+
+	assert.ErrorIs(t, ContainerOptions{User: "-u 0"}.Apply(config, hostConfig), ErrInvalid)
+}
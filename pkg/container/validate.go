@@ -0,0 +1,81 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Container names which are accepted by the engine (same rule as docker)
+var validContainerName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// Check if a container name is valid. Names are provided by the cloud, so they
+// must not be interpreted as flags or contain characters which the engine does not allow
+func ValidateContainerName(name string) error {
+	if !validContainerName.MatchString(name) {
+		return fmt.Errorf("%w container name. Names must be at least 2 characters long, start with a letter or digit, and only contain letters, digits, '_', '.' or '-'. name=%s", ErrInvalid, name)
+	}
+	return nil
+}
+
+// Hostnames (RFC 1123 labels) which can be resolved by other containers in the shared network
+var validHostname = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// Check if a name is a valid hostname (RFC 1123 label), e.g. a network alias
+func ValidateHostname(name string) error {
+	if !validHostname.MatchString(name) {
+		return fmt.Errorf("%w hostname. Hostnames must be 1-63 characters long, start and end with a lowercase letter or digit, and only contain lowercase letters, digits or '-'. name=%s", ErrInvalid, name)
+	}
+	return nil
+}
+
+type DefaultImagePolicy string
+
+const (
+	// Use the latest tag of the image with the same name as the container
+	DefaultImagePolicyLatest DefaultImagePolicy = "latest"
+
+	// Use the image reference template where {{name}} is replaced with the container name
+	DefaultImagePolicyTemplate DefaultImagePolicy = "template"
+
+	// Fail the installation
+	DefaultImagePolicyFail DefaultImagePolicy = "fail"
+)
+
+// Controls which image is used when no image reference (module version) is given
+type DefaultImageOptions struct {
+	Policy   DefaultImagePolicy
+	Template string
+}
+
+// Get the image reference to use for a container when no image reference was given
+func (o DefaultImageOptions) ImageRef(containerName string) (string, error) {
+	switch o.Policy {
+	case DefaultImagePolicyLatest:
+		return ParseImageRef(containerName + ":latest")
+	case DefaultImagePolicyTemplate:
+		if o.Template == "" {
+			return "", fmt.Errorf("%w default image template. The template must not be empty", ErrInvalid)
+		}
+		return ParseImageRef(strings.ReplaceAll(o.Template, "{{name}}", containerName))
+	default:
+		return ParseImageRef("")
+	}
+}
+
+// Parse and normalize an image reference, e.g. "nginx:latest" or "docker.io/library/nginx@sha256:..."
+func ParseImageRef(imageRef string) (string, error) {
+	if imageRef == "" {
+		return "", fmt.Errorf("%w image reference. The module version must be set to the image to use", ErrInvalid)
+	}
+	if strings.HasPrefix(imageRef, "-") {
+		return "", fmt.Errorf("%w image reference. ref=%s", ErrInvalid, imageRef)
+	}
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("%w image reference. ref=%s, err=%w", ErrInvalid, imageRef, err)
+	}
+	return reference.FamiliarString(named), nil
+}
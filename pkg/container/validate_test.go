@@ -0,0 +1,65 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateContainerName(t *testing.T) {
+	for _, name := range []string{"nginx", "app1", "my-app", "my_app", "app.local", "A1", "app-", strings.Repeat("a", 100)} {
+		assert.NoError(t, ValidateContainerName(name), name)
+	}
+	for _, name := range []string{"", "a", "-rm", "--privileged", "_app", ".app", "a/b", "app name", "app;rm", "a@b"} {
+		assert.ErrorIs(t, ValidateContainerName(name), ErrInvalid, name)
+	}
+}
+
+func Test_ValidateHostname(t *testing.T) {
+	for _, name := range []string{"a", "db", "my-app", "app1", strings.Repeat("a", 63)} {
+		assert.NoError(t, ValidateHostname(name), name)
+	}
+	for _, name := range []string{"", "-db", "db-", "my_app", "app.local", "App", strings.Repeat("a", 64), "a b"} {
+		assert.ErrorIs(t, ValidateHostname(name), ErrInvalid, name)
+	}
+}
+
+func Test_DefaultImageRef(t *testing.T) {
+	ref, err := DefaultImageOptions{Policy: DefaultImagePolicyLatest}.ImageRef("nginx")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:latest", ref)
+
+	ref, err = DefaultImageOptions{Policy: DefaultImagePolicyTemplate, Template: "registry.local/{{name}}:latest"}.ImageRef("app")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.local/app:latest", ref)
+
+	_, err = DefaultImageOptions{Policy: DefaultImagePolicyTemplate}.ImageRef("app")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = DefaultImageOptions{Policy: DefaultImagePolicyFail}.ImageRef("app")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func Test_ParseImageRef(t *testing.T) {
+	ref, err := ParseImageRef("nginx:1.27")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.27", ref)
+
+	ref, err = ParseImageRef("docker.io/library/nginx:1.27")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.27", ref)
+
+	ref, err = ParseImageRef("ghcr.io/thin-edge/tedge:latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "ghcr.io/thin-edge/tedge:latest", ref)
+
+	ref, err = ParseImageRef("nginx:1.27@" + testDigest)
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.27@"+testDigest, ref)
+
+	for _, value := range []string{"", "--privileged", "Nginx", "nginx:bad tag", "nginx@sha256:123"} {
+		_, err := ParseImageRef(value)
+		assert.ErrorIs(t, err, ErrInvalid, value)
+	}
+}
@@ -0,0 +1,107 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Enricher computes additional twin fields of a container, e.g. license information
+// or the application version read from an http endpoint of the container
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, item container.TedgeContainer) (map[string]any, error)
+}
+
+// Input which is written to the stdin of an executable enricher
+type Input struct {
+	Name      string              `json:"name"`
+	ID        string              `json:"id"`
+	Image     string              `json:"image"`
+	IPAddress string              `json:"ipAddress,omitempty"`
+	Labels    map[string]string   `json:"labels"`
+	Container container.Container `json:"container"`
+}
+
+// Executable (drop-in) enricher. The executable is called with the container name as the first argument
+// and the container details as json on stdin. It must print a json object with the additional fields to stdout
+type Exec struct {
+	Path string
+}
+
+func (e *Exec) Name() string {
+	return filepath.Base(e.Path)
+}
+
+func (e *Exec) Enrich(ctx context.Context, item container.TedgeContainer) (map[string]any, error) {
+	input, err := json.Marshal(Input{
+		Name:      item.Name,
+		ID:        item.Container.Id,
+		Image:     item.Container.Image,
+		IPAddress: item.Container.IPAddress,
+		Labels:    item.Container.Labels,
+		Container: item.Container,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, e.Path, item.Name)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("enricher failed. name=%s, stderr=%s. %w", e.Name(), strings.TrimSpace(stderr.String()), err)
+	}
+
+	fields := make(map[string]any)
+	if len(bytes.TrimSpace(out)) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(out, &fields); err != nil {
+		return nil, fmt.Errorf("enricher did not print a json object. name=%s. %w", e.Name(), err)
+	}
+	return fields, nil
+}
+
+// Load the executable enrichers from a drop-in directory, in lexical order.
+// Hidden and non-executable files are ignored, and a missing directory has no enrichers
+func Load(dir string) ([]Enricher, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	enrichers := make([]Enricher, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// follow symlinks, e.g. to an executable which is installed elsewhere
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			slog.Debug("Ignoring non-executable file in enricher directory.", "path", path)
+			continue
+		}
+		slog.Info("Using enricher.", "path", path)
+		enrichers = append(enrichers, &Exec{Path: path})
+	}
+	return enrichers, nil
+}
@@ -0,0 +1,39 @@
+package enrich
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\nread -r input\necho \"{\\\"license\\\":\\\"MIT\\\",\\\"name\\\":\\\"$1\\\"}\"\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "10-license"), []byte(script), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not executable"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte(script), 0755))
+
+	enrichers, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Len(t, enrichers, 1)
+	assert.Equal(t, "10-license", enrichers[0].Name())
+
+	fields, err := enrichers[0].Enrich(context.Background(), container.TedgeContainer{Name: "app"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"license": "MIT", "name": "app"}, fields)
+
+	enrichers, err = Load(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, enrichers)
+}
+
+func Test_ExecInvalidOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid")
+	assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho not json\n"), 0755))
+	_, err := (&Exec{Path: path}).Enrich(context.Background(), container.TedgeContainer{Name: "app"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,212 @@
+package eventsocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Unix socket which can be read by multiple consumers at the same time
+	TypeSocket = "socket"
+
+	// Named pipe (FIFO) which is read by a single consumer
+	TypeFIFO = "fifo"
+)
+
+// Interval to check if a consumer opened the named pipe
+var FIFOPollInterval = time.Second
+
+// Normalized container lifecycle event, which is written as a single json line
+type Event struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Image    string    `json:"image,omitempty"`
+	Project  string    `json:"project,omitempty"`
+	Service  string    `json:"service,omitempty"`
+	ExitCode string    `json:"exitCode,omitempty"`
+}
+
+// Forward events to local consumers via a unix socket or named pipe.
+// Events are dropped for consumers which don't keep up, so the monitor is never blocked
+type Server struct {
+	Path string
+	Type string
+
+	// Number of events which are buffered per consumer
+	Buffer int
+
+	mutex     sync.Mutex
+	consumers map[*consumer]struct{}
+}
+
+type consumer struct {
+	w     io.WriteCloser
+	lines chan []byte
+}
+
+func NewServer(path string, socketType string, buffer int) *Server {
+	return &Server{
+		Path:      path,
+		Type:      socketType,
+		Buffer:    max(buffer, 1),
+		consumers: make(map[*consumer]struct{}),
+	}
+}
+
+// Write the event to all connected consumers
+func (s *Server) Publish(evt Event) {
+	b, err := json.Marshal(evt)
+	if err != nil {
+		slog.Warn("Could not marshal event.", "err", err)
+		return
+	}
+	line := append(b, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.consumers {
+		select {
+		case c.lines <- line:
+		default:
+			slog.Debug("Dropping event for slow consumer.", "path", s.Path, "action", evt.Action)
+		}
+	}
+}
+
+// Number of connected consumers
+func (s *Server) Consumers() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.consumers)
+}
+
+func (s *Server) add(w io.WriteCloser) *consumer {
+	c := &consumer{
+		w:     w,
+		lines: make(chan []byte, s.Buffer),
+	}
+	s.mutex.Lock()
+	s.consumers[c] = struct{}{}
+	s.mutex.Unlock()
+	return c
+}
+
+func (s *Server) remove(c *consumer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.consumers[c]; ok {
+		delete(s.consumers, c)
+		close(c.lines)
+	}
+}
+
+// Write the events to the consumer until it disconnects or the context is cancelled
+func (s *Server) serve(ctx context.Context, c *consumer) {
+	defer c.w.Close()
+	defer s.remove(c)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-c.lines:
+			if !ok {
+				return
+			}
+			if _, err := c.w.Write(line); err != nil {
+				slog.Debug("Closing event consumer.", "path", s.Path, "err", err)
+				return
+			}
+		}
+	}
+}
+
+// Accept consumers until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	switch s.Type {
+	case TypeSocket, "":
+		return s.serveSocket(ctx)
+	case TypeFIFO:
+		return s.serveFIFO(ctx)
+	default:
+		return fmt.Errorf("unsupported event socket type. type=%s", s.Type)
+	}
+}
+
+func (s *Server) serveSocket(ctx context.Context) error {
+	// Remove the socket of a previous run
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "unix", s.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(s.Path, 0o660); err != nil {
+		slog.Warn("Could not set event socket permissions.", "path", s.Path, "err", err)
+	}
+	slog.Info("Event socket is listening.", "path", s.Path)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Warn("Could not accept event socket connection.", "err", err)
+			continue
+		}
+		go s.serve(ctx, s.add(conn))
+	}
+}
+
+func (s *Server) serveFIFO(ctx context.Context) error {
+	info, err := os.Stat(s.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := syscall.Mkfifo(s.Path, 0o660); err != nil {
+			return fmt.Errorf("could not create named pipe. path=%s, err=%w", s.Path, err)
+		}
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeNamedPipe == 0:
+		return fmt.Errorf("path exists but is not a named pipe. path=%s", s.Path)
+	}
+	slog.Info("Event named pipe is ready.", "path", s.Path)
+
+	// A named pipe can only be opened for writing once a consumer opened it for reading
+	ticker := time.NewTicker(FIFOPollInterval)
+	defer ticker.Stop()
+	for {
+		file, err := os.OpenFile(s.Path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err == nil {
+			s.serve(ctx, s.add(file))
+		} else if !errors.Is(err, syscall.ENXIO) {
+			slog.Warn("Could not open event named pipe.", "path", s.Path, "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
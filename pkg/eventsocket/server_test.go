@@ -0,0 +1,75 @@
+package eventsocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServerSocket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "events.sock")
+	server := NewServer(path, TypeSocket, 10)
+	go func() {
+		_ = server.ListenAndServe(ctx)
+	}()
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("unix", path)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		return server.Consumers() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	server.Publish(Event{Action: "start", ID: "abc", Name: "app1"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	require.NoError(t, err)
+	evt := Event{}
+	require.NoError(t, json.Unmarshal(line, &evt))
+	assert.Equal(t, "start", evt.Action)
+	assert.Equal(t, "app1", evt.Name)
+
+	// Consumers are removed once they disconnect
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		server.Publish(Event{Action: "stop", ID: "abc"})
+		return server.Consumers() == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func Test_ServerDropsEventsForSlowConsumers(t *testing.T) {
+	server := NewServer("", TypeSocket, 1)
+	c := server.add(nopWriteCloser{})
+	server.Publish(Event{Action: "start"})
+	server.Publish(Event{Action: "stop"})
+	assert.Len(t, c.lines, 1)
+
+	server.remove(c)
+	server.remove(c)
+	assert.Equal(t, 0, server.Consumers())
+}
+
+func Test_ServerUnsupportedType(t *testing.T) {
+	server := NewServer(filepath.Join(t.TempDir(), "events"), "udp", 1)
+	assert.ErrorContains(t, server.ListenAndServe(context.Background()), "unsupported event socket type")
+}
+
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeStatsD = "statsd"
+	TypeInflux = "influx"
+)
+
+// Exporter configuration, e.g.
+//
+//	[[monitor.metrics.exporters]]
+//	type = "statsd"
+//	address = "127.0.0.1:8125"
+type Config struct {
+	// statsd or influx
+	Type string `mapstructure:"type"`
+
+	// statsd: host:port (udp). influx: udp://host:port or the http(s) write url,
+	// e.g. http://127.0.0.1:8086/api/v2/write?org=site&bucket=containers
+	Address string `mapstructure:"address"`
+
+	// statsd: prefix of the metric names. influx: measurement name
+	Prefix string `mapstructure:"prefix"`
+
+	// influx: api token (http only)
+	Token string `mapstructure:"token"`
+}
+
+// Metric values of a single container
+type Sample struct {
+	Name    string
+	Project string
+	Fields  map[string]float64
+	Time    time.Time
+}
+
+// Exporter sends the container metrics to a local metrics backend
+type Exporter interface {
+	Export(ctx context.Context, samples []Sample) error
+	Close() error
+}
+
+// Create an exporter from its configuration
+func New(config Config) (Exporter, error) {
+	switch config.Type {
+	case TypeStatsD:
+		prefix := config.Prefix
+		if prefix == "" {
+			prefix = "tedge.container"
+		}
+		conn, err := net.Dial("udp", config.Address)
+		if err != nil {
+			return nil, err
+		}
+		return &StatsD{conn: conn, Prefix: prefix}, nil
+	case TypeInflux:
+		measurement := config.Prefix
+		if measurement == "" {
+			measurement = "container"
+		}
+		if address, ok := strings.CutPrefix(config.Address, "udp://"); ok {
+			conn, err := net.Dial("udp", address)
+			if err != nil {
+				return nil, err
+			}
+			return &Influx{conn: conn, Measurement: measurement}, nil
+		}
+		if _, err := url.ParseRequestURI(config.Address); err != nil {
+			return nil, fmt.Errorf("invalid influx address. address=%s, err=%w", config.Address, err)
+		}
+		return &Influx{
+			URL:         config.Address,
+			Token:       config.Token,
+			Measurement: measurement,
+			client:      &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown metrics exporter type. type=%s", config.Type)
+	}
+}
+
+// Create the exporters. Invalid exporters are skipped (and logged) so that they don't prevent the monitor from starting
+func NewExporters(configs []Config) []Exporter {
+	exporters := make([]Exporter, 0, len(configs))
+	for _, config := range configs {
+		exp, err := New(config)
+		if err != nil {
+			slog.Warn("Could not create metrics exporter.", "type", config.Type, "address", config.Address, "err", err)
+			continue
+		}
+		slog.Info("Using metrics exporter.", "type", config.Type, "address", config.Address)
+		exporters = append(exporters, exp)
+	}
+	return exporters
+}
+
+func sortedKeys(fields map[string]float64) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// StatsD exports each value as a gauge, e.g. tedge.container.app1.cpu:1.5|g
+type StatsD struct {
+	conn   net.Conn
+	Prefix string
+}
+
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", " ", "_", "\n", "_")
+
+func FormatStatsD(prefix string, sample Sample) []string {
+	name := statsdReplacer.Replace(sample.Name)
+	lines := make([]string, 0, len(sample.Fields))
+	for _, key := range sortedKeys(sample.Fields) {
+		lines = append(lines, fmt.Sprintf("%s.%s.%s:%s|g", prefix, name, key, formatFloat(sample.Fields[key])))
+	}
+	return lines
+}
+
+func (s *StatsD) Export(ctx context.Context, samples []Sample) error {
+	for _, sample := range samples {
+		// Send one packet per container to stay below the maximum udp packet size
+		lines := FormatStatsD(s.Prefix, sample)
+		if _, err := s.conn.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// Influx exports the values using the InfluxDB line protocol (via udp or http)
+type Influx struct {
+	URL         string
+	Token       string
+	Measurement string
+
+	conn   net.Conn
+	client *http.Client
+}
+
+var tagReplacer = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func FormatLineProtocol(measurement string, sample Sample) string {
+	tags := "name=" + tagReplacer.Replace(sample.Name)
+	if sample.Project != "" {
+		tags += ",project=" + tagReplacer.Replace(sample.Project)
+	}
+	fields := make([]string, 0, len(sample.Fields))
+	for _, key := range sortedKeys(sample.Fields) {
+		fields = append(fields, key+"="+formatFloat(sample.Fields[key]))
+	}
+	return fmt.Sprintf("%s,%s %s %d", tagReplacer.Replace(measurement), tags, strings.Join(fields, ","), sample.Time.UnixNano())
+}
+
+func (i *Influx) Export(ctx context.Context, samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(samples))
+	for _, sample := range samples {
+		lines = append(lines, FormatLineProtocol(i.Measurement, sample))
+	}
+
+	if i.conn != nil {
+		for _, line := range lines {
+			if _, err := i.conn.Write([]byte(line + "\n")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.URL, bytes.NewBufferString(strings.Join(lines, "\n")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.Token)
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("influx write failed. status=%s", resp.Status)
+	}
+	return nil
+}
+
+func (i *Influx) Close() error {
+	if i.conn != nil {
+		return i.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSample = Sample{
+	Name:    "app1@web",
+	Project: "app1",
+	Fields: map[string]float64{
+		"cpu":    1.5,
+		"memory": 20,
+	},
+	Time: time.Unix(1700000000, 0),
+}
+
+func Test_FormatStatsD(t *testing.T) {
+	assert.Equal(t, []string{
+		"tedge.container.app1_web.cpu:1.5|g",
+		"tedge.container.app1_web.memory:20|g",
+	}, FormatStatsD("tedge.container", testSample))
+}
+
+func Test_FormatLineProtocol(t *testing.T) {
+	assert.Equal(t, "container,name=app1@web,project=app1 cpu=1.5,memory=20 1700000000000000000", FormatLineProtocol("container", testSample))
+}
+
+func Test_InfluxHTTP(t *testing.T) {
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exp, err := New(Config{Type: TypeInflux, Address: server.URL + "/api/v2/write?bucket=containers", Token: "abc"})
+	assert.NoError(t, err)
+	assert.NoError(t, exp.Export(context.Background(), []Sample{testSample}))
+	assert.Equal(t, FormatLineProtocol("container", testSample), body)
+	assert.Equal(t, "Token abc", auth)
+
+	_, err = New(Config{Type: "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package firewall
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+type Backend string
+
+const (
+	BackendIPTables Backend = "iptables"
+	BackendNFTables Backend = "nftables"
+)
+
+// Prefix of the comment which is added to each rule, followed by the container name and short id,
+// e.g. "tedge-container:app/0123456789ab"
+const commentPrefix = "tedge-container:"
+
+// Port which should be allowed through the firewall
+type Port struct {
+	Port     int
+	Protocol string
+}
+
+// Manage firewall rules which allow access to the ports published by containers.
+// Published ports are forwarded to the container (DNAT), so the rules match on the
+// original destination port of the connection
+type Firewall struct {
+	Backend Backend
+
+	// Table and chain which the rules are added to.
+	// iptables defaults to filter/DOCKER-USER, nftables defaults to "inet filter"/forward
+	Table string
+	Chain string
+
+	// Also manage the IPv6 rules (ip6tables). The nftables rules of the "inet" family apply to both
+	IPv6 bool
+
+	// Command runner. Returns the stdout of the command
+	Run func(ctx context.Context, name string, args ...string) (string, error)
+}
+
+func NewFirewall(backend Backend, table string, chain string, ipv6 bool) (*Firewall, error) {
+	fw := &Firewall{
+		Backend: backend,
+		Table:   table,
+		Chain:   chain,
+		IPv6:    ipv6,
+		Run:     runCommand,
+	}
+	switch backend {
+	case BackendIPTables:
+		if fw.Table == "" {
+			fw.Table = "filter"
+		}
+		if fw.Chain == "" {
+			fw.Chain = "DOCKER-USER"
+		}
+	case BackendNFTables:
+		if fw.Table == "" {
+			fw.Table = "inet filter"
+		}
+		if fw.Chain == "" {
+			fw.Chain = "forward"
+		}
+	default:
+		return nil, fmt.Errorf("invalid firewall backend. Only iptables or nftables are supported. value=%s", backend)
+	}
+	return fw, nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return string(out), fmt.Errorf("%s failed. err=%w, stderr=%s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return string(out), err
+	}
+	return string(out), nil
+}
+
+func shortID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
+
+func comment(containerName string, containerID string) string {
+	return commentPrefix + containerName + "/" + shortID(containerID)
+}
+
+// Check if a rule's comment belongs to the container (any instance of it)
+func matchesContainer(value string, containerName string) bool {
+	return value == commentPrefix+containerName || strings.HasPrefix(value, commentPrefix+containerName+"/")
+}
+
+// The iptables commands which manage the rules, e.g. iptables and ip6tables
+func (f *Firewall) iptablesCommands() []string {
+	if f.IPv6 {
+		return []string{"iptables", "ip6tables"}
+	}
+	return []string{"iptables"}
+}
+
+// The engine publishes the ports on both the IPv4 and IPv6 addresses, but the rules don't include the address
+func dedupePorts(ports []Port) []Port {
+	seen := make(map[Port]bool)
+	out := make([]Port, 0, len(ports))
+	for _, port := range ports {
+		port.Protocol = normalizeProtocol(port.Protocol)
+		if !seen[port] {
+			seen[port] = true
+			out = append(out, port)
+		}
+	}
+	return out
+}
+
+func normalizeProtocol(protocol string) string {
+	if protocol == "" {
+		return "tcp"
+	}
+	return strings.ToLower(protocol)
+}
+
+func (f *Firewall) iptablesRule(containerName string, containerID string, port Port) []string {
+	return []string{
+		"-p", normalizeProtocol(port.Protocol),
+		"-m", "conntrack", "--ctorigdstport", strconv.Itoa(port.Port), "--ctdir", "ORIGINAL",
+		"-m", "comment", "--comment", comment(containerName, containerID),
+		"-j", "ACCEPT",
+	}
+}
+
+func (f *Firewall) nftablesRule(containerName string, containerID string, port Port) []string {
+	return []string{
+		"meta", "l4proto", normalizeProtocol(port.Protocol),
+		"ct", "original", "proto-dst", strconv.Itoa(port.Port),
+		"accept",
+		"comment", strconv.Quote(comment(containerName, containerID)),
+	}
+}
+
+// Allow access to the given ports of a container. Any existing rules of the container (including the rules of
+// previous instances with the same name) are replaced, so the rules can be refreshed whenever the container is
+// started, e.g. when the engine assigned different random host ports
+func (f *Firewall) Allow(ctx context.Context, containerName string, containerID string, ports []Port) error {
+	if err := f.Remove(ctx, containerName); err != nil {
+		return err
+	}
+	for _, port := range dedupePorts(ports) {
+		slog.Info("Adding firewall rule.", "backend", f.Backend, "container", containerName, "id", shortID(containerID), "port", port.Port, "protocol", port.Protocol)
+		switch f.Backend {
+		case BackendIPTables:
+			args := append([]string{"-t", f.Table, "-I", f.Chain}, f.iptablesRule(containerName, containerID, port)...)
+			for _, command := range f.iptablesCommands() {
+				if _, err := f.Run(ctx, command, args...); err != nil {
+					return err
+				}
+			}
+		case BackendNFTables:
+			args := append(append([]string{"insert", "rule"}, strings.Fields(f.Table)...), f.Chain)
+			if _, err := f.Run(ctx, "nft", append(args, f.nftablesRule(containerName, containerID, port)...)...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Remove all of the rules which were added for a container
+func (f *Firewall) Remove(ctx context.Context, containerName string) error {
+	switch f.Backend {
+	case BackendIPTables:
+		for _, command := range f.iptablesCommands() {
+			out, err := f.Run(ctx, command, "-t", f.Table, "-S", f.Chain)
+			if err != nil {
+				return err
+			}
+			for _, rule := range findIPTablesRules(out, containerName) {
+				slog.Info("Removing firewall rule.", "backend", f.Backend, "command", command, "container", containerName, "rule", strings.Join(rule, " "))
+				if _, err := f.Run(ctx, command, append([]string{"-t", f.Table, "-D", f.Chain}, rule...)...); err != nil {
+					return err
+				}
+			}
+		}
+	case BackendNFTables:
+		table := strings.Fields(f.Table)
+		out, err := f.Run(ctx, "nft", append(append([]string{"-a", "list", "chain"}, table...), f.Chain)...)
+		if err != nil {
+			return err
+		}
+		for _, handle := range findNFTablesHandles(out, containerName) {
+			slog.Info("Removing firewall rule.", "backend", f.Backend, "container", containerName, "handle", handle)
+			args := append(append([]string{"delete", "rule"}, table...), f.Chain, "handle", handle)
+			if _, err := f.Run(ctx, "nft", args...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Find the rules (without the chain) of a container from the output of "iptables -S <chain>"
+func findIPTablesRules(output string, containerName string) [][]string {
+	rules := make([][]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "-A" {
+			continue
+		}
+		for i, field := range fields {
+			if field == "--comment" && i+1 < len(fields) && matchesContainer(strings.Trim(fields[i+1], `"`), containerName) {
+				// Use the unquoted comment as the arguments are not passed via a shell
+				rule := fields[2:]
+				rule[i-1] = strings.Trim(fields[i+1], `"`)
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+// Find the rule handles of a container from the output of "nft -a list chain <table> <chain>"
+func findNFTablesHandles(output string, containerName string) []string {
+	handles := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, value, found := strings.Cut(line, "comment ")
+		if !found {
+			continue
+		}
+		value, _, _ = strings.Cut(value, " # handle")
+		if unquoted, err := strconv.Unquote(strings.TrimSpace(value)); err != nil || !matchesContainer(unquoted, containerName) {
+			continue
+		}
+		if _, handle, found := strings.Cut(line, "# handle "); found {
+			handles = append(handles, strings.TrimSpace(handle))
+		}
+	}
+	return handles
+}
+
+// Allow access to the ports which are currently published by a container
+func AllowContainer(ctx context.Context, f *Firewall, cli *container.ContainerClient, containerName string) error {
+	id, hostPorts, err := cli.GetPublishedPorts(ctx, containerName)
+	if err != nil {
+		return err
+	}
+	ports := make([]Port, 0, len(hostPorts))
+	for _, hostPort := range hostPorts {
+		ports = append(ports, Port{
+			Port:     hostPort.Port,
+			Protocol: hostPort.Protocol,
+		})
+	}
+	return f.Allow(ctx, containerName, id, ports)
+}
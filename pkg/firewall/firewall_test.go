@@ -0,0 +1,66 @@
+package firewall
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindIPTablesRules(t *testing.T) {
+	output := `-N DOCKER-USER
+-A DOCKER-USER -p tcp -m conntrack --ctorigdstport 8080 --ctdir ORIGINAL -m comment --comment tedge-container:app/0123456789ab -j ACCEPT
+-A DOCKER-USER -p tcp -m conntrack --ctorigdstport 8081 --ctdir ORIGINAL -m comment --comment tedge-container:app -j ACCEPT
+-A DOCKER-USER -p udp -m conntrack --ctorigdstport 5000 --ctdir ORIGINAL -m comment --comment "tedge-container:other/ba9876543210" -j ACCEPT
+-A DOCKER-USER -p udp -m conntrack --ctorigdstport 5001 --ctdir ORIGINAL -m comment --comment tedge-container:app2/ba9876543210 -j ACCEPT
+-A DOCKER-USER -j RETURN
+`
+	rules := findIPTablesRules(output, "app")
+	assert.Equal(t, [][]string{
+		strings.Fields("-p tcp -m conntrack --ctorigdstport 8080 --ctdir ORIGINAL -m comment --comment tedge-container:app/0123456789ab -j ACCEPT"),
+		strings.Fields("-p tcp -m conntrack --ctorigdstport 8081 --ctdir ORIGINAL -m comment --comment tedge-container:app -j ACCEPT"),
+	}, rules)
+	assert.Equal(t, [][]string{
+		strings.Fields("-p udp -m conntrack --ctorigdstport 5000 --ctdir ORIGINAL -m comment --comment tedge-container:other/ba9876543210 -j ACCEPT"),
+	}, findIPTablesRules(output, "other"))
+	assert.Empty(t, findIPTablesRules(output, "missing"))
+}
+
+func Test_FindNFTablesHandles(t *testing.T) {
+	output := `table inet filter {
+	chain forward { # handle 2
+		meta l4proto tcp ct original proto-dst 8080 accept comment "tedge-container:app/0123456789ab" # handle 7
+		meta l4proto tcp ct original proto-dst 9000 accept comment "tedge-container:app2/0123456789ab" # handle 8
+		meta l4proto tcp ct original proto-dst 8081 accept comment "tedge-container:app" # handle 9
+	}
+}`
+	assert.Equal(t, []string{"7", "9"}, findNFTablesHandles(output, "app"))
+	assert.Equal(t, []string{"8"}, findNFTablesHandles(output, "app2"))
+}
+
+func Test_AllowReplacesExistingRules(t *testing.T) {
+	fw, err := NewFirewall(BackendIPTables, "", "", true)
+	assert.NoError(t, err)
+
+	commands := make([]string, 0)
+	fw.Run = func(ctx context.Context, name string, args ...string) (string, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if name == "iptables" && len(args) > 2 && args[2] == "-S" {
+			return "-A DOCKER-USER -p tcp -m conntrack --ctorigdstport 80 --ctdir ORIGINAL -m comment --comment tedge-container:app/aaaaaaaaaaaa -j ACCEPT\n", nil
+		}
+		return "", nil
+	}
+	ports := []Port{{Port: 8080, Protocol: "tcp"}, {Port: 8080, Protocol: "TCP"}}
+	assert.NoError(t, fw.Allow(context.Background(), "app", "0123456789abcdef", ports))
+	assert.Equal(t, []string{
+		"iptables -t filter -S DOCKER-USER",
+		"iptables -t filter -D DOCKER-USER -p tcp -m conntrack --ctorigdstport 80 --ctdir ORIGINAL -m comment --comment tedge-container:app/aaaaaaaaaaaa -j ACCEPT",
+		"ip6tables -t filter -S DOCKER-USER",
+		"iptables -t filter -I DOCKER-USER -p tcp -m conntrack --ctorigdstport 8080 --ctdir ORIGINAL -m comment --comment tedge-container:app/0123456789ab -j ACCEPT",
+		"ip6tables -t filter -I DOCKER-USER -p tcp -m conntrack --ctorigdstport 8080 --ctdir ORIGINAL -m comment --comment tedge-container:app/0123456789ab -j ACCEPT",
+	}, commands)
+
+	_, err = NewFirewall("ufw", "", "", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default number of transitions which are kept per container
+var DefaultSize = 50
+
+// State transition of a container, e.g. started, died or removed
+type Transition struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	ContainerID string    `json:"containerId,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	ExitCode    string    `json:"exitCode,omitempty"`
+}
+
+// Ring buffer of the most recent state transitions of each container, which is persisted to a file
+type History struct {
+	Path string `json:"-"`
+	Size int    `json:"-"`
+
+	mu         sync.Mutex
+	Containers map[string][]Transition `json:"containers"`
+}
+
+// Load the history from a file. An empty history is returned if the file does not exist
+func Load(path string, size int) (*History, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	h := &History{
+		Path:       path,
+		Size:       size,
+		Containers: make(map[string][]Transition),
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return h, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, err
+	}
+	if h.Containers == nil {
+		h.Containers = make(map[string][]Transition)
+	}
+	return h, nil
+}
+
+// Record a transition of a container. The oldest transitions are dropped once the buffer is full.
+// Transitions which were already recorded (e.g. by another monitor instance) are ignored
+func (h *History) Record(name string, transition Transition) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, item := range h.Containers[name] {
+		if item.Time.Equal(transition.Time) && item.Action == transition.Action && item.ContainerID == transition.ContainerID {
+			return nil
+		}
+	}
+	items := append(h.Containers[name], transition)
+	if len(items) > h.Size {
+		items = items[len(items)-h.Size:]
+	}
+	h.Containers[name] = items
+	return h.save()
+}
+
+// Check if no transitions have been recorded yet
+func (h *History) Empty() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.Containers) == 0
+}
+
+// Get the transitions of a container (oldest first) which occurred at or after the given time
+func (h *History) Get(name string, since time.Time) []Transition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]Transition, 0)
+	for _, item := range h.Containers[name] {
+		if !item.Time.Before(since) {
+			out = append(out, item)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out
+}
+
+// Write the history to a temporary file first so a partially written file is never read
+func (h *History) save() error {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0755); err != nil {
+		return err
+	}
+	tmp := h.Path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.Path)
+}
@@ -0,0 +1,37 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := Load(path, 2)
+	assert.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	assert.NoError(t, h.Record("app1", Transition{Time: start, Action: "started"}))
+	assert.NoError(t, h.Record("app1", Transition{Time: start.Add(time.Minute), Action: "died", ExitCode: "137"}))
+	assert.NoError(t, h.Record("app1", Transition{Time: start.Add(2 * time.Minute), Action: "started"}))
+	assert.NoError(t, h.Record("app2", Transition{Time: start, Action: "created"}))
+	assert.NoError(t, h.Record("app2", Transition{Time: start, Action: "created"}))
+
+	// Only the most recent transitions are kept
+	items := h.Get("app1", time.Time{})
+	assert.Len(t, items, 2)
+	assert.Equal(t, "died", items[0].Action)
+	assert.Equal(t, "137", items[0].ExitCode)
+
+	assert.Len(t, h.Get("app1", start.Add(90*time.Second)), 1)
+	assert.Len(t, h.Get("unknown", time.Time{}), 0)
+
+	// The history is persisted
+	loaded, err := Load(path, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, items, loaded.Get("app1", time.Time{}))
+	assert.Len(t, loaded.Get("app2", time.Time{}), 1)
+}
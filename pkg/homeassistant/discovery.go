@@ -0,0 +1,153 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Default topic prefix which Home Assistant listens to for MQTT discovery messages
+var DefaultDiscoveryPrefix = "homeassistant"
+
+var invalidObjectID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Convert a name to a valid discovery node/object id
+func ObjectID(name string) string {
+	return strings.Trim(invalidObjectID.ReplaceAllString(name, "_"), "_")
+}
+
+type Options struct {
+	// Discovery topic prefix, e.g. homeassistant
+	Prefix string
+
+	// Node id (used to group the entities of the device), e.g. the device id
+	NodeID string
+
+	// Health topic of the monitor, which is used as the entity availability
+	AvailabilityTopic string
+}
+
+// Service (container, container group or stack) which is published as Home Assistant entities
+type Service struct {
+	Name string
+
+	// Topic of the health status message, e.g. {"status":"up"}
+	HealthTopic string
+
+	// Topic of the resource usage measurement, e.g. {"container":{"cpu":1.2,"memory":3.4}}. Optional
+	MetricsTopic string
+}
+
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+type sensor struct {
+	component string
+	suffix    string
+	config    map[string]any
+}
+
+func (o Options) prefix() string {
+	if o.Prefix == "" {
+		return DefaultDiscoveryPrefix
+	}
+	return o.Prefix
+}
+
+func (o Options) sensors(service Service) []sensor {
+	sensors := []sensor{
+		{
+			component: "binary_sensor",
+			suffix:    "status",
+			config: map[string]any{
+				"name":           "Status",
+				"state_topic":    service.HealthTopic,
+				"value_template": "{{ value_json.status }}",
+				"payload_on":     "up",
+				"payload_off":    "down",
+				"device_class":   "running",
+			},
+		},
+	}
+	if service.MetricsTopic != "" {
+		sensors = append(sensors,
+			sensor{
+				component: "sensor",
+				suffix:    "cpu",
+				config: map[string]any{
+					"name":                "CPU",
+					"state_topic":         service.MetricsTopic,
+					"value_template":      "{{ value_json.container.cpu }}",
+					"unit_of_measurement": "%",
+					"state_class":         "measurement",
+					"icon":                "mdi:cpu-64-bit",
+				},
+			},
+			sensor{
+				component: "sensor",
+				suffix:    "memory",
+				config: map[string]any{
+					"name":                "Memory",
+					"state_topic":         service.MetricsTopic,
+					"value_template":      "{{ value_json.container.memory }}",
+					"unit_of_measurement": "%",
+					"state_class":         "measurement",
+					"icon":                "mdi:memory",
+				},
+			},
+		)
+	}
+	return sensors
+}
+
+func (o Options) topic(component string, service string, suffix string) string {
+	return strings.Join([]string{o.prefix(), component, ObjectID(o.NodeID), ObjectID(service) + "_" + suffix, "config"}, "/")
+}
+
+// Get the discovery messages of a service. Each service is represented as a Home Assistant device
+func (o Options) Discovery(service Service) ([]Message, error) {
+	nodeID := ObjectID(o.NodeID)
+	objectID := ObjectID(service.Name)
+	messages := make([]Message, 0)
+	for _, s := range o.sensors(service) {
+		config := s.config
+		config["unique_id"] = strings.Join([]string{"tedge", nodeID, objectID, s.suffix}, "_")
+		config["object_id"] = strings.Join([]string{nodeID, objectID, s.suffix}, "_")
+		config["device"] = map[string]any{
+			"identifiers":  []string{"tedge_" + nodeID + "_" + objectID},
+			"name":         service.Name,
+			"manufacturer": "thin-edge.io",
+			"model":        "container",
+		}
+		if o.AvailabilityTopic != "" {
+			config["availability"] = []map[string]any{
+				{
+					"topic":                 o.AvailabilityTopic,
+					"value_template":        "{{ value_json.status }}",
+					"payload_available":     "up",
+					"payload_not_available": "down",
+				},
+			}
+		}
+		payload, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, Message{
+			Topic:   o.topic(s.component, service.Name, s.suffix),
+			Payload: payload,
+		})
+	}
+	return messages, nil
+}
+
+// Get the discovery topics of a service which need to be cleared to remove it from Home Assistant
+func (o Options) RemovalTopics(name string) []string {
+	topics := make([]string, 0)
+	for _, s := range o.sensors(Service{Name: name, MetricsTopic: "-"}) {
+		topics = append(topics, o.topic(s.component, name, s.suffix))
+	}
+	return topics
+}
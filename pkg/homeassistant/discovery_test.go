@@ -0,0 +1,42 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Discovery(t *testing.T) {
+	opts := Options{
+		NodeID:            "rpi4-d83add",
+		AvailabilityTopic: "te/device/main/service/tedge-container-plugin/status/health",
+	}
+	messages, err := opts.Discovery(Service{
+		Name:         "my.app",
+		HealthTopic:  "te/device/main/service/my.app/status/health",
+		MetricsTopic: "te/device/main/service/my.app/m/resource_usage",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "homeassistant/binary_sensor/rpi4-d83add/my_app_status/config", messages[0].Topic)
+	assert.Equal(t, "homeassistant/sensor/rpi4-d83add/my_app_cpu/config", messages[1].Topic)
+	assert.Equal(t, "homeassistant/sensor/rpi4-d83add/my_app_memory/config", messages[2].Topic)
+
+	config := map[string]any{}
+	assert.NoError(t, json.Unmarshal(messages[0].Payload, &config))
+	assert.Equal(t, "tedge_rpi4-d83add_my_app_status", config["unique_id"])
+	assert.Equal(t, "te/device/main/service/my.app/status/health", config["state_topic"])
+	assert.Equal(t, "running", config["device_class"])
+
+	// Services without metrics only have a status entity
+	messages, err = opts.Discovery(Service{Name: "stack1", HealthTopic: "te/device/main/service/stack1/status/health"})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	assert.Equal(t, []string{
+		"homeassistant/binary_sensor/rpi4-d83add/my_app_status/config",
+		"homeassistant/sensor/rpi4-d83add/my_app_cpu/config",
+		"homeassistant/sensor/rpi4-d83add/my_app_memory/config",
+	}, opts.RemovalTopics("my.app"))
+}
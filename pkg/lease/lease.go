@@ -0,0 +1,231 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Default duration of a claim. The active instance renews the claim after a third of the duration
+var DefaultDuration = 60 * time.Second
+
+// Time to wait for the retained claim (or the answer to our own claim) to be delivered
+var SettleDelay = 2 * time.Second
+
+var ErrLost = errors.New("lease was taken over by another instance")
+
+var ErrTimeout = errors.New("timed out waiting for the lease")
+
+// Claim published (as a retained message) by the instance which is allowed to publish
+type Claim struct {
+	ID         string    `json:"id"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Check if the instance can claim the lease, which is the case if there is no claim from another instance,
+// or the other instance did not renew its claim within the duration. The liveness is based on when the claim
+// was received (monotonic clock) rather than the claim's expiry time, so it is not affected by clock jumps
+// (e.g. on devices without a RTC) or clock differences between the instances
+func canClaim(current *Claim, receivedAt time.Time, id string, now time.Time, duration time.Duration) bool {
+	return current == nil || current.ID == id || now.Sub(receivedAt) >= duration
+}
+
+// Lease ensures that only one instance of the monitor is active (e.g. when both the packaged and the
+// containerized version are running). The instances compete for a retained claim on a MQTT topic, and
+// the instance which does not hold the claim stays idle. The claim is cleared by the broker (last will)
+// if the instance holding it disconnects unexpectedly, so a restarted instance does not have to wait for it
+type Lease struct {
+	Client   mqtt.Client
+	Topic    string
+	ID       string
+	Duration time.Duration
+
+	// Maximum time to wait for the lease. 0 = wait forever
+	MaxWait time.Duration
+
+	mutex      sync.Mutex
+	current    *Claim
+	receivedAt time.Time
+	acquiredAt time.Time
+}
+
+// Unique identifier of the current process
+func InstanceID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// Create a new lease. The client options should not set an OnConnect handler or last will
+// as they are used to subscribe to the claim topic and to clear the claim
+func NewLease(opts *mqtt.ClientOptions, topic string, id string, duration time.Duration, maxWait time.Duration) *Lease {
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+	l := &Lease{
+		Topic:    topic,
+		ID:       id,
+		Duration: duration,
+		MaxWait:  maxWait,
+	}
+	opts.SetBinaryWill(topic, []byte{}, 1, true)
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		tok := c.Subscribe(topic, 1, l.handleClaim)
+		<-tok.Done()
+		if err := tok.Error(); err != nil {
+			slog.Warn("Could not subscribe to lease topic.", "topic", topic, "err", err)
+		}
+	})
+	l.Client = mqtt.NewClient(opts)
+	return l
+}
+
+func (l *Lease) handleClaim(_ mqtt.Client, m mqtt.Message) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.receivedAt = time.Now()
+	if len(m.Payload()) == 0 {
+		l.current = nil
+		return
+	}
+	claim := &Claim{}
+	if err := json.Unmarshal(m.Payload(), claim); err != nil {
+		slog.Warn("Could not unmarshal lease claim.", "topic", m.Topic(), "err", err)
+		return
+	}
+	l.current = claim
+}
+
+func (l *Lease) getCurrent() (*Claim, time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.current, l.receivedAt
+}
+
+func (l *Lease) publish(payload []byte) error {
+	tok := l.Client.Publish(l.Topic, 1, true, payload)
+	if !tok.WaitTimeout(10 * time.Second) {
+		return errors.New("timed out publishing lease claim")
+	}
+	return tok.Error()
+}
+
+func (l *Lease) claim() error {
+	now := time.Now()
+	if l.acquiredAt.IsZero() {
+		l.acquiredAt = now
+	}
+	payload, err := json.Marshal(Claim{
+		ID:         l.ID,
+		AcquiredAt: l.acquiredAt,
+		ExpiresAt:  now.Add(l.Duration),
+	})
+	if err != nil {
+		return err
+	}
+	return l.publish(payload)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Acquire the lease. This blocks until no other instance holds a valid claim, the maximum wait
+// time is reached or the context is cancelled
+func (l *Lease) Acquire(ctx context.Context) error {
+	if l.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.MaxWait)
+		defer cancel()
+	}
+	err := l.acquire(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		l.Client.Disconnect(250)
+		return fmt.Errorf("%w after %s", ErrTimeout, l.MaxWait)
+	}
+	return err
+}
+
+func (l *Lease) acquire(ctx context.Context) error {
+	tok := l.Client.Connect()
+	if !tok.WaitTimeout(30 * time.Second) {
+		return errors.New("timed out connecting to broker")
+	}
+	if err := tok.Error(); err != nil {
+		return err
+	}
+
+	// Wait for the retained claim of another instance
+	if err := sleep(ctx, SettleDelay); err != nil {
+		return err
+	}
+
+	for {
+		if current, receivedAt := l.getCurrent(); canClaim(current, receivedAt, l.ID, time.Now(), l.Duration) {
+			if err := l.claim(); err != nil {
+				return err
+			}
+
+			// Another instance could have claimed the lease at the same time, the last claim wins
+			if err := sleep(ctx, SettleDelay); err != nil {
+				return err
+			}
+			if current, _ := l.getCurrent(); current != nil && current.ID == l.ID {
+				slog.Info("Acquired instance lease.", "id", l.ID, "topic", l.Topic)
+				return nil
+			}
+		} else {
+			slog.Warn("Another instance of the monitor is already active. Waiting until its lease expires.", "id", l.ID, "holder", current.ID, "renewedAt", receivedAt)
+		}
+
+		if err := sleep(ctx, l.Duration/3); err != nil {
+			return err
+		}
+	}
+}
+
+// Renew the lease until the context is cancelled. ErrLost is returned if another instance took over the lease
+func (l *Lease) Hold(ctx context.Context) error {
+	ticker := time.NewTicker(l.Duration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if current, receivedAt := l.getCurrent(); current != nil && !canClaim(current, receivedAt, l.ID, time.Now(), l.Duration) {
+				slog.Warn("Instance lease was taken over by another instance.", "id", l.ID, "holder", current.ID)
+				return ErrLost
+			}
+			if err := l.claim(); err != nil {
+				slog.Warn("Could not renew instance lease.", "err", err)
+			}
+		}
+	}
+}
+
+// Release the lease (if it is held by this instance) so that another instance can take over immediately
+func (l *Lease) Release() {
+	if current, _ := l.getCurrent(); current != nil && current.ID == l.ID {
+		if err := l.publish([]byte{}); err != nil {
+			slog.Warn("Could not release instance lease.", "err", err)
+		} else {
+			slog.Info("Released instance lease.", "id", l.ID)
+		}
+	}
+	l.Client.Disconnect(250)
+}
@@ -0,0 +1,21 @@
+package lease
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_canClaim(t *testing.T) {
+	now := time.Now()
+	duration := time.Minute
+
+	assert.True(t, canClaim(nil, time.Time{}, "host:1", now, duration))
+	assert.True(t, canClaim(&Claim{ID: "host:1"}, now, "host:1", now, duration))
+	assert.True(t, canClaim(&Claim{ID: "host:2"}, now.Add(-duration), "host:1", now, duration))
+	assert.False(t, canClaim(&Claim{ID: "host:2"}, now.Add(-time.Second), "host:1", now, duration))
+
+	// the expiry time of the claim is ignored, e.g. claims dated in the future after a clock jump
+	assert.True(t, canClaim(&Claim{ID: "host:2", ExpiresAt: now.Add(24 * time.Hour)}, now.Add(-duration), "host:1", now, duration))
+}
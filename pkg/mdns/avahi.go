@@ -0,0 +1,143 @@
+package mdns
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix of the service files which are managed by the announcer
+const filePrefix = "tedge-container-"
+
+// Service which is announced on the local network
+type Service struct {
+	// Instance name, e.g. "my-app on %h"
+	Name string
+
+	// Service type, e.g. "_http._tcp"
+	Type string
+
+	Port int
+	TXT  []string
+}
+
+func (s Service) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("service name must not be empty")
+	}
+	if !strings.HasPrefix(s.Type, "_") || !(strings.HasSuffix(s.Type, "._tcp") || strings.HasSuffix(s.Type, "._udp")) {
+		return fmt.Errorf("invalid service type. expected format _<name>._tcp or _<name>._udp. value=%s", s.Type)
+	}
+	if s.Port <= 0 || s.Port > 65535 {
+		return fmt.Errorf("invalid port. value=%d", s.Port)
+	}
+	return nil
+}
+
+// Announce services via Avahi by writing static service files to its services directory.
+// Avahi watches the directory, so the services are (un)published when the files change
+type Announcer struct {
+	Dir string
+}
+
+func NewAnnouncer(dir string) *Announcer {
+	return &Announcer{
+		Dir: dir,
+	}
+}
+
+type avahiServiceGroup struct {
+	XMLName xml.Name `xml:"service-group"`
+	Name    avahiName
+	Service avahiService `xml:"service"`
+}
+
+type avahiName struct {
+	XMLName          xml.Name `xml:"name"`
+	ReplaceWildcards string   `xml:"replace-wildcards,attr"`
+	Value            string   `xml:",chardata"`
+}
+
+type avahiService struct {
+	Type   string   `xml:"type"`
+	Port   int      `xml:"port"`
+	Record []string `xml:"txt-record"`
+}
+
+func renderService(s Service) ([]byte, error) {
+	b, err := xml.MarshalIndent(avahiServiceGroup{
+		Name: avahiName{
+			ReplaceWildcards: "yes",
+			Value:            s.Name,
+		},
+		Service: avahiService{
+			Type:   s.Type,
+			Port:   s.Port,
+			Record: s.TXT,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	out.WriteString(xml.Header)
+	out.WriteString("<!DOCTYPE service-group SYSTEM \"avahi-service.dtd\">\n")
+	out.Write(b)
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+func (a *Announcer) path(key string) string {
+	return filepath.Join(a.Dir, filePrefix+key+".service")
+}
+
+// Announce the given services (indexed by container name) and stop announcing any
+// other services which were previously announced. Files are only written when
+// their contents change to avoid unnecessary re-announcements
+func (a *Announcer) Sync(services map[string]Service) error {
+	if err := os.MkdirAll(a.Dir, 0755); err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for key, service := range services {
+		if err := service.Validate(); err != nil {
+			slog.Warn("Ignoring invalid mDNS service.", "container", key, "err", err)
+			continue
+		}
+		contents, err := renderService(service)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		path := a.path(key)
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, contents) {
+			continue
+		}
+		slog.Info("Announcing service.", "container", key, "type", service.Type, "port", service.Port, "path", path)
+		if err := os.WriteFile(path, contents, 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	existing, err := filepath.Glob(filepath.Join(a.Dir, filePrefix+"*.service"))
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, path := range existing {
+		key := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), filePrefix), ".service")
+		if _, ok := services[key]; ok {
+			continue
+		}
+		slog.Info("Removing service announcement.", "container", key, "path", path)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
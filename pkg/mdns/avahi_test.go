@@ -0,0 +1,36 @@
+package mdns
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sync(t *testing.T) {
+	dir := t.TempDir()
+	announcer := NewAnnouncer(dir)
+
+	// Files which are not managed by the announcer are left untouched
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ssh.service"), []byte(""), 0644))
+
+	err := announcer.Sync(map[string]Service{
+		"app1": {Name: "app1 on %h", Type: "_http._tcp", Port: 8080, TXT: []string{"path=/"}},
+		"app2": {Name: "app2", Type: "invalid", Port: 8081},
+	})
+	assert.NoError(t, err)
+
+	contents, err := os.ReadFile(filepath.Join(dir, "tedge-container-app1.service"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), `<name replace-wildcards="yes">app1 on %h</name>`)
+	assert.Contains(t, string(contents), "<type>_http._tcp</type>")
+	assert.Contains(t, string(contents), "<port>8080</port>")
+	assert.Contains(t, string(contents), "<txt-record>path=/</txt-record>")
+	assert.NoFileExists(t, filepath.Join(dir, "tedge-container-app2.service"))
+
+	// Stale announcements are removed
+	assert.NoError(t, announcer.Sync(map[string]Service{}))
+	assert.NoFileExists(t, filepath.Join(dir, "tedge-container-app1.service"))
+	assert.FileExists(t, filepath.Join(dir, "ssh.service"))
+}
@@ -0,0 +1,173 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// Supported function codes
+const (
+	FuncReadDiscreteInputs   byte = 0x02
+	FuncReadHoldingRegisters byte = 0x03
+	FuncReadInputRegisters   byte = 0x04
+)
+
+// Exception codes
+const (
+	ExceptionIllegalFunction    byte = 0x01
+	ExceptionIllegalDataAddress byte = 0x02
+	ExceptionIllegalDataValue   byte = 0x03
+)
+
+const (
+	mbapHeaderSize = 7
+	maxRegisters   = 125
+	maxBits        = 2000
+)
+
+// Minimal read-only Modbus TCP server. The holding and input registers share the same values,
+// and a discrete input is set if the register with the same address is not zero.
+// Registers which are not set are read as zero
+type Server struct {
+	Addr string
+
+	mu        sync.RWMutex
+	registers map[uint16]uint16
+}
+
+func NewServer(addr string) *Server {
+	return &Server{
+		Addr:      addr,
+		registers: make(map[uint16]uint16),
+	}
+}
+
+// Replace all of the register values
+func (s *Server) SetRegisters(values map[uint16]uint16) {
+	registers := make(map[uint16]uint16, len(values))
+	for k, v := range values {
+		registers[k] = v
+	}
+	s.mu.Lock()
+	s.registers = registers
+	s.mu.Unlock()
+}
+
+func (s *Server) register(address uint16) uint16 {
+	return s.registers[address]
+}
+
+// Listen for connections until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("Modbus TCP server is listening.", "address", listener.Addr().String())
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Warn("Could not accept modbus connection.", "err", err)
+			continue
+		}
+		go s.handleConnection(ctx, conn)
+	}
+}
+
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	header := make([]byte, mbapHeaderSize)
+	for ctx.Err() == nil {
+		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Debug("Closing modbus connection.", "remote", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		// The length includes the unit id which is part of the header
+		length := binary.BigEndian.Uint16(header[4:6])
+		if binary.BigEndian.Uint16(header[2:4]) != 0 || length < 2 || length > 256 {
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := s.Process(pdu)
+		frame := make([]byte, mbapHeaderSize, mbapHeaderSize+len(response))
+		copy(frame, header[0:4])
+		binary.BigEndian.PutUint16(frame[4:6], uint16(len(response)+1))
+		frame[6] = header[6]
+		frame = append(frame, response...)
+		if _, err := conn.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+func exception(function byte, code byte) []byte {
+	return []byte{function | 0x80, code}
+}
+
+// Process a request PDU (function code and data) and return the response PDU
+func (s *Server) Process(pdu []byte) []byte {
+	if len(pdu) == 0 {
+		return exception(0, ExceptionIllegalFunction)
+	}
+	function := pdu[0]
+	switch function {
+	case FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+	default:
+		return exception(function, ExceptionIllegalFunction)
+	}
+	if len(pdu) != 5 {
+		return exception(function, ExceptionIllegalDataValue)
+	}
+	address := binary.BigEndian.Uint16(pdu[1:3])
+	quantity := binary.BigEndian.Uint16(pdu[3:5])
+
+	limit := uint16(maxRegisters)
+	if function == FuncReadDiscreteInputs {
+		limit = maxBits
+	}
+	if quantity == 0 || quantity > limit {
+		return exception(function, ExceptionIllegalDataValue)
+	}
+	if uint32(address)+uint32(quantity) > 0x10000 {
+		return exception(function, ExceptionIllegalDataAddress)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if function == FuncReadDiscreteInputs {
+		data := make([]byte, (quantity+7)/8)
+		for i := uint16(0); i < quantity; i++ {
+			if s.register(address+i) != 0 {
+				data[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{function, byte(len(data))}, data...)
+	}
+
+	data := make([]byte, 2*quantity)
+	for i := uint16(0); i < quantity; i++ {
+		binary.BigEndian.PutUint16(data[2*i:], s.register(address+i))
+	}
+	return append([]byte{function, byte(len(data))}, data...)
+}
@@ -0,0 +1,33 @@
+package modbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Process(t *testing.T) {
+	server := NewServer("")
+	server.SetRegisters(map[uint16]uint16{
+		0:   3,
+		1:   2,
+		2:   1,
+		101: 1,
+		103: 1,
+	})
+
+	// Read input registers 0-2
+	assert.Equal(t, []byte{0x04, 6, 0, 3, 0, 2, 0, 1}, server.Process([]byte{0x04, 0, 0, 0, 3}))
+
+	// Holding registers use the same values
+	assert.Equal(t, []byte{0x03, 4, 0, 2, 0, 1}, server.Process([]byte{0x03, 0, 1, 0, 2}))
+
+	// Discrete inputs 100-103
+	assert.Equal(t, []byte{0x02, 1, 0b1010}, server.Process([]byte{0x02, 0, 100, 0, 4}))
+
+	// Errors
+	assert.Equal(t, []byte{0x86, ExceptionIllegalFunction}, server.Process([]byte{0x06, 0, 0, 0, 1}))
+	assert.Equal(t, []byte{0x84, ExceptionIllegalDataValue}, server.Process([]byte{0x04, 0, 0, 0, 0}))
+	assert.Equal(t, []byte{0x84, ExceptionIllegalDataValue}, server.Process([]byte{0x04, 0, 0, 0, 126}))
+	assert.Equal(t, []byte{0x84, ExceptionIllegalDataAddress}, server.Process([]byte{0x04, 0xFF, 0xFF, 0, 2}))
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron expression with the standard 5 fields: minute hour day-of-month month day-of-week.
+// Each field supports "*", single values, ranges (1-5), lists (1,2,3) and steps (*/15, 1-30/5)
+type Cron struct {
+	Expression string
+
+	minute     []bool
+	hour       []bool
+	dayOfMonth []bool
+	month      []bool
+	dayOfWeek  []bool
+
+	// The day matches if either the day of month or day of week match when both are restricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+func ParseCron(expr string) (*Cron, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression, expected 5 fields. expr=%s", expr)
+	}
+
+	values := make([][]bool, len(cronFields))
+	for i, field := range cronFields {
+		v, err := parseCronField(parts[i], field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression. expr=%s, err=%w", expr, err)
+		}
+		values[i] = v
+	}
+	return &Cron{
+		Expression:    expr,
+		minute:        values[0],
+		hour:          values[1],
+		dayOfMonth:    values[2],
+		month:         values[3],
+		dayOfWeek:     values[4],
+		anyDayOfMonth: parts[2] == "*",
+		anyDayOfWeek:  parts[4] == "*",
+	}, nil
+}
+
+func parseCronField(value string, field cronField) ([]bool, error) {
+	matches := make([]bool, field.max+1)
+	for _, item := range strings.Split(value, ",") {
+		rangeValue, stepValue, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			v, err := strconv.Atoi(stepValue)
+			if err != nil || v < 1 {
+				return nil, fmt.Errorf("invalid %s step. value=%s", field.name, item)
+			}
+			step = v
+		}
+
+		start, end := field.min, field.max
+		if rangeValue != "*" {
+			startValue, endValue, isRange := strings.Cut(rangeValue, "-")
+			v, err := strconv.Atoi(startValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s. value=%s", field.name, item)
+			}
+			start, end = v, v
+			if isRange {
+				if end, err = strconv.Atoi(endValue); err != nil {
+					return nil, fmt.Errorf("invalid %s range. value=%s", field.name, item)
+				}
+			} else if hasStep {
+				end = field.max
+			}
+		}
+		if start < field.min || end > field.max || start > end {
+			return nil, fmt.Errorf("%s out of range (%d-%d). value=%s", field.name, field.min, field.max, item)
+		}
+		for i := start; i <= end; i += step {
+			matches[i] = true
+		}
+	}
+	return matches, nil
+}
+
+// Check if the time matches the expression (to the minute)
+func (c *Cron) Matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	dayOfMonth := c.dayOfMonth[t.Day()]
+	dayOfWeek := c.dayOfWeek[int(t.Weekday())]
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
@@ -0,0 +1,51 @@
+package schedule
+
+import (
+	"time"
+)
+
+// Limit how far ahead to search for the next window
+const maxSearch = 366 * 24 * time.Hour
+
+// Maintenance window which starts at the times matching the cron expression, and lasts for the given duration
+type Window struct {
+	Start    *Cron
+	Duration time.Duration
+}
+
+func NewWindow(expr string, duration time.Duration) (*Window, error) {
+	start, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Window{
+		Start:    start,
+		Duration: duration,
+	}, nil
+}
+
+// Check if the given time is within a maintenance window
+func (w *Window) Contains(t time.Time) bool {
+	start := t.Truncate(time.Minute)
+	for s := start; t.Sub(s) < w.Duration; s = s.Add(-time.Minute) {
+		if w.Start.Matches(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the start of the next maintenance window. If the given time is already within
+// a window, then the given time is returned. False is returned if no window could be found
+func (w *Window) Next(t time.Time) (time.Time, bool) {
+	if w.Contains(t) {
+		return t, true
+	}
+	start := t.Truncate(time.Minute).Add(time.Minute)
+	for s := start; s.Sub(t) < maxSearch; s = s.Add(time.Minute) {
+		if w.Start.Matches(s) {
+			return s, true
+		}
+	}
+	return time.Time{}, false
+}
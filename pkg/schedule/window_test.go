@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseCron(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "0 2 * * *", "*/15 1-5 * * 1-5", "0 0,12 1 */2 0"} {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func Test_CronMatchesDayOfWeekOrDayOfMonth(t *testing.T) {
+	cron, err := ParseCron("0 2 1 * 0")
+	assert.NoError(t, err)
+
+	// 2024-09-01 is a Sunday, 2024-09-08 is a Sunday, 2024-10-01 is a Tuesday
+	assert.True(t, cron.Matches(time.Date(2024, 9, 1, 2, 0, 0, 0, time.UTC)))
+	assert.True(t, cron.Matches(time.Date(2024, 9, 8, 2, 0, 0, 0, time.UTC)))
+	assert.True(t, cron.Matches(time.Date(2024, 10, 1, 2, 0, 0, 0, time.UTC)))
+	assert.False(t, cron.Matches(time.Date(2024, 9, 9, 2, 0, 0, 0, time.UTC)))
+}
+
+func Test_Window(t *testing.T) {
+	window, err := NewWindow("0 2 * * *", 2*time.Hour)
+	assert.NoError(t, err)
+
+	assert.True(t, window.Contains(time.Date(2024, 9, 1, 2, 0, 0, 0, time.UTC)))
+	assert.True(t, window.Contains(time.Date(2024, 9, 1, 3, 59, 0, 0, time.UTC)))
+	assert.False(t, window.Contains(time.Date(2024, 9, 1, 4, 0, 0, 0, time.UTC)))
+	assert.False(t, window.Contains(time.Date(2024, 9, 1, 1, 59, 0, 0, time.UTC)))
+
+	now := time.Date(2024, 9, 1, 12, 30, 0, 0, time.UTC)
+	next, ok := window.Next(now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 9, 2, 2, 0, 0, 0, time.UTC), next)
+
+	now = time.Date(2024, 9, 1, 2, 30, 0, 0, time.UTC)
+	next, ok = window.Next(now)
+	assert.True(t, ok)
+	assert.Equal(t, now, next)
+}
@@ -0,0 +1,228 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported signature schemes. The signature field is in the format <scheme>:<value>
+const (
+	// HMAC-SHA256 using a shared key, hex encoded
+	SchemeHMACSHA256 = "hmac-sha256"
+
+	// Ed25519 signature using the backend's private key, base64 encoded
+	SchemeEd25519 = "ed25519"
+)
+
+// Name of the command payload field which contains the signature
+var FieldSignature = "signature"
+
+// Mandatory command payload field (unix timestamp) after which the command is rejected
+var FieldExpiresAt = "expiresAt"
+
+// Field which is added to the signed content, so that the signature is bound to the command's topic
+// (target, operation and command id) and can't be replayed against another container or command
+var FieldTopic = "@topic"
+
+// Maximum time until a command expires. Commands which are valid for longer are rejected, which
+// limits the time a command id has to be remembered to reject replays
+var MaxValidity = 15 * time.Minute
+
+// Fields which are changed while the command is processed, so they are excluded from the signature
+var unsignedFields = []string{FieldSignature, "status", "reason"}
+
+var ErrUnauthorized = errors.New("unauthorized command")
+
+// Verify that command payloads were signed by an authorized backend
+type Verifier struct {
+	HMACKeys   [][]byte
+	PublicKeys []ed25519.PublicKey
+
+	// Commands (by topic) which were already accepted, and when they expire
+	mutex sync.Mutex
+	used  map[string]time.Time
+}
+
+// Create a verifier from the device provisioned keys. The HMAC key file can contain multiple keys
+// (one per line), and the public key file can contain multiple PEM encoded Ed25519 public keys to allow key rotation
+func NewVerifier(hmacKeyFile string, publicKeyFile string) (*Verifier, error) {
+	verifier := &Verifier{}
+	if hmacKeyFile != "" {
+		contents, err := os.ReadFile(hmacKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(contents), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				verifier.HMACKeys = append(verifier.HMACKeys, []byte(line))
+			}
+		}
+	}
+	if publicKeyFile != "" {
+		contents, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := ParsePublicKeys(contents)
+		if err != nil {
+			return nil, err
+		}
+		verifier.PublicKeys = keys
+	}
+	if len(verifier.HMACKeys) == 0 && len(verifier.PublicKeys) == 0 {
+		return nil, fmt.Errorf("no command verification keys found. hmac_key_file=%s, public_key_file=%s", hmacKeyFile, publicKeyFile)
+	}
+	return verifier, nil
+}
+
+// Parse PEM encoded Ed25519 public keys
+func ParsePublicKeys(contents []byte) ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0)
+	for {
+		block, rest := pem.Decode(contents)
+		if block == nil {
+			break
+		}
+		contents = rest
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("only ed25519 public keys are supported. type=%T", key)
+		}
+		keys = append(keys, publicKey)
+	}
+	return keys, nil
+}
+
+// Get the content of a command payload which is covered by the signature.
+// The unsigned fields are removed, the command's topic is added and the keys are sorted, so the result
+// does not depend on the field order
+func CanonicalPayload(topic string, payload []byte) ([]byte, map[string]any, error) {
+	fields := make(map[string]any)
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, nil, err
+	}
+	signed := make(map[string]any, len(fields))
+	for key, value := range fields {
+		signed[key] = value
+	}
+	for _, key := range unsignedFields {
+		delete(signed, key)
+	}
+	signed[FieldTopic] = topic
+	canonical, err := json.Marshal(signed)
+	return canonical, fields, err
+}
+
+// Sign a command payload (which is published on the given topic) using HMAC-SHA256 and return the signature field value
+func SignHMAC(key []byte, topic string, payload []byte) (string, error) {
+	canonical, _, err := CanonicalPayload(topic, payload)
+	if err != nil {
+		return "", err
+	}
+	return SchemeHMACSHA256 + ":" + hex.EncodeToString(computeHMAC(key, canonical)), nil
+}
+
+func computeHMAC(key []byte, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// Verify the signature of a command payload which was received on the given topic. The command must expire
+// within the maximum validity, and each command (topic) is only accepted once
+func (v *Verifier) Verify(topic string, payload []byte) error {
+	canonical, fields, err := CanonicalPayload(topic, payload)
+	if err != nil {
+		return fmt.Errorf("%w, invalid payload. err=%w", ErrUnauthorized, err)
+	}
+
+	value, _ := fields[FieldSignature].(string)
+	scheme, encoded, found := strings.Cut(value, ":")
+	if !found {
+		return fmt.Errorf("%w, missing or invalid signature", ErrUnauthorized)
+	}
+
+	if err := v.verifySignature(scheme, encoded, canonical); err != nil {
+		return err
+	}
+
+	expiry, ok := fields[FieldExpiresAt].(float64)
+	if !ok {
+		return fmt.Errorf("%w, missing %s field", ErrUnauthorized, FieldExpiresAt)
+	}
+	expiresAt := time.Unix(int64(expiry), 0)
+	now := time.Now()
+	if now.After(expiresAt) {
+		return fmt.Errorf("%w, command has expired. expiresAt=%d", ErrUnauthorized, expiresAt.Unix())
+	}
+	if expiresAt.Sub(now) > MaxValidity {
+		return fmt.Errorf("%w, command expires too far in the future. expiresAt=%d, maxValidity=%s", ErrUnauthorized, expiresAt.Unix(), MaxValidity)
+	}
+	return v.markUsed(topic, expiresAt, now)
+}
+
+// Remember an accepted command until it expires, and reject it if it was already accepted
+func (v *Verifier) markUsed(topic string, expiresAt time.Time, now time.Time) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.used == nil {
+		v.used = make(map[string]time.Time)
+	}
+	for key, expiry := range v.used {
+		if now.After(expiry) {
+			delete(v.used, key)
+		}
+	}
+	if _, ok := v.used[topic]; ok {
+		return fmt.Errorf("%w, command was already used. topic=%s", ErrUnauthorized, topic)
+	}
+	v.used[topic] = expiresAt
+	return nil
+}
+
+func (v *Verifier) verifySignature(scheme string, encoded string, message []byte) error {
+	switch scheme {
+	case SchemeHMACSHA256:
+		signature, err := hex.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("%w, invalid signature encoding. err=%w", ErrUnauthorized, err)
+		}
+		for _, key := range v.HMACKeys {
+			if hmac.Equal(signature, computeHMAC(key, message)) {
+				return nil
+			}
+		}
+	case SchemeEd25519:
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("%w, invalid signature encoding. err=%w", ErrUnauthorized, err)
+		}
+		for _, key := range v.PublicKeys {
+			if ed25519.Verify(key, message, signature) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("%w, unsupported signature scheme. scheme=%s", ErrUnauthorized, scheme)
+	}
+	return fmt.Errorf("%w, signature does not match any of the authorized keys", ErrUnauthorized)
+}
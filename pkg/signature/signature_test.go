@@ -0,0 +1,82 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTopic = "te/device/main/service/app1/cmd/container_restart/c8y-123"
+
+func withSignature(t *testing.T, payload map[string]any, signature string) []byte {
+	payload[FieldSignature] = signature
+	b, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	return b
+}
+
+func Test_VerifyHMAC(t *testing.T) {
+	verifier := &Verifier{HMACKeys: [][]byte{[]byte("old"), []byte("secret")}}
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	command := withSignature(t, map[string]any{"status": "init", "name": "app1", "action": "activate", FieldExpiresAt: expiresAt}, "")
+	signature, err := SignHMAC([]byte("secret"), testTopic, command)
+	assert.NoError(t, err)
+
+	// Field order and unsigned fields don't change the signature
+	valid := withSignature(t, map[string]any{"action": "activate", "name": "app1", "status": "executing", FieldExpiresAt: expiresAt}, signature)
+	assert.NoError(t, verifier.Verify(testTopic, valid))
+
+	// The same command can't be replayed
+	assert.True(t, errors.Is(verifier.Verify(testTopic, valid), ErrUnauthorized))
+
+	// The signature is bound to the command's topic (target and command id)
+	assert.True(t, errors.Is(verifier.Verify("te/device/main/service/app2/cmd/container_restart/c8y-123", valid), ErrUnauthorized))
+	assert.True(t, errors.Is(verifier.Verify("te/device/main/service/app1/cmd/container_restart/c8y-456", valid), ErrUnauthorized))
+
+	tampered := withSignature(t, map[string]any{"action": "deactivate", "name": "app1", "status": "init", FieldExpiresAt: expiresAt}, signature)
+	assert.True(t, errors.Is(verifier.Verify(testTopic, tampered), ErrUnauthorized))
+
+	unsigned := []byte(`{"status":"init","name":"app1","action":"activate"}`)
+	assert.True(t, errors.Is(verifier.Verify(testTopic, unsigned), ErrUnauthorized))
+
+	otherKey, err := SignHMAC([]byte("other"), testTopic, command)
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(verifier.Verify(testTopic, withSignature(t, map[string]any{"action": "activate", "name": "app1", FieldExpiresAt: expiresAt}, otherKey)), ErrUnauthorized))
+}
+
+func Test_VerifyEd25519(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err)
+	keys, err := ParsePublicKeys(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(t, err)
+	verifier := &Verifier{PublicKeys: keys}
+
+	sign := func(command map[string]any) []byte {
+		canonical, _, err := CanonicalPayload(testTopic, withSignature(t, command, ""))
+		assert.NoError(t, err)
+		return withSignature(t, command, SchemeEd25519+":"+base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, canonical)))
+	}
+
+	command := map[string]any{"name": "app1", "action": "activate", FieldExpiresAt: time.Now().Add(time.Minute).Unix()}
+	assert.NoError(t, verifier.Verify(testTopic, sign(command)))
+
+	// Expired commands and commands without an expiry are rejected even if the signature is valid
+	command[FieldExpiresAt] = time.Now().Add(-time.Minute).Unix()
+	assert.True(t, errors.Is(verifier.Verify(testTopic, sign(command)), ErrUnauthorized))
+
+	command[FieldExpiresAt] = time.Now().Add(MaxValidity + time.Minute).Unix()
+	assert.True(t, errors.Is(verifier.Verify(testTopic, sign(command)), ErrUnauthorized))
+
+	delete(command, FieldExpiresAt)
+	assert.True(t, errors.Is(verifier.Verify(testTopic, sign(command)), ErrUnauthorized))
+}
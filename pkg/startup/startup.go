@@ -0,0 +1,93 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Default interval between two checks of a dependency
+var DefaultInterval = 1 * time.Second
+
+// Maximum interval between two checks when backing off
+var MaxInterval = 10 * time.Second
+
+var ErrTimeout = errors.New("timed out")
+
+// Strategy waits for a dependency (e.g. the MQTT broker) to be ready before the monitor is started
+type Strategy struct {
+	// Name of the dependency used in the log messages
+	Name string
+
+	// Maximum duration to wait. 0 = wait forever
+	Timeout time.Duration
+
+	// Initial interval between two checks. The interval is doubled after each failed check (up to MaxInterval)
+	Interval time.Duration
+
+	// Check if the dependency is ready. A nil error means the dependency is ready
+	Check func(ctx context.Context) error
+}
+
+// Wait until the dependency is ready, the timeout is reached or the context is cancelled
+func (s Strategy) Wait(ctx context.Context) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := s.Check(ctx)
+		if err == nil {
+			slog.Info("Dependency is ready.", "name", s.Name, "attempts", attempt, "duration", time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		slog.Info("Waiting for dependency.", "name", s.Name, "attempt", attempt, "reason", err)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				slog.Error("Timed out waiting for dependency.", "name", s.Name, "timeout", s.Timeout, "reason", err)
+				return fmt.Errorf("%w waiting for %s after %s. %w", ErrTimeout, s.Name, s.Timeout, err)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		interval = min(interval*2, MaxInterval)
+	}
+}
+
+// TCP checks if a connection can be opened to the given address, e.g. the MQTT broker
+func TCP(address string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		dialer := net.Dialer{Timeout: 5 * time.Second}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// WaitAll waits for each of the strategies in order, and stops at the first one which is not ready
+func WaitAll(ctx context.Context, strategies ...Strategy) error {
+	for _, strategy := range strategies {
+		if err := strategy.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
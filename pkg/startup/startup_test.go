@@ -0,0 +1,54 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WaitUntilReady(t *testing.T) {
+	attempts := 0
+	strategy := Strategy{
+		Name:     "test",
+		Timeout:  time.Second,
+		Interval: time.Millisecond,
+		Check: func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("not ready")
+			}
+			return nil
+		},
+	}
+	assert.NoError(t, strategy.Wait(context.Background()))
+	assert.Equal(t, 3, attempts)
+}
+
+func Test_WaitTimeout(t *testing.T) {
+	strategy := Strategy{
+		Name:     "test",
+		Timeout:  20 * time.Millisecond,
+		Interval: time.Millisecond,
+		Check: func(ctx context.Context) error {
+			return errors.New("not ready")
+		},
+	}
+	err := strategy.Wait(context.Background())
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorContains(t, err, "not ready")
+}
+
+func Test_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+
+	assert.NoError(t, TCP(address)(context.Background()))
+
+	listener.Close()
+	assert.Error(t, TCP(address)(context.Background()))
+}
@@ -0,0 +1,53 @@
+package tedge
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var AlarmSeverityCritical = "critical"
+var AlarmSeverityMajor = "major"
+var AlarmSeverityMinor = "minor"
+var AlarmSeverityWarning = "warning"
+
+func PayloadAlarm(clock *Clock, payload map[string]any, text string, severity string) ([]byte, error) {
+	payload["text"] = text
+	payload["severity"] = severity
+	clock.SetTime(payload)
+	b, err := json.Marshal(payload)
+	return b, err
+}
+
+// Get the alarm topic for the given target and alarm type
+func GetAlarmTopic(target Target, alarmType string) string {
+	return GetTopic(target, "a", alarmType)
+}
+
+// Publish a single message using a short lived MQTT connection.
+// This is intended for short running commands (e.g. sm-plugins) which don't
+// register themselves as a service. An empty retained payload clears the retained message
+func PublishOnce(clientID string, config *ClientConfig, topic string, retained bool, payload []byte) error {
+	opts, _ := newMQTTClientOptions(config)
+	opts.SetClientID(clientID)
+	opts.SetCleanSession(true)
+	opts.SetConnectTimeout(5 * time.Second)
+
+	client := mqtt.NewClient(opts)
+	tok := client.Connect()
+	if !tok.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out connecting to broker")
+	}
+	if err := tok.Error(); err != nil {
+		return err
+	}
+	defer client.Disconnect(250)
+
+	tok = client.Publish(topic, 1, retained, payload)
+	if !tok.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("%w. topic=%s", ErrPublishTimeout, topic)
+	}
+	return tok.Error()
+}
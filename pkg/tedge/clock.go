@@ -0,0 +1,95 @@
+package tedge
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type TimeMode string
+
+const (
+	// Use the device's local time
+	TimeModeLocal TimeMode = "local"
+
+	// Don't include timestamps, and let the mapper set them
+	TimeModeOmit TimeMode = "omit"
+
+	// Omit timestamps until the system clock is synchronized (e.g. via NTP),
+	// then use a monotonic clock anchored to the synchronized time
+	TimeModeSynced TimeMode = "synced"
+)
+
+// Clock provides the timestamps which are included in the published messages
+type Clock struct {
+	Mode TimeMode
+
+	// Interval to check if the system clock is synchronized
+	CheckInterval time.Duration
+
+	// Check if the system clock is synchronized. Defaults to the kernel's clock state
+	isSynchronized func() bool
+
+	mutex     sync.Mutex
+	anchor    time.Time
+	synced    bool
+	lastCheck time.Time
+}
+
+func NewClock(mode TimeMode) *Clock {
+	if mode == "" {
+		mode = TimeModeLocal
+	}
+	return &Clock{
+		Mode:           mode,
+		CheckInterval:  60 * time.Second,
+		isSynchronized: ntpSynchronized,
+	}
+}
+
+// Now returns the current time, and false if no timestamp should be used
+func (c *Clock) Now() (time.Time, bool) {
+	if c == nil {
+		return time.Now(), true
+	}
+	switch c.Mode {
+	case TimeModeOmit:
+		return time.Time{}, false
+	case TimeModeSynced:
+		return c.syncedNow()
+	default:
+		return time.Now(), true
+	}
+}
+
+func (c *Clock) syncedNow() (time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.synced && time.Since(c.lastCheck) >= c.CheckInterval {
+		c.lastCheck = time.Now()
+		if c.isSynchronized() {
+			// time.Now() includes a monotonic clock reading, so any time derived
+			// from the anchor is not affected by later changes to the wall clock
+			c.anchor = time.Now()
+			c.synced = true
+			slog.Info("System clock is synchronized. Using monotonic time source.", "anchor", c.anchor)
+		}
+	}
+
+	if !c.synced {
+		return time.Time{}, false
+	}
+	return c.anchor.Add(time.Since(c.anchor)), true
+}
+
+// SetTime sets the "time" property (as a Unix timestamp) of the payload,
+// or removes it if no timestamp should be used
+func (c *Clock) SetTime(payload map[string]any) map[string]any {
+	if now, ok := c.Now(); ok {
+		payload["time"] = now.Unix()
+	} else {
+		delete(payload, "time")
+	}
+	return payload
+}
@@ -0,0 +1,12 @@
+package tedge
+
+import "golang.org/x/sys/unix"
+
+// Clock state returned by adjtimex when the clock is not synchronized
+const timeError = 5
+
+// Check if the kernel reports the system clock as being synchronized (e.g. by NTP)
+func ntpSynchronized() bool {
+	state, err := unix.Adjtimex(&unix.Timex{})
+	return err == nil && state != timeError
+}
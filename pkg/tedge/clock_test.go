@@ -0,0 +1,73 @@
+package tedge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClockNow(t *testing.T) {
+	testcases := []struct {
+		name         string
+		mode         TimeMode
+		synchronized bool
+		expectTime   bool
+	}{
+		{name: "default mode uses the local time", mode: "", synchronized: false, expectTime: true},
+		{name: "local", mode: TimeModeLocal, synchronized: false, expectTime: true},
+		{name: "omit", mode: TimeModeOmit, synchronized: true, expectTime: false},
+		{name: "synced with a synchronized clock", mode: TimeModeSynced, synchronized: true, expectTime: true},
+		{name: "synced with an unsynchronized clock", mode: TimeModeSynced, synchronized: false, expectTime: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewClock(tc.mode)
+			clock.isSynchronized = func() bool { return tc.synchronized }
+
+			before := time.Now()
+			now, ok := clock.Now()
+			assert.Equal(t, tc.expectTime, ok)
+			if tc.expectTime {
+				assert.False(t, now.Before(before))
+			} else {
+				assert.True(t, now.IsZero())
+			}
+
+			payload := clock.SetTime(map[string]any{"time": int64(0)})
+			_, hasTime := payload["time"]
+			assert.Equal(t, tc.expectTime, hasTime)
+		})
+	}
+}
+
+func Test_ClockSyncedRecheck(t *testing.T) {
+	synchronized := false
+	clock := NewClock(TimeModeSynced)
+	clock.CheckInterval = time.Hour
+	clock.isSynchronized = func() bool { return synchronized }
+
+	_, ok := clock.Now()
+	assert.False(t, ok)
+
+	// The clock state is only checked again after the check interval
+	synchronized = true
+	_, ok = clock.Now()
+	assert.False(t, ok)
+
+	clock.CheckInterval = 0
+	_, ok = clock.Now()
+	assert.True(t, ok)
+
+	// Once synchronized, the clock is not checked again
+	synchronized = false
+	_, ok = clock.Now()
+	assert.True(t, ok)
+}
+
+func Test_NilClock(t *testing.T) {
+	var clock *Clock
+	_, ok := clock.Now()
+	assert.True(t, ok)
+}
@@ -0,0 +1,128 @@
+package tedge
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Statuses of the thin-edge.io command lifecycle
+const (
+	CommandStatusInit       = "init"
+	CommandStatusExecuting  = "executing"
+	CommandStatusSuccessful = "successful"
+	CommandStatusFailed     = "failed"
+)
+
+// Command received on a <root>/<target>/cmd/<operation>/<id> topic.
+// The fields of the request are kept, so each status message includes the command's parameters
+type Command struct {
+	Topic     string
+	Operation string
+	ID        string
+
+	// Payload of the init message
+	Payload []byte
+
+	fields map[string]any
+}
+
+// Decode the init message's payload, e.g. into the operation specific parameters
+func (c *Command) Decode(v any) error {
+	return json.Unmarshal(c.Payload, v)
+}
+
+// Set a field which is included in the following status messages, e.g. the result of the command
+func (c *Command) Set(key string, value any) {
+	c.fields[key] = value
+}
+
+func (c *Command) statusPayload(status string, reason string) []byte {
+	c.fields["status"] = status
+	if reason != "" {
+		c.fields["reason"] = reason
+	} else {
+		delete(c.fields, "reason")
+	}
+	b, err := json.Marshal(c.fields)
+	if err != nil {
+		// Fallback to a payload which only contains the status, so the command does not remain in a pending state
+		b, _ = json.Marshal(map[string]any{"status": CommandStatusFailed, "reason": err.Error()})
+	}
+	return b
+}
+
+// Function which executes a command. Returning an error marks the command as failed, and the error is used as the reason
+type CommandFunc func(cmd *Command) error
+
+type CommandOptions struct {
+	// Optional check of the init message (and its topic) before the command is executed, e.g. to verify the
+	// command's signature. Commands which are rejected are marked as failed without being executed
+	Verify func(topic string, payload []byte) error
+}
+
+// Run a command through the lifecycle, where each status is published as a retained message on the command's topic:
+// init -> executing -> successful or failed. Messages which are not in the init state are ignored
+func runCommand(topic string, payload []byte, opts CommandOptions, handler CommandFunc, publish func(topic string, payload []byte) error) {
+	if len(payload) == 0 {
+		return
+	}
+	cmd := &Command{
+		Topic:     topic,
+		Operation: path.Base(path.Dir(topic)),
+		ID:        path.Base(topic),
+		Payload:   payload,
+		fields:    make(map[string]any),
+	}
+	if err := json.Unmarshal(payload, &cmd.fields); err != nil {
+		slog.Warn("Could not unmarshal command.", "topic", topic, "err", err)
+		return
+	}
+	if status, _ := cmd.fields["status"].(string); status != CommandStatusInit {
+		return
+	}
+
+	publishStatus := func(status string, reason string) {
+		if err := publish(topic, cmd.statusPayload(status, reason)); err != nil {
+			slog.Warn("Could not publish command status.", "topic", topic, "status", status, "err", err)
+		}
+	}
+
+	if opts.Verify != nil {
+		if err := opts.Verify(topic, payload); err != nil {
+			slog.Warn("Rejecting command.", "topic", topic, "err", err)
+			publishStatus(CommandStatusFailed, err.Error())
+			return
+		}
+	}
+
+	publishStatus(CommandStatusExecuting, "")
+	if err := handler(cmd); err != nil {
+		slog.Warn("Command failed.", "operation", cmd.Operation, "id", cmd.ID, "err", err)
+		publishStatus(CommandStatusFailed, err.Error())
+		return
+	}
+	publishStatus(CommandStatusSuccessful, "")
+}
+
+// Declare the command capability of the target, and execute the commands of the given operation
+func (c *Client) HandleCommands(target Target, operation string, opts CommandOptions, handler CommandFunc) error {
+	if err := c.Publish(GetTopic(target, "cmd", operation), 1, true, "{}"); err != nil {
+		return fmt.Errorf("could not declare command capability. operation=%s, err=%w", operation, err)
+	}
+	topic := GetTopic(target, "cmd", operation, "+")
+	slog.Info("Listening to commands on topic.", "topic", topic)
+	return c.Subscribe(topic, 1, func(_ mqtt.Client, m mqtt.Message) {
+		go runCommand(m.Topic(), m.Payload(), opts, handler, func(topic string, payload []byte) error {
+			return c.Publish(topic, 1, true, payload)
+		})
+	})
+}
+
+// Remove a previously declared command capability of the target, e.g. when the commands are disabled
+func (c *Client) RemoveCommandCapability(target Target, operation string) error {
+	return c.Publish(GetTopic(target, "cmd", operation), 1, true, "")
+}
@@ -0,0 +1,64 @@
+package tedge
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectStatuses(t *testing.T, payload string, opts CommandOptions, handler CommandFunc) []map[string]any {
+	messages := make([]map[string]any, 0)
+	runCommand("te/device/main///cmd/container_checkpoint/c8y-1", []byte(payload), opts, handler, func(topic string, payload []byte) error {
+		assert.Equal(t, "te/device/main///cmd/container_checkpoint/c8y-1", topic)
+		message := make(map[string]any)
+		assert.NoError(t, json.Unmarshal(payload, &message))
+		messages = append(messages, message)
+		return nil
+	})
+	return messages
+}
+
+func Test_runCommand(t *testing.T) {
+	messages := collectStatuses(t, `{"status":"init","name":"app1"}`, CommandOptions{}, func(cmd *Command) error {
+		assert.Equal(t, "container_checkpoint", cmd.Operation)
+		assert.Equal(t, "c8y-1", cmd.ID)
+		cmd.Set("checkpointId", "cp1")
+		return nil
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "executing", messages[0]["status"])
+	assert.Equal(t, "app1", messages[0]["name"])
+	assert.Equal(t, "successful", messages[1]["status"])
+	assert.Equal(t, "cp1", messages[1]["checkpointId"])
+
+	messages = collectStatuses(t, `{"status":"init","name":"app1"}`, CommandOptions{}, func(cmd *Command) error {
+		return errors.New("container is not running")
+	})
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "failed", messages[1]["status"])
+	assert.Equal(t, "container is not running", messages[1]["reason"])
+}
+
+func Test_runCommandIgnoredOrRejected(t *testing.T) {
+	executed := false
+	handler := func(cmd *Command) error {
+		executed = true
+		return nil
+	}
+
+	// Only commands in the init state are executed
+	assert.Empty(t, collectStatuses(t, `{"status":"executing","name":"app1"}`, CommandOptions{}, handler))
+	assert.Empty(t, collectStatuses(t, ``, CommandOptions{}, handler))
+
+	messages := collectStatuses(t, `{"status":"init","name":"app1"}`, CommandOptions{
+		Verify: func(topic string, payload []byte) error {
+			return errors.New("missing signature")
+		},
+	}, handler)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "failed", messages[0]["status"])
+	assert.Equal(t, "missing signature", messages[0]["reason"])
+	assert.False(t, executed)
+}
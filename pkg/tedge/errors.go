@@ -0,0 +1,21 @@
+package tedge
+
+import (
+	"errors"
+)
+
+var (
+	// The MQTT message was not acknowledged by the broker in time
+	ErrPublishTimeout = errors.New("timed out publishing message")
+
+	// The cloud rejected the request due to a conflict (e.g. a concurrent modification)
+	ErrCloudConflict = errors.New("cloud conflict")
+
+	// The managed object does not exist in the cloud (yet), e.g. the registration has not been processed
+	ErrCloudNotFound = errors.New("managed object not found")
+)
+
+// Check if an error is temporary and the action can be retried later
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrPublishTimeout) || errors.Is(err, ErrCloudConflict)
+}
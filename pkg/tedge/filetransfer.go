@@ -0,0 +1,80 @@
+package tedge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client for the thin-edge.io file transfer service, which is used to share files with
+// other thin-edge.io components (e.g. the mappers for uploading files to the cloud)
+type FileTransferClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewFileTransferClient(host string, port uint16, config *ClientConfig) *FileTransferClient {
+	client := &FileTransferClient{
+		BaseURL:    fmt.Sprintf("http://%s:%d", host, port),
+		HTTPClient: http.DefaultClient,
+	}
+	if fileExists(config.KeyFile) && fileExists(config.CertFile) {
+		client.BaseURL = fmt.Sprintf("https://%s:%d", host, port)
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: NewTLSConfig(config.KeyFile, config.CertFile, config.CAFile),
+			},
+		}
+	}
+	return client
+}
+
+// Get the url of a file in the file transfer repository
+func (c *FileTransferClient) URL(name string) string {
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return c.BaseURL + "/te/v1/files/" + strings.Join(parts, "/")
+}
+
+// Upload a file to the file transfer repository. The url of the uploaded file is returned
+func (c *FileTransferClient) Upload(ctx context.Context, name string, body io.Reader) (string, error) {
+	fileURL := c.URL(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fileURL, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to upload file. url=%s, status=%s", fileURL, resp.Status)
+	}
+	return fileURL, nil
+}
+
+// Download a file, e.g. from the file transfer repository. The client's certificates are used for https urls
+func (c *FileTransferClient) Download(ctx context.Context, fileURL string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to download file. url=%s, status=%s", fileURL, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
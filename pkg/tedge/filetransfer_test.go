@@ -0,0 +1,30 @@
+package tedge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileTransferUpload(t *testing.T) {
+	var gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := &FileTransferClient{BaseURL: server.URL, HTTPClient: server.Client()}
+	fileURL, err := client.Upload(context.Background(), "container-export/app1 image.tar", strings.NewReader("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/te/v1/files/container-export/app1%20image.tar", fileURL)
+	assert.Equal(t, "/te/v1/files/container-export/app1 image.tar", gotPath)
+	assert.Equal(t, "data", gotBody)
+}
@@ -0,0 +1,82 @@
+package tedge
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ContainerEntry is the thin-edge.io service which represents a container
+type ContainerEntry struct {
+	Target Target
+
+	// Shared is true if the service represents multiple containers (e.g. a compose project)
+	Shared bool
+}
+
+type twinContainer struct {
+	ContainerID string          `json:"containerId"`
+	Containers  []twinContainer `json:"containers"`
+}
+
+// Update the container index from a twin/container message
+func (c *Client) handleContainerTwinMessage(_ mqtt.Client, m mqtt.Message) {
+	topic := strings.TrimSuffix(m.Topic(), "/twin/container")
+	target, err := NewTargetFromTopic(topic)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Remove any existing entries for the target
+	for id, entry := range c.containers {
+		if entry.Target.Topic() == target.Topic() {
+			delete(c.containers, id)
+		}
+	}
+
+	if len(m.Payload()) == 0 {
+		return
+	}
+
+	payload := twinContainer{}
+	if err := json.Unmarshal(m.Payload(), &payload); err != nil {
+		slog.Warn("Could not unmarshal container twin message", "topic", m.Topic(), "err", err)
+		return
+	}
+	if payload.ContainerID != "" {
+		c.containers[payload.ContainerID] = ContainerEntry{
+			Target: *target,
+		}
+	}
+	for _, item := range payload.Containers {
+		if item.ContainerID != "" {
+			c.containers[item.ContainerID] = ContainerEntry{
+				Target: *target,
+				Shared: true,
+			}
+		}
+	}
+}
+
+// Lookup the service which represents the given container id.
+// The container id can either be the full or short (at least 12 characters) id
+func (c *Client) LookupContainer(containerID string) (ContainerEntry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if entry, ok := c.containers[containerID]; ok {
+		return entry, true
+	}
+	if len(containerID) >= 12 {
+		for id, entry := range c.containers {
+			if strings.HasPrefix(id, containerID) {
+				return entry, true
+			}
+		}
+	}
+	return ContainerEntry{}, false
+}
@@ -1,14 +1,28 @@
 package tedge
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
+// Template of the topic identifier of a device's services. {0} to {3} are replaced with the segments
+// of the device's topic identifier, and {name} with the name of the service
+var DefaultServiceTemplate = "{0}/{1}/service/{name}"
+
+var (
+	ErrInvalidTopicID         = errors.New("invalid topic identifier")
+	ErrInvalidServiceTemplate = errors.New("invalid service topic identifier template")
+)
+
 type Target struct {
 	RootPrefix    string
 	TopicID       string
 	CloudIdentity string
+
+	// Template of the topic identifier of the target's services. Empty = DefaultServiceTemplate
+	ServiceTemplate string
 }
 
 func (t *Target) ExternalID() string {
@@ -22,12 +36,86 @@ func (t *Target) Topic() string {
 	return GetTopic(*t)
 }
 
+func (t *Target) serviceTemplate() string {
+	if t.ServiceTemplate == "" {
+		return DefaultServiceTemplate
+	}
+	return t.ServiceTemplate
+}
+
+func expandServiceTemplate(template string, topicID string, name string) string {
+	segments := splitTopicID(topicID)
+	replacements := []string{"{name}", name}
+	for i, segment := range segments {
+		replacements = append(replacements, fmt.Sprintf("{%d}", i), segment)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
 func (t *Target) Service(name string) *Target {
-	target := NewTarget(t.RootPrefix, strings.Join(strings.Split(t.TopicID, "/")[0:2], "/")+"/service/"+name)
+	// The template is validated on startup, and the name can be a wildcard (e.g. for subscriptions), so only pad the segments
+	topicID := strings.Join(splitTopicID(expandServiceTemplate(t.serviceTemplate(), t.TopicID, name)), "/")
+	target := NewTarget(t.RootPrefix, topicID)
 	target.CloudIdentity = t.CloudIdentity
+	target.ServiceTemplate = t.ServiceTemplate
 	return target
 }
 
+// Get the name of the service from a topic of one of the target's services, e.g. te/device/main/service/app1/cmd/health/check
+func (t *Target) ServiceName(topic string) (string, bool) {
+	index := slices.Index(strings.Split(t.serviceTemplate(), "/"), "{name}")
+	prefix := strings.Count(t.RootPrefix, "/") + 1
+	parts := strings.Split(topic, "/")
+	if index < 0 || len(parts) <= prefix+index {
+		return "", false
+	}
+	return parts[prefix+index], parts[prefix+index] != ""
+}
+
+// Split a topic identifier into its 4 segments. Missing segments are empty
+func splitTopicID(topicID string) []string {
+	segments := strings.Split(topicID, "/")
+	for len(segments) < 4 {
+		segments = append(segments, "")
+	}
+	return segments
+}
+
+// Validate a topic identifier, and normalize it to the 4 segment format, e.g. device/child01 => device/child01//.
+// Identifiers of custom schemes are supported, however the segments can't contain MQTT wildcards,
+// and only the trailing segments can be empty
+func NormalizeTopicID(topicID string) (string, error) {
+	topicID = strings.TrimSpace(topicID)
+	segments := strings.Split(strings.TrimRight(topicID, "/"), "/")
+	if len(segments) > 4 {
+		return "", fmt.Errorf("%w, the identifier has more than 4 segments. topic_id=%s", ErrInvalidTopicID, topicID)
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return "", fmt.Errorf("%w, only the trailing segments can be empty. topic_id=%s", ErrInvalidTopicID, topicID)
+		}
+		if strings.ContainsAny(segment, "+#") {
+			return "", fmt.Errorf("%w, the segments can't contain wildcards. topic_id=%s", ErrInvalidTopicID, topicID)
+		}
+	}
+	return strings.Join(splitTopicID(strings.Join(segments, "/")), "/"), nil
+}
+
+// Check that the service template produces valid topic identifiers for the given device topic identifier
+func ValidateServiceTemplate(template string, topicID string) error {
+	if template == "" {
+		template = DefaultServiceTemplate
+	}
+	if !slices.Contains(strings.Split(template, "/"), "{name}") {
+		return fmt.Errorf("%w, {name} must be a complete segment. template=%s", ErrInvalidServiceTemplate, template)
+	}
+	serviceTopicID := expandServiceTemplate(template, topicID, "example")
+	if _, err := NormalizeTopicID(serviceTopicID); err != nil {
+		return fmt.Errorf("%w. template=%s, err=%w", ErrInvalidServiceTemplate, template, err)
+	}
+	return nil
+}
+
 func NewTarget(rootPrefix, topicID string) *Target {
 	if rootPrefix == "" {
 		rootPrefix = "te"
@@ -39,3 +39,45 @@ func Test_TargetExternalID(t *testing.T) {
 	target3 := target2.Service("foo")
 	assert.Equal(t, "device0001:device:child01:service:foo", target3.ExternalID())
 }
+
+func Test_TargetCustomServiceTemplate(t *testing.T) {
+	target := &Target{
+		RootPrefix:      "te",
+		TopicID:         "factory1/line2/robot3/",
+		ServiceTemplate: "{0}/{1}/{2}/{name}",
+	}
+	service := target.Service("app1")
+	assert.Equal(t, "te/factory1/line2/robot3/app1", service.Topic())
+	assert.Equal(t, "te/factory1/line2/robot3/+/cmd/health/check", GetTopic(*target.Service("+"), "cmd", "health", "check"))
+
+	name, ok := target.ServiceName("te/factory1/line2/robot3/app1/cmd/health/check")
+	assert.True(t, ok)
+	assert.Equal(t, "app1", name)
+
+	name, ok = NewTarget("te", "device/main//").ServiceName("te/device/main/service/app2/cmd/health/check")
+	assert.True(t, ok)
+	assert.Equal(t, "app2", name)
+}
+
+func Test_NormalizeTopicID(t *testing.T) {
+	topicID, err := NormalizeTopicID("device/child01")
+	assert.NoError(t, err)
+	assert.Equal(t, "device/child01//", topicID)
+
+	topicID, err = NormalizeTopicID("device/main//")
+	assert.NoError(t, err)
+	assert.Equal(t, "device/main//", topicID)
+
+	for _, value := range []string{"", "a/b/c/d/e", "device//service/app1", "device/+//"} {
+		_, err = NormalizeTopicID(value)
+		assert.ErrorIs(t, err, ErrInvalidTopicID, value)
+	}
+}
+
+func Test_ValidateServiceTemplate(t *testing.T) {
+	assert.NoError(t, ValidateServiceTemplate("", "device/main//"))
+	assert.NoError(t, ValidateServiceTemplate("{0}/{1}/{2}/{name}", "factory1/line2/robot3/"))
+	assert.ErrorIs(t, ValidateServiceTemplate("{0}/{1}/service", "device/main//"), ErrInvalidServiceTemplate)
+	assert.ErrorIs(t, ValidateServiceTemplate("{0}/{1}/{2}/{3}/{name}", "device/main//"), ErrInvalidServiceTemplate)
+	assert.ErrorIs(t, ValidateServiceTemplate("{0}/{1}/{2}/{name}", "device/main//"), ErrInvalidServiceTemplate)
+}
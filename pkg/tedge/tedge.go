@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"sync"
@@ -48,9 +49,22 @@ type Client struct {
 	Client           mqtt.Client
 	Target           Target
 	CumulocityClient *c8y.Client
+	Clock            *Clock
+
+	// Optional function called for each message which was successfully published, e.g. to mirror it to another broker
+	Mirror func(topic string, retained bool, payload []byte)
 
 	Entities map[string]any
 	mutex    sync.RWMutex
+
+	// Notifications of registered and removed entities
+	entityChanges chan EntityChange
+
+	// Index of container ids to services
+	containers map[string]ContainerEntry
+
+	subscriptions     map[string]byte
+	subscriptionMutex sync.Mutex
 }
 
 func fileExists(filePath string) bool {
@@ -112,6 +126,12 @@ type ClientConfig struct {
 
 	C8yHost string
 	C8yPort uint16
+
+	TimeMode TimeMode
+
+	// Override the MQTT client id, e.g. for one-off commands which run alongside the service.
+	// The last will (service is down) is only set when the service's own client id is used
+	ClientID string
 }
 
 func CumulocityClientFromConfig(useCerts bool, config *ClientConfig) *c8y.Client {
@@ -119,14 +139,17 @@ func CumulocityClientFromConfig(useCerts bool, config *ClientConfig) *c8y.Client
 	c8yURL := fmt.Sprintf("http://%s:%d/c8y", config.C8yHost, config.C8yPort)
 	if useCerts {
 		tlsconfig := NewTLSConfig(config.KeyFile, config.CertFile, config.CAFile)
-		transport := &http.Transport{TLSClientConfig: tlsconfig}
+		transport := &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsconfig,
+		}
 		httpClient = &http.Client{Transport: transport}
 		c8yURL = fmt.Sprintf("https://%s:%d/c8y", config.C8yHost, config.C8yPort)
 	}
 	return c8y.NewClient(httpClient, c8yURL, "", "", "", true)
 }
 
-func NewClient(parent Target, target Target, serviceName string, config *ClientConfig) *Client {
+func newMQTTClientOptions(config *ClientConfig) (*mqtt.ClientOptions, bool) {
 	opts := mqtt.NewClientOptions()
 	useCerts := fileExists(config.KeyFile) && fileExists(config.CertFile)
 	if useCerts && config.MqttPort != 1883 {
@@ -137,12 +160,29 @@ func NewClient(parent Target, target Target, serviceName string, config *ClientC
 	} else {
 		opts.AddBroker(fmt.Sprintf("tcp://%s:%d", config.MqttHost, config.MqttPort))
 	}
+	return opts, useCerts
+}
+
+// Options of a plain MQTT client (without a last will) which uses the same connection settings as the thin-edge.io client
+func NewMQTTClientOptions(clientID string, config *ClientConfig) *mqtt.ClientOptions {
+	opts, _ := newMQTTClientOptions(config)
+	opts.SetClientID(clientID)
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetKeepAlive(60 * time.Second)
+	return opts
+}
 
-	opts.SetClientID(serviceName)
-	opts.SetClientID(fmt.Sprintf("%s#%s", serviceName, target.Topic()))
+func NewClient(parent Target, target Target, serviceName string, config *ClientConfig) *Client {
+	opts, useCerts := newMQTTClientOptions(config)
+	if config.ClientID != "" {
+		opts.SetClientID(config.ClientID)
+	} else {
+		opts.SetClientID(fmt.Sprintf("%s#%s", serviceName, target.Topic()))
+		opts.SetWill(GetHealthTopic(target), PayloadHealthStatusDown(), 1, true)
+	}
 	opts.SetCleanSession(true)
 	// opts.SetOrderMatters(true)
-	opts.SetWill(GetHealthTopic(target), PayloadHealthStatusDown(), 1, true)
 	opts.SetAutoReconnect(true)
 	opts.SetAutoAckDisabled(false)
 	opts.SetResumeSubs(false)
@@ -152,7 +192,22 @@ func NewClient(parent Target, target Target, serviceName string, config *ClientC
 		slog.Info("MQTT Client is disconnected.", "err", err)
 	})
 
-	opts.SetOnConnectHandler(func(c mqtt.Client) {
+	c := &Client{
+		ServiceName:   serviceName,
+		Parent:        parent,
+		Target:        target,
+		Entities:      make(map[string]any),
+		entityChanges: make(chan EntityChange, 100),
+		containers:    make(map[string]ContainerEntry),
+		Clock:         NewClock(config.TimeMode),
+		subscriptions: map[string]byte{
+			target.RootPrefix + "/+/+/+/+":                           1,
+			GetTopic(*target.Service("+"), "cmd", "health", "check"): 1,
+			GetTopic(*target.Service("+"), "twin", "container"):      1,
+		},
+	}
+
+	opts.SetOnConnectHandler(func(mqttc mqtt.Client) {
 		slog.Info("MQTT Client is connected")
 
 		payload, err := PayloadRegistration(map[string]any{}, serviceName, "service", parent.TopicID)
@@ -160,7 +215,7 @@ func NewClient(parent Target, target Target, serviceName string, config *ClientC
 			slog.Error("Could not convert payload.", "err", err)
 			return
 		}
-		tok := c.Publish(GetTopicRegistration(target), 1, true, payload)
+		tok := mqttc.Publish(GetTopicRegistration(target), 1, true, payload)
 		<-tok.Done()
 		if err := tok.Error(); err != nil {
 			slog.Error("Failed to publish registration topic.", "err", err)
@@ -169,22 +224,20 @@ func NewClient(parent Target, target Target, serviceName string, config *ClientC
 		slog.Info("Registered service", "topic", GetTopicRegistration(target))
 
 		// Configure subscriptions
-		subscriptions := make(map[string]byte)
-		subscriptions[target.RootPrefix+"/+/+/+/+"] = 1
-		subscriptions[GetTopic(*target.Service("+"), "cmd", "health", "check")] = 1
+		subscriptions := c.getSubscriptions()
 		slog.Info("Subscribing to topics.", "topics", subscriptions)
-		tok = c.SubscribeMultiple(subscriptions, nil)
+		tok = mqttc.SubscribeMultiple(subscriptions, nil)
 		tok.Wait()
 
 		// Delay before publishing health status
 		// FIXME: This can be removed once thin-edge.io supports a registration API
 		time.Sleep(1000 * time.Millisecond)
-		payload, err = PayloadHealthStatus(map[string]any{}, StatusUp)
+		payload, err = json.Marshal(c.Clock.SetTime(map[string]any{"status": StatusUp}))
 		if err != nil {
 			return
 		}
 		topic := GetHealthTopic(target)
-		tok = c.Publish(topic, 1, true, payload)
+		tok = mqttc.Publish(topic, 1, true, payload)
 		<-tok.Done()
 		if err := tok.Error(); err != nil {
 			slog.Warn("Failed to publish health message.", "err", err)
@@ -193,25 +246,18 @@ func NewClient(parent Target, target Target, serviceName string, config *ClientC
 		slog.Info("Published health message.", "topic", topic, "payload", payload)
 	})
 
-	client := mqtt.NewClient(opts)
-
-	c8yclient := CumulocityClientFromConfig(useCerts, config)
+	c.Client = mqtt.NewClient(opts)
+	c.CumulocityClient = CumulocityClientFromConfig(useCerts, config)
 	slog.Info("MQTT Client options.", "clientID", opts.ClientID)
 
-	c := &Client{
-		ServiceName:      serviceName,
-		Client:           client,
-		Parent:           parent,
-		Target:           target,
-		CumulocityClient: c8yclient,
-		Entities:         make(map[string]any),
-	}
-
 	registrationTopics := GetTopic(*target.Service("+"))
 	slog.Info("Subscribing to registration topics.", "topic", registrationTopics)
 	c.Client.AddRoute(GetTopic(*target.Service("+")), func(mqttc mqtt.Client, m mqtt.Message) {
 		go c.handleRegistrationMessage(mqttc, m)
 	})
+	c.Client.AddRoute(GetTopic(*target.Service("+"), "twin", "container"), func(mqttc mqtt.Client, m mqtt.Message) {
+		go c.handleContainerTwinMessage(mqttc, m)
+	})
 	return c
 }
 
@@ -219,24 +265,71 @@ func (c *Client) handleRegistrationMessage(_ mqtt.Client, m mqtt.Message) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	change := EntityChange{
+		Topic: m.Topic(),
+	}
 	if len(m.Payload()) > 0 {
 		payload := make(map[string]any)
 		if err := json.Unmarshal(m.Payload(), &payload); err != nil {
 			slog.Warn("Could not unmarshal registration message", "err", err)
-		} else {
-			c.Entities[m.Topic()] = payload
+			return
 		}
+		c.Entities[m.Topic()] = payload
+		change.Type, _ = payload["type"].(string)
 	} else {
+		existing, ok := c.Entities[m.Topic()]
+		if !ok {
+			return
+		}
 		slog.Info("Removing entity from store.", "topic", m.Topic())
+		if payload, ok := existing.(map[string]any); ok {
+			change.Type, _ = payload["type"].(string)
+		}
 		delete(c.Entities, m.Topic())
+		change.Removed = true
+	}
+
+	// Don't block the message handler if the changes are not being consumed
+	select {
+	case c.entityChanges <- change:
+	default:
+		slog.Debug("Dropping entity change notification.", "topic", m.Topic())
+	}
+}
+
+func (c *Client) getSubscriptions() map[string]byte {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+	subscriptions := make(map[string]byte, len(c.subscriptions))
+	for topic, qos := range c.subscriptions {
+		subscriptions[topic] = qos
+	}
+	return subscriptions
+}
+
+// Subscribe to a topic with the given handler.
+// The subscription is restored automatically when the client reconnects
+func (c *Client) Subscribe(topic string, qos byte, handler mqtt.MessageHandler) error {
+	c.subscriptionMutex.Lock()
+	c.subscriptions[topic] = qos
+	c.subscriptionMutex.Unlock()
+
+	c.Client.AddRoute(topic, handler)
+	if !c.Client.IsConnected() {
+		return nil
 	}
+	tok := c.Client.Subscribe(topic, qos, nil)
+	if !tok.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("timed out")
+	}
+	return tok.Error()
 }
 
 // Connect the MQTT client to the thin-edge.io broker
 func (c *Client) Connect() error {
 	tok := c.Client.Connect()
 	if !tok.WaitTimeout(30 * time.Second) {
-		panic("Failed to connect to broker")
+		return errors.New("timed out connecting to broker")
 	}
 	<-tok.Done()
 	return tok.Error()
@@ -244,30 +337,165 @@ func (c *Client) Connect() error {
 
 // Delete a Cumulocity Managed object by External ID
 func (c *Client) DeleteCumulocityManagedObject(target Target) (bool, error) {
+	deleted, _, err := c.deleteCumulocityManagedObject(target)
+	return deleted, err
+}
+
+// Delete a Cumulocity Managed object by External ID, and retry if the request
+// failed due to a conflict (409) or server error (5xx)
+func (c *Client) DeleteCumulocityManagedObjectWithRetry(target Target, retries int, delay time.Duration) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		deleted, statusCode, err := c.deleteCumulocityManagedObject(target)
+		if err == nil || attempt >= retries || !isRetryableStatus(statusCode) {
+			return deleted, err
+		}
+		slog.Info("Retrying managed object deletion.", "name", target.ExternalID(), "status", statusCode, "attempt", attempt+1)
+		time.Sleep(delay * time.Duration(attempt+1))
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusConflict || statusCode >= 500
+}
+
+func wrapCloudError(statusCode int, err error) error {
+	if statusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %w", ErrCloudConflict, err)
+	}
+	return err
+}
+
+func (c *Client) deleteCumulocityManagedObject(target Target) (bool, int, error) {
 	slog.Info("Deleting service by external ID.", "name", target.ExternalID())
 	extID, resp, err := c.CumulocityClient.Identity.GetExternalID(context.Background(), "c8y_Serial", target.ExternalID())
 
 	if err != nil {
 		if resp != nil && resp.StatusCode() == http.StatusNotFound {
-			return false, nil
+			return false, resp.StatusCode(), nil
+		}
+		if resp != nil {
+			return false, resp.StatusCode(), wrapCloudError(resp.StatusCode(), err)
 		}
-		return false, err
+		return false, 0, err
 	}
 
-	if _, err := c.CumulocityClient.Inventory.Delete(context.Background(), extID.ManagedObject.ID); err != nil {
+	resp, err = c.CumulocityClient.Inventory.Delete(context.Background(), extID.ManagedObject.ID)
+	if err != nil {
 		slog.Warn("Failed to delete service", "id", extID.ManagedObject.ID, "err", err)
-		return false, err
+		if resp != nil {
+			if resp.StatusCode() == http.StatusNotFound {
+				// Already deleted
+				return false, resp.StatusCode(), nil
+			}
+			return false, resp.StatusCode(), wrapCloudError(resp.StatusCode(), err)
+		}
+		return false, 0, err
+	}
+	if resp == nil {
+		return true, 0, nil
+	}
+	return true, resp.StatusCode(), nil
+}
+
+// Update the fragments of a Cumulocity Managed object by External ID (via the local Cumulocity Proxy).
+// ErrCloudNotFound is returned if the managed object does not exist
+func (c *Client) UpdateCumulocityManagedObject(target Target, fragments map[string]any) error {
+	extID, resp, err := c.CumulocityClient.Identity.GetExternalID(context.Background(), "c8y_Serial", target.ExternalID())
+	if err != nil {
+		if resp != nil && resp.StatusCode() == http.StatusNotFound {
+			return fmt.Errorf("%w. name=%s", ErrCloudNotFound, target.ExternalID())
+		}
+		if resp != nil {
+			return wrapCloudError(resp.StatusCode(), err)
+		}
+		return err
+	}
+
+	_, resp, err = c.CumulocityClient.Inventory.Update(context.Background(), extID.ManagedObject.ID, fragments)
+	if err != nil && resp != nil {
+		return wrapCloudError(resp.StatusCode(), err)
 	}
-	return true, nil
+	return err
 }
 
 // Publish an MQTT message
 func (c *Client) Publish(topic string, qos byte, retained bool, payload any) error {
 	tok := c.Client.Publish(topic, 1, retained, payload)
-	if !tok.WaitTimeout(100 * time.Millisecond) {
-		return fmt.Errorf("timed out")
+	if !tok.WaitTimeout(PublishTimeout) {
+		return fmt.Errorf("%w. topic=%s", ErrPublishTimeout, topic)
+	}
+	if err := tok.Error(); err != nil {
+		return err
+	}
+	c.mirror(topic, retained, payload)
+	return nil
+}
+
+func (c *Client) mirror(topic string, retained bool, payload any) {
+	if c.Mirror != nil {
+		switch v := payload.(type) {
+		case []byte:
+			c.Mirror(topic, retained, v)
+		case string:
+			c.Mirror(topic, retained, []byte(v))
+		}
 	}
-	return tok.Error()
+}
+
+// Maximum duration to wait for a message to be published
+var PublishTimeout = 100 * time.Millisecond
+
+// Wait for the token to complete. A token which already completed is accepted even if the duration has passed
+func waitToken(tok mqtt.Token, d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-tok.Done():
+			return true
+		default:
+			return false
+		}
+	}
+	return tok.WaitTimeout(d)
+}
+
+// Message which is published as part of a batch
+type Message struct {
+	Topic    string
+	Retained bool
+	Payload  any
+}
+
+// Result of publishing a message of a batch
+type PublishResult struct {
+	Topic string
+	Err   error
+}
+
+// Publish the messages without waiting for each message to be acknowledged before publishing the next,
+// so the round trips to the broker overlap. The batch waits at most the publish timeout per message in total.
+// The results are in the same order as the messages
+func (c *Client) PublishBatch(messages []Message) []PublishResult {
+	tokens := make([]mqtt.Token, len(messages))
+	for i, msg := range messages {
+		tokens[i] = c.Client.Publish(msg.Topic, 1, msg.Retained, msg.Payload)
+	}
+
+	deadline := time.Now().Add(PublishTimeout * time.Duration(len(messages)))
+	results := make([]PublishResult, len(messages))
+	for i, tok := range tokens {
+		msg := messages[i]
+		results[i].Topic = msg.Topic
+		if !waitToken(tok, time.Until(deadline)) {
+			results[i].Err = fmt.Errorf("%w. topic=%s", ErrPublishTimeout, msg.Topic)
+			continue
+		}
+		if err := tok.Error(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		c.mirror(msg.Topic, msg.Retained, msg.Payload)
+	}
+	return results
 }
 
 // Deregister a thin-edge.io entity
@@ -294,9 +522,38 @@ func (c *Client) DeregisterEntity(target Target, retainedTopicPartials ...string
 	return nil
 }
 
-// Get the thin-edge.io entities that have already been registered (as retained messages)
+// Check if a registration message has been received for the given entity
+func (c *Client) HasEntity(target Target) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, ok := c.Entities[target.Topic()]
+	return ok
+}
+
+// Get a snapshot of the thin-edge.io entities that have already been registered (as retained messages).
+// The snapshot is a copy, so it can be read while the store is updated by new registration messages
 func (c *Client) GetEntities() (map[string]any, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	return c.Entities, nil
+	entities := make(map[string]any, len(c.Entities))
+	for topic, value := range c.Entities {
+		if payload, ok := value.(map[string]any); ok {
+			value = maps.Clone(payload)
+		}
+		entities[topic] = value
+	}
+	return entities, nil
+}
+
+// Registration or removal of an entity
+type EntityChange struct {
+	Topic   string
+	Type    string
+	Removed bool
+}
+
+// Get the notifications of registered and removed entities. Notifications are dropped
+// if they are not consumed, so the receiver should re-read the entities instead of relying on each change
+func (c *Client) EntityChanges() <-chan EntityChange {
+	return c.entityChanges
 }
@@ -0,0 +1,112 @@
+package tedge
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m testMessage) Duplicate() bool   { return false }
+func (m testMessage) Qos() byte         { return 1 }
+func (m testMessage) Retained() bool    { return true }
+func (m testMessage) Topic() string     { return m.topic }
+func (m testMessage) MessageID() uint16 { return 0 }
+func (m testMessage) Payload() []byte   { return m.payload }
+func (m testMessage) Ack()              {}
+
+func Test_GetEntities(t *testing.T) {
+	c := &Client{
+		Entities:      make(map[string]any),
+		entityChanges: make(chan EntityChange, 10),
+	}
+	topic := "te/device/main/service/app"
+	c.handleRegistrationMessage(nil, testMessage{topic: topic, payload: []byte(`{"@type":"service","type":"container"}`)})
+	assert.Equal(t, EntityChange{Topic: topic, Type: "container"}, <-c.EntityChanges())
+
+	// the snapshot is not affected by later changes
+	entities, err := c.GetEntities()
+	assert.NoError(t, err)
+	entities[topic].(map[string]any)["type"] = "modified"
+	c.handleRegistrationMessage(nil, testMessage{topic: topic})
+	assert.Equal(t, EntityChange{Topic: topic, Type: "container", Removed: true}, <-c.EntityChanges())
+	assert.Len(t, entities, 1)
+	assert.False(t, c.HasEntity(Target{RootPrefix: "te", TopicID: "device/main/service/app"}))
+
+	// removing an unknown entity is not a change
+	c.handleRegistrationMessage(nil, testMessage{topic: topic})
+	assert.Len(t, c.EntityChanges(), 0)
+}
+
+type testToken struct {
+	done chan struct{}
+	err  error
+}
+
+func newTestToken(complete bool, err error) *testToken {
+	tok := &testToken{done: make(chan struct{}), err: err}
+	if complete {
+		close(tok.done)
+	}
+	return tok
+}
+
+func (t *testToken) Wait() bool { <-t.done; return true }
+func (t *testToken) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+func (t *testToken) Done() <-chan struct{} { return t.done }
+func (t *testToken) Error() error          { return t.err }
+
+// MQTT client which completes the publish of each topic using the given token
+type testPublishClient struct {
+	mqtt.Client
+	tokens    map[string]*testToken
+	published []string
+}
+
+func (c *testPublishClient) Publish(topic string, qos byte, retained bool, payload any) mqtt.Token {
+	c.published = append(c.published, topic)
+	return c.tokens[topic]
+}
+
+func Test_PublishBatch(t *testing.T) {
+	mqttClient := &testPublishClient{
+		tokens: map[string]*testToken{
+			"a": newTestToken(true, nil),
+			"b": newTestToken(false, nil),
+			"c": newTestToken(true, errors.New("failed")),
+		},
+	}
+	mirrored := make([]string, 0)
+	c := &Client{
+		Client: mqttClient,
+		Mirror: func(topic string, retained bool, payload []byte) {
+			mirrored = append(mirrored, topic)
+		},
+	}
+
+	results := c.PublishBatch([]Message{
+		{Topic: "a", Payload: "1"},
+		{Topic: "b", Payload: "2"},
+		{Topic: "c", Payload: "3"},
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, mqttClient.published)
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, ErrPublishTimeout)
+	assert.EqualError(t, results[2].Err, "failed")
+	assert.Equal(t, []string{"a"}, mirrored)
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 func PathExists(p string) bool {
@@ -22,6 +24,27 @@ func CopyFile(src string, dst string) error {
 	return err
 }
 
+// Copy the contents of a directory (recursively) to another directory
+func CopyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return CopyFile(path, target)
+	})
+}
+
 func CommandExists(cmd string) bool {
 	_, err := exec.LookPath(cmd)
 	return err == nil
@@ -0,0 +1,300 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+)
+
+// Images used by the test environment. They can be overridden, e.g. to use a local registry mirror
+var (
+	MosquittoImage = getEnv("INTEGRATION_MOSQUITTO_IMAGE", "docker.io/library/eclipse-mosquitto:2")
+	EngineImage    = getEnv("INTEGRATION_ENGINE_IMAGE", "docker.io/library/docker:27-dind")
+	AppImage       = getEnv("INTEGRATION_APP_IMAGE", "docker.io/library/nginx:1-alpine")
+)
+
+// Maximum duration to wait for an expected message or state
+var WaitTimeout = 60 * time.Second
+
+func getEnv(key string, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// Environment is a throwaway set of dependencies: a mosquitto broker and an isolated container engine
+// (docker-in-docker), both running on the host's container engine. The containers are removed when the test ends
+type Environment struct {
+	// Container engine running the test dependencies
+	Host *client.Client
+
+	// Isolated container engine used by the code under test
+	Engine     *container.ContainerClient
+	EngineHost string
+
+	BrokerHost string
+	BrokerPort uint16
+
+	// Messages received from the broker
+	MQTT *Recorder
+}
+
+func NewEnvironment(t *testing.T) *Environment {
+	t.Helper()
+	ctx := context.Background()
+
+	host, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("could not connect to the host's container engine. %s", err)
+	}
+	if _, err := host.Ping(ctx); err != nil {
+		t.Skipf("container engine is not available. %s", err)
+	}
+	t.Cleanup(func() {
+		_ = host.Close()
+	})
+
+	env := &Environment{
+		Host:       host,
+		BrokerHost: "127.0.0.1",
+	}
+
+	brokerPort := startContainer(t, host, containerSDK.Config{
+		Image: MosquittoImage,
+		Cmd:   []string{"mosquitto", "-c", "/mosquitto-no-auth.conf"},
+	}, false, "1883/tcp")
+	env.BrokerPort = brokerPort
+
+	enginePort := startContainer(t, host, containerSDK.Config{
+		Image: EngineImage,
+		Env:   []string{"DOCKER_TLS_CERTDIR="},
+	}, true, "2375/tcp")
+	env.EngineHost = fmt.Sprintf("tcp://127.0.0.1:%d", enginePort)
+
+	// The code under test creates its own clients from the environment
+	t.Setenv("DOCKER_HOST", env.EngineHost)
+	engineClient, err := container.NewContainerClient()
+	if err != nil {
+		t.Fatalf("could not create container engine client. %s", err)
+	}
+	env.Engine = engineClient
+	t.Cleanup(func() {
+		_ = engineClient.Client.Close()
+	})
+
+	Eventually(t, "container engine is ready", func() error {
+		_, err := engineClient.Client.Ping(ctx)
+		return err
+	})
+
+	env.MQTT = NewRecorder(t, env.BrokerHost, env.BrokerPort)
+	return env
+}
+
+func pullImage(t *testing.T, c *client.Client, ref string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, _, err := c.ImageInspectWithRaw(ctx, ref); err == nil {
+		return
+	}
+	out, err := c.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		t.Fatalf("could not pull image. image=%s, err=%s", ref, err)
+	}
+	defer out.Close()
+	_, _ = io.Copy(io.Discard, out)
+}
+
+// Start a container and return the host port which the given container port is published on
+func startContainer(t *testing.T, c *client.Client, config containerSDK.Config, privileged bool, port nat.Port) uint16 {
+	t.Helper()
+	ctx := context.Background()
+	pullImage(t, c, config.Image)
+
+	config.ExposedPorts = nat.PortSet{port: struct{}{}}
+	resp, err := c.ContainerCreate(ctx, &config, &containerSDK.HostConfig{
+		Privileged: privileged,
+		PortBindings: nat.PortMap{
+			port: []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: ""}},
+		},
+	}, nil, nil, "")
+	if err != nil {
+		t.Fatalf("could not create container. image=%s, err=%s", config.Image, err)
+	}
+	t.Cleanup(func() {
+		_ = c.ContainerRemove(context.Background(), resp.ID, containerSDK.RemoveOptions{Force: true, RemoveVolumes: true})
+	})
+
+	if err := c.ContainerStart(ctx, resp.ID, containerSDK.StartOptions{}); err != nil {
+		t.Fatalf("could not start container. image=%s, err=%s", config.Image, err)
+	}
+
+	var hostPort uint16
+	Eventually(t, "container port is published", func() error {
+		info, err := c.ContainerInspect(ctx, resp.ID)
+		if err != nil {
+			return err
+		}
+		bindings := info.NetworkSettings.Ports[port]
+		if len(bindings) == 0 || bindings[0].HostPort == "" {
+			return fmt.Errorf("port is not published yet. port=%s", port)
+		}
+		v, err := strconv.ParseUint(bindings[0].HostPort, 10, 16)
+		if err != nil {
+			return err
+		}
+		hostPort = uint16(v)
+		return nil
+	})
+	return hostPort
+}
+
+// Run a container in the isolated container engine
+func (e *Environment) Run(t *testing.T, name string, imageRef string, cmd ...string) string {
+	t.Helper()
+	ctx := context.Background()
+	pullImage(t, e.Engine.Client, imageRef)
+	resp, err := e.Engine.Client.ContainerCreate(ctx, &containerSDK.Config{
+		Image: imageRef,
+		Cmd:   cmd,
+	}, nil, nil, nil, name)
+	if err != nil {
+		t.Fatalf("could not create container. name=%s, err=%s", name, err)
+	}
+	if err := e.Engine.Client.ContainerStart(ctx, resp.ID, containerSDK.StartOptions{}); err != nil {
+		t.Fatalf("could not start container. name=%s, err=%s", name, err)
+	}
+	return resp.ID
+}
+
+// Retry the check until it succeeds or WaitTimeout is reached
+func Eventually(t *testing.T, description string, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(WaitTimeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s. %s", description, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+type Message struct {
+	Topic    string
+	Payload  string
+	Retained bool
+	Time     time.Time
+}
+
+// Recorder stores all messages published to the broker so that tests can assert on the message sequences
+type Recorder struct {
+	client   mqtt.Client
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func NewRecorder(t *testing.T, host string, port uint16) *Recorder {
+	t.Helper()
+	r := &Recorder{
+		messages: make([]Message, 0),
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(fmt.Sprintf("tcp://%s:%d", host, port)).
+		SetClientID(fmt.Sprintf("integration-recorder-%d", time.Now().UnixNano())).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(500 * time.Millisecond)
+	r.client = mqtt.NewClient(opts)
+
+	tok := r.client.Connect()
+	if !tok.WaitTimeout(WaitTimeout) || tok.Error() != nil {
+		t.Fatalf("could not connect to broker. %v", tok.Error())
+	}
+	tok = r.client.Subscribe("#", 1, func(_ mqtt.Client, m mqtt.Message) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.messages = append(r.messages, Message{
+			Topic:    m.Topic(),
+			Payload:  string(m.Payload()),
+			Retained: m.Retained(),
+			Time:     time.Now(),
+		})
+	})
+	if !tok.WaitTimeout(WaitTimeout) || tok.Error() != nil {
+		t.Fatalf("could not subscribe to broker. %v", tok.Error())
+	}
+	t.Cleanup(func() {
+		r.client.Disconnect(250)
+	})
+	return r
+}
+
+// Get the messages published to the given topic
+func (r *Recorder) Messages(topic string) []Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	out := make([]Message, 0)
+	for _, m := range r.messages {
+		if m.Topic == topic {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Wait for a message on the given topic which contains all of the given values
+func (r *Recorder) WaitFor(t *testing.T, topic string, contains ...string) Message {
+	t.Helper()
+	var found Message
+	Eventually(t, fmt.Sprintf("message is received on %s containing %v", topic, contains), func() error {
+		messages := r.Messages(topic)
+		for i := len(messages) - 1; i >= 0; i-- {
+			if containsAll(messages[i].Payload, contains...) {
+				found = messages[i]
+				return nil
+			}
+		}
+		return fmt.Errorf("received %d messages on the topic", len(messages))
+	})
+	return found
+}
+
+// Wait until the retained message of the given topic was cleared
+func (r *Recorder) WaitForCleared(t *testing.T, topic string) {
+	t.Helper()
+	Eventually(t, fmt.Sprintf("retained message on %s is cleared", topic), func() error {
+		messages := r.Messages(topic)
+		if len(messages) > 0 && messages[len(messages)-1].Payload == "" {
+			return nil
+		}
+		return fmt.Errorf("last message was not empty")
+	})
+}
+
+func containsAll(payload string, values ...string) bool {
+	for _, v := range values {
+		if !strings.Contains(payload, v) {
+			return false
+		}
+	}
+	return true
+}
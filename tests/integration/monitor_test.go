@@ -0,0 +1,146 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	containerSDK "github.com/docker/docker/api/types/container"
+	containercmd "github.com/thin-edge/tedge-container-plugin/cli/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/app"
+	"github.com/thin-edge/tedge-container-plugin/pkg/cli"
+	"github.com/thin-edge/tedge-container-plugin/pkg/container"
+	"github.com/thin-edge/tedge-container-plugin/pkg/tedge"
+)
+
+func TestMain(m *testing.M) {
+	// Don't write the audit log, history etc. to the system paths
+	stateDir, err := os.MkdirTemp("", "tedge-container-integration")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("CONTAINER_MONITOR_AUDIT_ENABLED", "false")
+	os.Setenv("CONTAINER_MONITOR_HISTORY_PATH", stateDir+"/history.json")
+	os.Setenv("CONTAINER_MONITOR_ARCHIVE_PATH", stateDir+"/archive.json")
+
+	cliConfig := cli.Cli{}
+	cliConfig.OnInit()
+
+	code := m.Run()
+	_ = os.RemoveAll(stateDir)
+	os.Exit(code)
+}
+
+func serviceTopic(name string) string {
+	return tedge.GetTopicRegistration(*tedge.NewTarget("te", "device/main//").Service(name))
+}
+
+func healthTopic(name string) string {
+	return tedge.GetHealthTopic(*tedge.NewTarget("te", "device/main//").Service(name))
+}
+
+// Start the monitor against the test environment
+func startMonitor(t *testing.T, env *Environment) *app.App {
+	t.Helper()
+	device := tedge.Target{
+		RootPrefix:    "te",
+		TopicID:       "device/main//",
+		CloudIdentity: "integration",
+	}
+	application, err := app.NewApp(device, app.Config{
+		ServiceName:        "tedge-container-plugin",
+		EnableEngineEvents: true,
+		MQTTHost:           env.BrokerHost,
+		MQTTPort:           env.BrokerPort,
+		StateDir:           t.TempDir(),
+		UpdateWorkers:      2,
+	})
+	if err != nil {
+		t.Fatalf("could not create application. %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = application.Monitor(ctx, container.FilterOptions{})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer stopCancel()
+		_ = application.Stop(stopCtx, true)
+	})
+	return application
+}
+
+// Run a container plugin command (e.g. install or remove) like the software management plugin
+func runPluginCommand(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := containercmd.NewContainerCommand(cli.Cli{})
+	cmd.SetArgs(args)
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("command failed. args=%v, err=%s", args, err)
+	}
+}
+
+func TestMonitorContainerLifecycle(t *testing.T) {
+	env := NewEnvironment(t)
+	startMonitor(t, env)
+
+	name := fmt.Sprintf("lifecycle-%d", time.Now().Unix())
+	id := env.Run(t, name, AppImage)
+
+	env.MQTT.WaitFor(t, serviceTopic(name), `"@type":"service"`, `"name":"`+name+`"`)
+	env.MQTT.WaitFor(t, healthTopic(name), `"status":"up"`)
+
+	timeout := 5
+	if err := env.Engine.Client.ContainerStop(context.Background(), id, containerSDK.StopOptions{Timeout: &timeout}); err != nil {
+		t.Fatalf("could not stop container. %s", err)
+	}
+	env.MQTT.WaitFor(t, healthTopic(name), `"status":"down"`)
+	env.MQTT.WaitFor(t, "te/device/main/service/tedge-container-plugin/e/die", name)
+
+	if err := env.Engine.Client.ContainerRemove(context.Background(), id, containerSDK.RemoveOptions{}); err != nil {
+		t.Fatalf("could not remove container. %s", err)
+	}
+	env.MQTT.WaitForCleared(t, serviceTopic(name))
+}
+
+func TestInstallRemove(t *testing.T) {
+	env := NewEnvironment(t)
+	startMonitor(t, env)
+
+	name := fmt.Sprintf("install-%d", time.Now().Unix())
+	runPluginCommand(t, "install", name, "--module-version", AppImage)
+
+	Eventually(t, "installed container is running", func() error {
+		item, err := env.Engine.FindByServiceName(context.Background(), name)
+		if err != nil {
+			return err
+		}
+		if item.Container.State != "running" {
+			return fmt.Errorf("container is not running. state=%s", item.Container.State)
+		}
+		return nil
+	})
+	env.MQTT.WaitFor(t, healthTopic(name), `"status":"up"`)
+
+	runPluginCommand(t, "remove", name, "--module-version", AppImage)
+
+	Eventually(t, "container was removed", func() error {
+		items, err := env.Engine.List(context.Background(), container.FilterOptions{
+			Names: []string{fmt.Sprintf("^%s$", name)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			return fmt.Errorf("container still exists")
+		}
+		return nil
+	})
+	env.MQTT.WaitForCleared(t, serviceTopic(name))
+}